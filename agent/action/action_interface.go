@@ -19,3 +19,9 @@ type Action interface {
 	Resume() (interface{}, error)
 	Cancel() error
 }
+
+// ProgressReporter is implemented by asynchronous actions
+// that report progress of their running task via get_task
+type ProgressReporter interface {
+	Progress() interface{}
+}
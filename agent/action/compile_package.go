@@ -46,15 +46,21 @@ func (a CompilePackageAction) Run(blobID, sha1, name, version string, deps boshc
 		})
 	}
 
-	uploadedBlobID, uploadedSha1, err := a.compiler.Compile(pkg, modelsDeps)
+	compiled, err := a.compiler.Compile(pkg, modelsDeps)
 	if err != nil {
 		err = bosherr.WrapErrorf(err, "Compiling package %s", pkg.Name)
 		return
 	}
 
-	result := map[string]string{
-		"blobstore_id": uploadedBlobID,
-		"sha1":         uploadedSha1,
+	result := map[string]interface{}{
+		"blobstore_id": compiled.BlobstoreID,
+		"sha1":         compiled.Sha1,
+		"metadata":     compiled.Metadata,
+		"duration":     compiled.Duration.Seconds(),
+	}
+
+	if compiled.LogsBlobstoreID != "" {
+		result["logs_blobstore_id"] = compiled.LogsBlobstoreID
 	}
 
 	val = map[string]interface{}{
@@ -2,6 +2,7 @@ package action_test
 
 import (
 	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -55,8 +56,12 @@ var _ = Describe("CompilePackageAction", func() {
 
 	Describe("Run", func() {
 		It("compile package compiles the package abd returns blob id", func() {
-			compiler.CompileBlobID = "my-blob-id"
-			compiler.CompileSha1 = "some sha1"
+			compiler.CompileResult = boshcomp.Result{
+				BlobstoreID: "my-blob-id",
+				Sha1:        "some sha1",
+				Metadata:    boshcomp.Metadata{Name: "fake-package-name"},
+				Duration:    90 * time.Second,
+			}
 
 			expectedPkg := boshcomp.Package{
 				BlobstoreID: "fake-blobstore-id",
@@ -66,9 +71,11 @@ var _ = Describe("CompilePackageAction", func() {
 			}
 
 			expectedValue := map[string]interface{}{
-				"result": map[string]string{
+				"result": map[string]interface{}{
 					"blobstore_id": "my-blob-id",
 					"sha1":         "some sha1",
+					"metadata":     boshcomp.Metadata{Name: "fake-package-name"},
+					"duration":     float64(90),
 				},
 			}
 
@@ -101,6 +108,26 @@ var _ = Describe("CompilePackageAction", func() {
 			Expect(compiler.CompileDeps).To(ConsistOf(expectedDeps))
 		})
 
+		It("returns blob id of packaging script logs when they were uploaded", func() {
+			compiler.CompileResult = boshcomp.Result{
+				BlobstoreID:     "my-blob-id",
+				Sha1:            "some sha1",
+				LogsBlobstoreID: "my-logs-blob-id",
+			}
+
+			value, err := action.Run(getCompileActionArguments())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(map[string]interface{}{
+				"result": map[string]interface{}{
+					"blobstore_id":      "my-blob-id",
+					"sha1":              "some sha1",
+					"logs_blobstore_id": "my-logs-blob-id",
+					"metadata":          boshcomp.Metadata{},
+					"duration":          float64(0),
+				},
+			}))
+		})
+
 		It("returns error when compile fails", func() {
 			compiler.CompileErr = errors.New("fake-compile-error")
 
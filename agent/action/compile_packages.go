@@ -0,0 +1,79 @@
+package action
+
+import (
+	"errors"
+
+	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+)
+
+type CompilePackagesAction struct {
+	compiler boshcomp.Compiler
+}
+
+func NewCompilePackages(compiler boshcomp.Compiler) (compilePackages CompilePackagesAction) {
+	compilePackages.compiler = compiler
+	return
+}
+
+func (a CompilePackagesAction) IsAsynchronous() bool {
+	return true
+}
+
+func (a CompilePackagesAction) IsPersistent() bool {
+	return false
+}
+
+// Run compiles pkgs that may depend on each other;
+// packages they depend on that are already compiled are given in deps
+func (a CompilePackagesAction) Run(pkgs []boshcomp.BatchPackage, deps boshcomp.Dependencies) (val map[string]interface{}, err error) {
+	compiledDeps := []boshmodels.Package{}
+
+	for name, dep := range deps {
+		compiledDeps = append(compiledDeps, boshmodels.Package{
+			Name:    name,
+			Version: dep.Version,
+			Source: boshmodels.Source{
+				Sha1:        dep.Sha1,
+				BlobstoreID: dep.BlobstoreID,
+			},
+		})
+	}
+
+	compiled, err := a.compiler.CompileBatch(pkgs, compiledDeps)
+	if err != nil {
+		err = bosherr.WrapError(err, "Compiling packages")
+		return
+	}
+
+	result := map[string]map[string]interface{}{}
+
+	for name, pkgResult := range compiled {
+		pkgValue := map[string]interface{}{
+			"blobstore_id": pkgResult.BlobstoreID,
+			"sha1":         pkgResult.Sha1,
+			"metadata":     pkgResult.Metadata,
+			"duration":     pkgResult.Duration.Seconds(),
+		}
+
+		if pkgResult.LogsBlobstoreID != "" {
+			pkgValue["logs_blobstore_id"] = pkgResult.LogsBlobstoreID
+		}
+
+		result[name] = pkgValue
+	}
+
+	val = map[string]interface{}{
+		"result": result,
+	}
+	return
+}
+
+func (a CompilePackagesAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a CompilePackagesAction) Cancel() error {
+	return errors.New("not supported")
+}
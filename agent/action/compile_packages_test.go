@@ -0,0 +1,146 @@
+package action_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	fakecomp "github.com/cloudfoundry/bosh-agent/agent/compiler/fakes"
+)
+
+var _ = Describe("CompilePackagesAction", func() {
+	var (
+		compiler *fakecomp.FakeCompiler
+		action   CompilePackagesAction
+	)
+
+	BeforeEach(func() {
+		compiler = fakecomp.NewFakeCompiler()
+		action = NewCompilePackages(compiler)
+	})
+
+	It("is asynchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeTrue())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	Describe("Run", func() {
+		var (
+			pkgs []boshcomp.BatchPackage
+			deps boshcomp.Dependencies
+		)
+
+		BeforeEach(func() {
+			pkgs = []boshcomp.BatchPackage{
+				{
+					Package: boshcomp.Package{
+						BlobstoreID: "fake-first-blobstore-id",
+						Name:        "fake-first",
+						Sha1:        "fake-first-sha1",
+						Version:     "fake-first-version",
+					},
+					Dependencies: []string{"fake-compiled-dep"},
+				},
+				{
+					Package: boshcomp.Package{
+						BlobstoreID: "fake-second-blobstore-id",
+						Name:        "fake-second",
+						Sha1:        "fake-second-sha1",
+						Version:     "fake-second-version",
+					},
+					Dependencies: []string{"fake-first"},
+				},
+			}
+
+			deps = boshcomp.Dependencies{
+				"fake-compiled-dep": boshcomp.Package{
+					BlobstoreID: "fake-compiled-dep-blobstore-id",
+					Name:        "fake-compiled-dep",
+					Sha1:        "fake-compiled-dep-sha1",
+					Version:     "fake-compiled-dep-version",
+				},
+			}
+		})
+
+		It("compiles packages as a batch with already compiled dependencies", func() {
+			_, err := action.Run(pkgs, deps)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(compiler.CompileBatchPkgs).To(Equal(pkgs))
+			Expect(compiler.CompileBatchCompiledDeps).To(Equal([]boshmodels.Package{
+				{
+					Name:    "fake-compiled-dep",
+					Version: "fake-compiled-dep-version",
+					Source: boshmodels.Source{
+						Sha1:        "fake-compiled-dep-sha1",
+						BlobstoreID: "fake-compiled-dep-blobstore-id",
+					},
+				},
+			}))
+		})
+
+		It("returns blob ids and sha1s of compiled packages by package name", func() {
+			compiler.CompileBatchResults = map[string]boshcomp.Result{
+				"fake-first": boshcomp.Result{
+					BlobstoreID:     "fake-first-compiled-blob-id",
+					Sha1:            "fake-first-compiled-sha1",
+					LogsBlobstoreID: "fake-first-logs-blob-id",
+					Metadata:        boshcomp.Metadata{Name: "fake-first"},
+					Duration:        90 * time.Second,
+				},
+				"fake-second": boshcomp.Result{
+					BlobstoreID: "fake-second-compiled-blob-id",
+					Sha1:        "fake-second-compiled-sha1",
+				},
+			}
+
+			value, err := action.Run(pkgs, deps)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(map[string]interface{}{
+				"result": map[string]map[string]interface{}{
+					"fake-first": map[string]interface{}{
+						"blobstore_id":      "fake-first-compiled-blob-id",
+						"sha1":              "fake-first-compiled-sha1",
+						"logs_blobstore_id": "fake-first-logs-blob-id",
+						"metadata":          boshcomp.Metadata{Name: "fake-first"},
+						"duration":          float64(90),
+					},
+					"fake-second": map[string]interface{}{
+						"blobstore_id": "fake-second-compiled-blob-id",
+						"sha1":         "fake-second-compiled-sha1",
+						"metadata":     boshcomp.Metadata{},
+						"duration":     float64(0),
+					},
+				},
+			}))
+		})
+
+		It("returns error when compilation fails", func() {
+			compiler.CompileBatchErr = errors.New("fake-compile-error")
+
+			_, err := action.Run(pkgs, deps)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-compile-error"))
+		})
+
+		It("can be run with arguments from JSON payload", func() {
+			payload := `{"arguments":[
+				[{"blobstore_id":"fake-first-blobstore-id","name":"fake-first","sha1":"fake-first-sha1","version":"fake-first-version","dependencies":["fake-compiled-dep"]},
+				 {"blobstore_id":"fake-second-blobstore-id","name":"fake-second","sha1":"fake-second-sha1","version":"fake-second-version","dependencies":["fake-first"]}],
+				{"fake-compiled-dep":{"blobstore_id":"fake-compiled-dep-blobstore-id","name":"fake-compiled-dep","sha1":"fake-compiled-dep-sha1","version":"fake-compiled-dep-version"}}
+			]}`
+
+			_, err := NewRunner().Run(action, []byte(payload))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(compiler.CompileBatchPkgs).To(Equal(pkgs))
+		})
+	})
+})
@@ -5,6 +5,7 @@ import (
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	boshdrain "github.com/cloudfoundry/bosh-agent/agent/drain"
+	boshfreezer "github.com/cloudfoundry/bosh-agent/agent/freezer"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshblob "github.com/cloudfoundry/bosh-agent/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
@@ -31,6 +32,7 @@ func NewFactory(
 	jobSupervisor boshjobsuper.JobSupervisor,
 	specService boshas.V1Service,
 	drainScriptProvider boshdrain.ScriptProvider,
+	freezer boshfreezer.Freezer,
 	logger boshlog.Logger,
 ) (factory Factory) {
 	compressor := platform.GetCompressor()
@@ -61,6 +63,7 @@ func NewFactory(
 
 			// Compilation
 			"compile_package":    NewCompilePackage(compiler),
+			"compile_packages":   NewCompilePackages(compiler),
 			"release_apply_spec": NewReleaseApplySpec(platform),
 
 			// Disk management
@@ -68,11 +71,15 @@ func NewFactory(
 			"migrate_disk": NewMigrateDisk(platform, dirProvider),
 			"mount_disk":   NewMountDisk(settingsService, platform, platform, dirProvider),
 			"unmount_disk": NewUnmountDisk(settingsService, platform),
+			"resize_disk":  NewResizeDisk(settingsService, platform),
+			"freeze_disk":  NewFreezeDisk(settingsService, platform, specService, freezer),
+			"thaw_disk":    NewThawDisk(settingsService, platform, freezer),
 
 			// Networking
 			"prepare_network_change":     NewPrepareNetworkChange(platform.GetFs(), settingsService),
 			"prepare_configure_networks": NewPrepareConfigureNetworks(platform, settingsService),
 			"configure_networks":         NewConfigureNetworks(),
+			"sync_dns":                   NewSyncDNS(blobstore, settingsService, platform),
 		},
 	}
 	return
@@ -9,6 +9,7 @@ import (
 	fakeappl "github.com/cloudfoundry/bosh-agent/agent/applier/fakes"
 	fakecomp "github.com/cloudfoundry/bosh-agent/agent/compiler/fakes"
 	boshdrain "github.com/cloudfoundry/bosh-agent/agent/drain"
+	fakefreezer "github.com/cloudfoundry/bosh-agent/agent/freezer/fakes"
 	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	fakeblobstore "github.com/cloudfoundry/bosh-agent/blobstore/fakes"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
@@ -31,6 +32,7 @@ var _ = Describe("concreteFactory", func() {
 		jobSupervisor       *fakejobsuper.FakeJobSupervisor
 		specService         *fakeas.FakeV1Service
 		drainScriptProvider boshdrain.ScriptProvider
+		freezer             *fakefreezer.FakeFreezer
 		factory             Factory
 		logger              boshlog.Logger
 	)
@@ -46,6 +48,7 @@ var _ = Describe("concreteFactory", func() {
 		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 		specService = fakeas.NewFakeV1Service()
 		drainScriptProvider = boshdrain.NewConcreteScriptProvider(nil, nil, platform.GetDirProvider())
+		freezer = &fakefreezer.FakeFreezer{}
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 
 		factory = NewFactory(
@@ -59,6 +62,7 @@ var _ = Describe("concreteFactory", func() {
 			jobSupervisor,
 			specService,
 			drainScriptProvider,
+			freezer,
 			logger,
 		)
 	})
@@ -148,6 +152,12 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewConfigureNetworks()))
 	})
 
+	It("sync_dns", func() {
+		action, err := factory.Create("sync_dns")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewSyncDNS(blobstore, settingsService, platform)))
+	})
+
 	It("ssh", func() {
 		action, err := factory.Create("ssh")
 		Expect(err).ToNot(HaveOccurred())
@@ -172,12 +182,36 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewUnmountDisk(settingsService, platform)))
 	})
 
+	It("resize_disk", func() {
+		action, err := factory.Create("resize_disk")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewResizeDisk(settingsService, platform)))
+	})
+
+	It("freeze_disk", func() {
+		action, err := factory.Create("freeze_disk")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewFreezeDisk(settingsService, platform, specService, freezer)))
+	})
+
+	It("thaw_disk", func() {
+		action, err := factory.Create("thaw_disk")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewThawDisk(settingsService, platform, freezer)))
+	})
+
 	It("compile_package", func() {
 		action, err := factory.Create("compile_package")
 		Expect(err).ToNot(HaveOccurred())
 		Expect(action).To(Equal(NewCompilePackage(compiler)))
 	})
 
+	It("compile_packages", func() {
+		action, err := factory.Create("compile_packages")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewCompilePackages(compiler)))
+	})
+
 	It("run_errand", func() {
 		action, err := factory.Create("run_errand")
 		Expect(err).ToNot(HaveOccurred())
@@ -73,13 +73,16 @@ func (a FetchLogsAction) Run(logType string, filters []string) (value map[string
 
 	defer a.compressor.CleanUp(tarball)
 
-	blobID, _, err := a.blobstore.Create(tarball)
+	blobID, sha1, err := a.blobstore.Create(tarball)
 	if err != nil {
 		err = bosherr.WrapError(err, "Create file on blobstore")
 		return
 	}
 
-	value = map[string]string{"blobstore_id": blobID}
+	value = map[string]string{
+		"blobstore_id": blobID,
+		"sha1":         sha1,
+	}
 	return
 }
 
@@ -43,6 +43,7 @@ var _ = Describe("FetchLogsAction", func() {
 			copier.FilteredCopyToTempTempDir = "/fake-temp-dir"
 			compressor.CompressFilesInDirTarballPath = "logs_test.tar"
 			blobstore.CreateBlobID = "my-blob-id"
+			blobstore.CreateFingerprint = "my-blob-sha1"
 
 			logs, err := action.Run(logType, filters)
 			Expect(err).ToNot(HaveOccurred())
@@ -63,7 +64,7 @@ var _ = Describe("FetchLogsAction", func() {
 
 			Expect(compressor.CompressFilesInDirTarballPath).To(Equal(blobstore.CreateFileNames[0]))
 
-			boshassert.MatchesJSONString(GinkgoT(), logs, `{"blobstore_id":"my-blob-id"}`)
+			boshassert.MatchesJSONString(GinkgoT(), logs, `{"blobstore_id":"my-blob-id","sha1":"my-blob-sha1"}`)
 		}
 
 		It("logs errs if given invalid log type", func() {
@@ -0,0 +1,110 @@
+package action
+
+import (
+	"errors"
+	"time"
+
+	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshfreezer "github.com/cloudfoundry/bosh-agent/agent/freezer"
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+const freezeDiskDefaultTimeout = 60 * time.Second
+
+type FreezeDiskAction struct {
+	settingsService boshsettings.Service
+	platform        boshplatform.Platform
+	specService     boshas.V1Service
+	freezer         boshfreezer.Freezer
+}
+
+type FreezeDiskOptions struct {
+	// Run pre-snapshot hooks of jobs before freezing
+	// and post-snapshot hooks after thawing
+	RunHooks bool `json:"run_hooks"`
+
+	// Seconds after which disk is thawed automatically
+	Timeout int `json:"timeout"`
+}
+
+func NewFreezeDisk(
+	settingsService boshsettings.Service,
+	platform boshplatform.Platform,
+	specService boshas.V1Service,
+	freezer boshfreezer.Freezer,
+) (freezeDisk FreezeDiskAction) {
+	freezeDisk.settingsService = settingsService
+	freezeDisk.platform = platform
+	freezeDisk.specService = specService
+	freezeDisk.freezer = freezer
+	return
+}
+
+func (a FreezeDiskAction) IsAsynchronous() bool {
+	return true
+}
+
+func (a FreezeDiskAction) IsPersistent() bool {
+	return false
+}
+
+// Run freezes filesystem of a mounted persistent disk so that
+// it can be consistently snapshotted until thaw_disk is called
+func (a FreezeDiskAction) Run(diskCid string, options ...FreezeDiskOptions) (interface{}, error) {
+	settings := a.settingsService.GetSettings()
+
+	diskSettings, found := settings.PersistentDiskSettings(diskCid)
+	if !found {
+		return nil, bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCid)
+	}
+
+	// Disk is not necessarily mounted at store dir, e.g. while being migrated
+	mountPoint, isMounted, err := a.platform.GetPersistentDiskMountPoint(diskSettings)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Finding persistent disk mount point")
+	}
+
+	if !isMounted {
+		return nil, bosherr.Errorf("Persistent disk with volume id '%s' must be mounted to be frozen", diskCid)
+	}
+
+	var opts FreezeDiskOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	timeout := freezeDiskDefaultTimeout
+	if opts.Timeout > 0 {
+		timeout = time.Duration(opts.Timeout) * time.Second
+	}
+
+	var jobNames []string
+
+	if opts.RunHooks {
+		currentSpec, err := a.specService.Get()
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Getting current spec")
+		}
+
+		for _, job := range currentSpec.Jobs() {
+			jobNames = append(jobNames, job.Name)
+		}
+	}
+
+	err = a.freezer.Freeze(mountPoint, jobNames, timeout)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Freezing persistent disk")
+	}
+
+	return map[string]string{}, nil
+}
+
+func (a FreezeDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a FreezeDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
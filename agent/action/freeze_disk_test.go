@@ -0,0 +1,145 @@
+package action_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	fakefreezer "github.com/cloudfoundry/bosh-agent/agent/freezer/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+)
+
+var _ = Describe("FreezeDiskAction", func() {
+	var (
+		platform    *fakeplatform.FakePlatform
+		specService *fakeas.FakeV1Service
+		freezer     *fakefreezer.FakeFreezer
+		action      FreezeDiskAction
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		specService = fakeas.NewFakeV1Service()
+		freezer = &fakefreezer.FakeFreezer{}
+
+		settingsService := &fakesettings.FakeSettingsService{
+			Settings: boshsettings.Settings{
+				Disks: boshsettings.Disks{
+					Persistent: map[string]interface{}{
+						"vol-123": map[string]interface{}{
+							"volume_id": "2",
+							"path":      "/dev/sdf",
+						},
+					},
+				},
+			},
+		}
+
+		action = NewFreezeDisk(settingsService, platform, specService, freezer)
+	})
+
+	It("is asynchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeTrue())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	Context("when disk is mounted", func() {
+		BeforeEach(func() {
+			platform.GetPersistentDiskMountPointMountPoint = "/var/vcap/store"
+			platform.GetPersistentDiskMountPointFound = true
+
+			specService.Spec = boshas.V1ApplySpec{
+				JobSpec: boshas.JobSpec{
+					JobTemplateSpecs: []boshas.JobTemplateSpec{
+						{Name: "fake-job-1"},
+						{Name: "fake-job-2"},
+					},
+				},
+			}
+		})
+
+		It("freezes disk mount point with default timeout without running hooks", func() {
+			result, err := action.Run("vol-123")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(map[string]string{}))
+
+			Expect(platform.GetPersistentDiskMountPointSettings).To(Equal(boshsettings.DiskSettings{
+				ID:       "vol-123",
+				VolumeID: "2",
+				Path:     "/dev/sdf",
+			}))
+			Expect(freezer.FreezeMountPoint).To(Equal("/var/vcap/store"))
+			Expect(freezer.FreezeJobNames).To(BeEmpty())
+			Expect(freezer.FreezeTimeout).To(Equal(60 * time.Second))
+		})
+
+		It("freezes disk mounted outside of store directory", func() {
+			platform.GetPersistentDiskMountPointMountPoint = "/var/vcap/store_migration_target"
+
+			_, err := action.Run("vol-123")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(freezer.FreezeMountPoint).To(Equal("/var/vcap/store_migration_target"))
+		})
+
+		It("uses timeout from options", func() {
+			_, err := action.Run("vol-123", FreezeDiskOptions{Timeout: 5})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(freezer.FreezeTimeout).To(Equal(5 * time.Second))
+		})
+
+		It("runs hooks of current jobs when requested", func() {
+			_, err := action.Run("vol-123", FreezeDiskOptions{RunHooks: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(freezer.FreezeJobNames).To(Equal([]string{"fake-job-1", "fake-job-2"}))
+		})
+
+		It("returns error when getting current spec fails", func() {
+			specService.GetErr = errors.New("fake-get-err")
+
+			_, err := action.Run("vol-123", FreezeDiskOptions{RunHooks: true})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-get-err"))
+			Expect(freezer.FreezeMountPoint).To(BeEmpty())
+		})
+
+		It("returns error when freezing fails", func() {
+			freezer.FreezeErr = errors.New("fake-freeze-err")
+
+			_, err := action.Run("vol-123")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-freeze-err"))
+		})
+	})
+
+	It("returns error when disk is not mounted", func() {
+		_, err := action.Run("vol-123")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("must be mounted"))
+		Expect(freezer.FreezeMountPoint).To(BeEmpty())
+	})
+
+	It("returns error when finding disk mount point fails", func() {
+		platform.GetPersistentDiskMountPointErr = errors.New("fake-mount-point-err")
+
+		_, err := action.Run("vol-123")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-mount-point-err"))
+		Expect(freezer.FreezeMountPoint).To(BeEmpty())
+	})
+
+	It("returns error when disk could not be found", func() {
+		_, err := action.Run("vol-456")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("could not be found"))
+	})
+})
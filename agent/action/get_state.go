@@ -51,6 +51,8 @@ type GetStateV1ApplySpec struct {
 	Vitals       *boshvitals.Vitals `json:"vitals,omitempty"`
 	VM           boshsettings.VM    `json:"vm"`
 	Ntp          boshntp.Info       `json:"ntp"`
+
+	IPv6Addresses map[string]string `json:"ipv6_addresses,omitempty"`
 }
 
 func (a GetStateAction) Run(filters ...string) (GetStateV1ApplySpec, error) {
@@ -80,11 +82,11 @@ func (a GetStateAction) Run(filters ...string) (GetStateV1ApplySpec, error) {
 		vitalsReference,
 		settings.VM,
 		a.ntpService.GetInfo(),
+		settings.Networks.IPv6Addresses(),
 	}
 
-	if value.NetworkSpecs == nil {
-		value.NetworkSpecs = map[string]boshas.NetworkSpec{}
-	}
+	value.NetworkSpecs = a.populateDHCPNetworks(spec.NetworkSpecs, settings.Networks)
+
 	if value.ResourcePoolSpecs == nil {
 		value.ResourcePoolSpecs = map[string]interface{}{}
 	}
@@ -95,6 +97,31 @@ func (a GetStateAction) Run(filters ...string) (GetStateV1ApplySpec, error) {
 	return value, nil
 }
 
+// populateDHCPNetworks reports current addresses of DHCP networks since
+// leases may change after spec was applied. Persisted spec is not modified.
+func (a GetStateAction) populateDHCPNetworks(
+	networkSpecs map[string]boshas.NetworkSpec,
+	networks boshsettings.Networks,
+) map[string]boshas.NetworkSpec {
+	populatedSpecs := map[string]boshas.NetworkSpec{}
+
+	for networkName, networkSpec := range networkSpecs {
+		network, found := networks[networkName]
+		if found && network.IsDHCP() && network.Resolved {
+			fields := map[string]interface{}{}
+			for key, value := range networkSpec.Fields {
+				fields[key] = value
+			}
+
+			networkSpec = boshas.NetworkSpec{Fields: fields}.PopulateIPInfo(network.IP, network.Netmask, network.Gateway)
+		}
+
+		populatedSpecs[networkName] = networkSpec
+	}
+
+	return populatedSpecs
+}
+
 func (a GetStateAction) Resume() (interface{}, error) {
 	return nil, errors.New("not supported")
 }
@@ -91,6 +91,65 @@ var _ = Describe("GetState", func() {
 					Expect(state).To(Equal(expectedSpec))
 				})
 
+				It("returns IPv6 addresses of dual-stack networks", func() {
+					settingsService.Settings.Networks = boshsettings.Networks{
+						"fake-net": boshsettings.Network{IP: "10.0.0.5", IPv6: "2001:db8::5", IPv6Prefix: 64},
+					}
+
+					state, err := action.Run()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(state.IPv6Addresses).To(Equal(map[string]string{"fake-net": "2001:db8::5"}))
+				})
+
+				It("returns current addresses of DHCP networks without modifying current spec", func() {
+					settingsService.Settings.Networks = boshsettings.Networks{
+						"fake-dynamic": boshsettings.Network{
+							Type:     boshsettings.NetworkTypeDynamic,
+							IP:       "10.0.0.6",
+							Netmask:  "255.255.255.0",
+							Gateway:  "10.0.0.1",
+							Resolved: true,
+						},
+						"fake-manual": boshsettings.Network{IP: "10.0.1.5", Netmask: "255.255.255.0"},
+					}
+
+					specService.Spec = boshas.V1ApplySpec{
+						NetworkSpecs: map[string]boshas.NetworkSpec{
+							"fake-dynamic": boshas.NetworkSpec{
+								Fields: map[string]interface{}{"type": "dynamic", "ip": "10.0.0.5"},
+							},
+							"fake-manual": boshas.NetworkSpec{
+								Fields: map[string]interface{}{"ip": "10.0.1.5"},
+							},
+						},
+					}
+
+					state, err := action.Run()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(state.NetworkSpecs).To(Equal(map[string]boshas.NetworkSpec{
+						"fake-dynamic": boshas.NetworkSpec{
+							Fields: map[string]interface{}{
+								"type":    "dynamic",
+								"ip":      "10.0.0.6",
+								"netmask": "255.255.255.0",
+								"gateway": "10.0.0.1",
+							},
+						},
+						"fake-manual": boshas.NetworkSpec{
+							Fields: map[string]interface{}{"ip": "10.0.1.5"},
+						},
+					}))
+
+					Expect(specService.Spec.NetworkSpecs["fake-dynamic"].Fields["ip"]).To(Equal("10.0.0.5"))
+				})
+
+				It("does not include IPv6 addresses when networks do not have them", func() {
+					state, err := action.Run()
+					Expect(err).ToNot(HaveOccurred())
+					boshassert.LacksJSONKey(GinkgoT(), state, "ipv6_addresses")
+				})
+
 				It("returns state in full format", func() {
 					settingsService.Settings.AgentID = "my-agent-id"
 					settingsService.Settings.VM.Name = "vm-abc-def"
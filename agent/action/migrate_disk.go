@@ -2,6 +2,7 @@ package action
 
 import (
 	"errors"
+	"sync"
 
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
@@ -11,6 +12,19 @@ import (
 type MigrateDiskAction struct {
 	platform    boshplatform.Platform
 	dirProvider boshdirs.Provider
+
+	// Shared between Run and Progress which are called from different goroutines
+	progress *migrateDiskProgress
+}
+
+type MigrateDiskOptions struct {
+	// Compare checksums of copied files in addition to their sizes
+	VerifyChecksums bool `json:"verify_checksums"`
+}
+
+type migrateDiskProgress struct {
+	lock     sync.Mutex
+	progress *boshplatform.MigrationProgress
 }
 
 func NewMigrateDisk(
@@ -19,6 +33,7 @@ func NewMigrateDisk(
 ) (action MigrateDiskAction) {
 	action.platform = platform
 	action.dirProvider = dirProvider
+	action.progress = &migrateDiskProgress{}
 	return
 }
 
@@ -30,8 +45,22 @@ func (a MigrateDiskAction) IsPersistent() bool {
 	return false
 }
 
-func (a MigrateDiskAction) Run() (value interface{}, err error) {
-	err = a.platform.MigratePersistentDisk(a.dirProvider.StoreDir(), a.dirProvider.StoreMigrationDir())
+func (a MigrateDiskAction) Run(options ...MigrateDiskOptions) (value interface{}, err error) {
+	var opts MigrateDiskOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	a.progress.set(nil)
+
+	migrationOptions := boshplatform.MigrationOptions{
+		VerifyChecksums: opts.VerifyChecksums,
+		ProgressFunc: func(progress boshplatform.MigrationProgress) {
+			a.progress.set(&progress)
+		},
+	}
+
+	err = a.platform.MigratePersistentDisk(a.dirProvider.StoreDir(), a.dirProvider.StoreMigrationDir(), migrationOptions)
 	if err != nil {
 		err = bosherr.WrapError(err, "Migrating persistent disk")
 		return
@@ -41,6 +70,18 @@ func (a MigrateDiskAction) Run() (value interface{}, err error) {
 	return
 }
 
+// Progress returns progress of currently running migration
+func (a MigrateDiskAction) Progress() interface{} {
+	a.progress.lock.Lock()
+	defer a.progress.lock.Unlock()
+
+	if a.progress.progress == nil {
+		return nil
+	}
+
+	return *a.progress.progress
+}
+
 func (a MigrateDiskAction) Resume() (interface{}, error) {
 	return nil, errors.New("not supported")
 }
@@ -48,3 +89,10 @@ func (a MigrateDiskAction) Resume() (interface{}, error) {
 func (a MigrateDiskAction) Cancel() error {
 	return errors.New("not supported")
 }
+
+func (p *migrateDiskProgress) set(progress *boshplatform.MigrationProgress) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.progress = progress
+}
@@ -1,11 +1,14 @@
 package action_test
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
 	boshassert "github.com/cloudfoundry/bosh-agent/assert"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 )
@@ -38,6 +41,43 @@ func init() {
 
 			Expect(platform.MigratePersistentDiskFromMountPoint).To(Equal("/foo/store"))
 			Expect(platform.MigratePersistentDiskToMountPoint).To(Equal("/foo/store_migration_target"))
+			Expect(platform.MigratePersistentDiskOptions.VerifyChecksums).To(BeFalse())
+		})
+
+		It("requests checksum verification when specified in options", func() {
+			platform, action := buildMigrateDiskAction()
+
+			_, err := action.Run(MigrateDiskOptions{VerifyChecksums: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.MigratePersistentDiskOptions.VerifyChecksums).To(BeTrue())
+		})
+
+		It("returns error when migration fails", func() {
+			platform, action := buildMigrateDiskAction()
+			platform.MigratePersistentDiskErr = errors.New("fake-migrate-err")
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-migrate-err"))
+		})
+
+		It("reports latest migration progress", func() {
+			platform, action := buildMigrateDiskAction()
+			Expect(action.Progress()).To(BeNil())
+
+			platform.MigratePersistentDiskProgress = []boshplatform.MigrationProgress{
+				{Stage: boshplatform.MigrationStageCopying, TotalFiles: 2, CopiedFiles: 1},
+				{Stage: boshplatform.MigrationStageVerifying, TotalFiles: 2, CopiedFiles: 2},
+			}
+
+			_, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(action.Progress()).To(Equal(boshplatform.MigrationProgress{
+				Stage:       boshplatform.MigrationStageVerifying,
+				TotalFiles:  2,
+				CopiedFiles: 2,
+			}))
 		})
 	})
 }
@@ -0,0 +1,76 @@
+package action
+
+import (
+	"errors"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+type ResizeDiskAction struct {
+	settingsService boshsettings.Service
+	platform        boshplatform.Platform
+}
+
+func NewResizeDisk(
+	settingsService boshsettings.Service,
+	platform boshplatform.Platform,
+) (resizeDisk ResizeDiskAction) {
+	resizeDisk.settingsService = settingsService
+	resizeDisk.platform = platform
+	return
+}
+
+func (a ResizeDiskAction) IsAsynchronous() bool {
+	return true
+}
+
+func (a ResizeDiskAction) IsPersistent() bool {
+	return false
+}
+
+// Run grows partition and filesystem of a mounted persistent disk
+// to the size of its volume after it was grown by the IaaS
+func (a ResizeDiskAction) Run(diskCid string) (interface{}, error) {
+	settings := a.settingsService.GetSettings()
+
+	diskSettings, found := settings.PersistentDiskSettings(diskCid)
+	if !found {
+		return nil, bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCid)
+	}
+
+	isMounted, err := a.platform.IsPersistentDiskMounted(diskSettings)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Checking if persistent disk is mounted")
+	}
+
+	if !isMounted {
+		return nil, bosherr.Errorf("Persistent disk with volume id '%s' must be mounted to be resized", diskCid)
+	}
+
+	oldSize, newSize, err := a.platform.ResizePersistentDisk(diskSettings)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Resizing persistent disk")
+	}
+
+	type valueType struct {
+		OldSizeInMB uint64 `json:"old_size_in_mb"`
+		NewSizeInMB uint64 `json:"new_size_in_mb"`
+	}
+
+	value := valueType{
+		OldSizeInMB: oldSize / (1024 * 1024),
+		NewSizeInMB: newSize / (1024 * 1024),
+	}
+
+	return value, nil
+}
+
+func (a ResizeDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a ResizeDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
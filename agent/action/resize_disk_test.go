@@ -0,0 +1,93 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshassert "github.com/cloudfoundry/bosh-agent/assert"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+)
+
+var _ = Describe("ResizeDiskAction", func() {
+	var (
+		platform *fakeplatform.FakePlatform
+		action   ResizeDiskAction
+
+		expectedDiskSettings boshsettings.DiskSettings
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+
+		settingsService := &fakesettings.FakeSettingsService{
+			Settings: boshsettings.Settings{
+				Disks: boshsettings.Disks{
+					Persistent: map[string]interface{}{
+						"vol-123": map[string]interface{}{
+							"volume_id": "2",
+							"path":      "/dev/sdf",
+						},
+					},
+				},
+			},
+		}
+		action = NewResizeDisk(settingsService, platform)
+
+		expectedDiskSettings = boshsettings.DiskSettings{
+			ID:       "vol-123",
+			VolumeID: "2",
+			Path:     "/dev/sdf",
+		}
+	})
+
+	It("is asynchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeTrue())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	Context("when disk is mounted", func() {
+		BeforeEach(func() {
+			platform.MountedDevicePaths = []string{"/dev/sdf"}
+		})
+
+		It("resizes disk and reports old and new sizes", func() {
+			platform.ResizePersistentDiskOldSizeInBytes = 1024 * 1024 * 1024
+			platform.ResizePersistentDiskNewSizeInBytes = 2 * 1024 * 1024 * 1024
+
+			result, err := action.Run("vol-123")
+			Expect(err).ToNot(HaveOccurred())
+			boshassert.MatchesJSONString(GinkgoT(), result, `{"old_size_in_mb":1024,"new_size_in_mb":2048}`)
+
+			Expect(platform.ResizePersistentDiskSettings).To(Equal(expectedDiskSettings))
+		})
+
+		It("returns error when resizing disk fails", func() {
+			platform.ResizePersistentDiskErr = errors.New("fake-resize-err")
+
+			_, err := action.Run("vol-123")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-resize-err"))
+		})
+	})
+
+	It("returns error when disk is not mounted", func() {
+		_, err := action.Run("vol-123")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("must be mounted to be resized"))
+		Expect(platform.ResizePersistentDiskSettings).To(Equal(boshsettings.DiskSettings{}))
+	})
+
+	It("returns error when disk cannot be found", func() {
+		_, err := action.Run("fake-unknown-disk")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Persistent disk with volume id 'fake-unknown-disk' could not be found"))
+	})
+})
@@ -0,0 +1,75 @@
+package action
+
+import (
+	"encoding/json"
+	"errors"
+
+	boshblob "github.com/cloudfoundry/bosh-agent/blobstore"
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+type SyncDNSAction struct {
+	blobstore       boshblob.Blobstore
+	settingsService boshsettings.Service
+	platform        boshplatform.Platform
+}
+
+func NewSyncDNS(
+	blobstore boshblob.Blobstore,
+	settingsService boshsettings.Service,
+	platform boshplatform.Platform,
+) SyncDNSAction {
+	return SyncDNSAction{
+		blobstore:       blobstore,
+		settingsService: settingsService,
+		platform:        platform,
+	}
+}
+
+func (a SyncDNSAction) IsAsynchronous() bool {
+	return false
+}
+
+func (a SyncDNSAction) IsPersistent() bool {
+	return false
+}
+
+func (a SyncDNSAction) Run(blobID, sha1 string) (string, error) {
+	filePath, err := a.blobstore.Get(blobID, sha1)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Getting DNS records blob %s", blobID)
+	}
+
+	defer a.blobstore.CleanUp(filePath)
+
+	contents, err := a.platform.GetFs().ReadFile(filePath)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Reading DNS records")
+	}
+
+	var dnsRecords boshsettings.DNSRecords
+
+	err = json.Unmarshal(contents, &dnsRecords)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Unmarshalling DNS records")
+	}
+
+	settings := a.settingsService.GetSettings()
+
+	err = a.platform.SaveDNSRecords(dnsRecords, settings.AgentID, settings.FQDN())
+	if err != nil {
+		return "", bosherr.WrapError(err, "Saving DNS records")
+	}
+
+	return "synced", nil
+}
+
+func (a SyncDNSAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a SyncDNSAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,92 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakeblobstore "github.com/cloudfoundry/bosh-agent/blobstore/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+)
+
+var _ = Describe("SyncDNS", func() {
+	var (
+		action          SyncDNSAction
+		blobstore       *fakeblobstore.FakeBlobstore
+		settingsService *fakesettings.FakeSettingsService
+		platform        *fakeplatform.FakePlatform
+	)
+
+	BeforeEach(func() {
+		blobstore = fakeblobstore.NewFakeBlobstore()
+		settingsService = &fakesettings.FakeSettingsService{}
+		platform = fakeplatform.NewFakePlatform()
+		action = NewSyncDNS(blobstore, settingsService, platform)
+	})
+
+	It("is synchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeFalse())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	Describe("Run", func() {
+		BeforeEach(func() {
+			blobstore.GetFileName = "/fake-records-path"
+			platform.Fs.WriteFileString("/fake-records-path", `{"version":2,"records":[["10.0.0.5","db.example.com"]]}`)
+
+			settingsService.Settings.AgentID = "fake-agent-id"
+			settingsService.Settings.Networks = boshsettings.Networks{
+				"fake-net": boshsettings.Network{DNSSearch: []string{"example.com"}},
+			}
+		})
+
+		It("saves DNS records fetched from blobstore", func() {
+			resp, err := action.Run("fake-blob-id", "fake-sha1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp).To(Equal("synced"))
+
+			Expect(blobstore.GetBlobIDs).To(Equal([]string{"fake-blob-id"}))
+			Expect(blobstore.GetFingerprints).To(Equal([]string{"fake-sha1"}))
+			Expect(blobstore.CleanUpFileName).To(Equal("/fake-records-path"))
+
+			Expect(platform.SaveDNSRecordsDNSRecords).To(Equal(boshsettings.DNSRecords{
+				Version: 2,
+				Records: [][2]string{{"10.0.0.5", "db.example.com"}},
+			}))
+			Expect(platform.SaveDNSRecordsHostname).To(Equal("fake-agent-id"))
+			Expect(platform.SaveDNSRecordsFQDN).To(Equal("fake-agent-id.example.com"))
+		})
+
+		It("returns error if blob cannot be fetched", func() {
+			blobstore.GetError = errors.New("fake-get-err")
+
+			_, err := action.Run("fake-blob-id", "fake-sha1")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-get-err"))
+		})
+
+		It("returns error if DNS records are not valid json", func() {
+			platform.Fs.WriteFileString("/fake-records-path", "bad-json")
+
+			_, err := action.Run("fake-blob-id", "fake-sha1")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unmarshalling DNS records"))
+			Expect(blobstore.CleanUpFileName).To(Equal("/fake-records-path"))
+		})
+
+		It("returns error if DNS records cannot be saved", func() {
+			platform.SaveDNSRecordsError = errors.New("fake-save-err")
+
+			_, err := action.Run("fake-blob-id", "fake-sha1")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-save-err"))
+		})
+	})
+})
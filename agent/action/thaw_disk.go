@@ -0,0 +1,68 @@
+package action
+
+import (
+	"errors"
+
+	boshfreezer "github.com/cloudfoundry/bosh-agent/agent/freezer"
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+type ThawDiskAction struct {
+	settingsService boshsettings.Service
+	platform        boshplatform.Platform
+	freezer         boshfreezer.Freezer
+}
+
+func NewThawDisk(
+	settingsService boshsettings.Service,
+	platform boshplatform.Platform,
+	freezer boshfreezer.Freezer,
+) (thawDisk ThawDiskAction) {
+	thawDisk.settingsService = settingsService
+	thawDisk.platform = platform
+	thawDisk.freezer = freezer
+	return
+}
+
+func (a ThawDiskAction) IsAsynchronous() bool {
+	return true
+}
+
+func (a ThawDiskAction) IsPersistent() bool {
+	return false
+}
+
+func (a ThawDiskAction) Run(diskCid string) (interface{}, error) {
+	settings := a.settingsService.GetSettings()
+
+	diskSettings, found := settings.PersistentDiskSettings(diskCid)
+	if !found {
+		return nil, bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCid)
+	}
+
+	mountPoint, isMounted, err := a.platform.GetPersistentDiskMountPoint(diskSettings)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Finding persistent disk mount point")
+	}
+
+	if !isMounted {
+		return nil, bosherr.Errorf("Persistent disk with volume id '%s' must be mounted to be thawed", diskCid)
+	}
+
+	err = a.freezer.Thaw(mountPoint)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Thawing persistent disk")
+	}
+
+	return map[string]string{}, nil
+}
+
+func (a ThawDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a ThawDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,83 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakefreezer "github.com/cloudfoundry/bosh-agent/agent/freezer/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+)
+
+var _ = Describe("ThawDiskAction", func() {
+	var (
+		platform *fakeplatform.FakePlatform
+		freezer  *fakefreezer.FakeFreezer
+		action   ThawDiskAction
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		platform.GetPersistentDiskMountPointMountPoint = "/var/vcap/store_migration_target"
+		platform.GetPersistentDiskMountPointFound = true
+
+		freezer = &fakefreezer.FakeFreezer{}
+
+		settingsService := &fakesettings.FakeSettingsService{
+			Settings: boshsettings.Settings{
+				Disks: boshsettings.Disks{
+					Persistent: map[string]interface{}{
+						"vol-123": "/dev/sdf",
+					},
+				},
+			},
+		}
+
+		action = NewThawDisk(settingsService, platform, freezer)
+	})
+
+	It("is asynchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeTrue())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	It("thaws disk at its mount point", func() {
+		result, err := action.Run("vol-123")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(map[string]string{}))
+		Expect(freezer.ThawCalled).To(BeTrue())
+		Expect(freezer.ThawMountPoint).To(Equal("/var/vcap/store_migration_target"))
+		Expect(platform.GetPersistentDiskMountPointSettings.Path).To(Equal("/dev/sdf"))
+	})
+
+	It("returns error when disk is not mounted", func() {
+		platform.GetPersistentDiskMountPointFound = false
+
+		_, err := action.Run("vol-123")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("must be mounted"))
+		Expect(freezer.ThawCalled).To(BeFalse())
+	})
+
+	It("returns error when thawing fails", func() {
+		freezer.ThawErr = errors.New("fake-thaw-err")
+
+		_, err := action.Run("vol-123")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-thaw-err"))
+	})
+
+	It("returns error when disk could not be found", func() {
+		_, err := action.Run("vol-456")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("could not be found"))
+		Expect(freezer.ThawCalled).To(BeFalse())
+	})
+})
@@ -68,6 +68,10 @@ func (dispatcher concreteActionDispatcher) ResumePreviouslyDispatchedTasks() {
 			dispatcher.removeInfo,
 		)
 
+		if reporter, ok := action.(boshaction.ProgressReporter); ok {
+			task.ProgressFunc = reporter.Progress
+		}
+
 		dispatcher.taskService.StartTask(task)
 	}
 }
@@ -134,6 +138,10 @@ func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 		}
 	}
 
+	if reporter, ok := action.(boshaction.ProgressReporter); ok {
+		task.ProgressFunc = reporter.Progress
+	}
+
 	dispatcher.taskService.StartTask(task)
 
 	return boshhandler.NewValueResponse(boshtask.StateValue{
@@ -102,6 +102,13 @@ func init() {
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-cancel-err"))
 				})
+
+				It("allows task to report progress of the action", func() {
+					action.ProgressValue = "fake-progress"
+					dispatcher.Dispatch(req)
+
+					Expect(taskService.StartedTasks["fake-generated-task-id"].Progress()).To(Equal("fake-progress"))
+				})
 			}
 
 			Context("when action is not persistent", func() {
@@ -10,6 +10,7 @@ import (
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
 	boshtime "github.com/cloudfoundry/bosh-agent/time"
@@ -75,6 +76,10 @@ func (a Agent) Run() error {
 
 	go a.syslogServer.Start(a.handleSyslogMsg(errCh))
 
+	go a.monitorNetworkInterfaces(errCh)
+
+	go a.monitorDHCPAddresses(errCh)
+
 	select {
 	case err := <-errCh:
 		return err
@@ -143,6 +148,8 @@ func (a Agent) getHeartbeat() (Heartbeat, error) {
 		Index:    spec.Index,
 		JobState: a.jobSupervisor.Status(),
 		Vitals:   vitals,
+
+		IPv6Addresses: a.settingsService.GetSettings().Networks.IPv6Addresses(),
 	}
 	return hb, nil
 }
@@ -199,3 +206,89 @@ func (a Agent) handleSyslogMsg(errCh chan error) boshsyslog.CallbackFunc {
 		}
 	}
 }
+
+func (a Agent) monitorNetworkInterfaces(errCh chan error) {
+	defer a.logger.HandlePanic("Agent Monitor Network Interfaces")
+
+	err := a.platform.MonitorNetworkInterfaces(a.handleNetworkInterfaceAdded(errCh))
+	if err != nil {
+		a.logger.Error(agentLogTag, "Stopped monitoring network interfaces: %s", err.Error())
+	}
+}
+
+func (a Agent) handleNetworkInterfaceAdded(errCh chan error) boshnet.InterfaceAddedHandler {
+	return func(iface boshnet.NetworkInterface) error {
+		event := boshalert.NetworkInterfaceEvent{InterfaceName: iface.Name, MAC: iface.MAC}
+
+		// Settings are not reloaded here since the interface monitor
+		// runs concurrently with actions using them
+		settings := a.settingsService.GetSettings()
+
+		networkName, found := settings.Networks.NetworkForMAC(iface.MAC)
+		if found {
+			event.NetworkName = networkName
+
+			event.Err = a.platform.SetupNetworking(settings.Networks, settings.Mbus)
+			if event.Err != nil {
+				a.logger.Error(agentLogTag, "Failed configuring network interface %s: %s", iface.Name, event.Err.Error())
+			}
+		} else {
+			a.logger.Info(agentLogTag, "Network interface %s (%s) does not belong to any network", iface.Name, iface.MAC)
+		}
+
+		alert, err := boshalert.NewNetworkInterfaceAdapter(event, a.uuidGenerator, a.timeService).Alert()
+		if err != nil {
+			errCh <- bosherr.WrapError(err, "Adapting network interface alert")
+			return nil
+		}
+
+		err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Alert, alert)
+		if err != nil {
+			errCh <- bosherr.WrapError(err, "Sending network interface alert")
+		}
+
+		return nil
+	}
+}
+
+func (a Agent) monitorDHCPAddresses(errCh chan error) {
+	defer a.logger.HandlePanic("Agent Monitor DHCP Addresses")
+
+	networksFunc := func() boshsettings.Networks {
+		return a.settingsService.GetSettings().Networks
+	}
+
+	err := a.platform.MonitorDHCPAddresses(networksFunc, a.handleDHCPAddressChanged(errCh))
+	if err != nil {
+		a.logger.Error(agentLogTag, "Stopped monitoring DHCP addresses: %s", err.Error())
+	}
+}
+
+func (a Agent) handleDHCPAddressChanged(errCh chan error) boshnet.AddressChangeHandler {
+	return func(change boshnet.AddressChange) error {
+		event := boshalert.DHCPAddressEvent{
+			NetworkName:   change.NetworkName,
+			InterfaceName: change.InterfaceName,
+			PreviousIP:    change.PreviousIP,
+			IP:            change.IP,
+		}
+
+		event.Err = a.settingsService.UpdateDHCPAddress(change.NetworkName, change.IP, change.Netmask)
+		if event.Err != nil {
+			a.logger.Error(agentLogTag, "Failed updating address of network '%s': %s", change.NetworkName, event.Err.Error())
+		}
+
+		alert, err := boshalert.NewDHCPAddressAdapter(event, a.uuidGenerator, a.timeService).Alert()
+		if err != nil {
+			errCh <- bosherr.WrapError(err, "Adapting DHCP address alert")
+			return nil
+		}
+
+		err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Alert, alert)
+		if err != nil {
+			errCh <- bosherr.WrapError(err, "Sending DHCP address alert")
+		}
+
+		return nil
+	}
+}
@@ -18,7 +18,9 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	fakembus "github.com/cloudfoundry/bosh-agent/mbus/fakes"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
 	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
 	fakesyslog "github.com/cloudfoundry/bosh-agent/syslog/fakes"
@@ -289,6 +291,183 @@ func init() {
 					Message: expectedAlert,
 				}))
 			})
+
+			Context("when network interface is attached", func() {
+				var expectedTime time.Time
+
+				BeforeEach(func() {
+					handler.KeepOnRunning()
+
+					platform.MonitorNetworkInterfacesAddedInterface = &boshnet.NetworkInterface{Name: "eth1", MAC: "fake-mac"}
+
+					uuidGenerator.GeneratedUUID = "fake-uuid"
+					expectedTime = time.Now()
+					timeService.NowTimes = []time.Time{expectedTime}
+
+					// Fail the first time handler.Send is called for an alert (ignore heartbeats)
+					handler.SendCallback = func(input fakembus.SendInput) {
+						if input.Topic == boshhandler.Alert {
+							handler.SendErr = errors.New("stop")
+						}
+					}
+				})
+
+				It("configures networks and sends alert to health manager when interface belongs to a network", func() {
+					settingsService.Settings.Networks = boshsettings.Networks{
+						"fake-network": boshsettings.Network{Mac: "fake-mac"},
+					}
+					settingsService.Settings.Mbus = "nats://fake-mbus"
+
+					err := agent.Run()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("stop"))
+
+					Expect(settingsService.SettingsWereLoaded).To(BeFalse())
+					Expect(platform.SetupNetworkingNetworks).To(Equal(settingsService.Settings.Networks))
+					Expect(platform.SetupNetworkingMbusURL).To(Equal("nats://fake-mbus"))
+
+					Expect(handler.SendInputs()).To(ContainElement(fakembus.SendInput{
+						Target: boshhandler.HealthMonitor,
+						Topic:  boshhandler.Alert,
+						Message: boshalert.Alert{
+							ID:        "fake-uuid",
+							Severity:  boshalert.SeverityWarning,
+							Title:     "Network interface attached",
+							Summary:   "Configured network interface eth1 (fake-mac) for network 'fake-network'",
+							CreatedAt: expectedTime.Unix(),
+						},
+					}))
+				})
+
+				It("only sends alert without reloading settings when interface does not belong to any network", func() {
+					err := agent.Run()
+					Expect(err).To(HaveOccurred())
+
+					Expect(settingsService.SettingsWereLoaded).To(BeFalse())
+					Expect(platform.SetupNetworkingCalled).To(BeFalse())
+
+					Expect(handler.SendInputs()).To(ContainElement(fakembus.SendInput{
+						Target: boshhandler.HealthMonitor,
+						Topic:  boshhandler.Alert,
+						Message: boshalert.Alert{
+							ID:        "fake-uuid",
+							Severity:  boshalert.SeverityWarning,
+							Title:     "Network interface attached",
+							Summary:   "Network interface eth1 (fake-mac) does not belong to any network",
+							CreatedAt: expectedTime.Unix(),
+						},
+					}))
+				})
+
+				It("sends error alert when networks cannot be configured", func() {
+					settingsService.Settings.Networks = boshsettings.Networks{
+						"fake-network": boshsettings.Network{Bond: boshsettings.Bond{Members: []string{"fake-mac"}}},
+					}
+					platform.SetupNetworkingErr = errors.New("fake-setup-err")
+
+					err := agent.Run()
+					Expect(err).To(HaveOccurred())
+
+					Expect(handler.SendInputs()).To(ContainElement(fakembus.SendInput{
+						Target: boshhandler.HealthMonitor,
+						Topic:  boshhandler.Alert,
+						Message: boshalert.Alert{
+							ID:        "fake-uuid",
+							Severity:  boshalert.SeverityError,
+							Title:     "Network interface attached",
+							Summary:   "Failed to configure network interface eth1 (fake-mac) for network 'fake-network': fake-setup-err",
+							CreatedAt: expectedTime.Unix(),
+						},
+					}))
+				})
+			})
+
+			Context("when DHCP address changes", func() {
+				var expectedTime time.Time
+
+				BeforeEach(func() {
+					handler.KeepOnRunning()
+
+					platform.MonitorDHCPAddressesChange = &boshnet.AddressChange{
+						NetworkName:   "fake-network",
+						InterfaceName: "eth0",
+						PreviousIP:    "10.0.0.5",
+						IP:            "10.0.0.6",
+						Netmask:       "255.255.255.0",
+					}
+
+					settingsService.Settings.Networks = boshsettings.Networks{
+						"fake-network": boshsettings.Network{
+							Type:     boshsettings.NetworkTypeDynamic,
+							IP:       "10.0.0.5",
+							Netmask:  "255.255.0.0",
+							Gateway:  "10.0.0.1",
+							Resolved: true,
+						},
+					}
+
+					uuidGenerator.GeneratedUUID = "fake-uuid"
+					expectedTime = time.Now()
+					timeService.NowTimes = []time.Time{expectedTime}
+
+					// Fail the first time handler.Send is called for an alert (ignore heartbeats)
+					handler.SendCallback = func(input fakembus.SendInput) {
+						if input.Topic == boshhandler.Alert {
+							handler.SendErr = errors.New("stop")
+						}
+					}
+				})
+
+				It("monitors networks from current settings", func() {
+					err := agent.Run()
+					Expect(err).To(HaveOccurred())
+
+					Expect(platform.MonitorDHCPAddressesNetworks).To(Equal(settingsService.Settings.Networks))
+				})
+
+				It("updates DHCP address in settings and sends alert to health manager", func() {
+					err := agent.Run()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("stop"))
+
+					Expect(settingsService.UpdateDHCPAddressNetworkName).To(Equal("fake-network"))
+					Expect(settingsService.UpdateDHCPAddressIP).To(Equal("10.0.0.6"))
+					Expect(settingsService.UpdateDHCPAddressNetmask).To(Equal("255.255.255.0"))
+
+					Expect(specService.ActionsCalled).ToNot(ContainElement("Set"))
+
+					Expect(handler.SendInputs()).To(ContainElement(fakembus.SendInput{
+						Target: boshhandler.HealthMonitor,
+						Topic:  boshhandler.Alert,
+						Message: boshalert.Alert{
+							ID:        "fake-uuid",
+							Severity:  boshalert.SeverityWarning,
+							Title:     "Network address changed",
+							Summary:   "Address of network 'fake-network' on interface eth0 changed from 10.0.0.5 to 10.0.0.6",
+							CreatedAt: expectedTime.Unix(),
+						},
+					}))
+				})
+
+				It("sends error alert when settings cannot be updated", func() {
+					settingsService.UpdateDHCPAddressErr = errors.New("fake-update-err")
+
+					err := agent.Run()
+					Expect(err).To(HaveOccurred())
+
+					Expect(handler.SendInputs()).To(ContainElement(fakembus.SendInput{
+						Target: boshhandler.HealthMonitor,
+						Topic:  boshhandler.Alert,
+						Message: boshalert.Alert{
+							ID:        "fake-uuid",
+							Severity:  boshalert.SeverityError,
+							Title:     "Network address changed",
+							Summary:   "Address of network 'fake-network' on interface eth0 changed from 10.0.0.5 to 10.0.0.6; failed to update agent state: fake-update-err",
+							CreatedAt: expectedTime.Unix(),
+						},
+					}))
+				})
+			})
 		})
 	})
 }
@@ -0,0 +1,68 @@
+package alert
+
+import (
+	"fmt"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
+	boshuuid "github.com/cloudfoundry/bosh-agent/uuid"
+)
+
+// DHCPAddressEvent describes address change of a DHCP network after lease renewal
+type DHCPAddressEvent struct {
+	NetworkName   string
+	InterfaceName string
+	PreviousIP    string
+	IP            string
+
+	// Set when settings or apply spec could not be updated with new address
+	Err error
+}
+
+type dhcpAddressAdapter struct {
+	event         DHCPAddressEvent
+	uuidGenerator boshuuid.Generator
+	timeService   boshtime.Service
+}
+
+func NewDHCPAddressAdapter(
+	event DHCPAddressEvent,
+	uuidGenerator boshuuid.Generator,
+	timeService boshtime.Service,
+) Adapter {
+	return &dhcpAddressAdapter{
+		event:         event,
+		uuidGenerator: uuidGenerator,
+		timeService:   timeService,
+	}
+}
+
+func (m *dhcpAddressAdapter) IsIgnorable() bool {
+	return false
+}
+
+func (m *dhcpAddressAdapter) Alert() (Alert, error) {
+	uuid, err := m.uuidGenerator.Generate()
+	if err != nil {
+		return Alert{}, bosherr.WrapError(err, "Generating uuid")
+	}
+
+	severity := SeverityWarning
+	summary := fmt.Sprintf(
+		"Address of network '%s' on interface %s changed from %s to %s",
+		m.event.NetworkName, m.event.InterfaceName, m.event.PreviousIP, m.event.IP,
+	)
+
+	if m.event.Err != nil {
+		severity = SeverityError
+		summary = fmt.Sprintf("%s; failed to update agent state: %s", summary, m.event.Err.Error())
+	}
+
+	return Alert{
+		ID:        uuid,
+		Severity:  severity,
+		Title:     "Network address changed",
+		Summary:   summary,
+		CreatedAt: m.timeService.Now().Unix(),
+	}, nil
+}
@@ -0,0 +1,80 @@
+package alert_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+
+	faketime "github.com/cloudfoundry/bosh-agent/time/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-agent/uuid/fakes"
+)
+
+var _ = Describe("dhcpAddressAdapter", func() {
+	var (
+		timeService   *faketime.FakeService
+		uuidGenerator *fakeuuid.FakeGenerator
+		event         DHCPAddressEvent
+		expectedTime  time.Time
+	)
+
+	BeforeEach(func() {
+		timeService = &faketime.FakeService{}
+		uuidGenerator = &fakeuuid.FakeGenerator{GeneratedUUID: "fake-uuid"}
+
+		expectedTime = time.Now()
+		timeService.NowTimes = []time.Time{expectedTime}
+
+		event = DHCPAddressEvent{
+			NetworkName:   "fake-network",
+			InterfaceName: "eth0",
+			PreviousIP:    "10.0.0.5",
+			IP:            "10.0.0.6",
+		}
+	})
+
+	Describe("IsIgnorable", func() {
+		It("does not ignore address changes", func() {
+			adapter := NewDHCPAddressAdapter(event, uuidGenerator, timeService)
+			Expect(adapter.IsIgnorable()).To(BeFalse())
+		})
+	})
+
+	Describe("Alert", func() {
+		It("returns warning about changed address", func() {
+			adapter := NewDHCPAddressAdapter(event, uuidGenerator, timeService)
+
+			builtAlert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(builtAlert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityWarning,
+				Title:     "Network address changed",
+				Summary:   "Address of network 'fake-network' on interface eth0 changed from 10.0.0.5 to 10.0.0.6",
+				CreatedAt: expectedTime.Unix(),
+			}))
+		})
+
+		It("returns error when agent state could not be updated", func() {
+			event.Err = errors.New("fake-update-err")
+			adapter := NewDHCPAddressAdapter(event, uuidGenerator, timeService)
+
+			builtAlert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(builtAlert.Severity).To(Equal(SeverityError))
+			Expect(builtAlert.Summary).To(Equal("Address of network 'fake-network' on interface eth0 changed from 10.0.0.5 to 10.0.0.6; failed to update agent state: fake-update-err"))
+		})
+
+		It("returns error if uuid cannot be generated", func() {
+			uuidGenerator.GenerateError = errors.New("fake-uuid-err")
+			adapter := NewDHCPAddressAdapter(event, uuidGenerator, timeService)
+
+			_, err := adapter.Alert()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-uuid-err"))
+		})
+	})
+})
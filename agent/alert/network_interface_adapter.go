@@ -0,0 +1,73 @@
+package alert
+
+import (
+	"fmt"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
+	boshuuid "github.com/cloudfoundry/bosh-agent/uuid"
+)
+
+// NetworkInterfaceEvent describes network interface attached after boot
+type NetworkInterfaceEvent struct {
+	InterfaceName string
+	MAC           string
+
+	// Empty when interface does not belong to any network in settings
+	NetworkName string
+
+	// Set when interface could not be configured
+	Err error
+}
+
+type networkInterfaceAdapter struct {
+	event         NetworkInterfaceEvent
+	uuidGenerator boshuuid.Generator
+	timeService   boshtime.Service
+}
+
+func NewNetworkInterfaceAdapter(
+	event NetworkInterfaceEvent,
+	uuidGenerator boshuuid.Generator,
+	timeService boshtime.Service,
+) Adapter {
+	return &networkInterfaceAdapter{
+		event:         event,
+		uuidGenerator: uuidGenerator,
+		timeService:   timeService,
+	}
+}
+
+func (m *networkInterfaceAdapter) IsIgnorable() bool {
+	return false
+}
+
+func (m *networkInterfaceAdapter) Alert() (Alert, error) {
+	uuid, err := m.uuidGenerator.Generate()
+	if err != nil {
+		return Alert{}, bosherr.WrapError(err, "Generating uuid")
+	}
+
+	severity := SeverityWarning
+	iface := fmt.Sprintf("%s (%s)", m.event.InterfaceName, m.event.MAC)
+
+	var summary string
+
+	switch {
+	case m.event.NetworkName == "":
+		summary = fmt.Sprintf("Network interface %s does not belong to any network", iface)
+	case m.event.Err != nil:
+		severity = SeverityError
+		summary = fmt.Sprintf("Failed to configure network interface %s for network '%s': %s", iface, m.event.NetworkName, m.event.Err.Error())
+	default:
+		summary = fmt.Sprintf("Configured network interface %s for network '%s'", iface, m.event.NetworkName)
+	}
+
+	return Alert{
+		ID:        uuid,
+		Severity:  severity,
+		Title:     "Network interface attached",
+		Summary:   summary,
+		CreatedAt: m.timeService.Now().Unix(),
+	}, nil
+}
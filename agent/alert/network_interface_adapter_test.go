@@ -0,0 +1,89 @@
+package alert_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+
+	faketime "github.com/cloudfoundry/bosh-agent/time/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-agent/uuid/fakes"
+)
+
+var _ = Describe("networkInterfaceAdapter", func() {
+	var (
+		timeService   *faketime.FakeService
+		uuidGenerator *fakeuuid.FakeGenerator
+		event         NetworkInterfaceEvent
+		expectedTime  time.Time
+	)
+
+	BeforeEach(func() {
+		timeService = &faketime.FakeService{}
+		uuidGenerator = &fakeuuid.FakeGenerator{GeneratedUUID: "fake-uuid"}
+
+		expectedTime = time.Now()
+		timeService.NowTimes = []time.Time{expectedTime}
+
+		event = NetworkInterfaceEvent{
+			InterfaceName: "eth1",
+			MAC:           "fake-mac",
+			NetworkName:   "fake-network",
+		}
+	})
+
+	Describe("IsIgnorable", func() {
+		It("does not ignore attached interfaces", func() {
+			adapter := NewNetworkInterfaceAdapter(event, uuidGenerator, timeService)
+			Expect(adapter.IsIgnorable()).To(BeFalse())
+		})
+	})
+
+	Describe("Alert", func() {
+		It("returns warning about configured interface", func() {
+			adapter := NewNetworkInterfaceAdapter(event, uuidGenerator, timeService)
+
+			builtAlert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(builtAlert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityWarning,
+				Title:     "Network interface attached",
+				Summary:   "Configured network interface eth1 (fake-mac) for network 'fake-network'",
+				CreatedAt: expectedTime.Unix(),
+			}))
+		})
+
+		It("returns error when interface could not be configured", func() {
+			event.Err = errors.New("fake-configure-err")
+			adapter := NewNetworkInterfaceAdapter(event, uuidGenerator, timeService)
+
+			builtAlert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(builtAlert.Severity).To(Equal(SeverityError))
+			Expect(builtAlert.Summary).To(Equal("Failed to configure network interface eth1 (fake-mac) for network 'fake-network': fake-configure-err"))
+		})
+
+		It("returns warning when interface does not belong to any network", func() {
+			event.NetworkName = ""
+			adapter := NewNetworkInterfaceAdapter(event, uuidGenerator, timeService)
+
+			builtAlert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(builtAlert.Severity).To(Equal(SeverityWarning))
+			Expect(builtAlert.Summary).To(Equal("Network interface eth1 (fake-mac) does not belong to any network"))
+		})
+
+		It("returns error if uuid cannot be generated", func() {
+			uuidGenerator.GenerateError = errors.New("fake-uuid-err")
+			adapter := NewNetworkInterfaceAdapter(event, uuidGenerator, timeService)
+
+			_, err := adapter.Alert()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-uuid-err"))
+		})
+	})
+})
@@ -11,6 +11,8 @@ import (
 	boshsys "github.com/cloudfoundry/bosh-agent/system"
 )
 
+const bootstrapLogTag = "bootstrap"
+
 type Bootstrap interface {
 	Run() error
 }
@@ -64,11 +66,11 @@ func (boot bootstrap) Run() (err error) {
 		return bosherr.WrapError(err, "Settings user password")
 	}
 
-	if err = boot.platform.SetupHostname(settings.AgentID); err != nil {
+	if err = boot.platform.SetupHostname(settings.AgentID, settings.FQDN()); err != nil {
 		return bosherr.WrapError(err, "Setting up hostname")
 	}
 
-	if err = boot.platform.SetupNetworking(settings.Networks); err != nil {
+	if err = boot.platform.SetupNetworking(settings.Networks, settings.Mbus); err != nil {
 		return bosherr.WrapError(err, "Setting up networking")
 	}
 
@@ -76,7 +78,28 @@ func (boot bootstrap) Run() (err error) {
 		return bosherr.WrapError(err, "Setting up NTP servers")
 	}
 
-	ephemeralDiskPath := boot.platform.GetEphemeralDiskPath(settings.EphemeralDiskSettings())
+	ephemeralDisksSettings := settings.EphemeralDisksSettings()
+
+	var ephemeralDiskPaths []string
+	for _, diskSettings := range ephemeralDisksSettings {
+		realPath := boot.platform.GetEphemeralDiskPath(diskSettings)
+		if realPath != "" {
+			ephemeralDiskPaths = append(ephemeralDiskPaths, realPath)
+			continue
+		}
+
+		// Assembling remaining disks would silently shrink ephemeral disk;
+		// single missing disk is set up without device as before
+		if len(ephemeralDisksSettings) > 1 {
+			return bosherr.Errorf("Ephemeral disk '%s' could not be found", diskSettings.Path)
+		}
+	}
+
+	ephemeralDiskPath, err := boot.platform.AssembleEphemeralDisks(ephemeralDiskPaths)
+	if err != nil {
+		return bosherr.WrapError(err, "Assembling ephemeral disks")
+	}
+
 	if err = boot.platform.SetupEphemeralDiskWithPath(ephemeralDiskPath); err != nil {
 		return bosherr.WrapError(err, "Setting up ephemeral disk")
 	}
@@ -95,7 +118,15 @@ func (boot bootstrap) Run() (err error) {
 
 	for diskID := range settings.Disks.Persistent {
 		diskSettings, _ := settings.PersistentDiskSettings(diskID)
-		if boot.platform.MountPersistentDisk(diskSettings, boot.dirProvider.StoreDir()); err != nil {
+		err = boot.platform.MountPersistentDisk(diskSettings, boot.dirProvider.StoreDir())
+		if _, ok := err.(boshplatform.MigrationIncompleteError); ok {
+			// Agent still has to start so that director can resume migration
+			boot.logger.Warn(bootstrapLogTag, "Skipping mounting persistent disk '%s': %s", diskID, err.Error())
+			err = nil
+			continue
+		}
+
+		if err != nil {
 			return bosherr.WrapError(err, "Mounting persistent disk")
 		}
 	}
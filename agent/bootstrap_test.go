@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"time"
 
@@ -123,6 +124,21 @@ func init() {
 				Expect(platform.SetupHostnameHostname).To(Equal("foo-bar-baz-123"))
 			})
 
+			It("sets up hostname with fully qualified domain name of dns network", func() {
+				settingsService.Settings.AgentID = "foo-bar-baz-123"
+				settingsService.Settings.Networks = boshsettings.Networks{
+					"fake-net": boshsettings.Network{
+						Type:      "dynamic",
+						DNSSearch: []string{"example.com"},
+					},
+				}
+
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(platform.SetupHostnameHostname).To(Equal("foo-bar-baz-123"))
+				Expect(platform.SetupHostnameFQDN).To(Equal("foo-bar-baz-123.example.com"))
+			})
+
 			It("fetches initial settings", func() {
 				err := bootstrap()
 				Expect(err).NotTo(HaveOccurred())
@@ -142,10 +158,12 @@ func init() {
 					"bosh": boshsettings.Network{},
 				}
 				settingsService.Settings.Networks = networks
+				settingsService.Settings.Mbus = "nats://fake-mbus"
 
 				err := bootstrap()
 				Expect(err).NotTo(HaveOccurred())
 				Expect(platform.SetupNetworkingNetworks).To(Equal(networks))
+				Expect(platform.SetupNetworkingMbusURL).To(Equal("nats://fake-mbus"))
 			})
 
 			It("sets up ephemeral disk", func() {
@@ -154,9 +172,11 @@ func init() {
 				}
 
 				platform.GetEphemeralDiskPathRealPath = "/dev/sda"
+				platform.AssembleEphemeralDisksDevicePath = "/dev/sda"
 
 				err := bootstrap()
 				Expect(err).NotTo(HaveOccurred())
+				Expect(platform.AssembleEphemeralDisksDevicePaths).To(Equal([]string{"/dev/sda"}))
 				Expect(platform.SetupEphemeralDiskWithPathDevicePath).To(Equal("/dev/sda"))
 				Expect(platform.GetEphemeralDiskPathSettings).To(Equal(boshsettings.DiskSettings{
 					VolumeID: "fake-ephemeral-disk-setting",
@@ -164,6 +184,58 @@ func init() {
 				}))
 			})
 
+			It("sets up ephemeral disk assembled from multiple disks", func() {
+				settingsService.Settings.Disks = boshsettings.Disks{
+					Ephemeral: []interface{}{"fake-ephemeral-disk-setting-1", "fake-ephemeral-disk-setting-2"},
+				}
+
+				platform.GetEphemeralDiskPathRealPath = "/dev/sda"
+				platform.AssembleEphemeralDisksDevicePath = "/dev/md0"
+
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(platform.AssembleEphemeralDisksDevicePaths).To(Equal([]string{"/dev/sda", "/dev/sda"}))
+				Expect(platform.SetupEphemeralDiskWithPathDevicePath).To(Equal("/dev/md0"))
+			})
+
+			It("sets up ephemeral disk without device when ephemeral disk is not found", func() {
+				settingsService.Settings.Disks = boshsettings.Disks{
+					Ephemeral: "fake-ephemeral-disk-setting",
+				}
+
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(platform.AssembleEphemeralDisksDevicePaths).To(BeEmpty())
+				Expect(platform.SetupEphemeralDiskWithPathDevicePath).To(BeEmpty())
+			})
+
+			It("returns error when any of multiple ephemeral disks is not found", func() {
+				settingsService.Settings.Disks = boshsettings.Disks{
+					Ephemeral: []interface{}{"fake-ephemeral-disk-setting-1", "fake-ephemeral-disk-setting-2"},
+				}
+
+				err := bootstrap()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Ephemeral disk 'fake-ephemeral-disk-setting-1' could not be found"))
+				Expect(platform.AssembleEphemeralDisksDevicePaths).To(BeEmpty())
+			})
+
+			It("sets up ephemeral disk without device when no ephemeral disk is configured", func() {
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(platform.GetEphemeralDiskPathCalled).To(BeFalse())
+				Expect(platform.SetupEphemeralDiskWithPathDevicePath).To(BeEmpty())
+			})
+
+			It("returns error if assembling ephemeral disks fails", func() {
+				platform.AssembleEphemeralDisksErr = errors.New("fake-assemble-err")
+
+				err := bootstrap()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-assemble-err"))
+				Expect(platform.SetupEphemeralDiskWithPathDevicePath).To(BeEmpty())
+			})
+
 			It("returns error if setting ephemeral disk fails", func() {
 				platform.SetupEphemeralDiskWithPathErr = errors.New("fake-setup-ephemeral-disk-err")
 				err := bootstrap()
@@ -217,6 +289,29 @@ func init() {
 				Expect(platform.MountPersistentDiskMountPoint).To(Equal(dirProvider.StoreDir()))
 			})
 
+			It("returns error if mounting persistent disk fails", func() {
+				settingsService.Settings.Disks = boshsettings.Disks{
+					Persistent: map[string]interface{}{"vol-123": "/dev/sdb"},
+				}
+				platform.MountPersistentDiskErr = errors.New("fake-mount-persistent-disk-err")
+
+				err := bootstrap()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-mount-persistent-disk-err"))
+			})
+
+			It("skips mounting persistent disk that contains incomplete migration", func() {
+				settingsService.Settings.Disks = boshsettings.Disks{
+					Persistent: map[string]interface{}{"vol-123": "/dev/sdb"},
+				}
+				platform.MountPersistentDiskErr = boshplatform.MigrationIncompleteError{MigrationDir: "/fake-migration-dir"}
+
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(platform.MountPersistentDiskCalled).To(BeTrue())
+				Expect(platform.StartMonitStarted).To(BeTrue())
+			})
+
 			It("errors if there is more than one persistent disk", func() {
 				settingsService.Settings.Disks = boshsettings.Disks{
 					Persistent: map[string]interface{}{
@@ -321,6 +416,7 @@ func init() {
 
 			BeforeEach(func() {
 				fs = fakesys.NewFakeFileSystem()
+				fs.MkdirAll("/etc", os.FileMode(0755))
 				runner := fakesys.NewFakeCmdRunner()
 				dirProvider = boshdirs.NewProvider("/var/vcap/bosh")
 
@@ -356,18 +452,21 @@ func init() {
 
 				ipResolver := boship.NewResolver(boship.NetworkInterfaceToAddrsFunc)
 
-				arping := bosharp.NewArping(runner, fs, logger, boshplatform.ArpIterations, boshplatform.ArpIterationDelay, boshplatform.ArpInterfaceCheckDelay)
+				arping := bosharp.NewArping(runner, bosharp.NewICMPNeighborAdvertiser(), fs, logger, boshplatform.ArpIterations, boshplatform.ArpIterationDelay, boshplatform.ArpInterfaceCheckDelay)
 				interfaceConfigurationCreator := boshnet.NewInterfaceConfigurationCreator(logger)
 
-				ubuntuNetManager := boshnet.NewUbuntuNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, arping, logger)
+				ubuntuNetManager := boshnet.NewUbuntuNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, arping, boshnet.NewNoopConnectivityChecker(), logger)
+
+				interfaceMonitor := boshnet.NewPollingInterfaceMonitor(fs, udev, boshplatform.NetworkInterfaceMonitorInterval, logger)
 
 				monitRetryable := boshplatform.NewMonitRetryable(runner)
 				monitRetryStrategy := boshretry.NewAttemptRetryStrategy(10, 1*time.Second, monitRetryable, logger)
 
 				devicePathResolver := devicepathresolver.NewIdentityDevicePathResolver()
 
-				routesSearcher := boshnet.NewCmdRoutesSearcher(runner)
+				routesSearcher := boshnet.NewCmdRoutesSearcher(runner, logger)
 				defaultNetworkResolver = boshnet.NewDefaultNetworkResolver(routesSearcher, ipResolver)
+				addressMonitor := boshnet.NewPollingAddressMonitor(fs, routesSearcher, ipResolver, boshplatform.DHCPAddressMonitorInterval, logger)
 
 				platform = boshplatform.NewLinuxPlatform(
 					fs,
@@ -380,6 +479,8 @@ func init() {
 					linuxCdutil,
 					diskManager,
 					ubuntuNetManager,
+					interfaceMonitor,
+					addressMonitor,
 					monitRetryStrategy,
 					devicePathResolver,
 					500*time.Millisecond,
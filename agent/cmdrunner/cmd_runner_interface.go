@@ -13,6 +13,16 @@ type CmdResult struct {
 	Stderr []byte
 
 	ExitStatus int
+
+	// Complete, untruncated output is kept in these files
+	StdoutPath string
+	StderrPath string
+}
+
+// ExecError is returned when command ran but did not succeed
+type ExecError interface {
+	error
+	Result() *CmdResult
 }
 
 type CmdRunner interface {
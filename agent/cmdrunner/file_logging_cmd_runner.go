@@ -47,6 +47,10 @@ func (f FileLoggingExecErr) Error() string {
 	)
 }
 
+func (f FileLoggingExecErr) Result() *CmdResult {
+	return f.result
+}
+
 func NewFileLoggingCmdRunner(
 	fs boshsys.FileSystem,
 	cmdRunner boshsys.CmdRunner,
@@ -114,6 +118,9 @@ func (f FileLoggingCmdRunner) RunCommand(jobName string, taskName string, cmd bo
 		Stderr: stderr,
 
 		ExitStatus: exitStatus,
+
+		StdoutPath: stdoutPath,
+		StderrPath: stderrPath,
 	}
 
 	if runErr != nil {
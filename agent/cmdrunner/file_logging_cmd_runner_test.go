@@ -99,6 +99,9 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 					Stdout:            []byte("fake-stdout"),
 					Stderr:            []byte("fake-stderr"),
 					ExitStatus:        0,
+
+					StdoutPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stdout.log",
+					StderrPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stderr.log",
 				}
 
 				result, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
@@ -146,6 +149,17 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 				Expect(result).To(BeNil())
 			})
 
+			It("returns script error with result pointing to complete log files", func() {
+				_, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
+				Expect(err).To(HaveOccurred())
+
+				execErr, ok := err.(ExecError)
+				Expect(ok).To(BeTrue())
+				Expect(execErr.Result().ExitStatus).To(Equal(1))
+				Expect(execErr.Result().StdoutPath).To(Equal("/fake-base-dir/fake-log-dir-name/fake-log-file-name.stdout.log"))
+				Expect(execErr.Result().StderrPath).To(Equal("/fake-base-dir/fake-log-dir-name/fake-log-file-name.stderr.log"))
+			})
+
 			It("saves stdout to log file", func() {
 				_, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
 				Expect(err).To(HaveOccurred())
@@ -183,6 +197,9 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 					Stdout:            []byte("g-output-stdout"),
 					Stderr:            []byte("g-output-stderr"),
 					ExitStatus:        0,
+
+					StdoutPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stdout.log",
+					StderrPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stderr.log",
 				}
 
 				result, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
@@ -203,6 +220,9 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 					Stdout:            []byte("output-stdout"),
 					Stderr:            []byte("output-stderr"),
 					ExitStatus:        0,
+
+					StdoutPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stdout.log",
+					StderrPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stderr.log",
 				}
 
 				result, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
@@ -223,6 +243,9 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 					Stdout:            []byte("-output-std\nout"),
 					Stderr:            []byte("-output-std\nerr"),
 					ExitStatus:        0,
+
+					StdoutPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stdout.log",
+					StderrPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stderr.log",
 				}
 
 				result, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
@@ -243,6 +266,9 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 					Stdout:            []byte("иветstdout"),
 					Stderr:            []byte("иветstderr"),
 					ExitStatus:        0,
+
+					StdoutPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stdout.log",
+					StderrPath: "/fake-base-dir/fake-log-dir-name/fake-log-file-name.stderr.log",
 				}
 
 				result, err := runner.RunCommand("fake-log-dir-name", "fake-log-file-name", cmd)
@@ -0,0 +1,272 @@
+package compiler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	boshcmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
+)
+
+const (
+	cgroupResourceLimiterGroup = "bosh-packaging"
+
+	// CPU quota is expressed as runtime allowed per period
+	cgroupResourceLimiterCPUPeriod = 100000
+
+	// Exit statuses of timeout command when command timed out
+	// and when it had to be killed after not terminating in time
+	timeoutExitStatus       = 124
+	timeoutKillExitStatus   = 137
+	timeoutKillAfterSeconds = 10
+
+	// Processes left behind by packaging script are given
+	// this long to exit after being killed
+	cgroupResourceLimiterKillAttempts = 50
+	cgroupResourceLimiterKillInterval = 100 * time.Millisecond
+)
+
+type cgroupResourceLimiter struct {
+	limits      Limits
+	cgroupRoot  string
+	fs          boshsys.FileSystem
+	runner      boshsys.CmdRunner
+	timeService boshtime.Service
+
+	// Start times of limited commands by name
+	startTimes     map[string]time.Time
+	startTimesLock *sync.Mutex
+}
+
+// NewCgroupResourceLimiter limits CPU and memory with cgroup v2 mounted at cgroupRoot
+// and wall-clock time with timeout command
+func NewCgroupResourceLimiter(
+	limits Limits,
+	cgroupRoot string,
+	fs boshsys.FileSystem,
+	runner boshsys.CmdRunner,
+	timeService boshtime.Service,
+) ResourceLimiter {
+	return cgroupResourceLimiter{
+		limits:      limits,
+		cgroupRoot:  cgroupRoot,
+		fs:          fs,
+		runner:      runner,
+		timeService: timeService,
+
+		startTimes:     map[string]time.Time{},
+		startTimesLock: &sync.Mutex{},
+	}
+}
+
+func (l cgroupResourceLimiter) Limit(cmd boshsys.Command, name string) (boshsys.Command, error) {
+	var args []string
+
+	if l.usesCgroup() {
+		cgroupPath, err := l.createCgroup(name)
+		if err != nil {
+			return cmd, bosherr.WrapErrorf(err, "Creating cgroup for %s", name)
+		}
+
+		// Child processes stay in the cgroup joined before exec
+		args = append(args,
+			"bash", "-e", "-c",
+			fmt.Sprintf(`echo $$ > %s; exec "$@"`, shellQuote(filepath.Join(cgroupPath, "cgroup.procs"))),
+			"bosh-limits",
+		)
+	}
+
+	if l.limits.Timeout > 0 {
+		args = append(args,
+			"timeout",
+			fmt.Sprintf("--kill-after=%d", timeoutKillAfterSeconds),
+			strconv.Itoa(l.limits.Timeout),
+		)
+
+		l.startTimesLock.Lock()
+		l.startTimes[name] = l.timeService.Now()
+		l.startTimesLock.Unlock()
+	}
+
+	if len(args) == 0 {
+		return cmd, nil
+	}
+
+	cmd.Args = append(append(args[1:], cmd.Name), cmd.Args...)
+	cmd.Name = args[0]
+
+	return cmd, nil
+}
+
+func (l cgroupResourceLimiter) Release(name string, result *boshcmdrunner.CmdResult) (string, error) {
+	var exceeded string
+
+	timedOut := l.timedOut(name, result)
+
+	if l.usesCgroup() {
+		cgroupPath := l.cgroupPath(name)
+
+		oomKilled, err := l.oomKilled(cgroupPath)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Checking memory events of cgroup for %s", name)
+		}
+
+		if oomKilled {
+			exceeded = fmt.Sprintf("memory limit of %d MB", l.limits.MemoryMB)
+		}
+
+		err = l.removeCgroup(cgroupPath)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Removing cgroup for %s", name)
+		}
+	}
+
+	if exceeded == "" && timedOut {
+		exceeded = fmt.Sprintf("timeout of %d seconds", l.limits.Timeout)
+	}
+
+	return exceeded, nil
+}
+
+// timedOut checks whether timeout command stopped the command since
+// packaging script may also exit with timeout exit statuses on its own
+func (l cgroupResourceLimiter) timedOut(name string, result *boshcmdrunner.CmdResult) bool {
+	l.startTimesLock.Lock()
+	startTime, found := l.startTimes[name]
+	delete(l.startTimes, name)
+	l.startTimesLock.Unlock()
+
+	if !found || result == nil {
+		return false
+	}
+
+	if result.ExitStatus != timeoutExitStatus && result.ExitStatus != timeoutKillExitStatus {
+		return false
+	}
+
+	return l.timeService.Now().Sub(startTime) >= time.Duration(l.limits.Timeout)*time.Second
+}
+
+// removeCgroup kills processes left behind by packaging script
+// since cgroup can only be removed once it has no processes
+func (l cgroupResourceLimiter) removeCgroup(cgroupPath string) error {
+	// cgroup.kill is only available since Linux 5.14
+	killPath := filepath.Join(cgroupPath, "cgroup.kill")
+	if l.fs.FileExists(killPath) {
+		err := l.fs.WriteFileString(killPath, "1")
+		if err != nil {
+			return bosherr.WrapError(err, "Killing remaining processes")
+		}
+	}
+
+	procsPath := filepath.Join(cgroupPath, "cgroup.procs")
+
+	for i := 0; i < cgroupResourceLimiterKillAttempts; i++ {
+		procs, err := l.fs.ReadFileString(procsPath)
+		if err != nil || strings.TrimSpace(procs) == "" {
+			break
+		}
+
+		l.timeService.Sleep(cgroupResourceLimiterKillInterval)
+	}
+
+	// cgroupfs does not allow removing control files
+	_, _, _, err := l.runner.RunCommand("rmdir", cgroupPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Running rmdir")
+	}
+
+	return nil
+}
+
+func (l cgroupResourceLimiter) usesCgroup() bool {
+	return l.limits.CPUPercent > 0 || l.limits.MemoryMB > 0
+}
+
+func (l cgroupResourceLimiter) cgroupPath(name string) string {
+	return filepath.Join(l.cgroupRoot, cgroupResourceLimiterGroup, name)
+}
+
+func (l cgroupResourceLimiter) createCgroup(name string) (string, error) {
+	if !l.fs.FileExists(filepath.Join(l.cgroupRoot, "cgroup.controllers")) {
+		return "", bosherr.Errorf("cgroup v2 is not mounted at %s", l.cgroupRoot)
+	}
+
+	var controllers []string
+	if l.limits.CPUPercent > 0 {
+		controllers = append(controllers, "+cpu")
+	}
+	if l.limits.MemoryMB > 0 {
+		controllers = append(controllers, "+memory")
+	}
+
+	groupPath := filepath.Join(l.cgroupRoot, cgroupResourceLimiterGroup)
+
+	// Controllers must be enabled for children by each parent
+	for _, parentPath := range []string{l.cgroupRoot, groupPath} {
+		err := l.fs.MkdirAll(parentPath, 0755)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Creating cgroup %s", parentPath)
+		}
+
+		err = l.fs.WriteFileString(filepath.Join(parentPath, "cgroup.subtree_control"), strings.Join(controllers, " "))
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Enabling controllers in cgroup %s", parentPath)
+		}
+	}
+
+	cgroupPath := l.cgroupPath(name)
+
+	err := l.fs.MkdirAll(cgroupPath, 0755)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Creating cgroup %s", cgroupPath)
+	}
+
+	settings := map[string]string{}
+
+	if l.limits.CPUPercent > 0 {
+		quota := l.limits.CPUPercent * cgroupResourceLimiterCPUPeriod / 100
+		settings["cpu.max"] = fmt.Sprintf("%d %d", quota, cgroupResourceLimiterCPUPeriod)
+	}
+
+	if l.limits.MemoryMB > 0 {
+		settings["memory.max"] = strconv.Itoa(l.limits.MemoryMB * 1024 * 1024)
+	}
+
+	for fileName, value := range settings {
+		err := l.fs.WriteFileString(filepath.Join(cgroupPath, fileName), value)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Setting %s", fileName)
+		}
+	}
+
+	return cgroupPath, nil
+}
+
+func (l cgroupResourceLimiter) oomKilled(cgroupPath string) (bool, error) {
+	eventsPath := filepath.Join(cgroupPath, "memory.events")
+
+	if l.limits.MemoryMB == 0 || !l.fs.FileExists(eventsPath) {
+		return false, nil
+	}
+
+	events, err := l.fs.ReadFileString(eventsPath)
+	if err != nil {
+		return false, err
+	}
+
+	for _, line := range strings.Split(events, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" && fields[1] != "0" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,224 @@
+package compiler_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshcmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
+	. "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+	faketime "github.com/cloudfoundry/bosh-agent/time/fakes"
+)
+
+var _ = Describe("cgroupResourceLimiter", func() {
+	var (
+		fs          *fakesys.FakeFileSystem
+		runner      *fakesys.FakeCmdRunner
+		timeService *faketime.FakeService
+		limits      Limits
+		limiter     ResourceLimiter
+		cmd         boshsys.Command
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		fs.WriteFileString("/fake-cgroup/cgroup.controllers", "cpu memory")
+
+		runner = fakesys.NewFakeCmdRunner()
+		timeService = &faketime.FakeService{}
+
+		limits = Limits{}
+
+		cmd = boshsys.Command{
+			Name:       "bash",
+			Args:       []string{"-x", "packaging"},
+			Env:        map[string]string{"BOSH_PACKAGE_NAME": "fake-pkg"},
+			WorkingDir: "/fake-compile-dir/fake-pkg",
+		}
+	})
+
+	JustBeforeEach(func() {
+		limiter = NewCgroupResourceLimiter(limits, "/fake-cgroup", fs, runner, timeService)
+	})
+
+	Describe("Limit", func() {
+		Context("when cpu and memory are limited", func() {
+			BeforeEach(func() {
+				limits = Limits{CPUPercent: 150, MemoryMB: 512}
+			})
+
+			It("creates cgroup with limits", func() {
+				_, err := limiter.Limit(cmd, "fake-pkg")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.ReadFileString("/fake-cgroup/cgroup.subtree_control")).To(Equal("+cpu +memory"))
+				Expect(fs.ReadFileString("/fake-cgroup/bosh-packaging/cgroup.subtree_control")).To(Equal("+cpu +memory"))
+				Expect(fs.ReadFileString("/fake-cgroup/bosh-packaging/fake-pkg/cpu.max")).To(Equal("150000 100000"))
+				Expect(fs.ReadFileString("/fake-cgroup/bosh-packaging/fake-pkg/memory.max")).To(Equal("536870912"))
+			})
+
+			It("runs command after joining cgroup", func() {
+				limitedCmd, err := limiter.Limit(cmd, "fake-pkg")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(limitedCmd.Name).To(Equal("bash"))
+				Expect(limitedCmd.Args).To(Equal([]string{
+					"-e", "-c", `echo $$ > '/fake-cgroup/bosh-packaging/fake-pkg/cgroup.procs'; exec "$@"`, "bosh-limits",
+					"bash", "-x", "packaging",
+				}))
+				Expect(limitedCmd.Env).To(Equal(cmd.Env))
+				Expect(limitedCmd.WorkingDir).To(Equal(cmd.WorkingDir))
+			})
+
+			It("returns an error if cgroup v2 is not mounted", func() {
+				fs.RemoveAll("/fake-cgroup/cgroup.controllers")
+
+				_, err := limiter.Limit(cmd, "fake-pkg")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("cgroup v2 is not mounted at /fake-cgroup"))
+			})
+
+			It("returns an error if setting limits fails", func() {
+				fs.WriteFileError = errors.New("fake-write-err")
+
+				_, err := limiter.Limit(cmd, "fake-pkg")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+			})
+		})
+
+		Context("when only timeout is limited", func() {
+			BeforeEach(func() {
+				limits = Limits{Timeout: 3600}
+			})
+
+			It("runs command with timeout without creating cgroup", func() {
+				limitedCmd, err := limiter.Limit(cmd, "fake-pkg")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(limitedCmd.Name).To(Equal("timeout"))
+				Expect(limitedCmd.Args).To(Equal([]string{"--kill-after=10", "3600", "bash", "-x", "packaging"}))
+				Expect(fs.FileExists("/fake-cgroup/bosh-packaging")).To(BeFalse())
+			})
+		})
+
+		Context("when all resources are limited", func() {
+			BeforeEach(func() {
+				limits = Limits{MemoryMB: 512, Timeout: 3600}
+			})
+
+			It("runs command with timeout inside cgroup", func() {
+				limitedCmd, err := limiter.Limit(cmd, "fake-pkg")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(limitedCmd.Name).To(Equal("bash"))
+				Expect(limitedCmd.Args[4:]).To(Equal([]string{"timeout", "--kill-after=10", "3600", "bash", "-x", "packaging"}))
+				Expect(fs.ReadFileString("/fake-cgroup/cgroup.subtree_control")).To(Equal("+memory"))
+			})
+		})
+
+		Context("when nothing is limited", func() {
+			It("returns command as is", func() {
+				limitedCmd, err := limiter.Limit(cmd, "fake-pkg")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(limitedCmd).To(Equal(cmd))
+			})
+		})
+	})
+
+	Describe("Release", func() {
+		var startTime time.Time
+
+		BeforeEach(func() {
+			limits = Limits{MemoryMB: 512, Timeout: 3600}
+
+			startTime = time.Date(2015, time.June, 1, 10, 0, 0, 0, time.UTC)
+			timeService.NowTimes = []time.Time{startTime, startTime.Add(3600 * time.Second)}
+		})
+
+		JustBeforeEach(func() {
+			_, err := limiter.Limit(cmd, "fake-pkg")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("removes cgroup with rmdir", func() {
+			exceeded, err := limiter.Release("fake-pkg", &boshcmdrunner.CmdResult{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exceeded).To(BeEmpty())
+
+			Expect(runner.RunCommands).To(Equal([][]string{{"rmdir", "/fake-cgroup/bosh-packaging/fake-pkg"}}))
+		})
+
+		It("kills processes left in cgroup before removing it", func() {
+			fs.WriteFileString("/fake-cgroup/bosh-packaging/fake-pkg/cgroup.kill", "0")
+
+			_, err := limiter.Release("fake-pkg", &boshcmdrunner.CmdResult{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/fake-cgroup/bosh-packaging/fake-pkg/cgroup.kill")).To(Equal("1"))
+			Expect(runner.RunCommands).To(Equal([][]string{{"rmdir", "/fake-cgroup/bosh-packaging/fake-pkg"}}))
+		})
+
+		It("waits for processes left in cgroup to exit before removing it", func() {
+			fs.WriteFileString("/fake-cgroup/bosh-packaging/fake-pkg/cgroup.procs", "1234\n")
+
+			_, err := limiter.Release("fake-pkg", &boshcmdrunner.CmdResult{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(timeService.SleepInputs).To(HaveLen(50))
+			Expect(runner.RunCommands).To(Equal([][]string{{"rmdir", "/fake-cgroup/bosh-packaging/fake-pkg"}}))
+		})
+
+		It("reports exceeded memory limit when command was killed by OOM killer", func() {
+			fs.WriteFileString("/fake-cgroup/bosh-packaging/fake-pkg/memory.events", "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n")
+
+			exceeded, err := limiter.Release("fake-pkg", &boshcmdrunner.CmdResult{ExitStatus: 137})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exceeded).To(Equal("memory limit of 512 MB"))
+		})
+
+		It("does not report exceeded memory limit when nothing was killed by OOM killer", func() {
+			fs.WriteFileString("/fake-cgroup/bosh-packaging/fake-pkg/memory.events", "low 0\nhigh 0\nmax 3\noom 0\noom_kill 0\n")
+
+			exceeded, err := limiter.Release("fake-pkg", &boshcmdrunner.CmdResult{ExitStatus: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exceeded).To(BeEmpty())
+		})
+
+		It("reports exceeded timeout when command timed out", func() {
+			exceeded, err := limiter.Release("fake-pkg", &boshcmdrunner.CmdResult{ExitStatus: 124})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exceeded).To(Equal("timeout of 3600 seconds"))
+		})
+
+		It("reports exceeded timeout when command had to be killed after timing out", func() {
+			timeService.NowTimes = []time.Time{startTime.Add(3610 * time.Second)}
+
+			exceeded, err := limiter.Release("fake-pkg", &boshcmdrunner.CmdResult{ExitStatus: 137})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exceeded).To(Equal("timeout of 3600 seconds"))
+		})
+
+		It("does not report exceeded timeout when command exited with timeout exit status before timeout", func() {
+			timeService.NowTimes = []time.Time{startTime.Add(10 * time.Second)}
+
+			exceeded, err := limiter.Release("fake-pkg", &boshcmdrunner.CmdResult{ExitStatus: 124})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exceeded).To(BeEmpty())
+		})
+
+		It("returns an error if removing cgroup fails", func() {
+			runner.AddCmdResult("rmdir /fake-cgroup/bosh-packaging/fake-pkg", fakesys.FakeCmdResult{
+				Error: errors.New("fake-rmdir-err"),
+			})
+
+			_, err := limiter.Release("fake-pkg", &boshcmdrunner.CmdResult{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-rmdir-err"))
+		})
+	})
+})
@@ -1,11 +1,82 @@
 package compiler
 
 import (
+	"time"
+
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 )
 
 type Compiler interface {
-	Compile(pkg Package, deps []boshmodels.Package) (blobID, sha1 string, err error)
+	Compile(pkg Package, deps []boshmodels.Package) (Result, error)
+
+	// CompileBatch compiles packages in dependency order, returning results by package name;
+	// dependencies that are not part of the batch must be given as compiledDeps
+	CompileBatch(pkgs []BatchPackage, compiledDeps []boshmodels.Package) (map[string]Result, error)
+}
+
+type Result struct {
+	BlobstoreID string
+	Sha1        string
+
+	// Complete packaging script logs; empty when script was not run
+	LogsBlobstoreID string
+
+	Metadata Metadata
+
+	// Time spent fetching and compiling package; kept out of metadata
+	// so that compiled package contents do not change between compilations
+	Duration time.Duration
+}
+
+// MetadataPath is relative to compiled package contents
+const MetadataPath = ".bosh/compile_metadata.json"
+
+// Metadata describes how package was compiled
+// and is included with compiled package contents
+type Metadata struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	Dependencies []DependencyMetadata `json:"dependencies"`
+
+	Stemcell     StemcellMetadata `json:"stemcell"`
+	AgentVersion string           `json:"agent_version"`
+
+	// Environment variables given to packaging script
+	Env map[string]string `json:"env"`
+}
+
+type DependencyMetadata struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Sha1    string `json:"sha1"`
+}
+
+type StemcellMetadata struct {
+	OperatingSystem string `json:"operating_system"`
+	Version         string `json:"version"`
+}
+
+// Environment describes machine packages are compiled on
+type Environment struct {
+	StemcellOperatingSystem string
+	StemcellVersion         string
+	AgentVersion            string
+}
+
+// PackagingError is returned when packaging script fails
+// and carries blob id of its complete logs
+type PackagingError struct {
+	Err             error
+	LogsBlobstoreID string
+}
+
+func (e PackagingError) Error() string {
+	return e.Err.Error()
+}
+
+func (e PackagingError) BlobstoreID() string {
+	return e.LogsBlobstoreID
 }
 
 type Package struct {
@@ -16,3 +87,23 @@ type Package struct {
 }
 
 type Dependencies map[string]Package
+
+type BatchPackage struct {
+	Package
+
+	// Names of packages this package depends on
+	Dependencies []string
+}
+
+type Options struct {
+	// Run packaging scripts in fresh mount and PID namespaces
+	// with only the compile dir and installed packages visible
+	UseSandbox bool
+
+	// Resource limits enforced on each packaging script; zero values disable limits
+	PackagingLimits Limits
+
+	// Number of packages compiled concurrently by batch compilation;
+	// defaults to number of CPUs
+	Parallelism int
+}
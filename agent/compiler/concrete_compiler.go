@@ -1,8 +1,11 @@
 package compiler
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 
 	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
@@ -12,6 +15,7 @@ import (
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshcmd "github.com/cloudfoundry/bosh-agent/platform/commands"
 	boshsys "github.com/cloudfoundry/bosh-agent/system"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
 )
 
 type CompileDirProvider interface {
@@ -26,6 +30,14 @@ type concreteCompiler struct {
 	compileDirProvider CompileDirProvider
 	packageApplier     packages.Applier
 	packagesBc         boshbc.BundleCollection
+	sandbox            Sandbox
+	limiter            ResourceLimiter
+	environment        Environment
+	timeService        boshtime.Service
+	parallelism        int
+
+	// Serializes installation of dependencies during batch compilation
+	installLock *sync.Mutex
 }
 
 func NewConcreteCompiler(
@@ -36,7 +48,16 @@ func NewConcreteCompiler(
 	compileDirProvider CompileDirProvider,
 	packageApplier packages.Applier,
 	packagesBc boshbc.BundleCollection,
+	sandbox Sandbox,
+	limiter ResourceLimiter,
+	environment Environment,
+	timeService boshtime.Service,
+	parallelism int,
 ) Compiler {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
 	return concreteCompiler{
 		compressor:         compressor,
 		blobstore:          blobstore,
@@ -45,26 +66,193 @@ func NewConcreteCompiler(
 		compileDirProvider: compileDirProvider,
 		packageApplier:     packageApplier,
 		packagesBc:         packagesBc,
+		sandbox:            sandbox,
+		limiter:            limiter,
+		environment:        environment,
+		timeService:        timeService,
+		parallelism:        parallelism,
+		installLock:        &sync.Mutex{},
 	}
 }
 
-func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package) (string, string, error) {
+func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package) (Result, error) {
 	err := c.packageApplier.KeepOnly([]boshmodels.Package{})
 	if err != nil {
-		return "", "", bosherr.WrapError(err, "Removing packages")
+		return Result{}, bosherr.WrapError(err, "Removing packages")
+	}
+
+	err = c.installDependencies(deps)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, compiledPkgBundle, err := c.compile(pkg, deps, "compilation")
+	if err != nil {
+		return Result{}, err
+	}
+
+	err = compiledPkgBundle.Uninstall()
+	if err != nil {
+		return Result{}, bosherr.WrapError(err, "Uninstalling compiled package")
+	}
+
+	err = c.packageApplier.KeepOnly([]boshmodels.Package{})
+	if err != nil {
+		return Result{}, bosherr.WrapError(err, "Removing packages")
+	}
+
+	return result, nil
+}
+
+func (c concreteCompiler) CompileBatch(pkgs []BatchPackage, compiledDeps []boshmodels.Package) (map[string]Result, error) {
+	graph, err := newDependencyGraph(pkgs, compiledDeps)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Ordering packages")
+	}
+
+	err = c.packageApplier.KeepOnly([]boshmodels.Package{})
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Removing packages")
+	}
+
+	results, err := c.compileGraph(graph)
+
+	// Compiled packages are kept installed until the end
+	// so that dependent packages do not need to download them
+	cleanUpErr := c.packageApplier.KeepOnly([]boshmodels.Package{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cleanUpErr != nil {
+		return nil, bosherr.WrapError(cleanUpErr, "Removing packages")
+	}
+
+	return results, nil
+}
+
+type batchCompileResult struct {
+	name   string
+	result Result
+	err    error
+}
+
+func (c concreteCompiler) compileGraph(graph *dependencyGraph) (map[string]Result, error) {
+	results := map[string]Result{}
+	resultsCh := make(chan batchCompileResult)
+
+	ready := graph.Ready()
+
+	var running int
+	var firstErr error
+
+	for {
+		// Stop starting new compilations after first failure
+		for firstErr == nil && len(ready) > 0 && running < c.parallelism {
+			pkg := ready[0]
+			ready = ready[1:]
+
+			deps := graph.Dependencies(pkg, results)
+			running++
+
+			go func(pkg BatchPackage, deps []boshmodels.Package) {
+				result, err := c.compileInBatch(pkg.Package, deps)
+				resultsCh <- batchCompileResult{name: pkg.Name, result: result, err: err}
+			}(pkg, deps)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		compiled := <-resultsCh
+		running--
+
+		if compiled.err != nil {
+			if firstErr == nil {
+				firstErr = bosherr.WrapErrorf(compiled.err, "Compiling package %s", compiled.name)
+			}
+			continue
+		}
+
+		results[compiled.name] = compiled.result
+		ready = append(ready, graph.Complete(compiled.name)...)
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+func (c concreteCompiler) compileInBatch(pkg Package, deps []boshmodels.Package) (Result, error) {
+	c.installLock.Lock()
+	err := c.installDependencies(deps)
+	c.installLock.Unlock()
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Each package keeps its own logs since packages are compiled concurrently
+	result, compiledPkgBundle, err := c.compile(pkg, deps, filepath.Join("compilation", pkg.Name))
+	if err != nil {
+		return Result{}, err
+	}
+
+	c.installLock.Lock()
+	err = c.installAsDependency(pkg, result, compiledPkgBundle)
+	c.installLock.Unlock()
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, nil
+}
+
+// installAsDependency moves compiled package to the bundle that dependent
+// packages look up by sha1 so that it does not need to be downloaded again
+func (c concreteCompiler) installAsDependency(pkg Package, result Result, compiledPkgBundle boshbc.Bundle) error {
+	_, installPath, err := compiledPkgBundle.GetInstallPath()
+	if err != nil {
+		return bosherr.WrapError(err, "Getting compiled package install path")
+	}
+
+	depBundle, err := c.packagesBc.Get(compiledPackage(pkg, result))
+	if err != nil {
+		return bosherr.WrapError(err, "Getting bundle for compiled package")
+	}
+
+	_, _, err = depBundle.Install(installPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Installing compiled package")
 	}
 
+	return nil
+}
+
+func (c concreteCompiler) installDependencies(deps []boshmodels.Package) error {
 	for _, dep := range deps {
 		err := c.packageApplier.Apply(dep)
 		if err != nil {
-			return "", "", bosherr.WrapErrorf(err, "Installing dependent package: '%s'", dep.Name)
+			return bosherr.WrapErrorf(err, "Installing dependent package: '%s'", dep.Name)
 		}
 	}
 
+	return nil
+}
+
+// compile leaves compiled package installed but not enabled
+func (c concreteCompiler) compile(pkg Package, deps []boshmodels.Package, logsJobName string) (Result, boshbc.Bundle, error) {
+	startTime := c.timeService.Now()
+
 	compilePath := filepath.Join(c.compileDirProvider.CompileDir(), pkg.Name)
-	err = c.fetchAndUncompress(pkg, compilePath)
+	err := c.fetchAndUncompress(pkg, compilePath)
 	if err != nil {
-		return "", "", bosherr.WrapErrorf(err, "Fetching package %s", pkg.Name)
+		return Result{}, nil, bosherr.WrapErrorf(err, "Fetching package %s", pkg.Name)
 	}
 
 	defer c.fs.RemoveAll(compilePath)
@@ -76,68 +264,213 @@ func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package) (strin
 
 	compiledPkgBundle, err := c.packagesBc.Get(compiledPkg)
 	if err != nil {
-		return "", "", bosherr.WrapError(err, "Getting bundle for new package")
+		return Result{}, nil, bosherr.WrapError(err, "Getting bundle for new package")
 	}
 
 	_, installPath, err := compiledPkgBundle.InstallWithoutContents()
 	if err != nil {
-		return "", "", bosherr.WrapError(err, "Setting up new package bundle")
+		return Result{}, nil, bosherr.WrapError(err, "Setting up new package bundle")
 	}
 
 	_, enablePath, err := compiledPkgBundle.Enable()
 	if err != nil {
-		return "", "", bosherr.WrapError(err, "Enabling new package bundle")
+		return Result{}, nil, bosherr.WrapError(err, "Enabling new package bundle")
 	}
 
 	scriptPath := filepath.Join(compilePath, "packaging")
 
+	var logsBlobID string
+
+	env := map[string]string{
+		"BOSH_COMPILE_TARGET":  compilePath,
+		"BOSH_INSTALL_TARGET":  enablePath,
+		"BOSH_PACKAGE_NAME":    pkg.Name,
+		"BOSH_PACKAGE_VERSION": pkg.Version,
+	}
+
 	if c.fs.FileExists(scriptPath) {
 		command := boshsys.Command{
-			Name: "bash",
-			Args: []string{"-x", "packaging"},
-			Env: map[string]string{
-				"BOSH_COMPILE_TARGET":  compilePath,
-				"BOSH_INSTALL_TARGET":  enablePath,
-				"BOSH_PACKAGE_NAME":    pkg.Name,
-				"BOSH_PACKAGE_VERSION": pkg.Version,
-			},
+			Name:       "bash",
+			Args:       []string{"-x", "packaging"},
+			Env:        env,
 			WorkingDir: compilePath,
 		}
 
-		_, err := c.runner.RunCommand("compilation", "packaging", command)
+		depPaths, err := c.dependencyInstallPaths(deps)
 		if err != nil {
-			return "", "", bosherr.WrapError(err, "Running packaging script")
+			return Result{}, nil, err
+		}
+
+		command, err = c.sandbox.Isolate(command, depPaths, []string{compilePath, installPath})
+		if err != nil {
+			return Result{}, nil, bosherr.WrapError(err, "Isolating packaging script")
+		}
+
+		command, err = c.limiter.Limit(command, pkg.Name)
+		if err != nil {
+			return Result{}, nil, bosherr.WrapError(err, "Limiting packaging script resources")
+		}
+
+		cmdResult, runErr := c.runner.RunCommand(logsJobName, "packaging", command)
+		if execErr, ok := runErr.(boshcmdrunner.ExecError); ok {
+			cmdResult = execErr.Result()
+		}
+
+		exceeded, err := c.limiter.Release(pkg.Name, cmdResult)
+		if err != nil && runErr == nil {
+			return Result{}, nil, bosherr.WrapError(err, "Releasing packaging script resource limits")
+		}
+
+		if runErr != nil && exceeded != "" {
+			runErr = bosherr.WrapErrorf(runErr, "Packaging script exceeded %s", exceeded)
+		}
+
+		logsBlobID, err = c.uploadLogs(cmdResult)
+		if err != nil && runErr == nil {
+			return Result{}, nil, bosherr.WrapError(err, "Uploading packaging script logs")
+		}
+
+		if runErr != nil {
+			err = PackagingError{Err: runErr, LogsBlobstoreID: logsBlobID}
+			return Result{}, nil, bosherr.WrapError(err, "Running packaging script")
 		}
 	}
 
+	metadata := c.buildMetadata(pkg, deps, env)
+
+	err = c.writeMetadata(metadata, installPath)
+	if err != nil {
+		return Result{}, nil, bosherr.WrapError(err, "Writing compiled package metadata")
+	}
+
 	tmpPackageTar, err := c.compressor.CompressFilesInDir(installPath)
 	if err != nil {
-		return "", "", bosherr.WrapError(err, "Compressing compiled package")
+		return Result{}, nil, bosherr.WrapError(err, "Compressing compiled package")
 	}
 
 	defer c.compressor.CleanUp(tmpPackageTar)
 
 	uploadedBlobID, sha1, err := c.blobstore.Create(tmpPackageTar)
 	if err != nil {
-		return "", "", bosherr.WrapError(err, "Uploading compiled package")
+		return Result{}, nil, bosherr.WrapError(err, "Uploading compiled package")
+	}
+
+	if sha1 == "" {
+		return Result{}, nil, bosherr.Error("Uploading compiled package: blobstore did not return sha1")
 	}
 
 	err = compiledPkgBundle.Disable()
 	if err != nil {
-		return "", "", bosherr.WrapError(err, "Disabling compiled package")
+		return Result{}, nil, bosherr.WrapError(err, "Disabling compiled package")
 	}
 
-	err = compiledPkgBundle.Uninstall()
+	result := Result{
+		BlobstoreID:     uploadedBlobID,
+		Sha1:            sha1,
+		LogsBlobstoreID: logsBlobID,
+		Metadata:        metadata,
+		Duration:        c.timeService.Now().Sub(startTime),
+	}
+
+	return result, compiledPkgBundle, nil
+}
+
+func (c concreteCompiler) dependencyInstallPaths(deps []boshmodels.Package) ([]string, error) {
+	var paths []string
+
+	for _, dep := range deps {
+		depBundle, err := c.packagesBc.Get(dep)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Getting bundle for dependent package '%s'", dep.Name)
+		}
+
+		_, path, err := depBundle.GetInstallPath()
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Getting install path of dependent package '%s'", dep.Name)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+func (c concreteCompiler) buildMetadata(pkg Package, deps []boshmodels.Package, env map[string]string) Metadata {
+	depsMetadata := []DependencyMetadata{}
+
+	for _, dep := range deps {
+		depsMetadata = append(depsMetadata, DependencyMetadata{
+			Name:    dep.Name,
+			Version: dep.Version,
+			Sha1:    dep.Source.Sha1,
+		})
+	}
+
+	// Keep manifest reproducible regardless of dependencies order
+	sort.Sort(dependencyMetadataByName(depsMetadata))
+
+	return Metadata{
+		Name:         pkg.Name,
+		Version:      pkg.Version,
+		Dependencies: depsMetadata,
+		Stemcell: StemcellMetadata{
+			OperatingSystem: c.environment.StemcellOperatingSystem,
+			Version:         c.environment.StemcellVersion,
+		},
+		AgentVersion: c.environment.AgentVersion,
+		Env:          env,
+	}
+}
+
+type dependencyMetadataByName []DependencyMetadata
+
+func (s dependencyMetadataByName) Len() int           { return len(s) }
+func (s dependencyMetadataByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s dependencyMetadataByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (c concreteCompiler) writeMetadata(metadata Metadata, installPath string) error {
+	metadataBytes, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
-		return "", "", bosherr.WrapError(err, "Uninstalling compiled package")
+		return bosherr.WrapError(err, "Marshalling metadata")
 	}
 
-	err = c.packageApplier.KeepOnly([]boshmodels.Package{})
+	return c.fs.WriteFile(filepath.Join(installPath, MetadataPath), metadataBytes)
+}
+
+// uploadLogs uploads complete stdout and stderr of the packaging script
+// since command runner only keeps truncated output in memory
+func (c concreteCompiler) uploadLogs(cmdResult *boshcmdrunner.CmdResult) (string, error) {
+	if cmdResult == nil || cmdResult.StdoutPath == "" {
+		return "", nil
+	}
+
+	logsDir, err := c.fs.TempDir("bosh-compile-logs")
+	if err != nil {
+		return "", bosherr.WrapError(err, "Creating logs dir")
+	}
+
+	defer c.fs.RemoveAll(logsDir)
+
+	for _, logPath := range []string{cmdResult.StdoutPath, cmdResult.StderrPath} {
+		err := c.fs.CopyFile(logPath, filepath.Join(logsDir, filepath.Base(logPath)))
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Copying log %s", logPath)
+		}
+	}
+
+	tarball, err := c.compressor.CompressFilesInDir(logsDir)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Compressing logs")
+	}
+
+	defer c.compressor.CleanUp(tarball)
+
+	blobID, _, err := c.blobstore.Create(tarball)
 	if err != nil {
-		return "", "", bosherr.WrapError(err, "Removing packages")
+		return "", bosherr.WrapError(err, "Uploading logs")
 	}
 
-	return uploadedBlobID, sha1, nil
+	return blobID, nil
 }
 
 func (c concreteCompiler) fetchAndUncompress(pkg Package, targetDir string) error {
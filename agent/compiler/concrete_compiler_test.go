@@ -1,6 +1,7 @@
 package compiler_test
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"time"
@@ -8,15 +9,22 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	fakebc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection/fakes"
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshpackages "github.com/cloudfoundry/bosh-agent/agent/applier/packages"
 	fakepackages "github.com/cloudfoundry/bosh-agent/agent/applier/packages/fakes"
+	boshcmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
 	fakecmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner/fakes"
 	. "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	fakecomp "github.com/cloudfoundry/bosh-agent/agent/compiler/fakes"
 	fakeblobstore "github.com/cloudfoundry/bosh-agent/blobstore/fakes"
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	fakecmd "github.com/cloudfoundry/bosh-agent/platform/commands/fakes"
 	boshsys "github.com/cloudfoundry/bosh-agent/system"
 	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+	faketime "github.com/cloudfoundry/bosh-agent/time/fakes"
 )
 
 type FakeCompileDirProvider struct {
@@ -25,6 +33,14 @@ type FakeCompileDirProvider struct {
 
 func (cdp FakeCompileDirProvider) CompileDir() string { return cdp.Dir }
 
+type fakeExecErr struct {
+	result *boshcmdrunner.CmdResult
+}
+
+func (e fakeExecErr) Error() string { return "fake-exec-err" }
+
+func (e fakeExecErr) Result() *boshcmdrunner.CmdResult { return e.result }
+
 func getCompileArgs() (Package, []boshmodels.Package) {
 	pkg := Package{
 		BlobstoreID: "blobstore_id",
@@ -65,6 +81,9 @@ func init() {
 			runner         *fakecmdrunner.FakeFileLoggingCmdRunner
 			packageApplier *fakepackages.FakeApplier
 			packagesBc     *fakebc.FakeBundleCollection
+			sandbox        *fakecomp.FakeSandbox
+			limiter        *fakecomp.FakeResourceLimiter
+			timeService    *faketime.FakeService
 		)
 
 		BeforeEach(func() {
@@ -74,6 +93,9 @@ func init() {
 			runner = fakecmdrunner.NewFakeFileLoggingCmdRunner()
 			packageApplier = fakepackages.NewFakeApplier()
 			packagesBc = fakebc.NewFakeBundleCollection()
+			sandbox = fakecomp.NewFakeSandbox()
+			limiter = fakecomp.NewFakeResourceLimiter()
+			timeService = &faketime.FakeService{}
 
 			compiler = NewConcreteCompiler(
 				compressor,
@@ -83,6 +105,15 @@ func init() {
 				FakeCompileDirProvider{Dir: "/fake-compile-dir"},
 				packageApplier,
 				packagesBc,
+				sandbox,
+				limiter,
+				Environment{
+					StemcellOperatingSystem: "fake-os",
+					StemcellVersion:         "fake-stemcell-version",
+					AgentVersion:            "fake-agent-version",
+				},
+				timeService,
+				1,
 			)
 		})
 
@@ -107,6 +138,7 @@ func init() {
 				bundle.EnablePath = "/fake-dir/packages/pkg_name"
 
 				compressor.CompressFilesInDirTarballPath = "/tmp/compressed-compiled-package"
+				blobstore.CreateFingerprint = "fake-blob-sha1"
 
 				pkg, pkgDeps = getCompileArgs()
 			})
@@ -115,15 +147,100 @@ func init() {
 				blobstore.CreateBlobID = "fake-blob-id"
 				blobstore.CreateFingerprint = "fake-blob-sha1"
 
-				blobID, sha1, err := compiler.Compile(pkg, pkgDeps)
+				result, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(blobID).To(Equal("fake-blob-id"))
-				Expect(sha1).To(Equal("fake-blob-sha1"))
+				Expect(result.BlobstoreID).To(Equal("fake-blob-id"))
+				Expect(result.Sha1).To(Equal("fake-blob-sha1"))
+				Expect(result.LogsBlobstoreID).To(BeEmpty())
+			})
+
+			Describe("metadata", func() {
+				var expectedMetadata Metadata
+
+				BeforeEach(func() {
+					startTime := time.Date(2015, time.June, 1, 10, 0, 0, 0, time.UTC)
+					timeService.NowTimes = []time.Time{startTime, startTime.Add(90 * time.Second)}
+
+					// Dependencies are listed by name regardless of given order
+					pkgDeps[0], pkgDeps[1] = pkgDeps[1], pkgDeps[0]
+
+					expectedMetadata = Metadata{
+						Name:    "pkg_name",
+						Version: "pkg_version",
+						Dependencies: []DependencyMetadata{
+							{Name: "first_dep_name", Version: "first_dep_version", Sha1: "first_dep_sha1"},
+							{Name: "sec_dep_name", Version: "sec_dep_version", Sha1: "sec_dep_sha1"},
+						},
+						Stemcell: StemcellMetadata{
+							OperatingSystem: "fake-os",
+							Version:         "fake-stemcell-version",
+						},
+						AgentVersion: "fake-agent-version",
+						Env: map[string]string{
+							"BOSH_COMPILE_TARGET":  "/fake-compile-dir/pkg_name",
+							"BOSH_INSTALL_TARGET":  "/fake-dir/packages/pkg_name",
+							"BOSH_PACKAGE_NAME":    "pkg_name",
+							"BOSH_PACKAGE_VERSION": "pkg_version",
+						},
+					}
+				})
+
+				It("returns metadata describing compilation", func() {
+					result, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.Metadata).To(Equal(expectedMetadata))
+				})
+
+				It("returns compilation duration without including it in metadata", func() {
+					var metadataBytes []byte
+
+					blobstore.CreateCallBack = func() {
+						metadataBytes, _ = fs.ReadFile("/fake-dir/data/packages/pkg_name/pkg_version/.bosh/compile_metadata.json")
+					}
+
+					result, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result.Duration).To(Equal(90 * time.Second))
+					Expect(string(metadataBytes)).ToNot(ContainSubstring("duration"))
+				})
+
+				It("includes metadata with compiled package before compressing it", func() {
+					var metadataBytes []byte
+
+					blobstore.CreateCallBack = func() {
+						metadataBytes, _ = fs.ReadFile("/fake-dir/data/packages/pkg_name/pkg_version/.bosh/compile_metadata.json")
+					}
+
+					_, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).ToNot(HaveOccurred())
+
+					var metadata Metadata
+					err = json.Unmarshal(metadataBytes, &metadata)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metadata).To(Equal(expectedMetadata))
+				})
+
+				It("returns an error if writing metadata fails", func() {
+					fs.WriteFileError = errors.New("fake-write-err")
+
+					_, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+					Expect(blobstore.CreateFileNames).To(BeEmpty())
+				})
+			})
+
+			It("returns an error if blobstore does not return sha1 of created compiled package", func() {
+				blobstore.CreateFingerprint = ""
+
+				_, err := compiler.Compile(pkg, pkgDeps)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("blobstore did not return sha1"))
 			})
 
 			It("cleans up all packages before and after applying dependent packages", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(packageApplier.ActionsCalled).To(Equal([]string{"KeepOnly", "Apply", "Apply", "KeepOnly"}))
 				Expect(packageApplier.KeptOnlyPackages).To(BeEmpty())
@@ -132,13 +249,13 @@ func init() {
 			It("returns an error if cleaning up packages fails", func() {
 				packageApplier.KeepOnlyErr = errors.New("fake-keep-only-error")
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-keep-only-error"))
 			})
 
 			It("fetches source package from blobstore without checking SHA1 by default because of Director bug", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(blobstore.GetBlobIDs[0]).To(Equal("blobstore_id"))
@@ -146,7 +263,7 @@ func init() {
 			})
 
 			It("fetches source package from blobstore and checks SHA1 by default in future", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(blobstore.GetBlobIDs[0]).To(Equal("blobstore_id"))
@@ -162,7 +279,7 @@ func init() {
 			It("returns an error if removing compile target directory during uncompression fails", func() {
 				fs.RegisterRemoveAllError("/fake-compile-dir/pkg_name", errors.New("fake-remove-error"))
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-remove-error"))
 			})
@@ -170,7 +287,7 @@ func init() {
 			It("returns an error if creating compile target directory during uncompression fails", func() {
 				fs.RegisterMkdirAllError("/fake-compile-dir/pkg_name", errors.New("fake-mkdir-error"))
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-mkdir-error"))
 			})
@@ -178,7 +295,7 @@ func init() {
 			It("returns an error if removing temporary compile target directory during uncompression fails", func() {
 				fs.RegisterRemoveAllError("/fake-compile-dir/pkg_name-bosh-agent-unpack", errors.New("fake-remove-error"))
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-remove-error"))
 			})
@@ -186,25 +303,25 @@ func init() {
 			It("returns an error if creating temporary compile target directory during uncompression fails", func() {
 				fs.RegisterMkdirAllError("/fake-compile-dir/pkg_name-bosh-agent-unpack", errors.New("fake-mkdir-error"))
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-mkdir-error"))
 			})
 
 			It("installs dependent packages", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(packageApplier.AppliedPackages).To(Equal(pkgDeps))
 			})
 
 			It("cleans up the compile directory", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(fs.FileExists("/fake-compile-dir/pkg_name")).To(BeFalse())
 			})
 
 			It("installs, enables and later cleans up bundle", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(bundle.ActionsCalled).To(Equal([]string{
 					"InstallWithoutContents",
@@ -222,7 +339,7 @@ func init() {
 				})
 
 				It("runs packaging script ", func() {
-					_, _, err := compiler.Compile(pkg, pkgDeps)
+					_, err := compiler.Compile(pkg, pkgDeps)
 					Expect(err).ToNot(HaveOccurred())
 
 					expectedCmd := boshsys.Command{
@@ -243,23 +360,191 @@ func init() {
 					Expect(runner.RunCommandTaskName).To(Equal("packaging"))
 				})
 
+				It("runs packaging script isolated by sandbox with only dependencies, compile dir and install dir visible", func() {
+					sandbox.IsolateCmd = boshsys.Command{Name: "fake-isolated-cmd"}
+
+					packagesBc.FakeGet(pkgDeps[0]).GetDirPath = "/fake-dir/data/packages/first_dep_name/first_dep_version"
+					packagesBc.FakeGet(pkgDeps[1]).GetDirPath = "/fake-dir/data/packages/sec_dep_name/sec_dep_version"
+
+					_, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(sandbox.IsolateCmds).To(HaveLen(1))
+					Expect(sandbox.IsolateCmds[0].Name).To(Equal("bash"))
+					Expect(sandbox.IsolateReadOnlyDirs).To(Equal([][]string{{
+						"/fake-dir/data/packages/first_dep_name/first_dep_version",
+						"/fake-dir/data/packages/sec_dep_name/sec_dep_version",
+					}}))
+					Expect(sandbox.IsolateWritableDirs).To(Equal([][]string{{
+						"/fake-compile-dir/pkg_name",
+						"/fake-dir/data/packages/pkg_name/pkg_version",
+					}}))
+
+					Expect(runner.RunCommands).To(Equal([]boshsys.Command{{Name: "fake-isolated-cmd"}}))
+				})
+
+				It("runs isolated packaging script within resource limits", func() {
+					sandbox.IsolateCmd = boshsys.Command{Name: "fake-isolated-cmd"}
+					limiter.LimitCmd = boshsys.Command{Name: "fake-limited-cmd"}
+					runner.RunCommandResult = &boshcmdrunner.CmdResult{ExitStatus: 0}
+
+					_, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(limiter.LimitCmds).To(Equal([]boshsys.Command{{Name: "fake-isolated-cmd"}}))
+					Expect(limiter.LimitNames).To(Equal([]string{"pkg_name"}))
+					Expect(runner.RunCommands).To(Equal([]boshsys.Command{{Name: "fake-limited-cmd"}}))
+
+					Expect(limiter.ReleaseNames).To(Equal([]string{"pkg_name"}))
+					Expect(limiter.ReleaseResults).To(Equal([]*boshcmdrunner.CmdResult{runner.RunCommandResult}))
+				})
+
+				It("returns an error if limiting packaging script resources fails", func() {
+					limiter.LimitErr = errors.New("fake-limit-err")
+
+					_, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-limit-err"))
+					Expect(runner.RunCommands).To(BeEmpty())
+				})
+
+				It("returns an error if releasing resource limits fails", func() {
+					limiter.ReleaseErr = errors.New("fake-release-err")
+
+					_, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-release-err"))
+				})
+
+				It("returns an error describing exceeded limit when packaging script fails", func() {
+					runner.RunCommandErr = errors.New("fake-packaging-error")
+					limiter.ReleaseExceeded = "fake-limit"
+
+					_, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Packaging script exceeded fake-limit: fake-packaging-error"))
+
+					complexErr, ok := err.(bosherr.ComplexError)
+					Expect(ok).To(BeTrue())
+					Expect(complexErr.Cause).To(BeAssignableToTypeOf(PackagingError{}))
+				})
+
+				It("returns an error if dependency install path cannot be found", func() {
+					packagesBc.FakeGet(pkgDeps[0]).GetDirError = errors.New("fake-get-install-path-err")
+
+					_, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Getting install path of dependent package 'first_dep_name'"))
+					Expect(err.Error()).To(ContainSubstring("fake-get-install-path-err"))
+				})
+
+				It("returns an error if isolating packaging script fails", func() {
+					sandbox.IsolateErr = errors.New("fake-isolate-err")
+
+					_, err := compiler.Compile(pkg, pkgDeps)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-isolate-err"))
+					Expect(runner.RunCommands).To(BeEmpty())
+				})
+
 				It("propagates the error from packaging script", func() {
 					runner.RunCommandErr = errors.New("fake-packaging-error")
 
-					_, _, err := compiler.Compile(pkg, pkgDeps)
+					_, err := compiler.Compile(pkg, pkgDeps)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-packaging-error"))
 				})
+
+				Context("when packaging script logs are available", func() {
+					var cmdResult *boshcmdrunner.CmdResult
+
+					BeforeEach(func() {
+						fs.WriteFileString("/fake-logs/packaging.stdout.log", "fake-stdout")
+						fs.WriteFileString("/fake-logs/packaging.stderr.log", "fake-stderr")
+						fs.TempDirDir = "/fake-tmp-logs-dir"
+
+						cmdResult = &boshcmdrunner.CmdResult{
+							StdoutPath: "/fake-logs/packaging.stdout.log",
+							StderrPath: "/fake-logs/packaging.stderr.log",
+						}
+
+						compressor.CompressFilesInDirTarballPath = "/fake-compressed-tarball"
+						blobstore.CreateBlobIDs = []string{"fake-logs-blob-id", "fake-blob-id"}
+					})
+
+					It("uploads complete logs and returns their blob id", func() {
+						runner.RunCommandResult = cmdResult
+
+						var logsCopied []string
+						blobstore.CreateCallBack = func() {
+							if len(blobstore.CreateFileNames) == 1 {
+								logsCopied = []string{
+									compressor.CompressFilesInDirDir,
+									fs.GetFileTestStat("/fake-tmp-logs-dir/packaging.stdout.log").StringContents(),
+									fs.GetFileTestStat("/fake-tmp-logs-dir/packaging.stderr.log").StringContents(),
+								}
+							}
+						}
+
+						result, err := compiler.Compile(pkg, pkgDeps)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(logsCopied).To(Equal([]string{"/fake-tmp-logs-dir", "fake-stdout", "fake-stderr"}))
+						Expect(result.BlobstoreID).To(Equal("fake-blob-id"))
+						Expect(result.LogsBlobstoreID).To(Equal("fake-logs-blob-id"))
+
+						Expect(fs.FileExists("/fake-tmp-logs-dir")).To(BeFalse())
+					})
+
+					It("returns an error if uploading logs fails", func() {
+						runner.RunCommandResult = cmdResult
+						blobstore.CreateErrs = []error{errors.New("fake-create-logs-err")}
+
+						_, err := compiler.Compile(pkg, pkgDeps)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-create-logs-err"))
+					})
+
+					Context("when packaging script fails", func() {
+						BeforeEach(func() {
+							runner.RunCommandErr = fakeExecErr{result: cmdResult}
+						})
+
+						It("uploads complete logs and returns packaging error with their blob id", func() {
+							_, err := compiler.Compile(pkg, pkgDeps)
+							Expect(err).To(HaveOccurred())
+							Expect(err.Error()).To(ContainSubstring("fake-exec-err"))
+
+							complexErr, ok := err.(bosherr.ComplexError)
+							Expect(ok).To(BeTrue())
+							Expect(complexErr.Cause).To(Equal(PackagingError{
+								Err:             fakeExecErr{result: cmdResult},
+								LogsBlobstoreID: "fake-logs-blob-id",
+							}))
+
+							Expect(blobstore.CreateFileNames).To(Equal([]string{"/fake-compressed-tarball"}))
+						})
+
+						It("returns packaging error if uploading logs fails", func() {
+							blobstore.CreateErrs = []error{errors.New("fake-create-logs-err")}
+
+							_, err := compiler.Compile(pkg, pkgDeps)
+							Expect(err).To(HaveOccurred())
+							Expect(err.Error()).To(ContainSubstring("fake-exec-err"))
+							Expect(err.Error()).ToNot(ContainSubstring("fake-create-logs-err"))
+						})
+					})
+				})
 			})
 
 			It("does not run packaging script when script does not exist", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(runner.RunCommands).To(BeEmpty())
 			})
 
 			It("compresses compiled package", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 
 				// archive was downloaded from the blobstore and decompress to this temp dir
@@ -278,7 +563,7 @@ func init() {
 			It("uploads compressed package to blobstore", func() {
 				compressor.CompressFilesInDirTarballPath = "/tmp/compressed-compiled-package"
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(blobstore.CreateFileNames[0]).To(Equal("/tmp/compressed-compiled-package"))
 			})
@@ -286,7 +571,7 @@ func init() {
 			It("returs error if uploading compressed package fails", func() {
 				blobstore.CreateErr = errors.New("fake-create-err")
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-create-err"))
 			})
@@ -298,7 +583,7 @@ func init() {
 					beforeCleanUpTarballPath = compressor.CleanUpTarballPath
 				}
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, err := compiler.Compile(pkg, pkgDeps)
 				Expect(err).ToNot(HaveOccurred())
 
 				// Compressed package is not cleaned up before blobstore upload
@@ -309,5 +594,215 @@ func init() {
 				Expect(afterCleanUpTarballPath).To(Equal("/tmp/compressed-compiled-package"))
 			})
 		})
+
+		Describe("CompileBatch", func() {
+			var (
+				pkgs         []BatchPackage
+				compiledDeps []boshmodels.Package
+			)
+
+			batchPackage := func(name string, deps ...string) BatchPackage {
+				return BatchPackage{
+					Package: Package{
+						BlobstoreID: name + "-source-blob-id",
+						Name:        name,
+						Sha1:        name + "-source-sha1",
+						Version:     name + "-version",
+					},
+					Dependencies: deps,
+				}
+			}
+
+			BeforeEach(func() {
+				pkgs = []BatchPackage{
+					batchPackage("pkg-b", "pkg-a"),
+					batchPackage("pkg-a", "compiled-dep"),
+					batchPackage("pkg-c"),
+				}
+
+				compiledDeps = []boshmodels.Package{
+					{
+						Name:    "compiled-dep",
+						Version: "compiled-dep-version",
+						Source: boshmodels.Source{
+							Sha1:        "compiled-dep-sha1",
+							BlobstoreID: "compiled-dep-blob-id",
+						},
+					},
+				}
+
+				blobstore.CreateBlobIDs = []string{"pkg-a-blob-id", "pkg-c-blob-id", "pkg-b-blob-id"}
+				blobstore.CreateFingerprints = []string{"pkg-a-sha1", "pkg-c-sha1", "pkg-b-sha1"}
+			})
+
+			It("compiles packages after their dependencies and returns results by package name", func() {
+				results, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(blobstore.GetBlobIDs).To(Equal([]string{
+					"pkg-a-source-blob-id",
+					"pkg-c-source-blob-id",
+					"pkg-b-source-blob-id",
+				}))
+
+				Expect(results).To(HaveLen(3))
+
+				for _, name := range []string{"pkg-a", "pkg-b", "pkg-c"} {
+					Expect(results[name].BlobstoreID).To(Equal(name + "-blob-id"))
+					Expect(results[name].Sha1).To(Equal(name + "-sha1"))
+					Expect(results[name].Metadata.Name).To(Equal(name))
+				}
+
+				Expect(results["pkg-b"].Metadata.Dependencies).To(Equal([]DependencyMetadata{
+					{Name: "pkg-a", Version: "pkg-a-version", Sha1: "pkg-a-sha1"},
+				}))
+			})
+
+			It("installs already compiled dependencies and freshly compiled packages as dependencies", func() {
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(packageApplier.AppliedPackages).To(Equal([]boshmodels.Package{
+					compiledDeps[0],
+					{
+						Name:    "pkg-a",
+						Version: "pkg-a-version",
+						Source: boshmodels.Source{
+							Sha1:        "pkg-a-sha1",
+							BlobstoreID: "pkg-a-blob-id",
+						},
+					},
+				}))
+			})
+
+			It("keeps compiled packages installed until all packages are compiled", func() {
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).ToNot(HaveOccurred())
+
+				bundle := packagesBc.FakeGet(boshmodels.Package{Name: "pkg-a", Version: "pkg-a-version"})
+				Expect(bundle.ActionsCalled).To(Equal([]string{"InstallWithoutContents", "Enable", "Disable"}))
+
+				depBundle := packagesBc.FakeGet(boshmodels.Package{
+					Name:    "pkg-a",
+					Version: "pkg-a-version",
+					Source:  boshmodels.Source{Sha1: "pkg-a-sha1", BlobstoreID: "pkg-a-blob-id"},
+				})
+				Expect(depBundle.ActionsCalled).To(Equal([]string{"Install"}))
+
+				Expect(packageApplier.ActionsCalled).To(Equal([]string{"KeepOnly", "Apply", "Apply", "KeepOnly"}))
+				Expect(packageApplier.KeptOnlyPackages).To(BeEmpty())
+			})
+
+			It("returns an error if installing compiled package as dependency fails", func() {
+				depBundle := packagesBc.FakeGet(boshmodels.Package{
+					Name:    "pkg-a",
+					Version: "pkg-a-version",
+					Source:  boshmodels.Source{Sha1: "pkg-a-sha1", BlobstoreID: "pkg-a-blob-id"},
+				})
+				depBundle.InstallError = errors.New("fake-install-err")
+
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Compiling package pkg-a"))
+				Expect(err.Error()).To(ContainSubstring("fake-install-err"))
+			})
+
+			It("does not download packages compiled in the same batch when installing them as dependencies", func() {
+				logger := boshlog.NewLogger(boshlog.LevelNone)
+				fileBc := boshbc.NewFileBundleCollection("/fake-data", "/fake-enable", "packages", fs, logger)
+				applier := boshpackages.NewCompiledPackageApplier(fileBc, true, blobstore, compressor, fs, logger)
+
+				compiler = NewConcreteCompiler(
+					compressor,
+					blobstore,
+					fs,
+					runner,
+					FakeCompileDirProvider{Dir: "/fake-compile-dir"},
+					applier,
+					fileBc,
+					sandbox,
+					limiter,
+					Environment{},
+					timeService,
+					1,
+				)
+
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(blobstore.GetBlobIDs).To(Equal([]string{
+					"compiled-dep-blob-id",
+					"pkg-a-source-blob-id",
+					"pkg-c-source-blob-id",
+					"pkg-b-source-blob-id",
+				}))
+			})
+
+			It("keeps packaging script logs of each package separately", func() {
+				compressor.DecompressFileToDirCallBack = func() {
+					for _, name := range []string{"pkg-a", "pkg-b", "pkg-c"} {
+						fs.WriteFileString("/fake-compile-dir/"+name+"/packaging", "hi")
+					}
+				}
+
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(runner.RunCommands).To(HaveLen(3))
+				Expect(runner.RunCommandJobName).To(Equal("compilation/pkg-b"))
+				Expect(runner.RunCommandTaskName).To(Equal("packaging"))
+			})
+
+			It("does not compile packages after one fails and returns its error", func() {
+				blobstore.GetErrs = []error{errors.New("fake-get-err")}
+
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Compiling package pkg-a"))
+				Expect(err.Error()).To(ContainSubstring("fake-get-err"))
+
+				Expect(blobstore.GetBlobIDs).To(Equal([]string{"pkg-a-source-blob-id"}))
+				Expect(packageApplier.ActionsCalled).To(Equal([]string{"KeepOnly", "Apply", "KeepOnly"}))
+			})
+
+			It("returns an error if dependency is not specified", func() {
+				pkgs = append(pkgs, batchPackage("pkg-d", "fake-unknown-dep"))
+
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Dependency 'fake-unknown-dep' of package 'pkg-d' is not specified"))
+				Expect(blobstore.GetBlobIDs).To(BeEmpty())
+			})
+
+			It("returns an error if package is specified more than once", func() {
+				pkgs = append(pkgs, batchPackage("pkg-a"))
+
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Package 'pkg-a' is specified more than once"))
+				Expect(blobstore.GetBlobIDs).To(BeEmpty())
+			})
+
+			It("returns an error if packages have cyclic dependencies", func() {
+				pkgs = []BatchPackage{
+					batchPackage("pkg-a", "pkg-b"),
+					batchPackage("pkg-b", "pkg-a"),
+					batchPackage("pkg-c"),
+				}
+
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Packages [pkg-a pkg-b] have cyclic dependencies"))
+				Expect(blobstore.GetBlobIDs).To(BeEmpty())
+			})
+
+			It("returns an error if removing packages after compilation fails", func() {
+				packageApplier.KeepOnlyErr = errors.New("fake-keep-only-err")
+
+				_, err := compiler.CompileBatch(pkgs, compiledDeps)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-keep-only-err"))
+			})
+		})
 	})
 }
@@ -0,0 +1,160 @@
+package compiler
+
+import (
+	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+)
+
+// dependencyGraph tracks which packages of a batch
+// can be compiled once their dependencies are compiled
+type dependencyGraph struct {
+	pkgs         map[string]BatchPackage
+	compiledDeps map[string]boshmodels.Package
+
+	// Names of batch packages that depend on a package
+	dependents map[string][]string
+
+	// Number of batch dependencies that are not compiled yet
+	pending map[string]int
+
+	order []string
+}
+
+func newDependencyGraph(pkgs []BatchPackage, compiledDeps []boshmodels.Package) (*dependencyGraph, error) {
+	g := &dependencyGraph{
+		pkgs:         map[string]BatchPackage{},
+		compiledDeps: map[string]boshmodels.Package{},
+		dependents:   map[string][]string{},
+		pending:      map[string]int{},
+	}
+
+	for _, dep := range compiledDeps {
+		g.compiledDeps[dep.Name] = dep
+	}
+
+	for _, pkg := range pkgs {
+		if _, found := g.pkgs[pkg.Name]; found {
+			return nil, bosherr.Errorf("Package '%s' is specified more than once", pkg.Name)
+		}
+
+		g.pkgs[pkg.Name] = pkg
+		g.order = append(g.order, pkg.Name)
+	}
+
+	for _, pkg := range pkgs {
+		for _, depName := range pkg.Dependencies {
+			if _, found := g.pkgs[depName]; found {
+				g.dependents[depName] = append(g.dependents[depName], pkg.Name)
+				g.pending[pkg.Name]++
+				continue
+			}
+
+			if _, found := g.compiledDeps[depName]; !found {
+				return nil, bosherr.Errorf("Dependency '%s' of package '%s' is not specified", depName, pkg.Name)
+			}
+		}
+	}
+
+	err := g.checkCycles()
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// Ready returns packages without batch dependencies
+func (g *dependencyGraph) Ready() []BatchPackage {
+	var ready []BatchPackage
+
+	for _, name := range g.order {
+		if g.pending[name] == 0 {
+			ready = append(ready, g.pkgs[name])
+		}
+	}
+
+	return ready
+}
+
+// Complete returns packages that became ready after given package was compiled
+func (g *dependencyGraph) Complete(name string) []BatchPackage {
+	var ready []BatchPackage
+
+	for _, dependentName := range g.dependents[name] {
+		g.pending[dependentName]--
+
+		if g.pending[dependentName] == 0 {
+			ready = append(ready, g.pkgs[dependentName])
+		}
+	}
+
+	return ready
+}
+
+// Dependencies resolves dependencies of a package to compiled packages
+func (g *dependencyGraph) Dependencies(pkg BatchPackage, results map[string]Result) []boshmodels.Package {
+	var deps []boshmodels.Package
+
+	for _, depName := range pkg.Dependencies {
+		if result, found := results[depName]; found {
+			deps = append(deps, compiledPackage(g.pkgs[depName].Package, result))
+		} else {
+			deps = append(deps, g.compiledDeps[depName])
+		}
+	}
+
+	return deps
+}
+
+// compiledPackage identifies package compiled in a batch by its compiled sha1
+func compiledPackage(pkg Package, result Result) boshmodels.Package {
+	return boshmodels.Package{
+		Name:    pkg.Name,
+		Version: pkg.Version,
+		Source: boshmodels.Source{
+			Sha1:        result.Sha1,
+			BlobstoreID: result.BlobstoreID,
+		},
+	}
+}
+
+func (g *dependencyGraph) checkCycles() error {
+	pending := map[string]int{}
+	for name, count := range g.pending {
+		pending[name] = count
+	}
+
+	var queue []string
+	for _, name := range g.order {
+		if pending[name] == 0 {
+			queue = append(queue, name)
+		}
+	}
+
+	var visited int
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		visited++
+
+		for _, dependentName := range g.dependents[name] {
+			pending[dependentName]--
+			if pending[dependentName] == 0 {
+				queue = append(queue, dependentName)
+			}
+		}
+	}
+
+	if visited != len(g.order) {
+		var cyclic []string
+		for _, name := range g.order {
+			if pending[name] > 0 {
+				cyclic = append(cyclic, name)
+			}
+		}
+		return bosherr.Errorf("Packages %v have cyclic dependencies", cyclic)
+	}
+
+	return nil
+}
@@ -8,9 +8,13 @@ import (
 type FakeCompiler struct {
 	CompilePkg    boshcomp.Package
 	CompileDeps   []boshmodels.Package
-	CompileBlobID string
-	CompileSha1   string
+	CompileResult boshcomp.Result
 	CompileErr    error
+
+	CompileBatchPkgs         []boshcomp.BatchPackage
+	CompileBatchCompiledDeps []boshmodels.Package
+	CompileBatchResults      map[string]boshcomp.Result
+	CompileBatchErr          error
 }
 
 func NewFakeCompiler() (c *FakeCompiler) {
@@ -18,11 +22,14 @@ func NewFakeCompiler() (c *FakeCompiler) {
 	return
 }
 
-func (c *FakeCompiler) Compile(pkg boshcomp.Package, deps []boshmodels.Package) (blobID, sha1 string, err error) {
+func (c *FakeCompiler) Compile(pkg boshcomp.Package, deps []boshmodels.Package) (boshcomp.Result, error) {
 	c.CompilePkg = pkg
 	c.CompileDeps = deps
-	blobID = c.CompileBlobID
-	sha1 = c.CompileSha1
-	err = c.CompileErr
-	return
+	return c.CompileResult, c.CompileErr
+}
+
+func (c *FakeCompiler) CompileBatch(pkgs []boshcomp.BatchPackage, compiledDeps []boshmodels.Package) (map[string]boshcomp.Result, error) {
+	c.CompileBatchPkgs = pkgs
+	c.CompileBatchCompiledDeps = compiledDeps
+	return c.CompileBatchResults, c.CompileBatchErr
 }
@@ -0,0 +1,39 @@
+package fakes
+
+import (
+	boshcmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+type FakeResourceLimiter struct {
+	LimitCmds  []boshsys.Command
+	LimitNames []string
+	LimitCmd   boshsys.Command
+	LimitErr   error
+
+	ReleaseNames    []string
+	ReleaseResults  []*boshcmdrunner.CmdResult
+	ReleaseExceeded string
+	ReleaseErr      error
+}
+
+func NewFakeResourceLimiter() *FakeResourceLimiter {
+	return &FakeResourceLimiter{}
+}
+
+func (l *FakeResourceLimiter) Limit(cmd boshsys.Command, name string) (boshsys.Command, error) {
+	l.LimitCmds = append(l.LimitCmds, cmd)
+	l.LimitNames = append(l.LimitNames, name)
+
+	if l.LimitCmd.Name == "" {
+		return cmd, l.LimitErr
+	}
+
+	return l.LimitCmd, l.LimitErr
+}
+
+func (l *FakeResourceLimiter) Release(name string, result *boshcmdrunner.CmdResult) (string, error) {
+	l.ReleaseNames = append(l.ReleaseNames, name)
+	l.ReleaseResults = append(l.ReleaseResults, result)
+	return l.ReleaseExceeded, l.ReleaseErr
+}
@@ -0,0 +1,30 @@
+package fakes
+
+import (
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+type FakeSandbox struct {
+	IsolateCmds         []boshsys.Command
+	IsolateReadOnlyDirs [][]string
+	IsolateWritableDirs [][]string
+
+	IsolateCmd boshsys.Command
+	IsolateErr error
+}
+
+func NewFakeSandbox() *FakeSandbox {
+	return &FakeSandbox{}
+}
+
+func (s *FakeSandbox) Isolate(cmd boshsys.Command, readOnlyDirs, writableDirs []string) (boshsys.Command, error) {
+	s.IsolateCmds = append(s.IsolateCmds, cmd)
+	s.IsolateReadOnlyDirs = append(s.IsolateReadOnlyDirs, readOnlyDirs)
+	s.IsolateWritableDirs = append(s.IsolateWritableDirs, writableDirs)
+
+	if s.IsolateCmd.Name == "" {
+		return cmd, s.IsolateErr
+	}
+
+	return s.IsolateCmd, s.IsolateErr
+}
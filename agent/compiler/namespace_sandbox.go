@@ -0,0 +1,113 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+const namespaceSandboxExecutable = "unshare"
+
+type namespaceSandbox struct {
+	hiddenDirs   []string
+	readOnlyDirs []string
+	runner       boshsys.CmdRunner
+}
+
+// NewNamespaceSandbox hides hiddenDirs behind empty tmpfs mounts
+// inside a private mount namespace, bind mounting readOnlyDirs back,
+// and runs the command in its own PID namespace with a fresh /tmp.
+// All other mounts are remounted read-only, so the command
+// can only write to its writable dirs and /tmp.
+// Mounts disappear together with the namespace when command exits.
+func NewNamespaceSandbox(
+	hiddenDirs []string,
+	readOnlyDirs []string,
+	runner boshsys.CmdRunner,
+) Sandbox {
+	return namespaceSandbox{
+		hiddenDirs:   hiddenDirs,
+		readOnlyDirs: readOnlyDirs,
+		runner:       runner,
+	}
+}
+
+func (s namespaceSandbox) Isolate(cmd boshsys.Command, readOnlyDirs, writableDirs []string) (boshsys.Command, error) {
+	if !s.runner.CommandExists(namespaceSandboxExecutable) {
+		return cmd, bosherr.Errorf("Sandbox executable %s not found in PATH", namespaceSandboxExecutable)
+	}
+
+	var visibleDirs []string
+	visibleDirs = append(visibleDirs, s.readOnlyDirs...)
+	visibleDirs = append(visibleDirs, readOnlyDirs...)
+	visibleDirs = append(visibleDirs, writableDirs...)
+
+	script := s.script(visibleDirs, writableDirs, cmd.WorkingDir)
+
+	args := []string{
+		"--mount", "--pid", "--fork", "--mount-proc",
+		"bash", "-e", "-c", script, "bosh-sandbox",
+		cmd.Name,
+	}
+
+	cmd.Args = append(args, cmd.Args...)
+	cmd.Name = namespaceSandboxExecutable
+
+	return cmd, nil
+}
+
+func (s namespaceSandbox) script(visibleDirs, writableDirs []string, workingDir string) string {
+	lines := []string{
+		// Make sure that none of the mounts below propagate to the host
+		"mount --make-rprivate /",
+		"stage=$(mktemp -d)",
+	}
+
+	for i, dir := range visibleDirs {
+		stageDir := fmt.Sprintf(`"$stage/%d"`, i)
+		lines = append(lines,
+			fmt.Sprintf("mkdir -p %s", stageDir),
+			fmt.Sprintf("mount --rbind %s %s", shellQuote(dir), stageDir),
+		)
+	}
+
+	for _, dir := range s.hiddenDirs {
+		lines = append(lines, fmt.Sprintf("mount -t tmpfs -o mode=0755 bosh-sandbox %s", shellQuote(dir)))
+	}
+
+	for i, dir := range visibleDirs {
+		lines = append(lines,
+			fmt.Sprintf("mkdir -p %s", shellQuote(dir)),
+			fmt.Sprintf(`mount --rbind "$stage/%d" %s`, i, shellQuote(dir)),
+		)
+	}
+
+	// Staging dir lives in /tmp and is hidden by fresh /tmp
+	lines = append(lines, "mount -t tmpfs -o mode=1777 bosh-sandbox /tmp")
+
+	// Some special mounts cannot be remounted but root must be read-only
+	lines = append(lines,
+		`for dir in $(awk '{ print $2 }' /proc/self/mounts | sort -u); do`,
+		`  mount -o remount,bind,ro "$(printf '%b' "$dir")" 2>/dev/null || true`,
+		`done`,
+		"mount -o remount,bind,ro /",
+	)
+
+	for _, dir := range append(append([]string{}, writableDirs...), "/tmp") {
+		lines = append(lines, fmt.Sprintf("mount -o remount,bind,rw %s", shellQuote(dir)))
+	}
+
+	if workingDir != "" {
+		lines = append(lines, fmt.Sprintf("cd %s", shellQuote(workingDir)))
+	}
+
+	lines = append(lines, `exec "$@"`)
+
+	return strings.Join(lines, "\n")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
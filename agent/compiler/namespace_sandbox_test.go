@@ -0,0 +1,192 @@
+package compiler_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+)
+
+var _ = Describe("namespaceSandbox", func() {
+	var (
+		runner  *fakesys.FakeCmdRunner
+		sandbox Sandbox
+		cmd     boshsys.Command
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		sandbox = NewNamespaceSandbox(
+			[]string{"/fake-base-dir"},
+			[]string{"/fake-base-dir/packages"},
+			runner,
+		)
+
+		cmd = boshsys.Command{
+			Name:       "bash",
+			Args:       []string{"-x", "packaging"},
+			Env:        map[string]string{"BOSH_PACKAGE_NAME": "fake-pkg"},
+			WorkingDir: "/fake-compile-dir/fake-pkg",
+		}
+	})
+
+	Describe("Isolate", func() {
+		Context("when unshare is available", func() {
+			BeforeEach(func() {
+				runner.CommandExistsValue = true
+			})
+
+			It("runs command through unshare in new mount and pid namespaces", func() {
+				isolatedCmd, err := sandbox.Isolate(cmd, []string{"/fake-base-dir/data/packages/fake-dep/1"}, []string{"/fake-compile-dir/fake-pkg"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(isolatedCmd.Name).To(Equal("unshare"))
+				Expect(isolatedCmd.Args[:5]).To(Equal([]string{"--mount", "--pid", "--fork", "--mount-proc", "bash"}))
+				Expect(isolatedCmd.Args[len(isolatedCmd.Args)-4:]).To(Equal([]string{"bosh-sandbox", "bash", "-x", "packaging"}))
+			})
+
+			It("keeps command environment and working dir", func() {
+				isolatedCmd, err := sandbox.Isolate(cmd, []string{"/fake-base-dir/data/packages/fake-dep/1"}, []string{"/fake-compile-dir/fake-pkg"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(isolatedCmd.Env).To(Equal(cmd.Env))
+				Expect(isolatedCmd.WorkingDir).To(Equal(cmd.WorkingDir))
+			})
+
+			It("hides hidden dirs, bind mounts visible dirs back and only keeps writable dirs writable", func() {
+				isolatedCmd, err := sandbox.Isolate(cmd, []string{"/fake-base-dir/data/packages/fake-dep/1"}, []string{"/fake-compile-dir/fake-pkg"})
+				Expect(err).ToNot(HaveOccurred())
+
+				script := isolatedCmd.Args[7]
+				Expect(script).To(Equal(`mount --make-rprivate /
+stage=$(mktemp -d)
+mkdir -p "$stage/0"
+mount --rbind '/fake-base-dir/packages' "$stage/0"
+mkdir -p "$stage/1"
+mount --rbind '/fake-base-dir/data/packages/fake-dep/1' "$stage/1"
+mkdir -p "$stage/2"
+mount --rbind '/fake-compile-dir/fake-pkg' "$stage/2"
+mount -t tmpfs -o mode=0755 bosh-sandbox '/fake-base-dir'
+mkdir -p '/fake-base-dir/packages'
+mount --rbind "$stage/0" '/fake-base-dir/packages'
+mkdir -p '/fake-base-dir/data/packages/fake-dep/1'
+mount --rbind "$stage/1" '/fake-base-dir/data/packages/fake-dep/1'
+mkdir -p '/fake-compile-dir/fake-pkg'
+mount --rbind "$stage/2" '/fake-compile-dir/fake-pkg'
+mount -t tmpfs -o mode=1777 bosh-sandbox /tmp
+for dir in $(awk '{ print $2 }' /proc/self/mounts | sort -u); do
+  mount -o remount,bind,ro "$(printf '%b' "$dir")" 2>/dev/null || true
+done
+mount -o remount,bind,ro /
+mount -o remount,bind,rw '/fake-compile-dir/fake-pkg'
+mount -o remount,bind,rw '/tmp'
+cd '/fake-compile-dir/fake-pkg'
+exec "$@"`))
+			})
+
+			It("quotes paths", func() {
+				isolatedCmd, err := sandbox.Isolate(cmd, nil, []string{"/fake-dir/it's"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isolatedCmd.Args[7]).To(ContainSubstring(`mkdir -p '/fake-dir/it'\''s'`))
+			})
+		})
+
+		// Namespaces can only be created by root
+		realRunner := boshsys.NewExecCmdRunner(boshlog.NewLogger(boshlog.LevelNone))
+		if os.Getuid() == 0 && realRunner.CommandExists("unshare") {
+			Context("when running isolated command", func() {
+				var (
+					rootDir    string
+					baseDir    string
+					depDir     string
+					compileDir string
+					installDir string
+				)
+
+				BeforeEach(func() {
+					var err error
+
+					// Sandbox replaces /tmp so test dirs cannot be inside it
+					rootDir, err = ioutil.TempDir("/var/tmp", "bosh-sandbox-test")
+					Expect(err).ToNot(HaveOccurred())
+
+					baseDir = filepath.Join(rootDir, "base")
+					depDir = filepath.Join(baseDir, "data", "packages", "fake-dep", "1")
+					installDir = filepath.Join(baseDir, "data", "packages", "fake-pkg", "1")
+					compileDir = filepath.Join(rootDir, "compile", "fake-pkg")
+
+					for _, dir := range []string{depDir, installDir, compileDir, filepath.Join(baseDir, "packages")} {
+						Expect(os.MkdirAll(dir, os.ModePerm)).To(Succeed())
+					}
+
+					Expect(ioutil.WriteFile(filepath.Join(depDir, "dep-file"), []byte("dep"), os.ModePerm)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(baseDir, "secret"), []byte("secret"), os.ModePerm)).To(Succeed())
+
+					sandbox = NewNamespaceSandbox([]string{baseDir}, []string{filepath.Join(baseDir, "packages")}, realRunner)
+				})
+
+				AfterEach(func() {
+					if rootDir != "" {
+						os.RemoveAll(rootDir)
+					}
+				})
+
+				fileExists := func(path string) bool {
+					_, err := os.Stat(path)
+					return err == nil
+				}
+
+				run := func(script string) (string, int) {
+					cmd := boshsys.Command{Name: "bash", Args: []string{"-c", script}, WorkingDir: compileDir}
+
+					isolatedCmd, err := sandbox.Isolate(cmd, []string{depDir}, []string{compileDir, installDir})
+					Expect(err).ToNot(HaveOccurred())
+
+					stdout, _, exitStatus, _ := realRunner.RunComplexCommand(isolatedCmd)
+					return stdout, exitStatus
+				}
+
+				It("allows writing to compile dir, install dir and /tmp", func() {
+					_, exitStatus := run("touch ./compiled " + installDir + "/installed /tmp/tmp-file")
+					Expect(exitStatus).To(Equal(0))
+
+					Expect(fileExists(filepath.Join(compileDir, "compiled"))).To(BeTrue())
+					Expect(fileExists(filepath.Join(installDir, "installed"))).To(BeTrue())
+				})
+
+				It("does not allow writing outside of compile dir and install dir", func() {
+					_, exitStatus := run("touch " + rootDir + "/outside")
+					Expect(exitStatus).ToNot(Equal(0))
+					Expect(fileExists(filepath.Join(rootDir, "outside"))).To(BeFalse())
+
+					_, exitStatus = run("touch " + depDir + "/dep-modified")
+					Expect(exitStatus).ToNot(Equal(0))
+					Expect(fileExists(filepath.Join(depDir, "dep-modified"))).To(BeFalse())
+				})
+
+				It("only shows dependencies inside hidden dirs", func() {
+					stdout, exitStatus := run("cat " + depDir + "/dep-file; ls " + baseDir)
+					Expect(exitStatus).To(Equal(0))
+					Expect(stdout).To(Equal("dep" + "data\npackages\n"))
+				})
+			})
+		}
+
+		Context("when unshare is not available", func() {
+			It("returns an error", func() {
+				runner.CommandExistsValue = false
+
+				_, err := sandbox.Isolate(cmd, []string{"/fake-base-dir/data/packages/fake-dep/1"}, []string{"/fake-compile-dir/fake-pkg"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unshare not found"))
+			})
+		})
+	})
+})
@@ -0,0 +1,20 @@
+package compiler
+
+import (
+	boshcmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+type noopResourceLimiter struct{}
+
+func NewNoopResourceLimiter() ResourceLimiter {
+	return noopResourceLimiter{}
+}
+
+func (l noopResourceLimiter) Limit(cmd boshsys.Command, _ string) (boshsys.Command, error) {
+	return cmd, nil
+}
+
+func (l noopResourceLimiter) Release(_ string, _ *boshcmdrunner.CmdResult) (string, error) {
+	return "", nil
+}
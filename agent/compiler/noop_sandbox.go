@@ -0,0 +1,15 @@
+package compiler
+
+import (
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+type noopSandbox struct{}
+
+func NewNoopSandbox() Sandbox {
+	return noopSandbox{}
+}
+
+func (s noopSandbox) Isolate(cmd boshsys.Command, _, _ []string) (boshsys.Command, error) {
+	return cmd, nil
+}
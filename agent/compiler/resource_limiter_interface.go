@@ -0,0 +1,25 @@
+package compiler
+
+import (
+	boshcmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+type ResourceLimiter interface {
+	// Limit returns a command that runs cmd within resource limits identified by name
+	Limit(cmd boshsys.Command, name string) (boshsys.Command, error)
+
+	// Release removes limits identified by name once command exited
+	// and describes the limit that command exceeded, if any
+	Release(name string, result *boshcmdrunner.CmdResult) (exceeded string, err error)
+}
+
+type Limits struct {
+	// Percentage of a single CPU, e.g. 200 allows using two CPUs fully
+	CPUPercent int
+
+	MemoryMB int
+
+	// Wall-clock time in seconds
+	Timeout int
+}
@@ -0,0 +1,13 @@
+package compiler
+
+import (
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+type Sandbox interface {
+	// Isolate returns a command that runs cmd on a read-only filesystem
+	// so that only readOnlyDirs, writableDirs (and dirs configured by the sandbox)
+	// remain accessible inside directories hidden by the sandbox.
+	// Only writableDirs can be modified by cmd.
+	Isolate(cmd boshsys.Command, readOnlyDirs, writableDirs []string) (boshsys.Command, error)
+}
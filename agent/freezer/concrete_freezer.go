@@ -0,0 +1,169 @@
+package freezer
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+const concreteFreezerLogTag = "concreteFreezer"
+
+type concreteFreezer struct {
+	platform boshplatform.Platform
+	jobsDir  string
+	logger   boshlog.Logger
+
+	lock          sync.Mutex
+	frozen        bool
+	timedOut      bool
+	mountPoint    string
+	timeout       time.Duration
+	jobNames      []string
+	watchdogTimer *time.Timer
+}
+
+func NewConcreteFreezer(platform boshplatform.Platform, jobsDir string, logger boshlog.Logger) Freezer {
+	return &concreteFreezer{
+		platform: platform,
+		jobsDir:  jobsDir,
+		logger:   logger,
+	}
+}
+
+func (f *concreteFreezer) Freeze(mountPoint string, jobNames []string, timeout time.Duration) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.frozen {
+		return bosherr.Errorf("Filesystem mounted at %s is already frozen", f.mountPoint)
+	}
+
+	err := f.runHooks(jobNames, "pre-snapshot")
+	if err != nil {
+		f.runPostSnapshotHooks(jobNames)
+		return bosherr.WrapError(err, "Running pre-snapshot hooks")
+	}
+
+	err = f.platform.FreezeFilesystem(mountPoint)
+	if err != nil {
+		f.runPostSnapshotHooks(jobNames)
+		return bosherr.WrapError(err, "Freezing filesystem")
+	}
+
+	f.frozen = true
+	f.timedOut = false
+	f.mountPoint = mountPoint
+	f.timeout = timeout
+	f.jobNames = jobNames
+
+	// Watchdog makes sure that disk does not stay frozen when director fails to thaw it
+	f.watchdogTimer = time.AfterFunc(timeout, f.thawAfterTimeout)
+
+	return nil
+}
+
+func (f *concreteFreezer) Thaw(mountPoint string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// Filesystem mounted elsewhere is not frozen
+	if mountPoint != f.mountPoint {
+		return nil
+	}
+
+	if !f.frozen {
+		if f.timedOut {
+			f.timedOut = false
+			return bosherr.Errorf("Filesystem mounted at %s was already thawed after freeze timeout", f.mountPoint)
+		}
+		return nil
+	}
+
+	err := f.thaw()
+
+	// Watchdog is kept around to retry if thawing failed
+	if !f.frozen {
+		f.watchdogTimer.Stop()
+	}
+
+	return err
+}
+
+func (f *concreteFreezer) thawAfterTimeout() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.frozen {
+		return
+	}
+
+	f.logger.Error(concreteFreezerLogTag, "Thawing filesystem mounted at %s after freeze timeout", f.mountPoint)
+
+	err := f.thaw()
+	if err != nil && f.frozen {
+		f.logger.Error(concreteFreezerLogTag, "Failed to thaw filesystem after freeze timeout, retrying in %s: %s", f.timeout, err)
+		f.watchdogTimer.Reset(f.timeout)
+		return
+	}
+
+	if err != nil {
+		f.logger.Error(concreteFreezerLogTag, "Failed to run post-snapshot hooks after freeze timeout: %s", err)
+	}
+
+	f.timedOut = true
+}
+
+func (f *concreteFreezer) thaw() error {
+	err := f.platform.ThawFilesystem(f.mountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Thawing filesystem")
+	}
+
+	f.frozen = false
+
+	err = f.runHooks(f.jobNames, "post-snapshot")
+	if err != nil {
+		return bosherr.WrapError(err, "Running post-snapshot hooks")
+	}
+
+	return nil
+}
+
+// runPostSnapshotHooks gives jobs a chance to resume work when freezing did not succeed
+func (f *concreteFreezer) runPostSnapshotHooks(jobNames []string) {
+	err := f.runHooks(jobNames, "post-snapshot")
+	if err != nil {
+		f.logger.Error(concreteFreezerLogTag, "Failed to run post-snapshot hooks: %s", err)
+	}
+}
+
+func (f *concreteFreezer) runHooks(jobNames []string, hookName string) error {
+	for _, jobName := range jobNames {
+		hookPath := filepath.Join(f.jobsDir, jobName, "bin", hookName)
+
+		if !f.platform.GetFs().FileExists(hookPath) {
+			continue
+		}
+
+		f.logger.Info(concreteFreezerLogTag, "Running %s hook of job %s", hookName, jobName)
+
+		command := boshsys.Command{
+			Name: hookPath,
+			Env: map[string]string{
+				"PATH": "/usr/sbin:/usr/bin:/sbin:/bin",
+			},
+		}
+
+		_, _, _, err := f.platform.GetRunner().RunComplexCommand(command)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Running %s hook of job %s", hookName, jobName)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package freezer_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/freezer"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+)
+
+var _ = Describe("concreteFreezer", func() {
+	var (
+		platform *fakeplatform.FakePlatform
+		runner   *fakesys.FakeCmdRunner
+		freezer  Freezer
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		runner = platform.Runner
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		freezer = NewConcreteFreezer(platform, "/fake-jobs-dir", logger)
+
+		platform.Fs.WriteFileString("/fake-jobs-dir/job-with-hooks/bin/pre-snapshot", "")
+		platform.Fs.WriteFileString("/fake-jobs-dir/job-with-hooks/bin/post-snapshot", "")
+	})
+
+	ranHooks := func() []string {
+		var hooks []string
+		for _, cmd := range runner.RunComplexCommands {
+			hooks = append(hooks, cmd.Name)
+		}
+		return hooks
+	}
+
+	Describe("Freeze", func() {
+		It("runs pre-snapshot hooks of jobs that have them and freezes filesystem", func() {
+			err := freezer.Freeze("/fake-store", []string{"job-with-hooks", "job-without-hooks"}, time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ranHooks()).To(Equal([]string{"/fake-jobs-dir/job-with-hooks/bin/pre-snapshot"}))
+			Expect(platform.FreezeFilesystemMountPoints).To(Equal([]string{"/fake-store"}))
+		})
+
+		It("does not run hooks when no jobs are given", func() {
+			err := freezer.Freeze("/fake-store", nil, time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ranHooks()).To(BeEmpty())
+			Expect(platform.FreezeFilesystemMountPoints).To(Equal([]string{"/fake-store"}))
+		})
+
+		It("returns error when filesystem is already frozen", func() {
+			err := freezer.Freeze("/fake-store", nil, time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = freezer.Freeze("/fake-store", nil, time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("already frozen"))
+		})
+
+		It("runs post-snapshot hooks and does not freeze when pre-snapshot hook fails", func() {
+			runner.AddCmdResult("/fake-jobs-dir/job-with-hooks/bin/pre-snapshot", fakesys.FakeCmdResult{
+				Error: errors.New("fake-hook-err"),
+			})
+
+			err := freezer.Freeze("/fake-store", []string{"job-with-hooks"}, time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-hook-err"))
+
+			Expect(platform.FreezeFilesystemMountPoints).To(BeEmpty())
+			Expect(ranHooks()).To(Equal([]string{
+				"/fake-jobs-dir/job-with-hooks/bin/pre-snapshot",
+				"/fake-jobs-dir/job-with-hooks/bin/post-snapshot",
+			}))
+		})
+
+		It("runs post-snapshot hooks when freezing fails", func() {
+			platform.FreezeFilesystemErr = errors.New("fake-freeze-err")
+
+			err := freezer.Freeze("/fake-store", []string{"job-with-hooks"}, time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-freeze-err"))
+
+			Expect(ranHooks()).To(ContainElement("/fake-jobs-dir/job-with-hooks/bin/post-snapshot"))
+		})
+
+		It("thaws filesystem and runs post-snapshot hooks once timeout passes", func() {
+			err := freezer.Freeze("/fake-store", []string{"job-with-hooks"}, 10*time.Millisecond)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() int { return len(runner.RunComplexCommands) }).Should(Equal(2))
+			Expect(platform.ThawFilesystemMountPoints).To(Equal([]string{"/fake-store"}))
+			Expect(ranHooks()[1]).To(Equal("/fake-jobs-dir/job-with-hooks/bin/post-snapshot"))
+		})
+
+		It("keeps retrying to thaw filesystem when thawing after timeout fails", func() {
+			platform.ThawFilesystemErr = errors.New("fake-thaw-err")
+
+			err := freezer.Freeze("/fake-store", nil, 10*time.Millisecond)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() int { return len(platform.ThawFilesystemMountPoints) }).Should(BeNumerically(">=", 2))
+
+			// Filesystem is still frozen rather than reported as thawed after timeout
+			err = freezer.Thaw("/fake-store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-thaw-err"))
+		})
+	})
+
+	Describe("Thaw", func() {
+		It("thaws filesystem and runs post-snapshot hooks of frozen jobs", func() {
+			err := freezer.Freeze("/fake-store", []string{"job-with-hooks"}, time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = freezer.Thaw("/fake-store")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(platform.ThawFilesystemMountPoints).To(Equal([]string{"/fake-store"}))
+			Expect(ranHooks()).To(Equal([]string{
+				"/fake-jobs-dir/job-with-hooks/bin/pre-snapshot",
+				"/fake-jobs-dir/job-with-hooks/bin/post-snapshot",
+			}))
+		})
+
+		It("stops watchdog so that filesystem is not thawed again", func() {
+			err := freezer.Freeze("/fake-store", nil, 10*time.Millisecond)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = freezer.Thaw("/fake-store")
+			Expect(err).ToNot(HaveOccurred())
+
+			Consistently(func() int { return len(platform.ThawFilesystemMountPoints) }, 50*time.Millisecond).Should(Equal(1))
+		})
+
+		It("does nothing when filesystem mounted at given mount point is not frozen", func() {
+			err := freezer.Freeze("/fake-store", nil, time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = freezer.Thaw("/fake-other-store")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.ThawFilesystemMountPoints).To(BeEmpty())
+		})
+
+		It("does nothing when filesystem is not frozen", func() {
+			err := freezer.Thaw("/fake-store")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.ThawFilesystemMountPoints).To(BeEmpty())
+		})
+
+		It("returns error when filesystem was thawed because of timeout", func() {
+			err := freezer.Freeze("/fake-store", nil, 10*time.Millisecond)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() int { return len(platform.ThawFilesystemMountPoints) }).Should(Equal(1))
+
+			err = freezer.Thaw("/fake-store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("already thawed after freeze timeout"))
+
+			err = freezer.Thaw("/fake-store")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("keeps filesystem frozen and returns error when thawing fails", func() {
+			err := freezer.Freeze("/fake-store", nil, time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			platform.ThawFilesystemErr = errors.New("fake-thaw-err")
+
+			err = freezer.Thaw("/fake-store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-thaw-err"))
+
+			platform.ThawFilesystemErr = nil
+
+			err = freezer.Thaw("/fake-store")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.ThawFilesystemMountPoints).To(HaveLen(2))
+		})
+	})
+})
@@ -0,0 +1,29 @@
+package fakes
+
+import (
+	"time"
+)
+
+type FakeFreezer struct {
+	FreezeMountPoint string
+	FreezeJobNames   []string
+	FreezeTimeout    time.Duration
+	FreezeErr        error
+
+	ThawCalled     bool
+	ThawMountPoint string
+	ThawErr        error
+}
+
+func (f *FakeFreezer) Freeze(mountPoint string, jobNames []string, timeout time.Duration) error {
+	f.FreezeMountPoint = mountPoint
+	f.FreezeJobNames = jobNames
+	f.FreezeTimeout = timeout
+	return f.FreezeErr
+}
+
+func (f *FakeFreezer) Thaw(mountPoint string) error {
+	f.ThawCalled = true
+	f.ThawMountPoint = mountPoint
+	return f.ThawErr
+}
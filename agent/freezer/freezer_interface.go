@@ -0,0 +1,15 @@
+package freezer
+
+import (
+	"time"
+)
+
+type Freezer interface {
+	// Freeze runs pre-snapshot hooks of given jobs and freezes filesystem mounted at mount point;
+	// filesystem is thawed automatically if Thaw is not called before timeout passes
+	Freeze(mountPoint string, jobNames []string, timeout time.Duration) (err error)
+
+	// Thaw thaws filesystem mounted at mount point if it is frozen and runs post-snapshot hooks
+	// of jobs given to Freeze; it returns an error if filesystem was already thawed because of the timeout
+	Thaw(mountPoint string) (err error)
+}
@@ -9,6 +9,8 @@ type Heartbeat struct {
 	Index    *int              `json:"index"`
 	JobState string            `json:"job_state"`
 	Vitals   boshvitals.Vitals `json:"vitals"`
+
+	IPv6Addresses map[string]string `json:"ipv6_addresses,omitempty"`
 }
 
 //Heartbeat payload example:
@@ -2,6 +2,8 @@ package app
 
 import (
 	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 
 	boshagent "github.com/cloudfoundry/bosh-agent/agent"
@@ -14,6 +16,7 @@ import (
 	boshrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	boshdrain "github.com/cloudfoundry/bosh-agent/agent/drain"
+	boshfreezer "github.com/cloudfoundry/bosh-agent/agent/freezer"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshblob "github.com/cloudfoundry/bosh-agent/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
@@ -67,7 +70,7 @@ func (app *app) Setup(args []string) error {
 		return bosherr.WrapError(err, "Getting platform")
 	}
 
-	settingsSourceFactory := boshinf.NewSettingsSourceFactory(config.Infrastructure.Settings, app.platform, app.logger)
+	settingsSourceFactory := boshinf.NewSettingsSourceFactory(config.Infrastructure.Settings, config.Retry.Registry, app.platform, app.logger)
 	settingsSource, err := settingsSourceFactory.New()
 	if err != nil {
 		return bosherr.WrapError(err, "Getting Settings Source")
@@ -98,14 +101,14 @@ func (app *app) Setup(args []string) error {
 		return bosherr.WrapError(err, "Getting mbus handler")
 	}
 
-	blobstoreProvider := boshblob.NewProvider(app.platform, dirProvider, app.logger)
+	blobstoreProvider := boshblob.NewProvider(app.platform, dirProvider, config.Retry.Blobstore, app.logger)
 
 	blobstore, err := blobstoreProvider.Get(settingsService.GetSettings().Blobstore)
 	if err != nil {
 		return bosherr.WrapError(err, "Getting blobstore")
 	}
 
-	monitClientProvider := boshmonit.NewProvider(app.platform, app.logger)
+	monitClientProvider := boshmonit.NewProvider(app.platform, config.Retry.Monit, config.Retry.MonitUnavailable, app.logger)
 
 	monitClient, err := monitClientProvider.Get()
 	if err != nil {
@@ -127,7 +130,9 @@ func (app *app) Setup(args []string) error {
 
 	notifier := boshnotif.NewNotifier(mbusHandler)
 
-	applier, compiler := app.buildApplierAndCompiler(dirProvider, blobstore, jobSupervisor)
+	timeService := boshtime.NewConcreteService()
+
+	applier, compiler := app.buildApplierAndCompiler(dirProvider, blobstore, jobSupervisor, timeService, config.Compiler)
 
 	uuidGen := boshuuid.NewGenerator()
 
@@ -162,6 +167,7 @@ func (app *app) Setup(args []string) error {
 		jobSupervisor,
 		specService,
 		drainScriptProvider,
+		boshfreezer.NewConcreteFreezer(app.platform, dirProvider.JobsDir(), app.logger),
 		app.logger,
 	)
 
@@ -177,8 +183,6 @@ func (app *app) Setup(args []string) error {
 
 	syslogServer := boshsyslog.NewServer(33331, app.logger)
 
-	timeService := boshtime.NewConcreteService()
-
 	app.agent = boshagent.New(
 		app.logger,
 		mbusHandler,
@@ -212,6 +216,8 @@ func (app *app) buildApplierAndCompiler(
 	dirProvider boshdirs.Provider,
 	blobstore boshblob.Blobstore,
 	jobSupervisor boshjobsuper.JobSupervisor,
+	timeService boshtime.Service,
+	compilerOptions boshcomp.Options,
 ) (boshapplier.Applier, boshcomp.Compiler) {
 	jobsBc := boshbc.NewFileBundleCollection(
 		dirProvider.DataDir(),
@@ -259,6 +265,28 @@ func (app *app) buildApplierAndCompiler(
 		10*1024, // 10 Kb
 	)
 
+	sandbox := boshcomp.NewNoopSandbox()
+	if compilerOptions.UseSandbox {
+		// Packaging scripts only see their dependencies (via package symlinks)
+		// and their compile dir on otherwise read-only filesystem
+		sandbox = boshcomp.NewNamespaceSandbox(
+			[]string{dirProvider.BaseDir()},
+			[]string{filepath.Join(dirProvider.BaseDir(), "packages")},
+			platformRunner,
+		)
+	}
+
+	limiter := boshcomp.NewNoopResourceLimiter()
+	if compilerOptions.PackagingLimits != (boshcomp.Limits{}) {
+		limiter = boshcomp.NewCgroupResourceLimiter(
+			compilerOptions.PackagingLimits,
+			"/sys/fs/cgroup",
+			fileSystem,
+			platformRunner,
+			timeService,
+		)
+	}
+
 	compiler := boshcomp.NewConcreteCompiler(
 		app.platform.GetCompressor(),
 		blobstore,
@@ -267,11 +295,43 @@ func (app *app) buildApplierAndCompiler(
 		dirProvider,
 		packageApplierProvider.Root(),
 		packageApplierProvider.RootBundleCollection(),
+		sandbox,
+		limiter,
+		app.compileEnvironment(dirProvider),
+		timeService,
+		compilerParallelism(compilerOptions),
 	)
 
 	return applier, compiler
 }
 
+func (app *app) compileEnvironment(dirProvider boshdirs.Provider) boshcomp.Environment {
+	fs := app.platform.GetFs()
+
+	// Stemcell builder leaves these behind; missing files are reported as empty
+	readEtcFile := func(name string) string {
+		contents, err := fs.ReadFileString(filepath.Join(dirProvider.EtcDir(), name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(contents)
+	}
+
+	return boshcomp.Environment{
+		StemcellOperatingSystem: readEtcFile("operating_system"),
+		StemcellVersion:         readEtcFile("stemcell_version"),
+		AgentVersion:            VersionLabel,
+	}
+}
+
+func compilerParallelism(options boshcomp.Options) int {
+	if options.Parallelism > 0 {
+		return options.Parallelism
+	}
+
+	return runtime.NumCPU()
+}
+
 func (app *app) loadConfig(path string) (Config, error) {
 	// Use one off copy of file system to read configuration file
 	fs := boshsys.NewOsFileSystem(app.logger)
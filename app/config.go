@@ -3,15 +3,30 @@ package app
 import (
 	"encoding/json"
 
+	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
 	boshsys "github.com/cloudfoundry/bosh-agent/system"
 )
 
 type Config struct {
 	Platform       boshplatform.Options
 	Infrastructure boshinf.Options
+	Retry          RetryOptions
+	Compiler       boshcomp.Options
+}
+
+// RetryOptions tune retry policy per subsystem;
+// unset values fall back to each subsystem's defaults
+type RetryOptions struct {
+	Blobstore boshretry.Options
+	Registry  boshretry.Options
+	Monit     boshretry.Options
+
+	// Attempts made while monit is unavailable because it is starting jobs
+	MonitUnavailable boshretry.Options
 }
 
 func LoadConfigFromPath(fs boshsys.FileSystem, path string) (Config, error) {
@@ -6,6 +6,7 @@ import (
 
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
 	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
 )
 
@@ -58,6 +59,18 @@ var _ = Describe("LoadConfigFromPath", func() {
 				  "UseServerName": true,
 				  "UseRegistry": true
 				}
+			},
+			"Retry": {
+				"Blobstore": {
+					"MaxAttempts": 5,
+					"InitialDelay": 1000,
+					"MaxDelay": 30000,
+					"Multiplier": 2,
+					"Jitter": 0.2
+				},
+				"Registry": { "MaxElapsedTime": 120000 },
+				"Monit": { "MaxAttempts": 40 },
+				"MonitUnavailable": { "MaxAttempts": -1 }
 			}
 		}`)
 
@@ -99,6 +112,19 @@ var _ = Describe("LoadConfigFromPath", func() {
 					UseRegistry:   true,
 				},
 			},
+			Retry: RetryOptions{
+				Blobstore: boshretry.Options{
+					MaxAttempts:  5,
+					InitialDelay: 1000,
+					MaxDelay:     30000,
+					Multiplier:   2,
+					Jitter:       0.2,
+				},
+				Registry: boshretry.Options{MaxElapsedTime: 120000},
+				Monit:    boshretry.Options{MaxAttempts: 40},
+
+				MonitUnavailable: boshretry.Options{MaxAttempts: boshretry.Unlimited},
+			},
 		}))
 	})
 
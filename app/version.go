@@ -0,0 +1,5 @@
+package app
+
+// VersionLabel is set at build time, e.g.
+// go build -ldflags "-X github.com/cloudfoundry/bosh-agent/app.VersionLabel=1.2.3"
+var VersionLabel = "[DEV BUILD]"
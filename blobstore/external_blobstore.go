@@ -69,12 +69,26 @@ func (b externalBlobstore) Create(fileName string) (string, string, error) {
 		return "", "", bosherr.WrapError(err, "Generating UUID")
 	}
 
+	// Fingerprint the file while the external cli is reading it for upload
+	fingerprintCh := make(chan fingerprintResult, 1)
+	go func() {
+		fingerprint, err := calculateFileSha1(b.fs, filePath)
+		fingerprintCh <- fingerprintResult{fingerprint: fingerprint, err: err}
+	}()
+
 	err = b.run("put", filePath, blobID)
+
+	result := <-fingerprintCh
+
 	if err != nil {
 		return "", "", bosherr.WrapError(err, "Making put command")
 	}
 
-	return blobID, "", nil
+	if result.err != nil {
+		return "", "", bosherr.WrapError(result.err, "Calculating fingerprint")
+	}
+
+	return blobID, result.fingerprint, nil
 }
 
 func (b externalBlobstore) Validate() error {
@@ -138,11 +138,12 @@ var _ = Describe("externalBlobstore", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			uuidGen.GeneratedUUID = "some-uuid"
+			fs.WriteFileString(expectedPath, "fake-file-contents")
 
 			blobID, fingerprint, err := blobstore.Create(fileName)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(blobID).To(Equal("some-uuid"))
-			Expect(fingerprint).To(BeEmpty())
+			Expect(fingerprint).To(Equal("8339901dbc0614a725804995f0a9ae8319338818"))
 
 			Expect(len(runner.RunCommands)).To(Equal(1))
 			Expect(runner.RunCommands[0]).To(Equal([]string{
@@ -150,5 +151,28 @@ var _ = Describe("externalBlobstore", func() {
 				expectedPath, "some-uuid",
 			}))
 		})
+
+		It("returns error if put command fails", func() {
+			fileName := "../Fixtures/some.config"
+			expectedPath, err := filepath.Abs(fileName)
+			Expect(err).ToNot(HaveOccurred())
+
+			uuidGen.GeneratedUUID = "some-uuid"
+			runner.AddCmdResult(strings.Join([]string{
+				"bosh-blobstore-fake-provider", "-c", configPath, "put", expectedPath, "some-uuid",
+			}, " "), fakesys.FakeCmdResult{Error: errors.New("fake-put-error")})
+
+			_, _, err = blobstore.Create(fileName)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-put-error"))
+		})
+
+		It("returns error if fingerprinting the file fails", func() {
+			fs.OpenFileErr = errors.New("fake-open-file-error")
+
+			_, _, err := blobstore.Create("../Fixtures/some.config")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-open-file-error"))
+		})
 	})
 })
@@ -0,0 +1,34 @@
+package blobstore
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+type fingerprintResult struct {
+	fingerprint string
+	err         error
+}
+
+func calculateFileSha1(fs boshsys.FileSystem, fileName string) (string, error) {
+	file, err := fs.OpenFile(fileName, os.O_RDONLY, 0)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Opening file for sha1 calculation")
+	}
+
+	defer file.Close()
+
+	h := sha1.New()
+
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Reading file for sha1 calculation")
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
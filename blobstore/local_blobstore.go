@@ -73,6 +73,13 @@ func (b localBlobstore) Create(fileName string) (blobID string, fingerprint stri
 		blobID = ""
 		return
 	}
+
+	fingerprint, err = calculateFileSha1(b.fs, filepath.Join(b.path(), blobID))
+	if err != nil {
+		err = bosherr.WrapError(err, "Calculating fingerprint")
+		blobID = ""
+		return
+	}
 	return
 }
 
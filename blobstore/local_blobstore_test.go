@@ -121,7 +121,7 @@ var _ = Describe("localBlobstore", func() {
 			blobID, fingerprint, err := blobstore.Create("/fake-file.txt")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(blobID).To(Equal("some-uuid"))
-			Expect(fingerprint).To(BeEmpty())
+			Expect(fingerprint).To(Equal("8339901dbc0614a725804995f0a9ae8319338818"))
 
 			dirStats := fs.GetFileTestStat(fakeBlobstorePath)
 			Expect(dirStats).ToNot(BeNil())
@@ -148,6 +148,16 @@ var _ = Describe("localBlobstore", func() {
 			Expect(err.Error()).To(ContainSubstring("fake-mkdir-error"))
 		})
 
+		It("errs when fingerprinting the blob errs", func() {
+			fs.WriteFileString("/fake-file.txt", "fake-file-contents")
+			fs.OpenFileErr = errors.New("fake-open-file-error")
+
+			blobID, _, err := blobstore.Create("/fake-file.txt")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-open-file-error"))
+			Expect(blobID).To(BeEmpty())
+		})
+
 		It("errs when copy file errs", func() {
 			fs.WriteFileString("/fake-file.txt", "fake-file-contents")
 
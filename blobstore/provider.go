@@ -7,26 +7,37 @@ import (
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
 	boshuuid "github.com/cloudfoundry/bosh-agent/uuid"
 )
 
+var defaultRetryOptions = boshretry.Options{
+	MaxAttempts: 3,
+}
+
 type Provider struct {
-	platform    boshplatform.Platform
-	dirProvider boshdir.Provider
-	uuidGen     boshuuid.Generator
-	logger      boshlog.Logger
+	platform     boshplatform.Platform
+	dirProvider  boshdir.Provider
+	uuidGen      boshuuid.Generator
+	retryOptions boshretry.Options
+	timeService  boshtime.Service
+	logger       boshlog.Logger
 }
 
 func NewProvider(
 	platform boshplatform.Platform,
 	dirProvider boshdir.Provider,
+	retryOptions boshretry.Options,
 	logger boshlog.Logger,
 ) (p Provider) {
 	p.uuidGen = boshuuid.NewGenerator()
 	p.platform = platform
 	p.dirProvider = dirProvider
+	p.retryOptions = retryOptions.WithDefaults(defaultRetryOptions)
+	p.timeService = boshtime.NewConcreteService()
 	p.logger = logger
 	return
 }
@@ -59,7 +70,7 @@ func (p Provider) Get(settings boshsettings.Blobstore) (blobstore Blobstore, err
 
 	blobstore = NewSHA1VerifiableBlobstore(blobstore)
 
-	blobstore = NewRetryableBlobstore(blobstore, 3, p.logger)
+	blobstore = NewRetryableBlobstore(blobstore, p.retryOptions, p.timeService, p.logger)
 
 	err = blobstore.Validate()
 	if err != nil {
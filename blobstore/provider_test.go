@@ -7,8 +7,10 @@ import (
 	. "github.com/cloudfoundry/bosh-agent/blobstore"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
 	boshuuid "github.com/cloudfoundry/bosh-agent/uuid"
 )
 
@@ -23,7 +25,7 @@ var _ = Describe("Provider", func() {
 		platform = fakeplatform.NewFakePlatform()
 		dirProvider := boshdir.NewProvider("/var/vcap")
 		logger = boshlog.NewLogger(boshlog.LevelNone)
-		provider = NewProvider(platform, dirProvider, logger)
+		provider = NewProvider(platform, dirProvider, boshretry.Options{}, logger)
 	})
 
 	Describe("Get", func() {
@@ -55,13 +57,40 @@ var _ = Describe("Provider", func() {
 				"/var/vcap/bosh/etc/blobstore-fake-external-type.json",
 			)
 			expectedBlobstore = NewSHA1VerifiableBlobstore(expectedBlobstore)
-			expectedBlobstore = NewRetryableBlobstore(expectedBlobstore, 3, logger)
+			expectedBlobstore = NewRetryableBlobstore(
+				expectedBlobstore,
+				boshretry.Options{MaxAttempts: 3},
+				boshtime.NewConcreteService(),
+				logger,
+			)
 			Expect(blobstore).To(Equal(expectedBlobstore))
 
 			err = expectedBlobstore.Validate()
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("get external with configured retry options", func() {
+			retryOptions := boshretry.Options{MaxAttempts: 5, InitialDelay: 1000, Multiplier: 2}
+			provider = NewProvider(platform, boshdir.NewProvider("/var/vcap"), retryOptions, logger)
+
+			platform.Runner.CommandExistsValue = true
+
+			blobstore, err := provider.Get(boshsettings.Blobstore{Type: "fake-external-type"})
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedBlobstore := NewExternalBlobstore(
+				"fake-external-type",
+				nil,
+				platform.GetFs(),
+				platform.GetRunner(),
+				boshuuid.NewGenerator(),
+				"/var/vcap/bosh/etc/blobstore-fake-external-type.json",
+			)
+			expectedBlobstore = NewSHA1VerifiableBlobstore(expectedBlobstore)
+			expectedBlobstore = NewRetryableBlobstore(expectedBlobstore, retryOptions, boshtime.NewConcreteService(), logger)
+			Expect(blobstore).To(Equal(expectedBlobstore))
+		})
+
 		It("get external errs when external command not in path", func() {
 			options := map[string]interface{}{"key": "value"}
 
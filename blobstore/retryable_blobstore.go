@@ -1,42 +1,63 @@
 package blobstore
 
 import (
+	"fmt"
+
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
 )
 
 type retryableBlobstore struct {
-	blobstore Blobstore
-	maxTries  int
+	blobstore    Blobstore
+	retryOptions boshretry.Options
+	timeService  boshtime.Service
 
 	logTag string
 	logger boshlog.Logger
 }
 
-func NewRetryableBlobstore(blobstore Blobstore, maxTries int, logger boshlog.Logger) Blobstore {
+func NewRetryableBlobstore(
+	blobstore Blobstore,
+	retryOptions boshretry.Options,
+	timeService boshtime.Service,
+	logger boshlog.Logger,
+) Blobstore {
 	return retryableBlobstore{
-		blobstore: blobstore,
-		maxTries:  maxTries,
-		logTag:    "retryableBlobstore",
-		logger:    logger,
+		blobstore:    blobstore,
+		retryOptions: retryOptions,
+		timeService:  timeService,
+		logTag:       "retryableBlobstore",
+		logger:       logger,
 	}
 }
 
 func (b retryableBlobstore) Get(blobID, fingerprint string) (string, error) {
 	var fileName string
-	var lastErr error
+	var attempt int
+
+	getRetryable := boshretry.NewRetryable(func() (bool, error) {
+		var err error
+
+		attempt++
 
-	for i := 0; i < b.maxTries; i++ {
-		fileName, lastErr = b.blobstore.Get(blobID, fingerprint)
-		if lastErr == nil {
-			return fileName, nil
+		fileName, err = b.blobstore.Get(blobID, fingerprint)
+		if err != nil {
+			b.logger.Info(b.logTag,
+				"Failed to get blob with error '%s', %s", err.Error(), b.describeAttempt(attempt))
+			return true, err
 		}
 
-		b.logger.Info(b.logTag,
-			"Failed to get blob with error '%s', attempt %d out of %d", lastErr.Error(), i, b.maxTries)
+		return false, nil
+	})
+
+	err := boshretry.NewBackoffRetryStrategy(b.retryOptions, getRetryable, b.timeService, b.logger).Try()
+	if err != nil {
+		return "", bosherr.WrapError(err, "Getting blob from inner blobstore")
 	}
 
-	return "", bosherr.WrapError(lastErr, "Getting blob from inner blobstore")
+	return fileName, nil
 }
 
 func (b retryableBlobstore) CleanUp(fileName string) error {
@@ -46,25 +67,43 @@ func (b retryableBlobstore) CleanUp(fileName string) error {
 func (b retryableBlobstore) Create(fileName string) (string, string, error) {
 	var blobID string
 	var fingerprint string
-	var lastErr error
+	var attempt int
+
+	createRetryable := boshretry.NewRetryable(func() (bool, error) {
+		var err error
+
+		attempt++
 
-	for i := 0; i < b.maxTries; i++ {
-		blobID, fingerprint, lastErr = b.blobstore.Create(fileName)
-		if lastErr == nil {
-			return blobID, fingerprint, nil
+		blobID, fingerprint, err = b.blobstore.Create(fileName)
+		if err != nil {
+			b.logger.Info(b.logTag,
+				"Failed to create blob with error %s, %s", err.Error(), b.describeAttempt(attempt))
+			return true, err
 		}
 
-		b.logger.Info(b.logTag,
-			"Failed to create blob with error %s, attempt %d out of %d", lastErr.Error(), i, b.maxTries)
+		return false, nil
+	})
+
+	err := boshretry.NewBackoffRetryStrategy(b.retryOptions, createRetryable, b.timeService, b.logger).Try()
+	if err != nil {
+		return "", "", bosherr.WrapError(err, "Creating blob in inner blobstore")
 	}
 
-	return "", "", bosherr.WrapError(lastErr, "Creating blob in inner blobstore")
+	return blobID, fingerprint, nil
 }
 
 func (b retryableBlobstore) Validate() error {
-	if b.maxTries < 1 {
+	if b.retryOptions.MaxAttempts < 1 && b.retryOptions.MaxAttempts != boshretry.Unlimited {
 		return bosherr.Error("Max tries must be > 0")
 	}
 
 	return b.blobstore.Validate()
 }
+
+func (b retryableBlobstore) describeAttempt(attempt int) string {
+	if b.retryOptions.MaxAttempts == boshretry.Unlimited {
+		return fmt.Sprintf("attempt %d", attempt)
+	}
+
+	return fmt.Sprintf("attempt %d out of %d", attempt, b.retryOptions.MaxAttempts)
+}
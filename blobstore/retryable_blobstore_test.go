@@ -2,6 +2,7 @@ package blobstore_test
 
 import (
 	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -10,19 +11,23 @@ import (
 	fakeblob "github.com/cloudfoundry/bosh-agent/blobstore/fakes"
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
+	faketime "github.com/cloudfoundry/bosh-agent/time/fakes"
 )
 
 var _ = Describe("retryableBlobstore", func() {
 	var (
 		innerBlobstore     *fakeblob.FakeBlobstore
+		timeService        *faketime.FakeService
 		logger             boshlog.Logger
 		retryableBlobstore boshblob.Blobstore
 	)
 
 	BeforeEach(func() {
 		innerBlobstore = &fakeblob.FakeBlobstore{}
+		timeService = &faketime.FakeService{}
 		logger = boshlog.NewLogger(boshlog.LevelNone)
-		retryableBlobstore = boshblob.NewRetryableBlobstore(innerBlobstore, 3, logger)
+		retryableBlobstore = boshblob.NewRetryableBlobstore(innerBlobstore, boshretry.Options{MaxAttempts: 3}, timeService, logger)
 	})
 
 	Describe("Get", func() {
@@ -158,17 +163,42 @@ var _ = Describe("retryableBlobstore", func() {
 		})
 	})
 
+	Describe("retry policy", func() {
+		It("waits between attempts according to retry options", func() {
+			options := boshretry.Options{MaxAttempts: 3, InitialDelay: 100, Multiplier: 2}
+			retryableBlobstore = boshblob.NewRetryableBlobstore(innerBlobstore, options, timeService, logger)
+
+			innerBlobstore.GetErrs = []error{
+				errors.New("fake-get-err-1"),
+				errors.New("fake-get-err-2"),
+				errors.New("fake-get-err-3"),
+			}
+
+			_, err := retryableBlobstore.Get("fake-blob-id", "fake-fingerprint")
+			Expect(err).To(HaveOccurred())
+			Expect(timeService.SleepInputs).To(Equal([]time.Duration{
+				100 * time.Millisecond,
+				200 * time.Millisecond,
+			}))
+		})
+	})
+
 	Describe("Validate", func() {
 		It("returns error if max tries is < 1", func() {
-			err := boshblob.NewRetryableBlobstore(innerBlobstore, -1, logger).Validate()
+			err := boshblob.NewRetryableBlobstore(innerBlobstore, boshretry.Options{MaxAttempts: -2}, timeService, logger).Validate()
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Max tries must be > 0"))
 
-			err = boshblob.NewRetryableBlobstore(innerBlobstore, 0, logger).Validate()
+			err = boshblob.NewRetryableBlobstore(innerBlobstore, boshretry.Options{}, timeService, logger).Validate()
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Max tries must be > 0"))
 		})
 
+		It("allows unlimited max tries", func() {
+			err := boshblob.NewRetryableBlobstore(innerBlobstore, boshretry.Options{MaxAttempts: boshretry.Unlimited}, timeService, logger).Validate()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
 		It("delegates to inner blobstore to validate", func() {
 			err := retryableBlobstore.Validate()
 			Expect(err).ToNot(HaveOccurred())
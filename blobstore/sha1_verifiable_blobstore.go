@@ -44,12 +44,16 @@ func (b sha1VerifiableBlobstore) CleanUp(fileName string) error {
 }
 
 func (b sha1VerifiableBlobstore) Create(fileName string) (blobID string, fingerprint string, err error) {
-	fingerprint, err = calculateSha1(fileName)
-	if err != nil {
+	blobID, fingerprint, err = b.blobstore.Create(fileName)
+	if err != nil || fingerprint != "" {
 		return
 	}
 
-	blobID, _, err = b.blobstore.Create(fileName)
+	// Inner blobstore did not fingerprint uploaded file
+	fingerprint, err = calculateSha1(fileName)
+	if err != nil {
+		blobID = ""
+	}
 	return
 }
 
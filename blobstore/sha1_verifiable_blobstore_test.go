@@ -94,6 +94,16 @@ var _ = Describe("sha1VerifiableBlobstore", func() {
 			Expect(innerBlobstore.CreateFileNames[0]).To(Equal(fixturePath))
 		})
 
+		It("returns sha1 calculated by inner blobstore if one is returned", func() {
+			innerBlobstore.CreateBlobID = "fake-blob-id"
+			innerBlobstore.CreateFingerprint = "fake-inner-sha1"
+
+			blobID, sha1, err := sha1VerifiableBlobstore.Create(fixturePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blobID).To(Equal("fake-blob-id"))
+			Expect(sha1).To(Equal("fake-inner-sha1"))
+		})
+
 		It("returns error if inner blobstore blob creation fails", func() {
 			innerBlobstore.CreateErr = errors.New("fake-create-error")
 
@@ -16,9 +16,16 @@ import (
 
 type Client interface {
 	Get(path string) (content io.ReadCloser, err error)
+	GetRange(path string, offset, length int64) (content io.ReadCloser, err error)
+	Head(path string) (info BlobInfo, err error)
 	Put(path string, content io.ReadCloser, contentLength int64) (err error)
 }
 
+type BlobInfo struct {
+	Size          int64
+	AcceptsRanges bool
+}
+
 func NewClient(config davconf.Config, httpClient boshhttp.Client) (c Client) {
 	return client{
 		config:     config,
@@ -52,6 +59,61 @@ func (c client) Get(path string) (content io.ReadCloser, err error) {
 	return
 }
 
+// GetRange returns length bytes of the blob starting at offset;
+// non-positive length returns everything up to the end of the blob
+func (c client) GetRange(path string, offset, length int64) (content io.ReadCloser, err error) {
+	req, err := c.createReq("GET", path, nil)
+	if err != nil {
+		return
+	}
+
+	if length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		err = bosherr.WrapErrorf(err, "Getting dav blob %s from byte %d", path, offset)
+		return
+	}
+
+	if resp.StatusCode != 206 {
+		err = fmt.Errorf("Getting dav blob %s from byte %d: Wrong response code: %d; body: %s", path, offset, resp.StatusCode, c.readAndTruncateBody(resp))
+		return
+	}
+
+	content = resp.Body
+	return
+}
+
+func (c client) Head(path string) (info BlobInfo, err error) {
+	req, err := c.createReq("HEAD", path, nil)
+	if err != nil {
+		return
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		err = bosherr.WrapErrorf(err, "Checking dav blob %s", path)
+		return
+	}
+
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+
+	if resp.StatusCode != 200 {
+		err = fmt.Errorf("Checking dav blob %s: Wrong response code: %d", path, resp.StatusCode)
+		return
+	}
+
+	info.Size = resp.ContentLength
+	info.AcceptsRanges = resp.Header.Get("Accept-Ranges") == "bytes"
+	return
+}
+
 func (c client) Put(path string, content io.ReadCloser, contentLength int64) (err error) {
 	req, err := c.createReq("PUT", path, content)
 	if err != nil {
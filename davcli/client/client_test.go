@@ -3,6 +3,7 @@ package client_test
 import (
 	"errors"
 	"io/ioutil"
+	"net/http"
 	"strings"
 
 	. "github.com/onsi/ginkgo"
@@ -65,6 +66,91 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Describe("GetRange", func() {
+		It("requests the given byte range and returns the response body", func() {
+			fakeHTTPClient.StatusCode = 206
+			fakeHTTPClient.SetMessage("response")
+
+			responseBody, err := client.GetRange("/", 10, 8)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeHTTPClient.Requests[0].Header.Get("Range")).To(Equal("bytes=10-17"))
+
+			buf := make([]byte, 1024)
+			n, _ := responseBody.Read(buf)
+			Expect(string(buf[0:n])).To(Equal("response"))
+		})
+
+		It("requests everything after offset when length is not positive", func() {
+			fakeHTTPClient.StatusCode = 206
+
+			_, err := client.GetRange("/", 10, -1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeHTTPClient.Requests[0].Header.Get("Range")).To(Equal("bytes=10-"))
+		})
+
+		Context("when the http request fails", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.Error = errors.New("")
+			})
+
+			It("returns err", func() {
+				responseBody, err := client.GetRange("/", 10, -1)
+				Expect(responseBody).To(BeNil())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Getting dav blob / from byte 10"))
+			})
+		})
+
+		Context("when the server ignores the range", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.StatusCode = 200
+				fakeHTTPClient.SetMessage("response")
+			})
+
+			It("returns err", func() {
+				responseBody, err := client.GetRange("/", 10, -1)
+				Expect(responseBody).To(BeNil())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Getting dav blob / from byte 10: Wrong response code: 200; body: response"))
+			})
+		})
+	})
+
+	Describe("Head", func() {
+		It("returns blob size and whether ranges are accepted", func() {
+			fakeHTTPClient.AddDoBehavior(&http.Response{
+				StatusCode:    200,
+				ContentLength: 1024,
+				Header:        http.Header{"Accept-Ranges": []string{"bytes"}},
+			}, nil)
+
+			info, err := client.Head("/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeHTTPClient.Requests[0].Method).To(Equal("HEAD"))
+			Expect(info).To(Equal(BlobInfo{Size: 1024, AcceptsRanges: true}))
+		})
+
+		It("reports that ranges are not accepted when the header is missing", func() {
+			fakeHTTPClient.AddDoBehavior(&http.Response{StatusCode: 200, ContentLength: 1024}, nil)
+
+			info, err := client.Head("/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.AcceptsRanges).To(BeFalse())
+		})
+
+		Context("when the http response code is not 200", func() {
+			BeforeEach(func() {
+				fakeHTTPClient.StatusCode = 404
+			})
+
+			It("returns err", func() {
+				_, err := client.Head("/")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Checking dav blob /: Wrong response code: 404"))
+			})
+		})
+	})
+
 	Describe("Put", func() {
 		Context("When the put request succeeds", func() {
 			itUploadsABlob := func() {
@@ -2,6 +2,8 @@ package fakes
 
 import (
 	"io"
+
+	davclient "github.com/cloudfoundry/bosh-agent/davcli/client"
 )
 
 type FakeClient struct {
@@ -9,6 +11,15 @@ type FakeClient struct {
 	GetContents io.ReadCloser
 	GetErr      error
 
+	GetRangeOffsets  []int64
+	GetRangeLengths  []int64
+	GetRangeContents []io.ReadCloser
+	GetRangeErr      error
+
+	HeadPath string
+	HeadInfo davclient.BlobInfo
+	HeadErr  error
+
 	PutPath          string
 	PutContents      string
 	PutContentLength int64
@@ -25,6 +36,27 @@ func (c *FakeClient) Get(path string) (io.ReadCloser, error) {
 	return c.GetContents, c.GetErr
 }
 
+func (c *FakeClient) GetRange(path string, offset, length int64) (io.ReadCloser, error) {
+	c.GetPath = path
+	c.GetRangeOffsets = append(c.GetRangeOffsets, offset)
+	c.GetRangeLengths = append(c.GetRangeLengths, length)
+
+	if len(c.GetRangeContents) == 0 {
+		return nil, c.GetRangeErr
+	}
+
+	content := c.GetRangeContents[0]
+	c.GetRangeContents = c.GetRangeContents[1:]
+
+	return content, c.GetRangeErr
+}
+
+func (c *FakeClient) Head(path string) (davclient.BlobInfo, error) {
+	c.HeadPath = path
+
+	return c.HeadInfo, c.HeadErr
+}
+
 func (c *FakeClient) Put(path string, content io.ReadCloser, contentLength int64) error {
 	c.PutPath = path
 	contentBytes := make([]byte, contentLength)
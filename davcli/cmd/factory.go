@@ -35,6 +35,6 @@ func (f *factory) SetConfig(config davconf.Config) {
 
 	f.cmds = map[string]Cmd{
 		"put": newPutCmd(client),
-		"get": newGetCmd(client),
+		"get": newGetCmd(client, config),
 	}
 }
@@ -2,17 +2,34 @@ package cmd
 
 import (
 	"errors"
-	davclient "github.com/cloudfoundry/bosh-agent/davcli/client"
+	"fmt"
 	"io"
+	"log"
 	"os"
+
+	davclient "github.com/cloudfoundry/bosh-agent/davcli/client"
+	davconf "github.com/cloudfoundry/bosh-agent/davcli/config"
 )
 
+const defaultResumeAttempts = 3
+
 type GetCmd struct {
-	client davclient.Client
+	client            davclient.Client
+	resumeAttempts    int
+	parallelChunks    int
+	parallelThreshold int64
 }
 
-func newGetCmd(client davclient.Client) (cmd GetCmd) {
+func newGetCmd(client davclient.Client, config davconf.Config) (cmd GetCmd) {
 	cmd.client = client
+
+	cmd.resumeAttempts = config.ResumeAttempts
+	if cmd.resumeAttempts <= 0 {
+		cmd.resumeAttempts = defaultResumeAttempts
+	}
+
+	cmd.parallelChunks = config.ParallelChunks
+	cmd.parallelThreshold = config.ParallelThreshold
 	return
 }
 
@@ -22,17 +39,126 @@ func (cmd GetCmd) Run(args []string) (err error) {
 		return
 	}
 
-	readCloser, err := cmd.client.Get(args[0])
+	targetFile, err := os.Create(args[1])
 	if err != nil {
 		return
 	}
-	defer readCloser.Close()
+	defer targetFile.Close()
 
-	targetFile, err := os.Create(args[1])
+	if cmd.parallelChunks > 1 {
+		info, err := cmd.client.Head(args[0])
+		if err != nil {
+			// Some servers and proxies reject HEAD requests but still serve GET requests
+			log.Printf("Checking blob size failed, getting blob in a single request: %s", err.Error())
+		} else if info.AcceptsRanges && info.Size > 0 && info.Size >= cmd.parallelThreshold {
+			return cmd.getInChunks(args[0], targetFile, info.Size)
+		}
+	}
+
+	return cmd.getRange(args[0], targetFile, 0, -1)
+}
+
+func (cmd GetCmd) getInChunks(path string, targetFile *os.File, size int64) error {
+	chunkSize := size / int64(cmd.parallelChunks)
+	if size%int64(cmd.parallelChunks) != 0 {
+		chunkSize++
+	}
+
+	errCh := make(chan error, cmd.parallelChunks)
+	chunks := 0
+
+	for offset := int64(0); offset < size; offset += chunkSize {
+		length := chunkSize
+		if offset+length > size {
+			length = size - offset
+		}
+
+		chunks++
+		go func(offset, length int64) {
+			errCh <- cmd.getRange(path, targetFile, offset, length)
+		}(offset, length)
+	}
+
+	var lastErr error
+	for i := 0; i < chunks; i++ {
+		err := <-errCh
+		if err != nil {
+			lastErr = err
+		}
+	}
+
+	if lastErr != nil {
+		return lastErr
+	}
+
+	fileInfo, err := targetFile.Stat()
 	if err != nil {
-		return
+		return err
 	}
 
-	_, err = io.Copy(targetFile, readCloser)
-	return
+	if fileInfo.Size() != size {
+		return fmt.Errorf("Getting dav blob %s: expected %d bytes, got %d", path, size, fileInfo.Size())
+	}
+
+	return nil
+}
+
+// getRange writes length bytes of the blob starting at offset to the same
+// offset in targetFile; negative length reads up to the end of the blob.
+// Interrupted transfers are resumed from the last byte written.
+func (cmd GetCmd) getRange(path string, targetFile *os.File, offset, length int64) error {
+	var written int64
+	var lastErr error
+
+	for attempt := 0; attempt <= cmd.resumeAttempts; attempt++ {
+		var content io.ReadCloser
+
+		if offset+written == 0 && length < 0 {
+			content, lastErr = cmd.client.Get(path)
+		} else if length < 0 {
+			content, lastErr = cmd.client.GetRange(path, offset+written, -1)
+		} else {
+			content, lastErr = cmd.client.GetRange(path, offset+written, length-written)
+		}
+
+		if lastErr != nil {
+			continue
+		}
+
+		var n int64
+		writer := &offsetWriter{file: targetFile, offset: offset + written}
+
+		if length < 0 {
+			n, lastErr = io.Copy(writer, content)
+		} else {
+			n, lastErr = io.Copy(writer, io.LimitReader(content, length-written))
+		}
+
+		content.Close()
+		written += n
+
+		if lastErr != nil {
+			continue
+		}
+
+		if length >= 0 && written < length {
+			lastErr = fmt.Errorf("Getting dav blob %s: received %d of %d bytes from byte %d", path, written, length, offset)
+			continue
+		}
+
+		return nil
+	}
+
+	return lastErr
+}
+
+type offsetWriter struct {
+	file   *os.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.file.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
 }
@@ -6,6 +6,9 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -69,6 +72,155 @@ func init() {
 			Expect(getFileContent(targetFilePath)).To(Equal("this is your blob"))
 		})
 
+		It("get resumes an interrupted download from the last received byte", func() {
+			requestedBlob := "0ca907f2-dde8-4413-a304-9076c9d0978b"
+			targetFilePath := filepath.Join(os.TempDir(), "testRunGetCommandResume.txt")
+			defer os.RemoveAll(targetFilePath)
+
+			var ranges []string
+
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+
+				if len(ranges) == 1 {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					Expect(err).ToNot(HaveOccurred())
+
+					conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 17\r\n\r\nthis is"))
+					conn.Close()
+					return
+				}
+
+				http.ServeContent(w, r, requestedBlob, time.Time{}, strings.NewReader("this is your blob"))
+			}
+
+			ts := httptest.NewServer(http.HandlerFunc(handler))
+			defer ts.Close()
+
+			err := runGet(davconf.Config{Endpoint: ts.URL}, []string{requestedBlob, targetFilePath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ranges).To(Equal([]string{"", "bytes=7-"}))
+			Expect(getFileContent(targetFilePath)).To(Equal("this is your blob"))
+		})
+
+		It("get gives up after the configured number of resume attempts", func() {
+			requestedBlob := "0ca907f2-dde8-4413-a304-9076c9d0978b"
+			targetFilePath := filepath.Join(os.TempDir(), "testRunGetCommandGiveUp.txt")
+			defer os.RemoveAll(targetFilePath)
+
+			requests := 0
+
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			ts := httptest.NewServer(http.HandlerFunc(handler))
+			defer ts.Close()
+
+			config := davconf.Config{Endpoint: ts.URL, ResumeAttempts: 2}
+
+			err := runGet(config, []string{requestedBlob, targetFilePath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Wrong response code: 500"))
+			Expect(requests).To(Equal(3))
+		})
+
+		It("get fetches large blobs in parallel chunks", func() {
+			requestedBlob := "0ca907f2-dde8-4413-a304-9076c9d0978b"
+			targetFilePath := filepath.Join(os.TempDir(), "testRunGetCommandChunks.txt")
+			defer os.RemoveAll(targetFilePath)
+
+			var lock sync.Mutex
+			var ranges []string
+
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "GET" {
+					lock.Lock()
+					ranges = append(ranges, r.Header.Get("Range"))
+					lock.Unlock()
+				}
+
+				http.ServeContent(w, r, requestedBlob, time.Time{}, strings.NewReader("this is your blob"))
+			}
+
+			ts := httptest.NewServer(http.HandlerFunc(handler))
+			defer ts.Close()
+
+			config := davconf.Config{
+				Endpoint:          ts.URL,
+				ParallelChunks:    3,
+				ParallelThreshold: 10,
+			}
+
+			err := runGet(config, []string{requestedBlob, targetFilePath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ranges).To(ConsistOf("bytes=0-5", "bytes=6-11", "bytes=12-16"))
+			Expect(getFileContent(targetFilePath)).To(Equal("this is your blob"))
+		})
+
+		It("get fetches blobs below the parallel threshold in a single request", func() {
+			requestedBlob := "0ca907f2-dde8-4413-a304-9076c9d0978b"
+			targetFilePath := filepath.Join(os.TempDir(), "testRunGetCommandSmall.txt")
+			defer os.RemoveAll(targetFilePath)
+
+			var ranges []string
+
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "GET" {
+					ranges = append(ranges, r.Header.Get("Range"))
+				}
+
+				http.ServeContent(w, r, requestedBlob, time.Time{}, strings.NewReader("this is your blob"))
+			}
+
+			ts := httptest.NewServer(http.HandlerFunc(handler))
+			defer ts.Close()
+
+			config := davconf.Config{
+				Endpoint:          ts.URL,
+				ParallelChunks:    3,
+				ParallelThreshold: 1024,
+			}
+
+			err := runGet(config, []string{requestedBlob, targetFilePath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ranges).To(Equal([]string{""}))
+			Expect(getFileContent(targetFilePath)).To(Equal("this is your blob"))
+		})
+
+		It("get fetches blob in a single request when server rejects HEAD requests", func() {
+			requestedBlob := "0ca907f2-dde8-4413-a304-9076c9d0978b"
+			targetFilePath := filepath.Join(os.TempDir(), "testRunGetCommandNoHead.txt")
+			defer os.RemoveAll(targetFilePath)
+
+			var ranges []string
+
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "HEAD" {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+
+				ranges = append(ranges, r.Header.Get("Range"))
+				http.ServeContent(w, r, requestedBlob, time.Time{}, strings.NewReader("this is your blob"))
+			}
+
+			ts := httptest.NewServer(http.HandlerFunc(handler))
+			defer ts.Close()
+
+			config := davconf.Config{
+				Endpoint:          ts.URL,
+				ParallelChunks:    3,
+				ParallelThreshold: 10,
+			}
+
+			err := runGet(config, []string{requestedBlob, targetFilePath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ranges).To(Equal([]string{""}))
+			Expect(getFileContent(targetFilePath)).To(Equal("this is your blob"))
+		})
+
 		It("get run with incorrect arg count", func() {
 			err := runGet(davconf.Config{}, []string{})
 			Expect(err).To(HaveOccurred())
@@ -4,4 +4,13 @@ type Config struct {
 	User     string
 	Password string
 	Endpoint string
+
+	// Number of times an interrupted download is resumed
+	// from the last received byte before giving up
+	ResumeAttempts int `json:"resume_attempts"`
+
+	// Blobs of at least ParallelThreshold bytes are fetched
+	// in ParallelChunks concurrent ranged requests
+	ParallelChunks    int   `json:"parallel_chunks"`
+	ParallelThreshold int64 `json:"parallel_threshold"`
 }
@@ -22,9 +22,16 @@ func (r valueResponse) Shorten() Response {
 	return r
 }
 
+// BlobError points to a blob with more details about the error (e.g. complete logs)
+type BlobError interface {
+	error
+	BlobstoreID() string
+}
+
 type exceptionResponse struct {
 	Exception struct {
-		Message string `json:"message,omitempty"`
+		Message     string `json:"message,omitempty"`
+		BlobstoreID string `json:"blobstore_id,omitempty"`
 	} `json:"exception"`
 
 	err error
@@ -33,6 +40,7 @@ type exceptionResponse struct {
 func NewExceptionResponse(err error) (resp Response) {
 	r := exceptionResponse{}
 	r.Exception.Message = err.Error()
+	r.Exception.BlobstoreID = findBlobstoreID(err)
 	r.err = err
 	return r
 }
@@ -41,9 +49,24 @@ func (r exceptionResponse) Shorten() Response {
 	if typedErr, ok := r.err.(bosherr.ShortenableError); ok {
 		sr := exceptionResponse{}
 		sr.Exception.Message = typedErr.ShortError()
+		sr.Exception.BlobstoreID = r.Exception.BlobstoreID
 		sr.err = typedErr
 		return sr
 	}
 
 	return r
 }
+
+func findBlobstoreID(err error) string {
+	switch typedErr := err.(type) {
+	case BlobError:
+		return typedErr.BlobstoreID()
+	case bosherr.ComplexError:
+		if blobID := findBlobstoreID(typedErr.Cause); blobID != "" {
+			return blobID
+		}
+		return findBlobstoreID(typedErr.Err)
+	}
+
+	return ""
+}
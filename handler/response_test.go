@@ -6,6 +6,7 @@ import (
 	. "github.com/onsi/ginkgo"
 
 	boshassert "github.com/cloudfoundry/bosh-agent/assert"
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	. "github.com/cloudfoundry/bosh-agent/handler"
 )
 
@@ -22,6 +23,15 @@ func (e *testShortError) ShortError() string {
 	return msg
 }
 
+type testBlobError struct {
+	msg    string
+	blobID string
+}
+
+func (e testBlobError) Error() string { return e.msg }
+
+func (e testBlobError) BlobstoreID() string { return e.blobID }
+
 var _ = Describe("NewValueResponse", func() {
 	It("can be serialized to JSON", func() {
 		resp := NewValueResponse("fake-value")
@@ -91,4 +101,30 @@ var _ = Describe("NewExceptionResponse", func() {
 			)
 		})
 	})
+
+	Context("with error that points to a blob", func() {
+		var err error
+
+		BeforeEach(func() {
+			err = bosherr.WrapError(testBlobError{msg: "fake-msg", blobID: "fake-blob-id"}, "fake-wrap")
+		})
+
+		It("can be serialized to JSON with blob id found in error causes", func() {
+			resp := NewExceptionResponse(err)
+			boshassert.MatchesJSONString(
+				GinkgoT(),
+				resp,
+				`{"exception":{"message":"fake-wrap: fake-msg","blobstore_id":"fake-blob-id"}}`,
+			)
+		})
+
+		It("keeps blob id when shortened", func() {
+			resp := NewExceptionResponse(err)
+			boshassert.MatchesJSONString(
+				GinkgoT(),
+				resp.Shorten(),
+				`{"exception":{"message":"fake-wrap: fake-msg","blobstore_id":"fake-blob-id"}}`,
+			)
+		})
+	})
 })
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
+)
+
+type backoffRetryClient struct {
+	delegate    Client
+	options     boshretry.Options
+	timeService boshtime.Service
+	logger      boshlog.Logger
+}
+
+func NewBackoffRetryClient(
+	delegate Client,
+	options boshretry.Options,
+	timeService boshtime.Service,
+	logger boshlog.Logger,
+) Client {
+	return &backoffRetryClient{
+		delegate:    delegate,
+		options:     options,
+		timeService: timeService,
+		logger:      logger,
+	}
+}
+
+func (r *backoffRetryClient) Do(req *http.Request) (*http.Response, error) {
+	requestRetryable := NewRequestRetryable(req, r.delegate, r.logger)
+	classifiedRetryable := boshretry.NewClassifiedRetryable(requestRetryable, IsRetryableError)
+	retryStrategy := boshretry.NewBackoffRetryStrategy(r.options, classifiedRetryable, r.timeService, r.logger)
+	err := retryStrategy.Try()
+
+	return requestRetryable.Response(), err
+}
@@ -0,0 +1,83 @@
+package http_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	fakehttp "github.com/cloudfoundry/bosh-agent/http/fakes"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
+	faketime "github.com/cloudfoundry/bosh-agent/time/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/http"
+)
+
+var _ = Describe("BackoffRetryClient", func() {
+	Describe("Do", func() {
+		var (
+			retryClient     Client
+			fakeClient      *fakehttp.FakeClient
+			fakeTimeService *faketime.FakeService
+		)
+
+		BeforeEach(func() {
+			fakeClient = fakehttp.NewFakeClient()
+			fakeTimeService = &faketime.FakeService{}
+			logger := boshlog.NewLogger(boshlog.LevelNone)
+			options := boshretry.Options{MaxAttempts: 4, InitialDelay: 10, Multiplier: 2}
+
+			retryClient = NewBackoffRetryClient(fakeClient, options, fakeTimeService, logger)
+		})
+
+		It("attempts once if request is successful", func() {
+			fakeClient.SetMessage("fake-response-body")
+			fakeClient.StatusCode = 200
+
+			req := &http.Request{}
+			resp, err := retryClient.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(readString(resp.Body)).To(Equal("fake-response-body"))
+
+			Expect(fakeClient.CallCount).To(Equal(1))
+		})
+
+		It("retries server errors with increasing delays", func() {
+			fakeClient.StatusCode = 503
+
+			resp, err := retryClient.Do(&http.Request{})
+			Expect(err).To(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(503))
+
+			Expect(fakeClient.CallCount).To(Equal(4))
+			Expect(fakeTimeService.SleepInputs).To(Equal([]time.Duration{
+				10 * time.Millisecond,
+				20 * time.Millisecond,
+				40 * time.Millisecond,
+			}))
+		})
+
+		It("retries connection errors", func() {
+			fakeClient.Error = errors.New("fake-connection-error")
+
+			_, err := retryClient.Do(&http.Request{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-connection-error"))
+
+			Expect(fakeClient.CallCount).To(Equal(4))
+		})
+
+		It("does not retry client errors", func() {
+			fakeClient.StatusCode = 404
+
+			resp, err := retryClient.Do(&http.Request{})
+			Expect(err).To(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(404))
+
+			Expect(fakeClient.CallCount).To(Equal(1))
+		})
+	})
+})
@@ -83,7 +83,10 @@ func (r *requestRetryable) Attempt() (bool, error) {
 	}
 
 	r.logger.Debug(r.logTag, "[requestID=%s] Request attempt failed (attempts=%d), response: %s", r.requestID, r.attempt, r.formatResponse(r.response))
-	return true, bosherr.Errorf("Request failed, response: %s", r.formatResponse(r.response))
+	return true, ResponseError{
+		StatusCode: r.response.StatusCode,
+		Response:   r.formatResponse(r.response),
+	}
 }
 
 func (r *requestRetryable) Response() *http.Response {
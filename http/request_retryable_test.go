@@ -13,7 +13,6 @@ import (
 	. "github.com/onsi/gomega"
 
 	fakehttp "github.com/cloudfoundry/bosh-agent/http/fakes"
-	fakeretry "github.com/cloudfoundry/bosh-agent/retrystrategy/fakes"
 
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 )
@@ -24,13 +23,11 @@ var _ = Describe("RequestRetryable", func() {
 			requestRetryable RequestRetryable
 			request          *http.Request
 			fakeClient       *fakehttp.FakeClient
-			fakeStrategy     *fakeretry.FakeRetryStrategy
 		)
 
 		BeforeEach(func() {
 			fakeClient = fakehttp.NewFakeClient()
 			logger := boshlog.NewLogger(boshlog.LevelNone)
-			fakeStrategy = fakeretry.NewFakeRetryStrategy()
 
 			request = &http.Request{
 				Body: ioutil.NopCloser(strings.NewReader("fake-request-body")),
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"fmt"
+)
+
+// ResponseError is returned when request completes with unsuccessful status code
+type ResponseError struct {
+	StatusCode int
+	Response   string
+}
+
+func (e ResponseError) Error() string {
+	return fmt.Sprintf("Request failed, response: %s", e.Response)
+}
+
+// IsRetryableError classifies connection errors, server errors,
+// request timeouts and throttling as worth retrying.
+// Other client errors will not succeed if request is repeated.
+func IsRetryableError(err error) bool {
+	respErr, ok := err.(ResponseError)
+	if !ok {
+		return true
+	}
+
+	switch {
+	case respErr.StatusCode >= 500:
+		return true
+	case respErr.StatusCode == 408 || respErr.StatusCode == 429:
+		return true
+	}
+
+	return false
+}
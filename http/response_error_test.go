@@ -0,0 +1,29 @@
+package http_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/http"
+)
+
+var _ = Describe("IsRetryableError", func() {
+	It("retries errors that are not response errors", func() {
+		Expect(IsRetryableError(errors.New("fake-connection-error"))).To(BeTrue())
+	})
+
+	It("retries server errors, request timeouts and throttling", func() {
+		Expect(IsRetryableError(ResponseError{StatusCode: 500})).To(BeTrue())
+		Expect(IsRetryableError(ResponseError{StatusCode: 503})).To(BeTrue())
+		Expect(IsRetryableError(ResponseError{StatusCode: 408})).To(BeTrue())
+		Expect(IsRetryableError(ResponseError{StatusCode: 429})).To(BeTrue())
+	})
+
+	It("does not retry other client errors", func() {
+		Expect(IsRetryableError(ResponseError{StatusCode: 400})).To(BeFalse())
+		Expect(IsRetryableError(ResponseError{StatusCode: 401})).To(BeFalse())
+		Expect(IsRetryableError(ResponseError{StatusCode: 404})).To(BeFalse())
+	})
+})
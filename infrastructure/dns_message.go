@@ -0,0 +1,155 @@
+package infrastructure
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+)
+
+// Minimal DNS wire format (RFC 1035) needed to look up A and AAAA records
+
+const (
+	dnsTypeA    uint16 = 1
+	dnsTypeAAAA uint16 = 28
+	dnsClassIN  uint16 = 1
+
+	dnsHeaderLength = 12
+
+	dnsFlagResponse         = 1 << 15
+	dnsFlagTruncated        = 1 << 9
+	dnsFlagRecursionDesired = 1 << 8
+	dnsRcodeMask            = 0xF
+
+	dnsRcodeNameError = 3
+)
+
+type dnsResponse struct {
+	id        uint16
+	truncated bool
+	rcode     int
+	addresses []net.IP
+}
+
+func buildDNSQuery(id uint16, host string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, dnsHeaderLength, dnsHeaderLength+len(host)+6)
+
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], dnsFlagRecursionDesired)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+
+	name := strings.TrimSuffix(host, ".")
+	if len(name) == 0 || len(name) > 253 {
+		return nil, bosherr.Errorf("Invalid host name '%s'", host)
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, bosherr.Errorf("Invalid host name '%s'", host)
+		}
+
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+
+	msg = append(msg, 0)
+	msg = append(msg, byte(qtype>>8), byte(qtype))
+	msg = append(msg, byte(dnsClassIN>>8), byte(dnsClassIN))
+
+	return msg, nil
+}
+
+// parseDNSResponse returns addresses from answer records of given type;
+// CNAME records are skipped since recursive servers include their targets
+func parseDNSResponse(msg []byte, qtype uint16) (dnsResponse, error) {
+	var response dnsResponse
+
+	if len(msg) < dnsHeaderLength {
+		return response, errors.New("DNS response is too short")
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&dnsFlagResponse == 0 {
+		return response, errors.New("DNS message is not a response")
+	}
+
+	response.id = binary.BigEndian.Uint16(msg[0:])
+	response.truncated = flags&dnsFlagTruncated != 0
+	response.rcode = int(flags & dnsRcodeMask)
+
+	questionCount := int(binary.BigEndian.Uint16(msg[4:]))
+	answerCount := int(binary.BigEndian.Uint16(msg[6:]))
+
+	offset := dnsHeaderLength
+
+	for i := 0; i < questionCount; i++ {
+		next, err := skipDNSName(msg, offset)
+		if err != nil {
+			return response, err
+		}
+
+		offset = next + 4
+	}
+
+	for i := 0; i < answerCount; i++ {
+		next, err := skipDNSName(msg, offset)
+		if err != nil {
+			return response, err
+		}
+
+		if next+10 > len(msg) {
+			return response, errors.New("DNS answer is truncated")
+		}
+
+		rrType := binary.BigEndian.Uint16(msg[next:])
+		rrClass := binary.BigEndian.Uint16(msg[next+2:])
+		dataLength := int(binary.BigEndian.Uint16(msg[next+8:]))
+
+		offset = next + 10
+		if offset+dataLength > len(msg) {
+			return response, errors.New("DNS answer is truncated")
+		}
+
+		data := msg[offset : offset+dataLength]
+		offset += dataLength
+
+		if rrType != qtype || rrClass != dnsClassIN {
+			continue
+		}
+
+		if (rrType == dnsTypeA && dataLength == net.IPv4len) || (rrType == dnsTypeAAAA && dataLength == net.IPv6len) {
+			response.addresses = append(response.addresses, net.IP(append([]byte{}, data...)))
+		}
+	}
+
+	return response, nil
+}
+
+// skipDNSName returns offset right after possibly compressed name
+func skipDNSName(msg []byte, offset int) (int, error) {
+	for {
+		if offset >= len(msg) {
+			return 0, errors.New("DNS name is truncated")
+		}
+
+		length := int(msg[offset])
+
+		switch {
+		case length == 0:
+			return offset + 1, nil
+
+		case length&0xC0 == 0xC0:
+			if offset+2 > len(msg) {
+				return 0, errors.New("DNS name is truncated")
+			}
+			return offset + 2, nil
+
+		case length&0xC0 != 0:
+			return 0, errors.New("DNS name has invalid label")
+		}
+
+		offset += 1 + length
+	}
+}
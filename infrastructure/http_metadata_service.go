@@ -7,21 +7,25 @@ import (
 	"net/http"
 
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshhttp "github.com/cloudfoundry/bosh-agent/http"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 )
 
 type httpMetadataService struct {
 	metadataHost string
 	resolver     DNSResolver
+	httpClient   boshhttp.Client
 }
 
 func NewHTTPMetadataService(
 	metadataHost string,
 	resolver DNSResolver,
+	httpClient boshhttp.Client,
 ) MetadataService {
 	return httpMetadataService{
 		metadataHost: metadataHost,
 		resolver:     resolver,
+		httpClient:   httpClient,
 	}
 }
 
@@ -31,7 +35,7 @@ func (ms httpMetadataService) Load() error {
 
 func (ms httpMetadataService) GetPublicKey() (string, error) {
 	url := fmt.Sprintf("%s/latest/meta-data/public-keys/0/openssh-key", ms.metadataHost)
-	resp, err := http.Get(url)
+	resp, err := httpGet(ms.httpClient, url)
 	if err != nil {
 		return "", bosherr.WrapError(err, "Getting open ssh key")
 	}
@@ -48,7 +52,7 @@ func (ms httpMetadataService) GetPublicKey() (string, error) {
 
 func (ms httpMetadataService) GetInstanceID() (string, error) {
 	url := fmt.Sprintf("%s/latest/meta-data/instance-id", ms.metadataHost)
-	resp, err := http.Get(url)
+	resp, err := httpGet(ms.httpClient, url)
 	if err != nil {
 		return "", bosherr.WrapError(err, "Getting instance id from url")
 	}
@@ -108,7 +112,7 @@ func (ms httpMetadataService) getUserData() (UserDataContentsType, error) {
 
 	userDataURL := fmt.Sprintf("%s/latest/user-data", ms.metadataHost)
 
-	userDataResp, err := http.Get(userDataURL)
+	userDataResp, err := httpGet(ms.httpClient, userDataURL)
 	if err != nil {
 		return userData, bosherr.WrapError(err, "Getting user data from url")
 	}
@@ -127,3 +131,21 @@ func (ms httpMetadataService) getUserData() (UserDataContentsType, error) {
 
 	return userData, nil
 }
+
+func httpGet(httpClient boshhttp.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -9,8 +9,12 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshhttp "github.com/cloudfoundry/bosh-agent/http"
 	. "github.com/cloudfoundry/bosh-agent/infrastructure"
 	fakeinf "github.com/cloudfoundry/bosh-agent/infrastructure/fakes"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
+	faketime "github.com/cloudfoundry/bosh-agent/time/fakes"
 )
 
 var _ = Describe("HTTPMetadataService", describeHTTPMetadataService)
@@ -23,7 +27,7 @@ func describeHTTPMetadataService() {
 
 	BeforeEach(func() {
 		dnsResolver = &fakeinf.FakeDNSResolver{}
-		metadataService = NewHTTPMetadataService("fake-metadata-host", dnsResolver)
+		metadataService = NewHTTPMetadataService("fake-metadata-host", dnsResolver, http.DefaultClient)
 	})
 
 	Describe("IsAvailable", func() {
@@ -49,7 +53,7 @@ func describeHTTPMetadataService() {
 
 			ts = httptest.NewServer(handler)
 
-			metadataService = NewHTTPMetadataService(ts.URL, dnsResolver)
+			metadataService = NewHTTPMetadataService(ts.URL, dnsResolver, http.DefaultClient)
 		})
 
 		AfterEach(func() {
@@ -80,7 +84,7 @@ func describeHTTPMetadataService() {
 
 			ts = httptest.NewServer(handler)
 
-			metadataService = NewHTTPMetadataService(ts.URL, dnsResolver)
+			metadataService = NewHTTPMetadataService(ts.URL, dnsResolver, http.DefaultClient)
 		})
 
 		AfterEach(func() {
@@ -92,6 +96,39 @@ func describeHTTPMetadataService() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(publicKey).To(Equal("fake-instance-id"))
 		})
+
+		Context("when metadata server is temporarily unavailable", func() {
+			var requests int
+
+			BeforeEach(func() {
+				ts.Close()
+
+				requests = 0
+
+				handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					if requests == 1 {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+
+					w.Write([]byte("fake-instance-id"))
+				})
+
+				ts = httptest.NewServer(handler)
+
+				logger := boshlog.NewLogger(boshlog.LevelNone)
+				httpClient := boshhttp.NewBackoffRetryClient(http.DefaultClient, boshretry.Options{MaxAttempts: 3}, &faketime.FakeService{}, logger)
+				metadataService = NewHTTPMetadataService(ts.URL, dnsResolver, httpClient)
+			})
+
+			It("retries according to retry policy of http client", func() {
+				instanceID, err := metadataService.GetInstanceID()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(instanceID).To(Equal("fake-instance-id"))
+				Expect(requests).To(Equal(2))
+			})
+		})
 	})
 
 	Describe("GetServerName", func() {
@@ -122,7 +159,7 @@ func describeHTTPMetadataService() {
 
 			handler := http.HandlerFunc(handlerFunc)
 			ts = httptest.NewServer(handler)
-			metadataService = NewHTTPMetadataService(ts.URL, dnsResolver)
+			metadataService = NewHTTPMetadataService(ts.URL, dnsResolver, http.DefaultClient)
 		})
 
 		AfterEach(func() {
@@ -189,7 +226,7 @@ func describeHTTPMetadataService() {
 
 			handler := http.HandlerFunc(handlerFunc)
 			ts = httptest.NewServer(handler)
-			metadataService = NewHTTPMetadataService(ts.URL, dnsResolver)
+			metadataService = NewHTTPMetadataService(ts.URL, dnsResolver, http.DefaultClient)
 		})
 
 		AfterEach(func() {
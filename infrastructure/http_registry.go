@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshhttp "github.com/cloudfoundry/bosh-agent/http"
 	boshplat "github.com/cloudfoundry/bosh-agent/platform"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 )
@@ -15,17 +15,20 @@ type httpRegistry struct {
 	metadataService   MetadataService
 	platform          boshplat.Platform
 	useServerNameAsID bool
+	httpClient        boshhttp.Client
 }
 
 func NewHTTPRegistry(
 	metadataService MetadataService,
 	platform boshplat.Platform,
 	useServerNameAsID bool,
+	httpClient boshhttp.Client,
 ) Registry {
 	return httpRegistry{
 		metadataService:   metadataService,
 		platform:          platform,
 		useServerNameAsID: useServerNameAsID,
+		httpClient:        httpClient,
 	}
 }
 
@@ -62,14 +65,15 @@ func (r httpRegistry) GetSettings() (boshsettings.Settings, error) {
 	}
 
 	if len(networks) > 0 {
-		err = r.platform.SetupNetworking(networks)
+		// Mbus is not known until settings are fetched from registry
+		err = r.platform.SetupNetworking(networks, "")
 		if err != nil {
 			return settings, bosherr.WrapError(err, "Setting up networks")
 		}
 	}
 
 	settingsURL := fmt.Sprintf("%s/instances/%s/settings", registryEndpoint, identifier)
-	wrapperResponse, err := http.Get(settingsURL)
+	wrapperResponse, err := httpGet(r.httpClient, settingsURL)
 	if err != nil {
 		return settings, bosherr.WrapError(err, "Getting settings from url")
 	}
@@ -0,0 +1,33 @@
+package infrastructure
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+)
+
+type multiDNSResolver struct {
+	resolvers []DNSResolver
+}
+
+// NewMultiDNSResolver returns resolver that falls back
+// on following resolvers when host cannot be resolved
+func NewMultiDNSResolver(resolvers ...DNSResolver) DNSResolver {
+	return multiDNSResolver{resolvers: resolvers}
+}
+
+func (r multiDNSResolver) LookupHost(dnsServers []string, host string) (string, error) {
+	var ipString string
+	var err error
+
+	if len(r.resolvers) == 0 {
+		return "", bosherr.Error("No DNS resolvers provided")
+	}
+
+	for _, resolver := range r.resolvers {
+		ipString, err = resolver.LookupHost(dnsServers, host)
+		if err == nil {
+			return ipString, nil
+		}
+	}
+
+	return "", bosherr.WrapErrorf(err, "Looking up host '%s' with all DNS resolvers", host)
+}
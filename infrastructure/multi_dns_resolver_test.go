@@ -0,0 +1,62 @@
+package infrastructure_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure"
+	fakeinf "github.com/cloudfoundry/bosh-agent/infrastructure/fakes"
+)
+
+var _ = Describe("multiDNSResolver", func() {
+	var (
+		primary  *fakeinf.FakeDNSResolver
+		fallback *fakeinf.FakeDNSResolver
+		resolver DNSResolver
+	)
+
+	BeforeEach(func() {
+		primary = &fakeinf.FakeDNSResolver{}
+		fallback = &fakeinf.FakeDNSResolver{}
+		resolver = NewMultiDNSResolver(primary, fallback)
+
+		fallback.RegisterRecord(fakeinf.FakeDNSRecord{
+			DNSServers: []string{"8.8.8.8"},
+			Host:       "fake-host",
+			IP:         "10.0.0.2",
+		})
+	})
+
+	Describe("LookupHost", func() {
+		It("returns ip resolved by first resolver", func() {
+			primary.RegisterRecord(fakeinf.FakeDNSRecord{
+				DNSServers: []string{"8.8.8.8"},
+				Host:       "fake-host",
+				IP:         "10.0.0.1",
+			})
+
+			ip, err := resolver.LookupHost([]string{"8.8.8.8"}, "fake-host")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("10.0.0.1"))
+		})
+
+		It("falls back on next resolver when host cannot be resolved", func() {
+			primary.LookupHostErr = errors.New("fake-lookup-err")
+
+			ip, err := resolver.LookupHost([]string{"8.8.8.8"}, "fake-host")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("10.0.0.2"))
+		})
+
+		It("returns error from last resolver when no resolver resolves host", func() {
+			primary.LookupHostErr = errors.New("fake-lookup-err-1")
+			fallback.LookupHostErr = errors.New("fake-lookup-err-2")
+
+			_, err := resolver.LookupHost([]string{"8.8.8.8"}, "fake-host")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-lookup-err-2"))
+		})
+	})
+})
@@ -0,0 +1,221 @@
+package infrastructure
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+)
+
+const nativeDNSResolverLogTag = "Native DNS Resolver"
+
+// Responses over UDP are limited to 512 bytes;
+// larger ones are truncated and have to be retried over TCP
+const dnsUDPMessageSize = 512
+
+// DNSDialer opens connections to DNS servers; net.Dialer satisfies it
+type DNSDialer interface {
+	Dial(network, address string) (net.Conn, error)
+}
+
+// NativeDNSResolver queries given DNS servers directly
+// without relying on system resolver configuration or dig
+type NativeDNSResolver struct {
+	dialer   DNSDialer
+	timeout  time.Duration
+	attempts int
+	logger   boshlog.Logger
+}
+
+func NewNativeDNSResolver(
+	dialer DNSDialer,
+	timeout time.Duration,
+	attempts int,
+	logger boshlog.Logger,
+) NativeDNSResolver {
+	return NativeDNSResolver{
+		dialer:   dialer,
+		timeout:  timeout,
+		attempts: attempts,
+		logger:   logger,
+	}
+}
+
+func (res NativeDNSResolver) LookupHost(dnsServers []string, host string) (string, error) {
+	if host == "localhost" {
+		return "127.0.0.1", nil
+	}
+
+	ip := net.ParseIP(host)
+	if ip != nil {
+		return host, nil
+	}
+
+	var err error
+	var ipString string
+
+	if len(dnsServers) == 0 {
+		err = errors.New("No DNS servers provided")
+	}
+
+	for _, dnsServer := range dnsServers {
+		for attempt := 1; attempt <= res.attempts; attempt++ {
+			ipString, err = res.lookupHostWithDNSServer(dnsServer, host)
+			if err == nil {
+				return ipString, nil
+			}
+
+			res.logger.Debug(nativeDNSResolverLogTag, "Attempt %d to resolve '%s' with DNS server %s failed: %s", attempt, host, dnsServer, err.Error())
+		}
+	}
+
+	return "", err
+}
+
+func (res NativeDNSResolver) lookupHostWithDNSServer(dnsServer string, host string) (string, error) {
+	for _, qtype := range []uint16{dnsTypeA, dnsTypeAAAA} {
+		addresses, err := res.query(dnsServer, host, qtype)
+		if err != nil {
+			return "", err
+		}
+
+		if len(addresses) > 0 {
+			return addresses[0].String(), nil
+		}
+	}
+
+	return "", bosherr.Errorf("Resolving host '%s': no addresses found", host)
+}
+
+func (res NativeDNSResolver) query(dnsServer string, host string, qtype uint16) ([]net.IP, error) {
+	id, err := res.generateID()
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := buildDNSQuery(id, host, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	address := net.JoinHostPort(dnsServer, "53")
+
+	response, err := res.exchange("udp", address, id, query, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.truncated {
+		response, err = res.exchange("tcp", address, id, query, qtype)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if response.rcode == dnsRcodeNameError {
+		return nil, bosherr.Errorf("Resolving host '%s': no such host", host)
+	}
+
+	if response.rcode != 0 {
+		return nil, bosherr.Errorf("Resolving host '%s': DNS server %s responded with code %d", host, dnsServer, response.rcode)
+	}
+
+	return response.addresses, nil
+}
+
+func (res NativeDNSResolver) exchange(network, address string, id uint16, query []byte, qtype uint16) (dnsResponse, error) {
+	conn, err := res.dialer.Dial(network, address)
+	if err != nil {
+		return dnsResponse{}, bosherr.WrapErrorf(err, "Connecting to DNS server %s over %s", address, network)
+	}
+
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(res.timeout))
+	if err != nil {
+		return dnsResponse{}, bosherr.WrapError(err, "Setting DNS query deadline")
+	}
+
+	var msg []byte
+
+	if network == "tcp" {
+		msg, err = res.exchangeStream(conn, query)
+	} else {
+		msg, err = res.exchangeDatagram(conn, query)
+	}
+
+	if err != nil {
+		return dnsResponse{}, bosherr.WrapErrorf(err, "Querying DNS server %s over %s", address, network)
+	}
+
+	response, err := parseDNSResponse(msg, qtype)
+	if err != nil {
+		return dnsResponse{}, bosherr.WrapErrorf(err, "Parsing response from DNS server %s", address)
+	}
+
+	if response.id != id {
+		return dnsResponse{}, bosherr.Errorf("Response from DNS server %s does not match query", address)
+	}
+
+	return response, nil
+}
+
+func (res NativeDNSResolver) exchangeDatagram(conn net.Conn, query []byte) ([]byte, error) {
+	_, err := conn.Write(query)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, dnsUDPMessageSize)
+
+	n, err := conn.Read(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg[:n], nil
+}
+
+// exchangeStream prefixes messages with their length as required over TCP
+func (res NativeDNSResolver) exchangeStream(conn net.Conn, query []byte) ([]byte, error) {
+	prefixedQuery := make([]byte, 2, 2+len(query))
+	binary.BigEndian.PutUint16(prefixedQuery, uint16(len(query)))
+
+	_, err := conn.Write(append(prefixedQuery, query...))
+	if err != nil {
+		return nil, err
+	}
+
+	length := make([]byte, 2)
+
+	_, err = io.ReadFull(conn, length)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, binary.BigEndian.Uint16(length))
+
+	_, err = io.ReadFull(conn, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// generateID returns random query ID so that spoofed responses are harder to forge
+func (res NativeDNSResolver) generateID() (uint16, error) {
+	id := make([]byte, 2)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Generating DNS query ID")
+	}
+
+	return binary.BigEndian.Uint16(id), nil
+}
@@ -0,0 +1,254 @@
+package infrastructure_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+)
+
+type fakeDNSQuery struct {
+	Network string
+	Address string
+	Type    uint16
+}
+
+// fakeDNSServer answers queries sent over in-memory connections
+type fakeDNSServer struct {
+	Queries     []fakeDNSQuery
+	queriesLock sync.Mutex
+
+	// Respond returns answers and response flags; nil answers with zero flags
+	// leave query unanswered so that it times out
+	Respond func(query fakeDNSQuery) ([]net.IP, uint16)
+
+	DialErrs map[string]error
+}
+
+func (s *fakeDNSServer) Dial(network, address string) (net.Conn, error) {
+	if err := s.DialErrs[address]; err != nil {
+		return nil, err
+	}
+
+	client, server := net.Pipe()
+
+	go func() {
+		defer server.Close()
+
+		msg := make([]byte, 512)
+		var n int
+		var err error
+
+		if network == "tcp" {
+			length := make([]byte, 2)
+			_, err = io.ReadFull(server, length)
+			if err != nil {
+				return
+			}
+			n, err = io.ReadFull(server, msg[:binary.BigEndian.Uint16(length)])
+		} else {
+			n, err = server.Read(msg)
+		}
+		if err != nil {
+			return
+		}
+
+		query := msg[:n]
+		qtype := binary.BigEndian.Uint16(query[n-4:])
+
+		fakeQuery := fakeDNSQuery{Network: network, Address: address, Type: qtype}
+
+		s.queriesLock.Lock()
+		s.Queries = append(s.Queries, fakeQuery)
+		answers, flags := s.Respond(fakeQuery)
+		s.queriesLock.Unlock()
+		if answers == nil && flags == 0 {
+			time.Sleep(time.Second)
+			return
+		}
+
+		response := buildFakeDNSResponse(query, answers, flags)
+		if network == "tcp" {
+			length := make([]byte, 2)
+			binary.BigEndian.PutUint16(length, uint16(len(response)))
+			response = append(length, response...)
+		}
+
+		server.Write(response)
+	}()
+
+	return client, nil
+}
+
+func buildFakeDNSResponse(query []byte, answers []net.IP, flags uint16) []byte {
+	response := append([]byte{}, query...)
+
+	binary.BigEndian.PutUint16(response[2:], 0x8180|flags)
+	binary.BigEndian.PutUint16(response[6:], uint16(len(answers)))
+
+	for _, answer := range answers {
+		rrType, rdata := uint16(1), []byte(answer.To4())
+		if rdata == nil {
+			rrType, rdata = 28, []byte(answer.To16())
+		}
+
+		// Name is compressed as pointer to the question
+		record := []byte{0xC0, 0x0C, 0, 0, 0, 1, 0, 0, 0, 60, 0, 0}
+		binary.BigEndian.PutUint16(record[2:], rrType)
+		binary.BigEndian.PutUint16(record[10:], uint16(len(rdata)))
+
+		response = append(response, record...)
+		response = append(response, rdata...)
+	}
+
+	return response
+}
+
+var _ = Describe("NativeDNSResolver", func() {
+	var (
+		server   *fakeDNSServer
+		resolver NativeDNSResolver
+	)
+
+	BeforeEach(func() {
+		server = &fakeDNSServer{
+			Respond: func(query fakeDNSQuery) ([]net.IP, uint16) {
+				if query.Type == 1 {
+					return []net.IP{net.ParseIP("74.125.19.99")}, 0
+				}
+				return []net.IP{}, 0
+			},
+			DialErrs: map[string]error{},
+		}
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		resolver = NewNativeDNSResolver(server, 50*time.Millisecond, 2, logger)
+	})
+
+	Describe("LookupHost", func() {
+		It("returns ip without querying DNS servers when host is an ip", func() {
+			ip, err := resolver.LookupHost([]string{"8.8.8.8"}, "74.125.239.101")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("74.125.239.101"))
+			Expect(server.Queries).To(BeEmpty())
+		})
+
+		It("returns 127.0.0.1 for 'localhost'", func() {
+			ip, err := resolver.LookupHost([]string{"8.8.8.8"}, "localhost")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("127.0.0.1"))
+		})
+
+		It("returns ip from A record queried over UDP", func() {
+			ip, err := resolver.LookupHost([]string{"8.8.8.8"}, "google.com.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("74.125.19.99"))
+
+			Expect(server.Queries).To(Equal([]fakeDNSQuery{
+				{Network: "udp", Address: "8.8.8.8:53", Type: 1},
+			}))
+		})
+
+		It("queries IPv6 DNS servers", func() {
+			_, err := resolver.LookupHost([]string{"2001:4860:4860::8888"}, "google.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(server.Queries).To(Equal([]fakeDNSQuery{
+				{Network: "udp", Address: "[2001:4860:4860::8888]:53", Type: 1},
+			}))
+		})
+
+		It("returns ip from AAAA record when there are no A records", func() {
+			server.Respond = func(query fakeDNSQuery) ([]net.IP, uint16) {
+				if query.Type == 28 {
+					return []net.IP{net.ParseIP("2001:db8::5")}, 0
+				}
+				return []net.IP{}, 0
+			}
+
+			ip, err := resolver.LookupHost([]string{"8.8.8.8"}, "google.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("2001:db8::5"))
+
+			Expect(server.Queries).To(Equal([]fakeDNSQuery{
+				{Network: "udp", Address: "8.8.8.8:53", Type: 1},
+				{Network: "udp", Address: "8.8.8.8:53", Type: 28},
+			}))
+		})
+
+		It("repeats query over TCP when UDP response is truncated", func() {
+			server.Respond = func(query fakeDNSQuery) ([]net.IP, uint16) {
+				if query.Network == "udp" {
+					return []net.IP{}, 1 << 9
+				}
+				return []net.IP{net.ParseIP("74.125.19.99")}, 0
+			}
+
+			ip, err := resolver.LookupHost([]string{"8.8.8.8"}, "google.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("74.125.19.99"))
+
+			Expect(server.Queries).To(Equal([]fakeDNSQuery{
+				{Network: "udp", Address: "8.8.8.8:53", Type: 1},
+				{Network: "tcp", Address: "8.8.8.8:53", Type: 1},
+			}))
+		})
+
+		It("retries query when DNS server does not respond in time", func() {
+			respond := server.Respond
+			server.Respond = func(query fakeDNSQuery) ([]net.IP, uint16) {
+				if len(server.Queries) == 1 {
+					return nil, 0
+				}
+				return respond(query)
+			}
+
+			ip, err := resolver.LookupHost([]string{"8.8.8.8"}, "google.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("74.125.19.99"))
+
+			Expect(server.Queries).To(HaveLen(2))
+		})
+
+		It("tries next DNS server when host cannot be resolved with previous one", func() {
+			server.DialErrs["127.0.0.127:53"] = errors.New("fake-dial-err")
+
+			ip, err := resolver.LookupHost([]string{"127.0.0.127", "8.8.8.8"}, "google.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("74.125.19.99"))
+		})
+
+		It("returns error when host does not exist", func() {
+			server.Respond = func(query fakeDNSQuery) ([]net.IP, uint16) {
+				return []net.IP{}, 3
+			}
+
+			_, err := resolver.LookupHost([]string{"8.8.8.8"}, "google.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no such host"))
+		})
+
+		It("returns error when no DNS server resolves host", func() {
+			server.DialErrs["127.0.0.127:53"] = errors.New("fake-dial-err-1")
+			server.DialErrs["127.0.0.128:53"] = errors.New("fake-dial-err-2")
+
+			_, err := resolver.LookupHost([]string{"127.0.0.127", "127.0.0.128"}, "google.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-dial-err-2"))
+		})
+
+		It("returns error when there are no DNS servers", func() {
+			_, err := resolver.LookupHost([]string{}, "google.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No DNS servers provided"))
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshhttp "github.com/cloudfoundry/bosh-agent/http"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	boshplat "github.com/cloudfoundry/bosh-agent/platform"
 	boshsys "github.com/cloudfoundry/bosh-agent/system"
@@ -18,6 +19,7 @@ type registryProvider struct {
 	useServerName   bool
 	platform        boshplat.Platform
 	fs              boshsys.FileSystem
+	httpClient      boshhttp.Client
 	logTag          string
 	logger          boshlog.Logger
 }
@@ -27,6 +29,7 @@ func NewRegistryProvider(
 	platform boshplat.Platform,
 	useServerName bool,
 	fs boshsys.FileSystem,
+	httpClient boshhttp.Client,
 	logger boshlog.Logger,
 ) RegistryProvider {
 	return &registryProvider{
@@ -34,6 +37,7 @@ func NewRegistryProvider(
 		platform:        platform,
 		useServerName:   useServerName,
 		fs:              fs,
+		httpClient:      httpClient,
 		logTag:          "registryProvider",
 		logger:          logger,
 	}
@@ -47,7 +51,7 @@ func (p *registryProvider) GetRegistry() (Registry, error) {
 
 	if strings.HasPrefix(registryEndpoint, "http") {
 		p.logger.Debug(p.logTag, "Using http registry at %s", registryEndpoint)
-		return NewHTTPRegistry(p.metadataService, p.platform, p.useServerName), nil
+		return NewHTTPRegistry(p.metadataService, p.platform, p.useServerName, p.httpClient), nil
 	}
 
 	p.logger.Debug(p.logTag, "Using file registry at %s", registryEndpoint)
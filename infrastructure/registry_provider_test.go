@@ -6,6 +6,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	fakehttp "github.com/cloudfoundry/bosh-agent/http/fakes"
 	. "github.com/cloudfoundry/bosh-agent/infrastructure"
 	fakeinf "github.com/cloudfoundry/bosh-agent/infrastructure/fakes"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
@@ -19,6 +20,7 @@ var _ = Describe("RegistryProvider", func() {
 		platform         *fakeplat.FakePlatform
 		useServerName    bool
 		fs               *fakesys.FakeFileSystem
+		httpClient       *fakehttp.FakeClient
 		registryProvider RegistryProvider
 	)
 
@@ -27,11 +29,12 @@ var _ = Describe("RegistryProvider", func() {
 		platform = &fakeplat.FakePlatform{}
 		useServerName = false
 		fs = fakesys.NewFakeFileSystem()
+		httpClient = fakehttp.NewFakeClient()
 	})
 
 	JustBeforeEach(func() {
 		logger := boshlog.NewLogger(boshlog.LevelNone)
-		registryProvider = NewRegistryProvider(metadataService, platform, useServerName, fs, logger)
+		registryProvider = NewRegistryProvider(metadataService, platform, useServerName, fs, httpClient, logger)
 	})
 
 	Describe("GetRegistry", func() {
@@ -46,7 +49,7 @@ var _ = Describe("RegistryProvider", func() {
 				It("returns an http registry that does not use server name as id", func() {
 					registry, err := registryProvider.GetRegistry()
 					Expect(err).ToNot(HaveOccurred())
-					Expect(registry).To(Equal(NewHTTPRegistry(metadataService, platform, false)))
+					Expect(registry).To(Equal(NewHTTPRegistry(metadataService, platform, false, httpClient)))
 				})
 			})
 
@@ -56,7 +59,7 @@ var _ = Describe("RegistryProvider", func() {
 				It("returns an http registry that uses server name as id", func() {
 					registry, err := registryProvider.GetRegistry()
 					Expect(err).ToNot(HaveOccurred())
-					Expect(registry).To(Equal(NewHTTPRegistry(metadataService, platform, true)))
+					Expect(registry).To(Equal(NewHTTPRegistry(metadataService, platform, true, httpClient)))
 				})
 			})
 		})
@@ -2,13 +2,25 @@ package infrastructure
 
 import (
 	"encoding/json"
+	"net"
+	"net/http"
+	"time"
 
 	mapstruc "github.com/mitchellh/mapstructure"
 
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshhttp "github.com/cloudfoundry/bosh-agent/http"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	boshplat "github.com/cloudfoundry/bosh-agent/platform"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
+)
+
+const (
+	// NativeDNSResolverTimeout applies to each query sent to a DNS server
+	NativeDNSResolverTimeout  = 2 * time.Second
+	NativeDNSResolverAttempts = 2
 )
 
 type Options struct {
@@ -61,21 +73,30 @@ type CDROMSourceOptions struct {
 
 func (o CDROMSourceOptions) sourceOptionsInterface() {}
 
+var defaultRetryOptions = boshretry.Options{
+	MaxAttempts:  3,
+	InitialDelay: 1000,
+	Multiplier:   2,
+}
+
 type SettingsSourceFactory struct {
-	options  SettingsOptions
-	platform boshplat.Platform
-	logger   boshlog.Logger
+	options      SettingsOptions
+	retryOptions boshretry.Options
+	platform     boshplat.Platform
+	logger       boshlog.Logger
 }
 
 func NewSettingsSourceFactory(
 	options SettingsOptions,
+	retryOptions boshretry.Options,
 	platform boshplat.Platform,
 	logger boshlog.Logger,
 ) SettingsSourceFactory {
 	return SettingsSourceFactory{
-		options:  options,
-		platform: platform,
-		logger:   logger,
+		options:      options,
+		retryOptions: retryOptions.WithDefaults(defaultRetryOptions),
+		platform:     platform,
+		logger:       logger,
 	}
 }
 
@@ -90,15 +111,24 @@ func (f SettingsSourceFactory) New() (boshsettings.Source, error) {
 func (f SettingsSourceFactory) buildWithRegistry() (boshsettings.Source, error) {
 	var metadataServices []MetadataService
 
+	// dig is kept as a fallback for DNS servers the native resolver cannot talk to
+	nativeDNSResolver := NewNativeDNSResolver(&net.Dialer{Timeout: NativeDNSResolverTimeout}, NativeDNSResolverTimeout, NativeDNSResolverAttempts, f.logger)
 	digDNSResolver := NewDigDNSResolver(f.platform.GetRunner(), f.logger)
-	resolver := NewRegistryEndpointResolver(digDNSResolver)
+	resolver := NewRegistryEndpointResolver(NewMultiDNSResolver(nativeDNSResolver, digDNSResolver))
+
+	httpClient := boshhttp.NewBackoffRetryClient(
+		http.DefaultClient,
+		f.retryOptions,
+		boshtime.NewConcreteService(),
+		f.logger,
+	)
 
 	for _, opts := range f.options.Sources {
 		var metadataService MetadataService
 
 		switch typedOpts := opts.(type) {
 		case HTTPSourceOptions:
-			metadataService = NewHTTPMetadataService(typedOpts.URI, resolver)
+			metadataService = NewHTTPMetadataService(typedOpts.URI, resolver, httpClient)
 
 		case ConfigDriveSourceOptions:
 			metadataService = NewConfigDriveMetadataService(
@@ -127,7 +157,7 @@ func (f SettingsSourceFactory) buildWithRegistry() (boshsettings.Source, error)
 	}
 
 	metadataService := NewMultiSourceMetadataService(metadataServices...)
-	registryProvider := NewRegistryProvider(metadataService, f.platform, f.options.UseServerName, f.platform.GetFs(), f.logger)
+	registryProvider := NewRegistryProvider(metadataService, f.platform, f.options.UseServerName, f.platform.GetFs(), httpClient, f.logger)
 	settingsSource := NewComplexSettingsSource(metadataService, registryProvider, f.logger)
 
 	return settingsSource, nil
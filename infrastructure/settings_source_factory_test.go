@@ -1,31 +1,47 @@
 package infrastructure_test
 
 import (
+	"net"
+	"net/http"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshhttp "github.com/cloudfoundry/bosh-agent/http"
 	. "github.com/cloudfoundry/bosh-agent/infrastructure"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	fakeplat "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
 )
 
 var _ = Describe("SettingsSourceFactory", func() {
 	Describe("New", func() {
 		var (
-			options  SettingsOptions
-			platform *fakeplat.FakePlatform
-			logger   boshlog.Logger
-			factory  SettingsSourceFactory
+			options      SettingsOptions
+			retryOptions boshretry.Options
+			platform     *fakeplat.FakePlatform
+			logger       boshlog.Logger
+			httpClient   boshhttp.Client
+			factory      SettingsSourceFactory
 		)
 
 		BeforeEach(func() {
 			options = SettingsOptions{}
+			retryOptions = boshretry.Options{MaxAttempts: 5}
 			platform = fakeplat.NewFakePlatform()
 			logger = boshlog.NewLogger(boshlog.LevelNone)
+
+			httpClient = boshhttp.NewBackoffRetryClient(
+				http.DefaultClient,
+				boshretry.Options{MaxAttempts: 5, InitialDelay: 1000, Multiplier: 2},
+				boshtime.NewConcreteService(),
+				logger,
+			)
 		})
 
 		JustBeforeEach(func() {
-			factory = NewSettingsSourceFactory(options, platform, logger)
+			factory = NewSettingsSourceFactory(options, retryOptions, platform, logger)
 		})
 
 		Context("when UseRegistry is set to true", func() {
@@ -42,10 +58,13 @@ var _ = Describe("SettingsSourceFactory", func() {
 					})
 
 					It("returns a settings source that uses HTTP to fetch settings", func() {
-						resolver := NewRegistryEndpointResolver(NewDigDNSResolver(platform.GetRunner(), logger))
-						httpMetadataService := NewHTTPMetadataService("http://fake-url", resolver)
+						resolver := NewRegistryEndpointResolver(NewMultiDNSResolver(
+							NewNativeDNSResolver(&net.Dialer{Timeout: NativeDNSResolverTimeout}, NativeDNSResolverTimeout, NativeDNSResolverAttempts, logger),
+							NewDigDNSResolver(platform.GetRunner(), logger),
+						))
+						httpMetadataService := NewHTTPMetadataService("http://fake-url", resolver, httpClient)
 						multiSourceMetadataService := NewMultiSourceMetadataService(httpMetadataService)
-						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), logger)
+						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), httpClient, logger)
 						httpSettingsSource := NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger)
 
 						settingsSource, err := factory.New()
@@ -69,7 +88,10 @@ var _ = Describe("SettingsSourceFactory", func() {
 					})
 
 					It("returns a settings source that uses config drive to fetch settings", func() {
-						resolver := NewRegistryEndpointResolver(NewDigDNSResolver(platform.GetRunner(), logger))
+						resolver := NewRegistryEndpointResolver(NewMultiDNSResolver(
+							NewNativeDNSResolver(&net.Dialer{Timeout: NativeDNSResolverTimeout}, NativeDNSResolverTimeout, NativeDNSResolverAttempts, logger),
+							NewDigDNSResolver(platform.GetRunner(), logger),
+						))
 						configDriveMetadataService := NewConfigDriveMetadataService(
 							resolver,
 							platform,
@@ -79,7 +101,7 @@ var _ = Describe("SettingsSourceFactory", func() {
 							logger,
 						)
 						multiSourceMetadataService := NewMultiSourceMetadataService(configDriveMetadataService)
-						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), logger)
+						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), httpClient, logger)
 						configDriveSettingsSource := NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger)
 
 						settingsSource, err := factory.New()
@@ -109,7 +131,7 @@ var _ = Describe("SettingsSourceFactory", func() {
 							logger,
 						)
 						multiSourceMetadataService := NewMultiSourceMetadataService(fileMetadataService)
-						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), logger)
+						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), httpClient, logger)
 						fileSettingsSource := NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger)
 
 						settingsSource, err := factory.New()
@@ -42,19 +42,13 @@ var _ = Describe("MonitRetryStrategy", func() {
 
 	Describe("Try", func() {
 		var (
-			request     *http.Request
-			response    *http.Response
 			lastError   error
-			err         error
 			unavailable *http.Response
 			notFound    *http.Response
 		)
 
 		BeforeEach(func() {
-			request = &http.Request{}
-			response = nil
 			lastError = errors.New("last-error")
-			err = nil
 			unavailable = &http.Response{StatusCode: 503}
 			notFound = &http.Response{StatusCode: 404}
 		})
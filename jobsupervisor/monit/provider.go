@@ -2,20 +2,25 @@ package monit
 
 import (
 	"net/http"
-	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshhttp "github.com/cloudfoundry/bosh-agent/http"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
 )
 
-const (
-	shortRetryStrategyAttempts = uint(20)
-	longRetryStrategyAttempts  = uint(300)
-	retryDelay                 = 1 * time.Second
-	monitHost                  = "127.0.0.1:2822"
-)
+const monitHost = "127.0.0.1:2822"
+
+var defaultRetryOptions = boshretry.Options{
+	MaxAttempts:  20,
+	InitialDelay: 1000,
+}
+
+var defaultUnavailableRetryOptions = boshretry.Options{
+	MaxAttempts: 300,
+}
 
 type ClientProvider interface {
 	Get() (Client, error)
@@ -28,21 +33,32 @@ type clientProvider struct {
 	longHTTPClient  boshhttp.Client
 }
 
-func NewProvider(platform boshplatform.Platform, logger boshlog.Logger) ClientProvider {
+// NewProvider takes unavailableRetryOptions for requests that wait for monit
+// to become available; only their MaxAttempts is used since delay is shared
+func NewProvider(
+	platform boshplatform.Platform,
+	retryOptions boshretry.Options,
+	unavailableRetryOptions boshretry.Options,
+	logger boshlog.Logger,
+) ClientProvider {
 	httpClient := http.DefaultClient
 
-	shortHTTPClient := boshhttp.NewRetryClient(
+	retryOptions = retryOptions.WithDefaults(defaultRetryOptions)
+	unavailableRetryOptions = unavailableRetryOptions.WithDefaults(defaultUnavailableRetryOptions)
+
+	shortHTTPClient := boshhttp.NewBackoffRetryClient(
 		httpClient,
-		shortRetryStrategyAttempts,
-		retryDelay,
+		retryOptions,
+		boshtime.NewConcreteService(),
 		logger,
 	)
 
+	// Monit can stay unavailable for a long time while it is starting jobs
 	longHTTPClient := NewMonitRetryClient(
 		httpClient,
-		longRetryStrategyAttempts,
-		shortRetryStrategyAttempts,
-		retryDelay,
+		maxAttempts(unavailableRetryOptions),
+		maxAttempts(retryOptions),
+		retryOptions.InitialDelayDuration(),
 		logger,
 	)
 
@@ -54,6 +70,13 @@ func NewProvider(platform boshplatform.Platform, logger boshlog.Logger) ClientPr
 	}
 }
 
+func maxAttempts(options boshretry.Options) uint {
+	if options.MaxAttempts == boshretry.Unlimited {
+		return ^uint(0)
+	}
+	return uint(options.MaxAttempts)
+}
+
 func (p clientProvider) Get() (client Client, err error) {
 	monitUser, monitPassword, err := p.platform.GetMonitCredentials()
 	if err != nil {
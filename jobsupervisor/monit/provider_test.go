@@ -10,6 +10,8 @@ import (
 	boshhttp "github.com/cloudfoundry/bosh-agent/http"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshretry "github.com/cloudfoundry/bosh-agent/retrystrategy"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
 
 	. "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
 )
@@ -22,12 +24,13 @@ var _ = Describe("clientProvider", func() {
 		platform.GetMonitCredentialsUsername = "fake-user"
 		platform.GetMonitCredentialsPassword = "fake-pass"
 
-		client, err := NewProvider(platform, logger).Get()
+		client, err := NewProvider(platform, boshretry.Options{}, boshretry.Options{}, logger).Get()
 		Expect(err).ToNot(HaveOccurred())
 
 		httpClient := http.DefaultClient
 
-		shortHTTPClient := boshhttp.NewRetryClient(httpClient, 20, 1*time.Second, logger)
+		retryOptions := boshretry.Options{MaxAttempts: 20, InitialDelay: 1000}
+		shortHTTPClient := boshhttp.NewBackoffRetryClient(httpClient, retryOptions, boshtime.NewConcreteService(), logger)
 		longHTTPClient := NewMonitRetryClient(httpClient, 300, 20, 1*time.Second, logger)
 
 		expectedClient := NewHTTPClient(
@@ -40,4 +43,41 @@ var _ = Describe("clientProvider", func() {
 		)
 		Expect(client).To(Equal(expectedClient))
 	})
+
+	It("Get with configured retry options", func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		platform := fakeplatform.NewFakePlatform()
+
+		retryOptions := boshretry.Options{MaxAttempts: 5, InitialDelay: 500, Multiplier: 2}
+
+		client, err := NewProvider(platform, retryOptions, boshretry.Options{MaxAttempts: 600}, logger).Get()
+		Expect(err).ToNot(HaveOccurred())
+
+		httpClient := http.DefaultClient
+
+		shortHTTPClient := boshhttp.NewBackoffRetryClient(httpClient, retryOptions, boshtime.NewConcreteService(), logger)
+		longHTTPClient := NewMonitRetryClient(httpClient, 600, 5, 500*time.Millisecond, logger)
+
+		expectedClient := NewHTTPClient("127.0.0.1:2822", "", "", shortHTTPClient, longHTTPClient, logger)
+		Expect(client).To(Equal(expectedClient))
+	})
+
+	It("Get with unlimited attempts while monit is unavailable", func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		platform := fakeplatform.NewFakePlatform()
+
+		unavailableRetryOptions := boshretry.Options{MaxAttempts: boshretry.Unlimited}
+
+		client, err := NewProvider(platform, boshretry.Options{}, unavailableRetryOptions, logger).Get()
+		Expect(err).ToNot(HaveOccurred())
+
+		httpClient := http.DefaultClient
+
+		retryOptions := boshretry.Options{MaxAttempts: 20, InitialDelay: 1000}
+		shortHTTPClient := boshhttp.NewBackoffRetryClient(httpClient, retryOptions, boshtime.NewConcreteService(), logger)
+		longHTTPClient := NewMonitRetryClient(httpClient, ^uint(0), 20, 1*time.Second, logger)
+
+		expectedClient := NewHTTPClient("127.0.0.1:2822", "", "", shortHTTPClient, longHTTPClient, logger)
+		Expect(client).To(Equal(expectedClient))
+	})
 })
@@ -0,0 +1,106 @@
+package disk
+
+import (
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+const (
+	PartitionerTypeSfdisk = "sfdisk"
+	PartitionerTypeParted = "parted"
+)
+
+// MBR partition tables cannot address space beyond 2TiB
+const mbrMaxDeviceSizeInBytes = uint64(2 * 1024 * 1024 * 1024 * 1024)
+
+// autoPartitioner keeps partition table type that device already uses
+// so that re-partitioning checks stay idempotent and otherwise picks
+// GPT only for devices that are too large for MBR
+type autoPartitioner struct {
+	mbrPartitioner Partitioner
+	gptPartitioner Partitioner
+	cmdRunner      boshsys.CmdRunner
+	logger         boshlog.Logger
+	logTag         string
+}
+
+func NewAutoPartitioner(
+	mbrPartitioner Partitioner,
+	gptPartitioner Partitioner,
+	cmdRunner boshsys.CmdRunner,
+	logger boshlog.Logger,
+) Partitioner {
+	return autoPartitioner{
+		mbrPartitioner: mbrPartitioner,
+		gptPartitioner: gptPartitioner,
+		cmdRunner:      cmdRunner,
+		logger:         logger,
+		logTag:         "AutoPartitioner",
+	}
+}
+
+func (p autoPartitioner) Partition(devicePath string, partitions []Partition) error {
+	partitioner, err := p.partitionerFor(devicePath)
+	if err != nil {
+		return err
+	}
+
+	return partitioner.Partition(devicePath, partitions)
+}
+
+func (p autoPartitioner) GrowPartition(devicePath string, partitionNumber int) error {
+	partitioner, err := p.partitionerFor(devicePath)
+	if err != nil {
+		return err
+	}
+
+	return partitioner.GrowPartition(devicePath, partitionNumber)
+}
+
+func (p autoPartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, error) {
+	return p.mbrPartitioner.GetDeviceSizeInBytes(devicePath)
+}
+
+func (p autoPartitioner) partitionerFor(devicePath string) (Partitioner, error) {
+	switch p.partitionTableType(devicePath) {
+	case "gpt":
+		return p.gptPartitioner, nil
+	case "msdos":
+		return p.mbrPartitioner, nil
+	}
+
+	size, err := p.mbrPartitioner.GetDeviceSizeInBytes(devicePath)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Getting size of %s", devicePath)
+	}
+
+	if size > mbrMaxDeviceSizeInBytes {
+		p.logger.Info(p.logTag, "Using GPT for %s since it is larger than 2TB", devicePath)
+		return p.gptPartitioner, nil
+	}
+
+	return p.mbrPartitioner, nil
+}
+
+// partitionTableType returns empty string when device has no partition table
+func (p autoPartitioner) partitionTableType(devicePath string) string {
+	stdout, _, _, err := p.cmdRunner.RunCommand("parted", "-m", "-s", devicePath, "print")
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(stdout, "\n")
+	if len(lines) < 2 {
+		return ""
+	}
+
+	deviceInfo := strings.Split(lines[1], ":")
+	if len(deviceInfo) < 6 {
+		return ""
+	}
+
+	return deviceInfo[5]
+}
@@ -0,0 +1,103 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakedisk "github.com/cloudfoundry/bosh-agent/platform/disk/fakes"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+)
+
+var _ = Describe("autoPartitioner", func() {
+	var (
+		runner         *fakesys.FakeCmdRunner
+		mbrPartitioner *fakedisk.FakePartitioner
+		gptPartitioner *fakedisk.FakePartitioner
+		partitioner    Partitioner
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		mbrPartitioner = fakedisk.NewFakePartitioner()
+		gptPartitioner = fakedisk.NewFakePartitioner()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+
+		partitioner = NewAutoPartitioner(mbrPartitioner, gptPartitioner, runner, logger)
+	})
+
+	partitions := []Partition{{Type: PartitionTypeLinux}}
+
+	Context("when device has no partition table", func() {
+		BeforeEach(func() {
+			runner.AddCmdResult("parted -m -s /dev/sdb print", fakesys.FakeCmdResult{
+				Stdout: "BYT;\n/dev/sdb:4398046511104B:scsi:512:512:unknown:Virtual Disk:;\n",
+				Error:  errors.New("fake-unrecognised-disk-label"),
+			})
+		})
+
+		It("uses GPT when device is larger than 2TB", func() {
+			mbrPartitioner.GetDeviceSizeInBytesSizes["/dev/sdb"] = 4 * 1024 * 1024 * 1024 * 1024
+
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gptPartitioner.PartitionDevicePath).To(Equal("/dev/sdb"))
+			Expect(gptPartitioner.PartitionPartitions).To(Equal(partitions))
+			Expect(mbrPartitioner.PartitionCalled).To(BeFalse())
+		})
+
+		It("uses MBR when device is not larger than 2TB", func() {
+			mbrPartitioner.GetDeviceSizeInBytesSizes["/dev/sdb"] = 2 * 1024 * 1024 * 1024 * 1024
+
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mbrPartitioner.PartitionDevicePath).To(Equal("/dev/sdb"))
+			Expect(gptPartitioner.PartitionCalled).To(BeFalse())
+		})
+
+		It("returns error when device size cannot be determined", func() {
+			mbrPartitioner.GetDeviceSizeInBytesErr = errors.New("fake-size-err")
+
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-size-err"))
+		})
+	})
+
+	Context("when device already has GPT partition table", func() {
+		BeforeEach(func() {
+			runner.AddCmdResult("parted -m -s /dev/sdb print", fakesys.FakeCmdResult{Stdout: devSdbPartedGptDump})
+		})
+
+		It("keeps using GPT regardless of device size", func() {
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gptPartitioner.PartitionCalled).To(BeTrue())
+			Expect(mbrPartitioner.PartitionCalled).To(BeFalse())
+		})
+
+		It("grows partition with GPT partitioner", func() {
+			err := partitioner.GrowPartition("/dev/sdb", 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gptPartitioner.GrowPartitionDevicePath).To(Equal("/dev/sdb"))
+			Expect(gptPartitioner.GrowPartitionPartitionNumber).To(Equal(2))
+		})
+	})
+
+	Context("when device already has MBR partition table", func() {
+		BeforeEach(func() {
+			runner.AddCmdResult("parted -m -s /dev/sdb print", fakesys.FakeCmdResult{Stdout: devSdbPartedMsdosDump})
+			mbrPartitioner.GetDeviceSizeInBytesSizes["/dev/sdb"] = 4 * 1024 * 1024 * 1024 * 1024
+		})
+
+		It("keeps using MBR even if device is larger than 2TB", func() {
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mbrPartitioner.PartitionCalled).To(BeTrue())
+			Expect(gptPartitioner.PartitionCalled).To(BeFalse())
+		})
+	})
+})
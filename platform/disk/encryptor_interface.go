@@ -0,0 +1,25 @@
+package disk
+
+type Encryptor interface {
+	IsEncrypted(partitionPath string) (encrypted bool, err error)
+
+	// IsOpen checks whether decrypted device for the partition is available
+	IsOpen(partitionPath string) (open bool, err error)
+
+	// Format sets up encryption on the partition with given key;
+	// it refuses to overwrite partitions that contain a filesystem
+	Format(partitionPath, key string) (err error)
+
+	// Open makes decrypted device available unless it is already open
+	Open(partitionPath, key string) (mappedPath string, err error)
+
+	// Close removes decrypted device; paths that do not
+	// refer to devices opened by the encryptor are ignored
+	Close(devicePath string) (err error)
+
+	// Resize grows decrypted device to the size of its partition;
+	// key is needed when volume key is kept in kernel keyring
+	Resize(partitionPath, key string) (err error)
+
+	MappedPath(partitionPath string) string
+}
@@ -10,6 +10,8 @@ type FakeDiskManager struct {
 	FakePartitioner           *FakePartitioner
 	FakeFormatter             *FakeFormatter
 	FakeMounter               *FakeMounter
+	FakeEncryptor             *FakeEncryptor
+	FakeRaidAssembler         *FakeRaidAssembler
 	FakeMountsSearcher        *FakeMountsSearcher
 	FakeRootDevicePartitioner *FakePartitioner
 	FakeDiskUtil              *fakedevutil.FakeDeviceUtil
@@ -21,6 +23,8 @@ func NewFakeDiskManager() *FakeDiskManager {
 		FakePartitioner:           NewFakePartitioner(),
 		FakeFormatter:             &FakeFormatter{},
 		FakeMounter:               &FakeMounter{},
+		FakeEncryptor:             &FakeEncryptor{},
+		FakeRaidAssembler:         &FakeRaidAssembler{},
 		FakeMountsSearcher:        &FakeMountsSearcher{},
 		FakeRootDevicePartitioner: NewFakePartitioner(),
 		FakeDiskUtil:              fakedevutil.NewFakeDeviceUtil(),
@@ -43,6 +47,14 @@ func (m *FakeDiskManager) GetMounter() boshdisk.Mounter {
 	return m.FakeMounter
 }
 
+func (m *FakeDiskManager) GetEncryptor() boshdisk.Encryptor {
+	return m.FakeEncryptor
+}
+
+func (m *FakeDiskManager) GetRaidAssembler() boshdisk.RaidAssembler {
+	return m.FakeRaidAssembler
+}
+
 func (m *FakeDiskManager) GetMountsSearcher() boshdisk.MountsSearcher {
 	return m.FakeMountsSearcher
 }
@@ -0,0 +1,66 @@
+package fakes
+
+type FakeEncryptor struct {
+	IsEncryptedPartitionPath string
+	IsEncryptedResult        bool
+	IsEncryptedErr           error
+
+	IsOpenPartitionPath string
+	IsOpenResult        bool
+	IsOpenErr           error
+
+	FormatPartitionPaths []string
+	FormatKeys           []string
+	FormatErr            error
+
+	OpenPartitionPaths []string
+	OpenKeys           []string
+	OpenErr            error
+
+	ClosedDevicePaths []string
+	CloseErr          error
+
+	ResizePartitionPath string
+	ResizeKey           string
+	ResizeErr           error
+}
+
+func (e *FakeEncryptor) IsEncrypted(partitionPath string) (bool, error) {
+	e.IsEncryptedPartitionPath = partitionPath
+	return e.IsEncryptedResult, e.IsEncryptedErr
+}
+
+func (e *FakeEncryptor) IsOpen(partitionPath string) (bool, error) {
+	e.IsOpenPartitionPath = partitionPath
+	return e.IsOpenResult, e.IsOpenErr
+}
+
+func (e *FakeEncryptor) Format(partitionPath, key string) error {
+	e.FormatPartitionPaths = append(e.FormatPartitionPaths, partitionPath)
+	e.FormatKeys = append(e.FormatKeys, key)
+	return e.FormatErr
+}
+
+func (e *FakeEncryptor) Open(partitionPath, key string) (string, error) {
+	e.OpenPartitionPaths = append(e.OpenPartitionPaths, partitionPath)
+	e.OpenKeys = append(e.OpenKeys, key)
+	if e.OpenErr != nil {
+		return "", e.OpenErr
+	}
+	return e.MappedPath(partitionPath), nil
+}
+
+func (e *FakeEncryptor) Close(devicePath string) error {
+	e.ClosedDevicePaths = append(e.ClosedDevicePaths, devicePath)
+	return e.CloseErr
+}
+
+func (e *FakeEncryptor) Resize(partitionPath, key string) error {
+	e.ResizePartitionPath = partitionPath
+	e.ResizeKey = key
+	return e.ResizeErr
+}
+
+func (e *FakeEncryptor) MappedPath(partitionPath string) string {
+	return partitionPath + "-mapped"
+}
@@ -8,11 +8,33 @@ type FakeFormatter struct {
 	FormatCalled         bool
 	FormatPartitionPaths []string
 	FormatFsTypes        []boshdisk.FileSystemType
+	FormatMkfsOptions    [][]string
+	FormatErr            error
+
+	GetFileSystemTypePartitionPaths []string
+	GetFileSystemTypeTypes          map[string]boshdisk.FileSystemType
+	GetFileSystemTypeErr            error
+
+	GrowFilesystemPartitionPath string
+	GrowFilesystemFsType        boshdisk.FileSystemType
+	GrowFilesystemErr           error
 }
 
-func (p *FakeFormatter) Format(partitionPath string, fsType boshdisk.FileSystemType) (err error) {
+func (p *FakeFormatter) Format(partitionPath string, fsType boshdisk.FileSystemType, mkfsOptions ...string) (err error) {
 	p.FormatCalled = true
 	p.FormatPartitionPaths = append(p.FormatPartitionPaths, partitionPath)
 	p.FormatFsTypes = append(p.FormatFsTypes, fsType)
-	return
+	p.FormatMkfsOptions = append(p.FormatMkfsOptions, mkfsOptions)
+	return p.FormatErr
+}
+
+func (p *FakeFormatter) GetFileSystemType(partitionPath string) (boshdisk.FileSystemType, error) {
+	p.GetFileSystemTypePartitionPaths = append(p.GetFileSystemTypePartitionPaths, partitionPath)
+	return p.GetFileSystemTypeTypes[partitionPath], p.GetFileSystemTypeErr
+}
+
+func (p *FakeFormatter) GrowFilesystem(partitionPath string, fsType boshdisk.FileSystemType) error {
+	p.GrowFilesystemPartitionPath = partitionPath
+	p.GrowFilesystemFsType = fsType
+	return p.GrowFilesystemErr
 }
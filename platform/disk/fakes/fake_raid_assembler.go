@@ -0,0 +1,13 @@
+package fakes
+
+type FakeRaidAssembler struct {
+	AssembleRaid0ArrayPath   string
+	AssembleRaid0DevicePaths []string
+	AssembleRaid0Err         error
+}
+
+func (a *FakeRaidAssembler) AssembleRaid0(arrayPath string, devicePaths []string) error {
+	a.AssembleRaid0ArrayPath = arrayPath
+	a.AssembleRaid0DevicePaths = devicePaths
+	return a.AssembleRaid0Err
+}
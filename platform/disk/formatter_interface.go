@@ -5,8 +5,16 @@ type FileSystemType string
 const (
 	FileSystemSwap FileSystemType = "swap"
 	FileSystemExt4 FileSystemType = "ext4"
+	FileSystemXFS  FileSystemType = "xfs"
 )
 
 type Formatter interface {
-	Format(partitionPath string, fsType FileSystemType) (err error)
+	// Format creates filesystem unless partition already has one of the given type
+	Format(partitionPath string, fsType FileSystemType, mkfsOptions ...string) (err error)
+
+	// GetFileSystemType returns empty type if partition does not contain a filesystem
+	GetFileSystemType(partitionPath string) (fsType FileSystemType, err error)
+
+	// GrowFilesystem extends filesystem to the size of its partition while it is mounted
+	GrowFilesystem(partitionPath string, fsType FileSystemType) (err error)
 }
@@ -13,6 +13,8 @@ type linuxDiskManager struct {
 	rootDevicePartitioner Partitioner
 	formatter             Formatter
 	mounter               Mounter
+	encryptor             Encryptor
+	raidAssembler         RaidAssembler
 	mountsSearcher        MountsSearcher
 	fs                    boshsys.FileSystem
 	logger                boshlog.Logger
@@ -23,8 +25,10 @@ func NewLinuxDiskManager(
 	runner boshsys.CmdRunner,
 	fs boshsys.FileSystem,
 	bindMount bool,
+	partitionerType string,
 ) (manager Manager) {
 	var mounter Mounter
+	var partitioner Partitioner
 	var mountsSearcher MountsSearcher
 
 	// By default we want to use most reliable source of
@@ -39,17 +43,36 @@ func NewLinuxDiskManager(
 		mountsSearcher = NewCmdMountsSearcher(runner)
 	}
 
+	encryptor := NewLuksEncryptor(runner, logger)
+
 	mounter = NewLinuxMounter(runner, mountsSearcher, 1*time.Second)
+	mounter = NewLinuxEncryptedMounter(mounter, encryptor, mountsSearcher)
 
 	if bindMount {
 		mounter = NewLinuxBindMounter(mounter)
 	}
 
+	switch partitionerType {
+	case PartitionerTypeSfdisk:
+		partitioner = NewSfdiskPartitioner(logger, runner)
+	case PartitionerTypeParted:
+		partitioner = NewPartedPartitioner(logger, runner)
+	default:
+		partitioner = NewAutoPartitioner(
+			NewSfdiskPartitioner(logger, runner),
+			NewPartedPartitioner(logger, runner),
+			runner,
+			logger,
+		)
+	}
+
 	return linuxDiskManager{
-		partitioner:           NewSfdiskPartitioner(logger, runner),
+		partitioner:           partitioner,
 		rootDevicePartitioner: NewRootDevicePartitioner(logger, runner, uint64(20*1024*1024)),
 		formatter:             NewLinuxFormatter(runner, fs),
 		mounter:               mounter,
+		encryptor:             encryptor,
+		raidAssembler:         NewMdadmRaidAssembler(runner, fs, logger),
 		mountsSearcher:        mountsSearcher,
 		fs:                    fs,
 		logger:                logger,
@@ -64,6 +87,8 @@ func (m linuxDiskManager) GetRootDevicePartitioner() Partitioner {
 
 func (m linuxDiskManager) GetFormatter() Formatter           { return m.formatter }
 func (m linuxDiskManager) GetMounter() Mounter               { return m.mounter }
+func (m linuxDiskManager) GetEncryptor() Encryptor           { return m.encryptor }
+func (m linuxDiskManager) GetRaidAssembler() RaidAssembler   { return m.raidAssembler }
 func (m linuxDiskManager) GetMountsSearcher() MountsSearcher { return m.mountsSearcher }
 
 func (m linuxDiskManager) GetDiskUtil(diskPath string) boshdevutil.DeviceUtil {
@@ -27,9 +27,13 @@ var _ = Describe("NewLinuxDiskManager", func() {
 	Context("when bindMount is set to false", func() {
 		It("returns disk manager configured not to do bind mounting", func() {
 			expectedMountsSearcher := NewProcMountsSearcher(fs)
-			expectedMounter := NewLinuxMounter(runner, expectedMountsSearcher, 1*time.Second)
+			expectedMounter := NewLinuxEncryptedMounter(
+				NewLinuxMounter(runner, expectedMountsSearcher, 1*time.Second),
+				NewLuksEncryptor(runner, logger),
+				expectedMountsSearcher,
+			)
 
-			diskManager := NewLinuxDiskManager(logger, runner, fs, false)
+			diskManager := NewLinuxDiskManager(logger, runner, fs, false, "")
 			Expect(diskManager.GetMounter()).To(Equal(expectedMounter))
 		})
 	})
@@ -37,10 +41,52 @@ var _ = Describe("NewLinuxDiskManager", func() {
 	Context("when bindMount is set to true", func() {
 		It("returns disk manager configured to do bind mounting", func() {
 			expectedMountsSearcher := NewCmdMountsSearcher(runner)
-			expectedMounter := NewLinuxBindMounter(NewLinuxMounter(runner, expectedMountsSearcher, 1*time.Second))
+			expectedMounter := NewLinuxBindMounter(NewLinuxEncryptedMounter(
+				NewLinuxMounter(runner, expectedMountsSearcher, 1*time.Second),
+				NewLuksEncryptor(runner, logger),
+				expectedMountsSearcher,
+			))
 
-			diskManager := NewLinuxDiskManager(logger, runner, fs, true)
+			diskManager := NewLinuxDiskManager(logger, runner, fs, true, "")
 			Expect(diskManager.GetMounter()).To(Equal(expectedMounter))
 		})
 	})
+
+	Context("when partitionerType is not set", func() {
+		It("returns disk manager that picks partitioner based on device", func() {
+			expectedPartitioner := NewAutoPartitioner(
+				NewSfdiskPartitioner(logger, runner),
+				NewPartedPartitioner(logger, runner),
+				runner,
+				logger,
+			)
+
+			diskManager := NewLinuxDiskManager(logger, runner, fs, false, "")
+			Expect(diskManager.GetPartitioner()).To(Equal(expectedPartitioner))
+		})
+	})
+
+	Context("when partitionerType is set to sfdisk", func() {
+		It("returns disk manager configured to create MBR partition tables", func() {
+			diskManager := NewLinuxDiskManager(logger, runner, fs, false, PartitionerTypeSfdisk)
+			Expect(diskManager.GetPartitioner()).To(Equal(NewSfdiskPartitioner(logger, runner)))
+		})
+	})
+
+	Context("when partitionerType is set to parted", func() {
+		It("returns disk manager configured to create GPT partition tables", func() {
+			diskManager := NewLinuxDiskManager(logger, runner, fs, false, PartitionerTypeParted)
+			Expect(diskManager.GetPartitioner()).To(Equal(NewPartedPartitioner(logger, runner)))
+		})
+	})
+
+	It("returns disk manager configured to encrypt partitions with LUKS", func() {
+		diskManager := NewLinuxDiskManager(logger, runner, fs, false, "")
+		Expect(diskManager.GetEncryptor()).To(Equal(NewLuksEncryptor(runner, logger)))
+	})
+
+	It("returns disk manager configured to assemble RAID arrays with mdadm", func() {
+		diskManager := NewLinuxDiskManager(logger, runner, fs, false, "")
+		Expect(diskManager.GetRaidAssembler()).To(Equal(NewMdadmRaidAssembler(runner, fs, logger)))
+	})
 })
@@ -0,0 +1,68 @@
+package disk
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+)
+
+// linuxEncryptedMounter closes decrypted devices once they are no longer mounted
+// so that unmounting and migrating encrypted disks does not leave them open
+type linuxEncryptedMounter struct {
+	delegateMounter Mounter
+	encryptor       Encryptor
+	mountsSearcher  MountsSearcher
+}
+
+func NewLinuxEncryptedMounter(delegateMounter Mounter, encryptor Encryptor, mountsSearcher MountsSearcher) Mounter {
+	return linuxEncryptedMounter{
+		delegateMounter: delegateMounter,
+		encryptor:       encryptor,
+		mountsSearcher:  mountsSearcher,
+	}
+}
+
+func (m linuxEncryptedMounter) Mount(partitionPath, mountPoint string, mountOptions ...string) error {
+	return m.delegateMounter.Mount(partitionPath, mountPoint, mountOptions...)
+}
+
+func (m linuxEncryptedMounter) RemountAsReadonly(mountPoint string) error {
+	return m.delegateMounter.RemountAsReadonly(mountPoint)
+}
+
+func (m linuxEncryptedMounter) Remount(fromMountPoint, toMountPoint string, mountOptions ...string) error {
+	return m.delegateMounter.Remount(fromMountPoint, toMountPoint, mountOptions...)
+}
+
+func (m linuxEncryptedMounter) SwapOn(partitionPath string) error {
+	return m.delegateMounter.SwapOn(partitionPath)
+}
+
+func (m linuxEncryptedMounter) Unmount(partitionOrMountPoint string) (bool, error) {
+	mounts, err := m.mountsSearcher.SearchMounts()
+	if err != nil {
+		return false, bosherr.WrapError(err, "Searching mounts")
+	}
+
+	didUnmount, err := m.delegateMounter.Unmount(partitionOrMountPoint)
+	if err != nil || !didUnmount {
+		return didUnmount, err
+	}
+
+	for _, mount := range mounts {
+		if mount.PartitionPath == partitionOrMountPoint || mount.MountPoint == partitionOrMountPoint {
+			err = m.encryptor.Close(mount.PartitionPath)
+			if err != nil {
+				return true, bosherr.WrapErrorf(err, "Closing encrypted device %s", mount.PartitionPath)
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func (m linuxEncryptedMounter) IsMountPoint(path string) (bool, error) {
+	return m.delegateMounter.IsMountPoint(path)
+}
+
+func (m linuxEncryptedMounter) IsMounted(partitionOrMountPoint string) (bool, error) {
+	return m.delegateMounter.IsMounted(partitionOrMountPoint)
+}
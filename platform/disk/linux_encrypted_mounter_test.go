@@ -0,0 +1,112 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakedisk "github.com/cloudfoundry/bosh-agent/platform/disk/fakes"
+)
+
+var _ = Describe("linuxEncryptedMounter", func() {
+	var (
+		delegateMounter *fakedisk.FakeMounter
+		encryptor       *fakedisk.FakeEncryptor
+		mountsSearcher  *fakedisk.FakeMountsSearcher
+		mounter         Mounter
+	)
+
+	BeforeEach(func() {
+		delegateMounter = &fakedisk.FakeMounter{}
+		encryptor = &fakedisk.FakeEncryptor{}
+		mountsSearcher = &fakedisk.FakeMountsSearcher{}
+		mounter = NewLinuxEncryptedMounter(delegateMounter, encryptor, mountsSearcher)
+
+		mountsSearcher.SearchMountsMounts = []Mount{
+			{PartitionPath: "/dev/mapper/fake-crypt", MountPoint: "/fake-mount-point"},
+			{PartitionPath: "/dev/sda1", MountPoint: "/"},
+		}
+	})
+
+	Describe("Mount", func() {
+		It("delegates to mounter", func() {
+			err := mounter.Mount("fake-partition-path", "fake-mount-path", "fake-opt1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(delegateMounter.MountPartitionPaths).To(Equal([]string{"fake-partition-path"}))
+			Expect(delegateMounter.MountMountPoints).To(Equal([]string{"fake-mount-path"}))
+			Expect(delegateMounter.MountMountOptions).To(Equal([][]string{{"fake-opt1"}}))
+		})
+	})
+
+	Describe("Remount", func() {
+		It("delegates to mounter without closing device that stays mounted", func() {
+			err := mounter.Remount("/fake-mount-point", "fake-to-mount-point")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(delegateMounter.RemountFromMountPoint).To(Equal("/fake-mount-point"))
+			Expect(delegateMounter.RemountToMountPoint).To(Equal("fake-to-mount-point"))
+			Expect(encryptor.ClosedDevicePaths).To(BeEmpty())
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("closes device that was mounted at given mount point", func() {
+			delegateMounter.UnmountDidUnmount = true
+
+			didUnmount, err := mounter.Unmount("/fake-mount-point")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(didUnmount).To(BeTrue())
+
+			Expect(delegateMounter.UnmountPartitionPathOrMountPoint).To(Equal("/fake-mount-point"))
+			Expect(encryptor.ClosedDevicePaths).To(Equal([]string{"/dev/mapper/fake-crypt"}))
+		})
+
+		It("closes device when unmounting it by device path", func() {
+			delegateMounter.UnmountDidUnmount = true
+
+			_, err := mounter.Unmount("/dev/mapper/fake-crypt")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encryptor.ClosedDevicePaths).To(Equal([]string{"/dev/mapper/fake-crypt"}))
+		})
+
+		It("does not close device if it was not unmounted", func() {
+			delegateMounter.UnmountDidUnmount = false
+
+			didUnmount, err := mounter.Unmount("/fake-mount-point")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(didUnmount).To(BeFalse())
+			Expect(encryptor.ClosedDevicePaths).To(BeEmpty())
+		})
+
+		It("returns error if unmounting fails", func() {
+			delegateMounter.UnmountErr = errors.New("fake-unmount-err")
+
+			_, err := mounter.Unmount("/fake-mount-point")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-unmount-err"))
+			Expect(encryptor.ClosedDevicePaths).To(BeEmpty())
+		})
+
+		It("returns error if closing device fails", func() {
+			delegateMounter.UnmountDidUnmount = true
+			encryptor.CloseErr = errors.New("fake-close-err")
+
+			didUnmount, err := mounter.Unmount("/fake-mount-point")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-close-err"))
+			Expect(didUnmount).To(BeTrue())
+		})
+
+		It("returns error if searching mounts fails", func() {
+			mountsSearcher.SearchMountsErr = errors.New("fake-search-mounts-err")
+
+			_, err := mounter.Unmount("/fake-mount-point")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-search-mounts-err"))
+			Expect(delegateMounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+		})
+	})
+})
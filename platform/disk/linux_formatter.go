@@ -1,12 +1,16 @@
 package disk
 
 import (
-	"fmt"
+	"regexp"
+	"strings"
+
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshsys "github.com/cloudfoundry/bosh-agent/system"
-	"strings"
 )
 
+// Leading space avoids matching PTTYPE and SEC_TYPE
+var blkidTypeRegexp = regexp.MustCompile(` TYPE="([^"]*)"`)
+
 type linuxFormatter struct {
 	runner boshsys.CmdRunner
 	fs     boshsys.FileSystem
@@ -19,36 +23,92 @@ func NewLinuxFormatter(runner boshsys.CmdRunner, fs boshsys.FileSystem) Formatte
 	}
 }
 
-func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err error) {
-	if f.partitionHasGivenType(partitionPath, fsType) {
-		return
+func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType, mkfsOptions ...string) (err error) {
+	existingFsType, err := f.GetFileSystemType(partitionPath)
+	if err == nil && existingFsType == fsType {
+		return nil
 	}
 
 	switch fsType {
 	case FileSystemSwap:
-		_, _, _, err = f.runner.RunCommand("mkswap", partitionPath)
+		_, _, _, err = f.runner.RunCommand("mkswap", append(mkfsOptions, partitionPath)...)
 		if err != nil {
 			err = bosherr.WrapError(err, "Shelling out to mkswap")
 		}
 
 	case FileSystemExt4:
+		args := []string{"-t", "ext4", "-j"}
 		if f.fs.FileExists("/sys/fs/ext4/features/lazy_itable_init") {
-			_, _, _, err = f.runner.RunCommand("mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1", partitionPath)
-		} else {
-			_, _, _, err = f.runner.RunCommand("mke2fs", "-t", "ext4", "-j", partitionPath)
+			args = append(args, "-E", "lazy_itable_init=1")
 		}
+		args = append(args, mkfsOptions...)
+
+		_, _, _, err = f.runner.RunCommand("mke2fs", append(args, partitionPath)...)
 		if err != nil {
 			err = bosherr.WrapError(err, "Shelling out to mke2fs")
 		}
+
+	case FileSystemXFS:
+		// Force is needed to overwrite existing signatures similarly to mke2fs
+		args := append([]string{"-f"}, mkfsOptions...)
+
+		_, _, _, err = f.runner.RunCommand("mkfs.xfs", append(args, partitionPath)...)
+		if err != nil {
+			err = bosherr.WrapError(err, "Shelling out to mkfs.xfs")
+		}
+
+	default:
+		err = bosherr.Errorf("Formatting %s filesystem is not supported", fsType)
 	}
 	return
 }
 
-func (f linuxFormatter) partitionHasGivenType(partitionPath string, fsType FileSystemType) bool {
-	stdout, _, _, err := f.runner.RunCommand("blkid", "-p", partitionPath)
+func (f linuxFormatter) GetFileSystemType(partitionPath string) (FileSystemType, error) {
+	stdout, _, exitStatus, err := f.runner.RunCommand("blkid", "-p", partitionPath)
 	if err != nil {
-		return false
+		// Exit status 2 means that no signatures were found
+		if exitStatus == 2 {
+			return "", nil
+		}
+		return "", bosherr.WrapError(err, "Shelling out to blkid")
+	}
+
+	matches := blkidTypeRegexp.FindStringSubmatch(stdout)
+	if len(matches) != 2 {
+		return "", nil
+	}
+
+	return FileSystemType(matches[1]), nil
+}
+
+func (f linuxFormatter) GrowFilesystem(partitionPath string, fsType FileSystemType) error {
+	switch fsType {
+	case FileSystemExt4:
+		_, _, _, err := f.runner.RunCommand("resize2fs", partitionPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Shelling out to resize2fs")
+		}
+
+	case FileSystemXFS:
+		// xfs_growfs only operates on mount points
+		stdout, _, _, err := f.runner.RunCommand("findmnt", "-n", "-o", "TARGET", "--source", partitionPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Finding mount point of %s", partitionPath)
+		}
+
+		mountPoint := strings.TrimSpace(strings.Split(stdout, "\n")[0])
+		if mountPoint == "" {
+			return bosherr.Errorf("Partition %s must be mounted to grow xfs filesystem", partitionPath)
+		}
+
+		_, _, _, err = f.runner.RunCommand("xfs_growfs", mountPoint)
+		if err != nil {
+			return bosherr.WrapError(err, "Shelling out to xfs_growfs")
+		}
+
+	default:
+		return bosherr.Errorf("Growing %s filesystem is not supported", fsType)
 	}
 
-	return strings.Contains(stdout, fmt.Sprintf(` TYPE="%s"`, fsType))
+	return nil
 }
@@ -1,6 +1,8 @@
 package disk_test
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -71,5 +73,120 @@ func init() {
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
 		})
+		It("linux format when using ext4 fs with mkfs options", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemExt4, "-m", "1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-m", "1", "/dev/xvda2"}))
+		})
+		It("linux format when using xfs fs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext4" yyyy zzzz`})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemXFS, "-m", "crc=1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkfs.xfs", "-f", "-m", "crc=1", "/dev/xvda2"}))
+		})
+		It("linux format when using xfs fs and partition is xfs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="xfs" yyyy zzzz`})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemXFS)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
+		})
+		It("linux format when using unsupported fs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemType("btrfs"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Formatting btrfs filesystem is not supported"))
+		})
+		It("linux get filesystem type", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{
+				Stdout: `/dev/xvda1: UUID="fake-uuid" VERSION="1.0" TYPE="xfs" USAGE="filesystem" PART_ENTRY_TYPE="0x83"`,
+			})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			fsType, err := formatter.GetFileSystemType("/dev/xvda1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fsType).To(Equal(FileSystemXFS))
+		})
+		It("linux get filesystem type when partition has no filesystem", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("fake-exit-2")})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			fsType, err := formatter.GetFileSystemType("/dev/xvda1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fsType).To(BeEmpty())
+		})
+		It("linux get filesystem type when blkid fails", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{ExitStatus: 4, Error: errors.New("fake-blkid-err")})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			_, err := formatter.GetFileSystemType("/dev/xvda1")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-blkid-err"))
+		})
+		It("linux grow filesystem when using ext4 fs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.GrowFilesystem("/dev/xvda1", FileSystemExt4)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands).To(Equal([][]string{{"resize2fs", "/dev/xvda1"}}))
+		})
+		It("linux grow filesystem when using xfs fs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("findmnt -n -o TARGET --source /dev/xvda1", fakesys.FakeCmdResult{Stdout: "/var/vcap/store\n"})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.GrowFilesystem("/dev/xvda1", FileSystemXFS)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"xfs_growfs", "/var/vcap/store"}))
+		})
+		It("linux grow filesystem when using xfs fs that is not mounted", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.GrowFilesystem("/dev/xvda1", FileSystemXFS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must be mounted"))
+		})
+		It("linux grow filesystem when using swap fs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.GrowFilesystem("/dev/xvda1", FileSystemSwap)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Growing swap filesystem is not supported"))
+
+			Expect(fakeRunner.RunCommands).To(BeEmpty())
+		})
 	})
 }
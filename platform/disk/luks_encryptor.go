@@ -0,0 +1,136 @@
+package disk
+
+import (
+	"path/filepath"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+// Prefix makes it possible to distinguish devices opened by the agent
+// from other device mapper devices (e.g. LVM volumes)
+const luksMapperPathPrefix = "/dev/mapper/bosh-crypt-"
+
+type luksEncryptor struct {
+	runner boshsys.CmdRunner
+	logTag string
+	logger boshlog.Logger
+}
+
+func NewLuksEncryptor(runner boshsys.CmdRunner, logger boshlog.Logger) Encryptor {
+	return luksEncryptor{
+		runner: runner,
+		logTag: "luksEncryptor",
+		logger: logger,
+	}
+}
+
+func (e luksEncryptor) IsEncrypted(partitionPath string) (bool, error) {
+	_, _, exitStatus, err := e.runner.RunCommand("cryptsetup", "isLuks", partitionPath)
+	return e.checkResult(exitStatus, err, "Shelling out to cryptsetup isLuks")
+}
+
+func (e luksEncryptor) IsOpen(partitionPath string) (bool, error) {
+	return e.isActive(e.mapperName(partitionPath))
+}
+
+func (e luksEncryptor) Format(partitionPath, key string) error {
+	// Exit status 2 means that no signatures were found
+	stdout, _, exitStatus, err := e.runner.RunCommand("blkid", "-p", "-s", "TYPE", "-o", "value", partitionPath)
+	if err != nil && exitStatus != 2 {
+		return bosherr.WrapError(err, "Shelling out to blkid")
+	}
+
+	fsType := strings.TrimSpace(stdout)
+	if fsType != "" && fsType != "crypto_LUKS" {
+		return bosherr.Errorf("Partition %s contains %s filesystem, refusing to encrypt it", partitionPath, fsType)
+	}
+
+	e.logger.Info(e.logTag, "Setting up encryption on %s", partitionPath)
+
+	_, _, _, err = e.runner.RunCommandWithInput(key, "cryptsetup", "luksFormat", "--batch-mode", "--key-file=-", partitionPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to cryptsetup luksFormat")
+	}
+
+	return nil
+}
+
+func (e luksEncryptor) Open(partitionPath, key string) (string, error) {
+	name := e.mapperName(partitionPath)
+
+	open, err := e.isActive(name)
+	if err != nil {
+		return "", err
+	}
+
+	if !open {
+		e.logger.Info(e.logTag, "Opening encrypted partition %s as %s", partitionPath, name)
+
+		_, _, _, err = e.runner.RunCommandWithInput(key, "cryptsetup", "luksOpen", "--key-file=-", partitionPath, name)
+		if err != nil {
+			return "", bosherr.WrapError(err, "Shelling out to cryptsetup luksOpen")
+		}
+	}
+
+	return e.MappedPath(partitionPath), nil
+}
+
+func (e luksEncryptor) Close(devicePath string) error {
+	if !strings.HasPrefix(devicePath, luksMapperPathPrefix) {
+		return nil
+	}
+
+	name := filepath.Base(devicePath)
+
+	open, err := e.isActive(name)
+	if err != nil || !open {
+		return err
+	}
+
+	e.logger.Info(e.logTag, "Closing encrypted device %s", name)
+
+	_, _, _, err = e.runner.RunCommand("cryptsetup", "luksClose", name)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to cryptsetup luksClose")
+	}
+
+	return nil
+}
+
+func (e luksEncryptor) Resize(partitionPath, key string) error {
+	_, _, _, err := e.runner.RunCommandWithInput(key, "cryptsetup", "resize", "--key-file=-", e.mapperName(partitionPath))
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to cryptsetup resize")
+	}
+
+	return nil
+}
+
+func (e luksEncryptor) MappedPath(partitionPath string) string {
+	return luksMapperPathPrefix + filepath.Base(partitionPath)
+}
+
+func (e luksEncryptor) mapperName(partitionPath string) string {
+	return filepath.Base(e.MappedPath(partitionPath))
+}
+
+func (e luksEncryptor) isActive(name string) (bool, error) {
+	_, _, exitStatus, err := e.runner.RunCommand("cryptsetup", "status", name)
+	return e.checkResult(exitStatus, err, "Shelling out to cryptsetup status")
+}
+
+// checkResult interprets non-zero exit status of cryptsetup query commands as negative answer
+func (e luksEncryptor) checkResult(exitStatus int, err error, msg string) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+
+	if exitStatus > 0 {
+		return false, nil
+	}
+
+	return false, bosherr.WrapError(err, msg)
+}
@@ -0,0 +1,165 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+)
+
+var _ = Describe("luksEncryptor", func() {
+	var (
+		runner    *fakesys.FakeCmdRunner
+		encryptor Encryptor
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		encryptor = NewLuksEncryptor(runner, logger)
+	})
+
+	notFound := fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")}
+
+	Describe("IsEncrypted", func() {
+		It("returns true when partition has LUKS header", func() {
+			encrypted, err := encryptor.IsEncrypted("/dev/sdb1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encrypted).To(BeTrue())
+			Expect(runner.RunCommands).To(Equal([][]string{{"cryptsetup", "isLuks", "/dev/sdb1"}}))
+		})
+
+		It("returns false when cryptsetup exits with non-zero status", func() {
+			runner.AddCmdResult("cryptsetup isLuks /dev/sdb1", notFound)
+
+			encrypted, err := encryptor.IsEncrypted("/dev/sdb1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encrypted).To(BeFalse())
+		})
+
+		It("returns error when cryptsetup cannot be run", func() {
+			runner.AddCmdResult("cryptsetup isLuks /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: -1, Error: errors.New("fake-run-err")})
+
+			_, err := encryptor.IsEncrypted("/dev/sdb1")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-run-err"))
+		})
+	})
+
+	Describe("IsOpen", func() {
+		It("checks status of mapped device", func() {
+			runner.AddCmdResult("cryptsetup status bosh-crypt-sdb1", notFound)
+
+			open, err := encryptor.IsOpen("/dev/sdb1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(open).To(BeFalse())
+		})
+	})
+
+	Describe("Format", func() {
+		It("sets up LUKS on empty partition passing key via stdin", func() {
+			runner.AddCmdResult("blkid -p -s TYPE -o value /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("fake-exit-2")})
+
+			err := encryptor.Format("/dev/sdb1", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommandsWithInput).To(Equal([][]string{
+				{"fake-key", "cryptsetup", "luksFormat", "--batch-mode", "--key-file=-", "/dev/sdb1"},
+			}))
+		})
+
+		It("refuses to encrypt partition with existing filesystem", func() {
+			runner.AddCmdResult("blkid -p -s TYPE -o value /dev/sdb1", fakesys.FakeCmdResult{Stdout: "ext4\n"})
+
+			err := encryptor.Format("/dev/sdb1", "fake-key")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Partition /dev/sdb1 contains ext4 filesystem, refusing to encrypt it"))
+			Expect(runner.RunCommandsWithInput).To(BeEmpty())
+		})
+
+		It("returns error when luksFormat fails", func() {
+			runner.AddCmdResult(
+				"fake-key cryptsetup luksFormat --batch-mode --key-file=- /dev/sdb1",
+				fakesys.FakeCmdResult{Error: errors.New("fake-luks-format-err")},
+			)
+
+			err := encryptor.Format("/dev/sdb1", "fake-key")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-luks-format-err"))
+		})
+	})
+
+	Describe("Open", func() {
+		It("opens partition as mapped device", func() {
+			runner.AddCmdResult("cryptsetup status bosh-crypt-sdb1", notFound)
+
+			mappedPath, err := encryptor.Open("/dev/sdb1", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mappedPath).To(Equal("/dev/mapper/bosh-crypt-sdb1"))
+			Expect(runner.RunCommandsWithInput).To(Equal([][]string{
+				{"fake-key", "cryptsetup", "luksOpen", "--key-file=-", "/dev/sdb1", "bosh-crypt-sdb1"},
+			}))
+		})
+
+		It("does not open partition again if it is already open", func() {
+			mappedPath, err := encryptor.Open("/dev/sdb1", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mappedPath).To(Equal("/dev/mapper/bosh-crypt-sdb1"))
+			Expect(runner.RunCommandsWithInput).To(BeEmpty())
+		})
+
+		It("returns error when luksOpen fails", func() {
+			runner.AddCmdResult("cryptsetup status bosh-crypt-sdb1", notFound)
+			runner.AddCmdResult(
+				"fake-key cryptsetup luksOpen --key-file=- /dev/sdb1 bosh-crypt-sdb1",
+				fakesys.FakeCmdResult{Error: errors.New("fake-luks-open-err")},
+			)
+
+			_, err := encryptor.Open("/dev/sdb1", "fake-key")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-luks-open-err"))
+		})
+	})
+
+	Describe("Close", func() {
+		It("closes open mapped device", func() {
+			err := encryptor.Close("/dev/mapper/bosh-crypt-sdb1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"cryptsetup", "status", "bosh-crypt-sdb1"},
+				{"cryptsetup", "luksClose", "bosh-crypt-sdb1"},
+			}))
+		})
+
+		It("does nothing when mapped device is already closed", func() {
+			runner.AddCmdResult("cryptsetup status bosh-crypt-sdb1", notFound)
+
+			err := encryptor.Close("/dev/mapper/bosh-crypt-sdb1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(Equal([][]string{{"cryptsetup", "status", "bosh-crypt-sdb1"}}))
+		})
+
+		It("ignores devices that were not opened by encryptor", func() {
+			err := encryptor.Close("/dev/mapper/vg-lv")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = encryptor.Close("/dev/sdb1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+	})
+
+	Describe("Resize", func() {
+		It("resizes mapped device passing key via stdin", func() {
+			err := encryptor.Resize("/dev/sdb1", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommandsWithInput).To(Equal([][]string{
+				{"fake-key", "cryptsetup", "resize", "--key-file=-", "bosh-crypt-sdb1"},
+			}))
+		})
+	})
+})
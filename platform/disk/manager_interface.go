@@ -9,6 +9,8 @@ type Manager interface {
 	GetRootDevicePartitioner() Partitioner
 	GetFormatter() Formatter
 	GetMounter() Mounter
+	GetEncryptor() Encryptor
+	GetRaidAssembler() RaidAssembler
 	GetMountsSearcher() MountsSearcher
 	GetDiskUtil(diskPath string) boshdevutil.DeviceUtil
 }
@@ -0,0 +1,171 @@
+package disk
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+type mdadmRaidAssembler struct {
+	runner boshsys.CmdRunner
+	fs     boshsys.FileSystem
+	logTag string
+	logger boshlog.Logger
+}
+
+func NewMdadmRaidAssembler(runner boshsys.CmdRunner, fs boshsys.FileSystem, logger boshlog.Logger) RaidAssembler {
+	return mdadmRaidAssembler{
+		runner: runner,
+		fs:     fs,
+		logTag: "mdadmRaidAssembler",
+		logger: logger,
+	}
+}
+
+func (a mdadmRaidAssembler) AssembleRaid0(arrayPath string, devicePaths []string) error {
+	active, matches, err := a.arrayMatches(arrayPath, devicePaths)
+	if err != nil {
+		return err
+	}
+
+	if matches {
+		a.logger.Info(a.logTag, "RAID0 array %s already consists of %s, skipping", arrayPath, devicePaths)
+		return nil
+	}
+
+	err = a.stopConflictingArrays(arrayPath, active, devicePaths)
+	if err != nil {
+		return err
+	}
+
+	// Array created on previous boot keeps its superblocks on member devices
+	// so it can be reassembled without losing ephemeral data
+	assembleArgs := append([]string{"--assemble", arrayPath}, devicePaths...)
+
+	_, _, _, err = a.runner.RunCommand("mdadm", assembleArgs...)
+	if err == nil {
+		active, matches, err = a.arrayMatches(arrayPath, devicePaths)
+		if err != nil {
+			return err
+		}
+
+		if matches {
+			a.logger.Info(a.logTag, "Reassembled RAID0 array %s from %s", arrayPath, devicePaths)
+			return nil
+		}
+
+		err = a.stopConflictingArrays(arrayPath, active, devicePaths)
+		if err != nil {
+			return err
+		}
+	}
+
+	a.logger.Info(a.logTag, "Creating RAID0 array %s from %s", arrayPath, devicePaths)
+
+	createArgs := append([]string{
+		"--create", arrayPath,
+		"--run",
+		"--force",
+		"--level=0",
+		fmt.Sprintf("--raid-devices=%d", len(devicePaths)),
+	}, devicePaths...)
+
+	_, _, _, err = a.runner.RunCommand("mdadm", createArgs...)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating RAID0 array %s", arrayPath)
+	}
+
+	return nil
+}
+
+// arrayMatches parses mdadm --detail --export output, e.g.:
+//
+//	MD_LEVEL=raid0
+//	MD_DEVICES=2
+//	MD_DEVICE_dev_xvdb_DEV=/dev/xvdb
+//	MD_DEVICE_dev_xvdc_DEV=/dev/xvdc
+func (a mdadmRaidAssembler) arrayMatches(arrayPath string, devicePaths []string) (bool, bool, error) {
+	stdout, _, exitStatus, err := a.runner.RunCommand("mdadm", "--detail", "--export", arrayPath)
+	if err != nil {
+		if exitStatus > 0 {
+			return false, false, nil
+		}
+		return false, false, bosherr.WrapError(err, "Shelling out to mdadm --detail")
+	}
+
+	var level string
+	var memberPaths []string
+
+	for _, line := range strings.Split(stdout, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		switch {
+		case parts[0] == "MD_LEVEL":
+			level = parts[1]
+		case strings.HasPrefix(parts[0], "MD_DEVICE_") && strings.HasSuffix(parts[0], "_DEV"):
+			memberPaths = append(memberPaths, parts[1])
+		}
+	}
+
+	if level != "raid0" || len(memberPaths) != len(devicePaths) {
+		return true, false, nil
+	}
+
+	expectedPaths := append([]string{}, devicePaths...)
+	sort.Strings(expectedPaths)
+	sort.Strings(memberPaths)
+
+	for i, memberPath := range memberPaths {
+		if memberPath != expectedPaths[i] {
+			return true, false, nil
+		}
+	}
+
+	return true, true, nil
+}
+
+// stopConflictingArrays stops arrays that hold any of the devices,
+// e.g. ones auto-assembled under different name during boot
+func (a mdadmRaidAssembler) stopConflictingArrays(arrayPath string, active bool, devicePaths []string) error {
+	arrayPaths := map[string]bool{}
+
+	if active {
+		arrayPaths[arrayPath] = true
+	}
+
+	for _, devicePath := range devicePaths {
+		holders, err := a.fs.Glob(filepath.Join("/sys/class/block", filepath.Base(devicePath), "holders", "md*"))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Finding arrays holding %s", devicePath)
+		}
+
+		for _, holder := range holders {
+			arrayPaths[filepath.Join("/dev", filepath.Base(holder))] = true
+		}
+	}
+
+	var sortedArrayPaths []string
+	for path := range arrayPaths {
+		sortedArrayPaths = append(sortedArrayPaths, path)
+	}
+	sort.Strings(sortedArrayPaths)
+
+	for _, path := range sortedArrayPaths {
+		a.logger.Info(a.logTag, "Stopping RAID array %s", path)
+
+		_, _, _, err := a.runner.RunCommand("mdadm", "--stop", path)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Stopping RAID array %s", path)
+		}
+	}
+
+	return nil
+}
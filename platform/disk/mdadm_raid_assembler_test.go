@@ -0,0 +1,150 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+)
+
+var _ = Describe("mdadmRaidAssembler", func() {
+	var (
+		runner    *fakesys.FakeCmdRunner
+		fs        *fakesys.FakeFileSystem
+		assembler RaidAssembler
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		fs = fakesys.NewFakeFileSystem()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		assembler = NewMdadmRaidAssembler(runner, fs, logger)
+	})
+
+	notFound := fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")}
+
+	matchingDetail := fakesys.FakeCmdResult{
+		Stdout: `MD_LEVEL=raid0
+MD_DEVICES=2
+MD_DEVICE_dev_xvdc_DEV=/dev/xvdc
+MD_DEVICE_dev_xvdb_DEV=/dev/xvdb
+`,
+	}
+
+	createCmd := []string{"mdadm", "--create", "/dev/md0", "--run", "--force", "--level=0", "--raid-devices=2", "/dev/xvdb", "/dev/xvdc"}
+
+	It("does nothing when array already consists of the same devices", func() {
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", matchingDetail)
+
+		err := assembler.AssembleRaid0("/dev/md0", []string{"/dev/xvdb", "/dev/xvdc"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runner.RunCommands).To(Equal([][]string{
+			{"mdadm", "--detail", "--export", "/dev/md0"},
+		}))
+	})
+
+	It("reassembles array created on previous boot", func() {
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", notFound)
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", matchingDetail)
+
+		err := assembler.AssembleRaid0("/dev/md0", []string{"/dev/xvdb", "/dev/xvdc"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runner.RunCommands).To(Equal([][]string{
+			{"mdadm", "--detail", "--export", "/dev/md0"},
+			{"mdadm", "--assemble", "/dev/md0", "/dev/xvdb", "/dev/xvdc"},
+			{"mdadm", "--detail", "--export", "/dev/md0"},
+		}))
+	})
+
+	It("creates array when devices cannot be reassembled", func() {
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", notFound)
+		runner.AddCmdResult("mdadm --assemble /dev/md0 /dev/xvdb /dev/xvdc", notFound)
+
+		err := assembler.AssembleRaid0("/dev/md0", []string{"/dev/xvdb", "/dev/xvdc"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runner.RunCommands).To(Equal([][]string{
+			{"mdadm", "--detail", "--export", "/dev/md0"},
+			{"mdadm", "--assemble", "/dev/md0", "/dev/xvdb", "/dev/xvdc"},
+			createCmd,
+		}))
+	})
+
+	It("stops existing array with different devices and recreates it", func() {
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", fakesys.FakeCmdResult{
+			Stdout: "MD_LEVEL=raid0\nMD_DEVICE_dev_xvdb_DEV=/dev/xvdb\n",
+		})
+		runner.AddCmdResult("mdadm --assemble /dev/md0 /dev/xvdb /dev/xvdc", notFound)
+
+		err := assembler.AssembleRaid0("/dev/md0", []string{"/dev/xvdb", "/dev/xvdc"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runner.RunCommands).To(Equal([][]string{
+			{"mdadm", "--detail", "--export", "/dev/md0"},
+			{"mdadm", "--stop", "/dev/md0"},
+			{"mdadm", "--assemble", "/dev/md0", "/dev/xvdb", "/dev/xvdc"},
+			createCmd,
+		}))
+	})
+
+	It("stops arrays auto-assembled from the devices under different name", func() {
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", notFound)
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", matchingDetail)
+		fs.SetGlob("/sys/class/block/xvdc/holders/md*", []string{"/sys/class/block/xvdc/holders/md127"})
+
+		err := assembler.AssembleRaid0("/dev/md0", []string{"/dev/xvdb", "/dev/xvdc"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runner.RunCommands).To(Equal([][]string{
+			{"mdadm", "--detail", "--export", "/dev/md0"},
+			{"mdadm", "--stop", "/dev/md127"},
+			{"mdadm", "--assemble", "/dev/md0", "/dev/xvdb", "/dev/xvdc"},
+			{"mdadm", "--detail", "--export", "/dev/md0"},
+		}))
+	})
+
+	It("recreates array when reassembled array does not consist of the same devices", func() {
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", notFound)
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", fakesys.FakeCmdResult{
+			Stdout: "MD_LEVEL=raid1\nMD_DEVICE_dev_xvdb_DEV=/dev/xvdb\nMD_DEVICE_dev_xvdc_DEV=/dev/xvdc\n",
+		})
+
+		err := assembler.AssembleRaid0("/dev/md0", []string{"/dev/xvdb", "/dev/xvdc"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runner.RunCommands).To(Equal([][]string{
+			{"mdadm", "--detail", "--export", "/dev/md0"},
+			{"mdadm", "--assemble", "/dev/md0", "/dev/xvdb", "/dev/xvdc"},
+			{"mdadm", "--detail", "--export", "/dev/md0"},
+			{"mdadm", "--stop", "/dev/md0"},
+			createCmd,
+		}))
+	})
+
+	It("returns error when mdadm cannot be run", func() {
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", fakesys.FakeCmdResult{ExitStatus: -1, Error: errors.New("fake-run-err")})
+
+		err := assembler.AssembleRaid0("/dev/md0", []string{"/dev/xvdb", "/dev/xvdc"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-run-err"))
+	})
+
+	It("returns error when stopping conflicting array fails", func() {
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", fakesys.FakeCmdResult{Stdout: "MD_LEVEL=raid1\n"})
+		runner.AddCmdResult("mdadm --stop /dev/md0", fakesys.FakeCmdResult{Error: errors.New("fake-stop-err")})
+
+		err := assembler.AssembleRaid0("/dev/md0", []string{"/dev/xvdb", "/dev/xvdc"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-stop-err"))
+	})
+
+	It("returns error when creating array fails", func() {
+		runner.AddCmdResult("mdadm --detail --export /dev/md0", notFound)
+		runner.AddCmdResult("mdadm --assemble /dev/md0 /dev/xvdb /dev/xvdc", notFound)
+		runner.AddCmdResult("mdadm --create /dev/md0 --run --force --level=0 --raid-devices=2 /dev/xvdb /dev/xvdc", fakesys.FakeCmdResult{Error: errors.New("fake-create-err")})
+
+		err := assembler.AssembleRaid0("/dev/md0", []string{"/dev/xvdb", "/dev/xvdc"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-create-err"))
+	})
+})
@@ -0,0 +1,205 @@
+package disk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+// partedPartitioner creates GPT partition tables which,
+// unlike MBR tables, can address devices larger than 2TB
+type partedPartitioner struct {
+	logger    boshlog.Logger
+	cmdRunner boshsys.CmdRunner
+	logTag    string
+}
+
+func NewPartedPartitioner(logger boshlog.Logger, cmdRunner boshsys.CmdRunner) Partitioner {
+	return partedPartitioner{
+		logger:    logger,
+		cmdRunner: cmdRunner,
+		logTag:    "PartedPartitioner",
+	}
+}
+
+// GPT partitions are named after their type so that type can be determined
+// before filesystem is created; parted fs types set corresponding type GUIDs
+var partedPartitionFsTypes = map[PartitionType]string{
+	PartitionTypeSwap:  "linux-swap",
+	PartitionTypeLinux: "ext4",
+}
+
+func (p partedPartitioner) Partition(devicePath string, partitions []Partition) error {
+	if p.diskMatchesPartitions(devicePath, partitions) {
+		p.logger.Info(p.logTag, "%s already partitioned as expected, skipping", devicePath)
+		return nil
+	}
+
+	_, _, _, err := p.cmdRunner.RunCommand("parted", "-s", devicePath, "mklabel", "gpt")
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating GPT partition table on `%s'", devicePath)
+	}
+
+	// To support optimal reads on HDDs and optimal erasure on SSD: use 1MiB partition alignments.
+	alignmentInBytes := uint64(1048576)
+
+	partitionStart := alignmentInBytes
+
+	for index, partition := range partitions {
+		partitionEnd := "100%"
+
+		if index < len(partitions)-1 {
+			partitionEnd = fmt.Sprintf("%d", partitionStart+partition.SizeInBytes-1)
+		}
+
+		p.logger.Info(p.logTag, "Creating %s partition %d with start %dB and end %s", partition.Type, index, partitionStart, partitionEnd)
+
+		_, _, _, err = p.cmdRunner.RunCommand(
+			"parted",
+			"-s",
+			"-a",
+			"optimal",
+			devicePath,
+			"unit",
+			"B",
+			"mkpart",
+			string(partition.Type),
+			partedPartitionFsTypes[partition.Type],
+			fmt.Sprintf("%d", partitionStart),
+			partitionEnd,
+		)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Partitioning disk `%s'", devicePath)
+		}
+
+		partitionStart = partitionStart + partition.SizeInBytes
+		if remainder := partitionStart % alignmentInBytes; remainder != 0 {
+			partitionStart = partitionStart + alignmentInBytes - remainder
+		}
+	}
+
+	return nil
+}
+
+func (p partedPartitioner) GrowPartition(devicePath string, partitionNumber int) error {
+	p.logger.Info(p.logTag, "Growing partition %d on %s", partitionNumber, devicePath)
+
+	// Backup GPT header has to be moved to the new end of the device first;
+	// without sgdisk parted is left to relocate it on its own
+	if p.cmdRunner.CommandExists("sgdisk") {
+		_, _, _, err := p.cmdRunner.RunCommand("sgdisk", "-e", devicePath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Relocating backup GPT header on %s", devicePath)
+		}
+	}
+
+	_, _, _, err := p.cmdRunner.RunCommand(
+		"parted", "-s", devicePath, "resizepart", strconv.Itoa(partitionNumber), "100%",
+	)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Growing partition %d on %s", partitionNumber, devicePath)
+	}
+
+	return nil
+}
+
+func (p partedPartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, error) {
+	stdout, _, _, err := p.cmdRunner.RunCommand("blockdev", "--getsize64", devicePath)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Shelling out to blockdev")
+	}
+
+	size, err := strconv.ParseUint(strings.TrimSpace(stdout), 10, 64)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Converting disk size to integer")
+	}
+
+	return size, nil
+}
+
+func (p partedPartitioner) diskMatchesPartitions(devicePath string, partitionsToMatch []Partition) bool {
+	label, existingPartitions, err := p.getPartitions(devicePath)
+	if err != nil {
+		p.logger.Debug(p.logTag, "Failed to get partitions of %s: %s", devicePath, err)
+		return false
+	}
+
+	if label != "gpt" || len(existingPartitions) < len(partitionsToMatch) {
+		return false
+	}
+
+	remainingDiskSpace, err := p.GetDeviceSizeInBytes(devicePath)
+	if err != nil {
+		return false
+	}
+
+	deltaInBytes := uint64(20 * 1024 * 1024)
+
+	for index, partitionToMatch := range partitionsToMatch {
+		if index == len(partitionsToMatch)-1 {
+			partitionToMatch.SizeInBytes = remainingDiskSpace
+		}
+
+		existingPartition := existingPartitions[index]
+		switch {
+		case existingPartition.Type != partitionToMatch.Type:
+			return false
+		case !withinDelta(existingPartition.SizeInBytes, partitionToMatch.SizeInBytes, deltaInBytes):
+			return false
+		}
+
+		remainingDiskSpace = remainingDiskSpace - partitionToMatch.SizeInBytes
+	}
+
+	return true
+}
+
+// getPartitions parses machine readable parted output, e.g.:
+//
+//	BYT;
+//	/dev/sdb:4398046511104B:scsi:512:512:gpt:Virtual Disk:;
+//	1:1048576B:1074790399B:1073741824B:linux-swap(v1):swap:;
+//	2:1074790400B:4398046494719B:4396971704320B:ext4:linux:;
+func (p partedPartitioner) getPartitions(devicePath string) (string, []Partition, error) {
+	stdout, _, _, err := p.cmdRunner.RunCommand("parted", "-m", "-s", devicePath, "unit", "B", "print")
+	if err != nil {
+		return "", nil, bosherr.WrapError(err, "Shelling out to parted")
+	}
+
+	allLines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(allLines) < 2 {
+		return "", nil, bosherr.Errorf("Parsing existing partitions of `%s'", devicePath)
+	}
+
+	deviceInfo := strings.Split(allLines[1], ":")
+	if len(deviceInfo) < 6 {
+		return "", nil, bosherr.Errorf("Parsing device information of `%s'", devicePath)
+	}
+
+	var partitions []Partition
+
+	for _, partitionLine := range allLines[2:] {
+		partitionInfo := strings.Split(strings.TrimSuffix(partitionLine, ";"), ":")
+		if len(partitionInfo) < 6 {
+			return "", nil, bosherr.Errorf("Parsing existing partitions of `%s'", devicePath)
+		}
+
+		size, err := strconv.ParseUint(strings.TrimRight(partitionInfo[3], "B"), 10, 64)
+		if err != nil {
+			return "", nil, bosherr.WrapErrorf(err, "Parsing existing partitions of `%s'", devicePath)
+		}
+
+		partitionType := PartitionTypeLinux
+		if partitionInfo[5] == string(PartitionTypeSwap) || strings.HasPrefix(partitionInfo[4], "linux-swap") {
+			partitionType = PartitionTypeSwap
+		}
+
+		partitions = append(partitions, Partition{SizeInBytes: size, Type: partitionType})
+	}
+
+	return deviceInfo[5], partitions, nil
+}
@@ -0,0 +1,158 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+)
+
+const devSdbPartedGptDump = `BYT;
+/dev/sdb:4398046511104B:scsi:512:512:gpt:Virtual Disk:;
+1:1048576B:1074790399B:1073741824B:linux-swap(v1):swap:;
+2:1074790400B:4398046494719B:4396971704320B:ext4:linux:;
+`
+
+const devSdbPartedMsdosDump = `BYT;
+/dev/sdb:4398046511104B:scsi:512:512:msdos:Virtual Disk:;
+1:1048576B:1074790399B:1073741824B:linux-swap(v1)::;
+`
+
+var _ = Describe("partedPartitioner", func() {
+	var (
+		runner      *fakesys.FakeCmdRunner
+		partitioner Partitioner
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+
+		partitioner = NewPartedPartitioner(logger, runner)
+	})
+
+	Describe("Partition", func() {
+		partitions := []Partition{
+			{SizeInBytes: 1024 * 1024 * 1024, Type: PartitionTypeSwap},
+			{Type: PartitionTypeLinux},
+		}
+
+		It("creates GPT partition table with aligned partitions named after their types", func() {
+			runner.AddCmdResult("parted -m -s /dev/sdb unit B print", fakesys.FakeCmdResult{
+				Stdout: "BYT;\n/dev/sdb:4398046511104B:scsi:512:512:unknown:Virtual Disk:;\n",
+				Error:  errors.New("fake-unrecognised-disk-label"),
+			})
+
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"parted", "-m", "-s", "/dev/sdb", "unit", "B", "print"},
+				{"parted", "-s", "/dev/sdb", "mklabel", "gpt"},
+				{"parted", "-s", "-a", "optimal", "/dev/sdb", "unit", "B", "mkpart", "swap", "linux-swap", "1048576", "1074790399"},
+				{"parted", "-s", "-a", "optimal", "/dev/sdb", "unit", "B", "mkpart", "linux", "ext4", "1074790400", "100%"},
+			}))
+		})
+
+		It("does not partition disk when partitions already match", func() {
+			runner.AddCmdResult("parted -m -s /dev/sdb unit B print", fakesys.FakeCmdResult{Stdout: devSdbPartedGptDump})
+			runner.AddCmdResult("blockdev --getsize64 /dev/sdb", fakesys.FakeCmdResult{Stdout: "4398046511104\n"})
+
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"parted", "-m", "-s", "/dev/sdb", "unit", "B", "print"},
+				{"blockdev", "--getsize64", "/dev/sdb"},
+			}))
+		})
+
+		It("re-partitions disk when partition types do not match", func() {
+			runner.AddCmdResult("parted -m -s /dev/sdb unit B print", fakesys.FakeCmdResult{Stdout: devSdbPartedGptDump})
+			runner.AddCmdResult("blockdev --getsize64 /dev/sdb", fakesys.FakeCmdResult{Stdout: "4398046511104\n"})
+
+			err := partitioner.Partition("/dev/sdb", []Partition{
+				{SizeInBytes: 1024 * 1024 * 1024, Type: PartitionTypeLinux},
+				{Type: PartitionTypeLinux},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(ContainElement([]string{"parted", "-s", "/dev/sdb", "mklabel", "gpt"}))
+		})
+
+		It("re-partitions disk that has MBR partition table", func() {
+			runner.AddCmdResult("parted -m -s /dev/sdb unit B print", fakesys.FakeCmdResult{Stdout: devSdbPartedMsdosDump})
+
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(ContainElement([]string{"parted", "-s", "/dev/sdb", "mklabel", "gpt"}))
+		})
+
+		It("returns error when creating partition table fails", func() {
+			runner.AddCmdResult("parted -s /dev/sdb mklabel gpt", fakesys.FakeCmdResult{Error: errors.New("fake-mklabel-err")})
+
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-mklabel-err"))
+		})
+
+		It("returns error when creating partition fails", func() {
+			runner.AddCmdResult(
+				"parted -s -a optimal /dev/sdb unit B mkpart swap linux-swap 1048576 1074790399",
+				fakesys.FakeCmdResult{Error: errors.New("fake-mkpart-err")},
+			)
+
+			err := partitioner.Partition("/dev/sdb", partitions)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-mkpart-err"))
+		})
+	})
+
+	Describe("GrowPartition", func() {
+		It("relocates backup GPT header and resizes partition to the end of the device", func() {
+			runner.CommandExistsValue = true
+
+			err := partitioner.GrowPartition("/dev/sdb", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"sgdisk", "-e", "/dev/sdb"},
+				{"parted", "-s", "/dev/sdb", "resizepart", "1", "100%"},
+			}))
+		})
+
+		It("only resizes partition when sgdisk is not available", func() {
+			runner.CommandExistsValue = false
+
+			err := partitioner.GrowPartition("/dev/sdb", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"parted", "-s", "/dev/sdb", "resizepart", "1", "100%"},
+			}))
+		})
+	})
+
+	Describe("GetDeviceSizeInBytes", func() {
+		It("returns size reported by blockdev", func() {
+			runner.AddCmdResult("blockdev --getsize64 /dev/sdb", fakesys.FakeCmdResult{Stdout: "4398046511104\n"})
+
+			size, err := partitioner.GetDeviceSizeInBytes("/dev/sdb")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(uint64(4398046511104)))
+		})
+
+		It("returns error when blockdev fails", func() {
+			runner.AddCmdResult("blockdev --getsize64 /dev/sdb", fakesys.FakeCmdResult{Error: errors.New("fake-blockdev-err")})
+
+			_, err := partitioner.GetDeviceSizeInBytes("/dev/sdb")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-blockdev-err"))
+		})
+	})
+})
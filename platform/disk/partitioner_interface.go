@@ -18,6 +18,9 @@ type Partition struct {
 type Partitioner interface {
 	Partition(devicePath string, partitions []Partition) (err error)
 	GetDeviceSizeInBytes(devicePath string) (size uint64, err error)
+
+	// GrowPartition extends partition (numbered from 1) to the end of the device
+	GrowPartition(devicePath string, partitionNumber int) (err error)
 }
 
 func (p Partition) String() string {
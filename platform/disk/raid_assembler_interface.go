@@ -0,0 +1,7 @@
+package disk
+
+type RaidAssembler interface {
+	// AssembleRaid0 stripes devices into RAID0 array available at arrayPath;
+	// existing array is reused when it consists of the same devices
+	AssembleRaid0(arrayPath string, devicePaths []string) (err error)
+}
@@ -94,6 +94,19 @@ func (p rootDevicePartitioner) Partition(devicePath string, partitions []Partiti
 	return nil
 }
 
+func (p rootDevicePartitioner) GrowPartition(devicePath string, partitionNumber int) error {
+	p.logger.Info(p.logTag, "Growing partition %d on %s", partitionNumber, devicePath)
+
+	_, _, _, err := p.cmdRunner.RunCommand(
+		"parted", "-s", devicePath, "resizepart", strconv.Itoa(partitionNumber), "100%",
+	)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Growing partition %d on %s", partitionNumber, devicePath)
+	}
+
+	return nil
+}
+
 func (p rootDevicePartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, error) {
 	p.logger.Debug(p.logTag, "Getting size of disk remaining after first partition")
 
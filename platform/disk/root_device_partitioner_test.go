@@ -353,4 +353,26 @@ var _ = Describe("rootDevicePartitioner", func() {
 			})
 		})
 	})
+
+	Describe("GrowPartition", func() {
+		It("resizes given partition to the end of the device", func() {
+			err := partitioner.GrowPartition("/dev/sda", 2)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCmdRunner.RunCommands).To(Equal([][]string{
+				{"parted", "-s", "/dev/sda", "resizepart", "2", "100%"},
+			}))
+		})
+
+		It("returns error when parted fails", func() {
+			fakeCmdRunner.AddCmdResult(
+				"parted -s /dev/sda resizepart 2 100%",
+				fakesys.FakeCmdResult{Error: errors.New("fake-parted-err")},
+			)
+
+			err := partitioner.GrowPartition("/dev/sda", 2)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-parted-err"))
+		})
+	})
 })
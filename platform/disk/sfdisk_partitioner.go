@@ -55,6 +55,21 @@ func (p sfdiskPartitioner) Partition(devicePath string, partitions []Partition)
 	return nil
 }
 
+func (p sfdiskPartitioner) GrowPartition(devicePath string, partitionNumber int) error {
+	p.logger.Info(p.logTag, "Growing partition %d on %s", partitionNumber, devicePath)
+
+	// Empty start keeps partition start and '+' size uses all following space;
+	// kernel is told about new partition table separately since disk may be in use
+	_, _, _, err := p.cmdRunner.RunCommandWithInput(
+		",+\n", "sfdisk", "--no-reread", "-N", strconv.Itoa(partitionNumber), devicePath,
+	)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to sfdisk")
+	}
+
+	return nil
+}
+
 func (p sfdiskPartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, error) {
 	stdout, _, _, err := p.cmdRunner.RunCommand("sfdisk", "-s", devicePath)
 	if err != nil {
@@ -1,6 +1,7 @@
 package disk_test
 
 import (
+	"errors"
 	"fmt"
 
 	. "github.com/onsi/ginkgo"
@@ -145,4 +146,23 @@ var _ = Describe("sfdiskPartitioner", func() {
 
 		Expect(0).To(Equal(len(runner.RunCommandsWithInput)))
 	})
+
+	Describe("GrowPartition", func() {
+		It("grows given partition to fill remaining space without rereading partition table", func() {
+			err := partitioner.GrowPartition("/dev/sda", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommandsWithInput).To(Equal([][]string{
+				{",+\n", "sfdisk", "--no-reread", "-N", "1", "/dev/sda"},
+			}))
+		})
+
+		It("returns error when sfdisk fails", func() {
+			runner.AddCmdResult(",+\n sfdisk --no-reread -N 1 /dev/sda", fakesys.FakeCmdResult{Error: errors.New("fake-sfdisk-err")})
+
+			err := partitioner.GrowPartition("/dev/sda", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-sfdisk-err"))
+		})
+	})
 })
@@ -0,0 +1,249 @@
+package platform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+// Marker is kept in the root of the new disk until its contents are verified
+// so that migration interrupted by agent restart is never mistaken for complete one
+const migrationIncompleteMarker = ".bosh-migration-incomplete"
+
+// MigrationIncompleteError is returned when disk with interrupted migration
+// is mounted anywhere but the migration target
+type MigrationIncompleteError struct {
+	MigrationDir string
+}
+
+func (e MigrationIncompleteError) Error() string {
+	return "Persistent disk contains incomplete migration and can only be mounted at " + e.MigrationDir
+}
+
+const (
+	MigrationStageCopying    = "copying"
+	MigrationStageVerifying  = "verifying"
+	MigrationStageRemounting = "remounting"
+)
+
+type MigrationProgress struct {
+	Stage string `json:"stage"`
+
+	TotalFiles uint64 `json:"total_files"`
+	TotalBytes uint64 `json:"total_bytes"`
+
+	CopiedFiles uint64 `json:"copied_files"`
+	CopiedBytes uint64 `json:"copied_bytes"`
+
+	VerifiedFiles uint64 `json:"verified_files"`
+}
+
+type MigrationOptions struct {
+	// Compare checksums of all files in addition to their sizes
+	VerifyChecksums bool
+
+	// ProgressFunc is called whenever migration progress changes; may be nil
+	ProgressFunc func(MigrationProgress)
+}
+
+type migrationEntry struct {
+	mode os.FileMode
+	size int64
+}
+
+// migrationTree maps paths relative to the mount point to their entries
+type migrationTree map[string]migrationEntry
+
+func (t migrationTree) totals() (files, bytes uint64) {
+	for _, entry := range t {
+		if entry.mode.IsRegular() {
+			files++
+			bytes += uint64(entry.size)
+		}
+	}
+	return
+}
+
+func listMigrationTree(fs boshsys.FileSystem, mountPoint string) (migrationTree, error) {
+	tree := migrationTree{}
+
+	err := fs.Walk(mountPoint, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(mountPoint, path)
+		if err != nil {
+			return err
+		}
+
+		if relPath == "." || relPath == migrationIncompleteMarker {
+			return nil
+		}
+
+		tree[relPath] = migrationEntry{mode: info.Mode(), size: info.Size()}
+
+		return nil
+	})
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Listing files in %s", mountPoint)
+	}
+
+	return tree, nil
+}
+
+type migrationVerifier struct {
+	fs       boshsys.FileSystem
+	progress *migrationProgressReporter
+}
+
+func (v migrationVerifier) Verify(fromMountPoint string, fromTree migrationTree, toMountPoint string, verifyChecksums bool) error {
+	toTree, err := listMigrationTree(v.fs, toMountPoint)
+	if err != nil {
+		return err
+	}
+
+	for relPath, fromEntry := range fromTree {
+		toEntry, found := toTree[relPath]
+		if !found {
+			return bosherr.Errorf("File %s is missing on new disk", relPath)
+		}
+
+		if fromEntry.mode.IsRegular() != toEntry.mode.IsRegular() || fromEntry.mode.IsDir() != toEntry.mode.IsDir() {
+			return bosherr.Errorf("File %s has different type on new disk", relPath)
+		}
+
+		if !fromEntry.mode.IsRegular() {
+			continue
+		}
+
+		if fromEntry.size != toEntry.size {
+			return bosherr.Errorf("File %s has size %d on new disk instead of %d", relPath, toEntry.size, fromEntry.size)
+		}
+
+		if verifyChecksums {
+			err = v.compareChecksums(filepath.Join(fromMountPoint, relPath), filepath.Join(toMountPoint, relPath))
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Verifying checksum of %s", relPath)
+			}
+		}
+
+		v.progress.Update(func(p *MigrationProgress) { p.VerifiedFiles++ })
+	}
+
+	if len(toTree) != len(fromTree) {
+		return bosherr.Errorf("New disk contains %d files instead of %d", len(toTree), len(fromTree))
+	}
+
+	return nil
+}
+
+func (v migrationVerifier) compareChecksums(fromPath, toPath string) error {
+	fromSum, err := v.checksum(fromPath)
+	if err != nil {
+		return err
+	}
+
+	toSum, err := v.checksum(toPath)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(fromSum, toSum) {
+		return bosherr.Error("Checksums do not match")
+	}
+
+	return nil
+}
+
+func (v migrationVerifier) checksum(path string) ([]byte, error) {
+	file, err := v.fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Opening %s", path)
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading %s", path)
+	}
+
+	return hash.Sum(nil), nil
+}
+
+type migrationProgressReporter struct {
+	progress     MigrationProgress
+	progressFunc func(MigrationProgress)
+	lock         sync.Mutex
+}
+
+func (r *migrationProgressReporter) Update(update func(*MigrationProgress)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	update(&r.progress)
+
+	if r.progressFunc != nil {
+		r.progressFunc(r.progress)
+	}
+}
+
+// rsyncProgressWriter parses overall progress printed by rsync --info=progress2, e.g.:
+//
+//	32,768  42%   31.25MB/s    0:00:00 (xfr#1, to-chk=2/4)
+type rsyncProgressWriter struct {
+	progress *migrationProgressReporter
+	buffer   []byte
+}
+
+var rsyncProgressRegexp = regexp.MustCompile(`^\s*([\d,]+)\s+\d+%.*xfr#(\d+)`)
+
+func (w *rsyncProgressWriter) Write(data []byte) (int, error) {
+	w.buffer = append(w.buffer, data...)
+
+	for {
+		index := bytes.IndexAny(w.buffer, "\r\n")
+		if index < 0 {
+			break
+		}
+
+		w.parseLine(string(w.buffer[:index]))
+		w.buffer = w.buffer[index+1:]
+	}
+
+	return len(data), nil
+}
+
+func (w *rsyncProgressWriter) parseLine(line string) {
+	matches := rsyncProgressRegexp.FindStringSubmatch(line)
+	if len(matches) != 3 {
+		return
+	}
+
+	copiedBytes, err := strconv.ParseUint(strings.Replace(matches[1], ",", "", -1), 10, 64)
+	if err != nil {
+		return
+	}
+
+	copiedFiles, err := strconv.ParseUint(matches[2], 10, 64)
+	if err != nil {
+		return
+	}
+
+	w.progress.Update(func(p *MigrationProgress) {
+		p.CopiedBytes = copiedBytes
+		p.CopiedFiles = copiedFiles
+	})
+}
@@ -8,6 +8,7 @@ import (
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	boshcmd "github.com/cloudfoundry/bosh-agent/platform/commands"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -100,11 +101,15 @@ func (p dummyPlatform) SetUserPassword(user, encryptedPwd string) (err error) {
 	return
 }
 
-func (p dummyPlatform) SetupHostname(hostname string) (err error) {
+func (p dummyPlatform) SetupHostname(hostname, fqdn string) (err error) {
 	return
 }
 
-func (p dummyPlatform) SetupNetworking(networks boshsettings.Networks) (err error) {
+func (p dummyPlatform) SaveDNSRecords(dnsRecords boshsettings.DNSRecords, hostname, fqdn string) error {
+	return nil
+}
+
+func (p dummyPlatform) SetupNetworking(networks boshsettings.Networks, mbusURL string) (err error) {
 	return
 }
 
@@ -116,6 +121,13 @@ func (p dummyPlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
 
+func (p dummyPlatform) AssembleEphemeralDisks(devicePaths []string) (string, error) {
+	if len(devicePaths) == 0 {
+		return "", nil
+	}
+	return devicePaths[0], nil
+}
+
 func (p dummyPlatform) SetupEphemeralDiskWithPath(devicePath string) (err error) {
 	return
 }
@@ -148,7 +160,19 @@ func (p dummyPlatform) GetFilesContentsFromDisk(diskPath string, fileNames []str
 	return
 }
 
-func (p dummyPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p dummyPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string, options MigrationOptions) (err error) {
+	return
+}
+
+func (p dummyPlatform) ResizePersistentDisk(diskSettings boshsettings.DiskSettings) (uint64, uint64, error) {
+	return 0, 0, nil
+}
+
+func (p dummyPlatform) FreezeFilesystem(mountPoint string) (err error) {
+	return
+}
+
+func (p dummyPlatform) ThawFilesystem(mountPoint string) (err error) {
 	return
 }
 
@@ -160,6 +184,10 @@ func (p dummyPlatform) IsPersistentDiskMounted(diskSettings boshsettings.DiskSet
 	return true, nil
 }
 
+func (p dummyPlatform) GetPersistentDiskMountPoint(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	return p.dirProvider.StoreDir(), true, nil
+}
+
 func (p dummyPlatform) StartMonit() (err error) {
 	return
 }
@@ -176,6 +204,14 @@ func (p dummyPlatform) PrepareForNetworkingChange() error {
 	return nil
 }
 
+func (p dummyPlatform) MonitorNetworkInterfaces(handler boshnet.InterfaceAddedHandler) error {
+	return nil
+}
+
+func (p dummyPlatform) MonitorDHCPAddresses(networksFunc boshnet.NetworksFunc, handler boshnet.AddressChangeHandler) error {
+	return nil
+}
+
 func (p dummyPlatform) GetDefaultNetwork() (boshsettings.Network, error) {
 	var network boshsettings.Network
 
@@ -6,8 +6,10 @@ import (
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshcmd "github.com/cloudfoundry/bosh-agent/platform/commands"
 	fakecmd "github.com/cloudfoundry/bosh-agent/platform/commands/fakes"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	fakevitals "github.com/cloudfoundry/bosh-agent/platform/vitals/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -43,9 +45,19 @@ type FakePlatform struct {
 
 	UserPasswords         map[string]string
 	SetupHostnameHostname string
+	SetupHostnameFQDN     string
+
+	SaveDNSRecordsDNSRecords boshsettings.DNSRecords
+	SaveDNSRecordsHostname   string
+	SaveDNSRecordsFQDN       string
+	SaveDNSRecordsError      error
 
 	SetTimeWithNtpServersServers []string
 
+	AssembleEphemeralDisksDevicePaths []string
+	AssembleEphemeralDisksDevicePath  string
+	AssembleEphemeralDisksErr         error
+
 	SetupEphemeralDiskWithPathDevicePath string
 	SetupEphemeralDiskWithPathErr        error
 
@@ -57,6 +69,7 @@ type FakePlatform struct {
 
 	SetupNetworkingCalled   bool
 	SetupNetworkingNetworks boshsettings.Networks
+	SetupNetworkingMbusURL  string
 	SetupNetworkingErr      error
 
 	MountPersistentDiskCalled     bool
@@ -86,6 +99,20 @@ type FakePlatform struct {
 
 	MigratePersistentDiskFromMountPoint string
 	MigratePersistentDiskToMountPoint   string
+	MigratePersistentDiskOptions        boshplatform.MigrationOptions
+	MigratePersistentDiskProgress       []boshplatform.MigrationProgress
+	MigratePersistentDiskErr            error
+
+	ResizePersistentDiskSettings       boshsettings.DiskSettings
+	ResizePersistentDiskOldSizeInBytes uint64
+	ResizePersistentDiskNewSizeInBytes uint64
+	ResizePersistentDiskErr            error
+
+	FreezeFilesystemMountPoints []string
+	FreezeFilesystemErr         error
+
+	ThawFilesystemMountPoints []string
+	ThawFilesystemErr         error
 
 	IsMountPointPath   string
 	IsMountPointResult bool
@@ -93,6 +120,11 @@ type FakePlatform struct {
 
 	MountedDevicePaths []string
 
+	GetPersistentDiskMountPointSettings   boshsettings.DiskSettings
+	GetPersistentDiskMountPointMountPoint string
+	GetPersistentDiskMountPointFound      bool
+	GetPersistentDiskMountPointErr        error
+
 	StartMonitStarted           bool
 	SetupMonitUserSetup         bool
 	GetMonitCredentialsUsername string
@@ -101,6 +133,15 @@ type FakePlatform struct {
 	PrepareForNetworkingChangeCalled bool
 	PrepareForNetworkingChangeErr    error
 
+	MonitorNetworkInterfacesAddedInterface *boshnet.NetworkInterface
+	MonitorNetworkInterfacesHandlerErr     error
+	MonitorNetworkInterfacesErr            error
+
+	MonitorDHCPAddressesNetworks   boshsettings.Networks
+	MonitorDHCPAddressesChange     *boshnet.AddressChange
+	MonitorDHCPAddressesHandlerErr error
+	MonitorDHCPAddressesErr        error
+
 	GetDefaultNetworkNetwork boshsettings.Network
 	GetDefaultNetworkErr     error
 }
@@ -187,14 +228,23 @@ func (p *FakePlatform) SetUserPassword(user, encryptedPwd string) (err error) {
 	return
 }
 
-func (p *FakePlatform) SetupHostname(hostname string) (err error) {
+func (p *FakePlatform) SetupHostname(hostname, fqdn string) (err error) {
 	p.SetupHostnameHostname = hostname
+	p.SetupHostnameFQDN = fqdn
 	return
 }
 
-func (p *FakePlatform) SetupNetworking(networks boshsettings.Networks) error {
+func (p *FakePlatform) SaveDNSRecords(dnsRecords boshsettings.DNSRecords, hostname, fqdn string) error {
+	p.SaveDNSRecordsDNSRecords = dnsRecords
+	p.SaveDNSRecordsHostname = hostname
+	p.SaveDNSRecordsFQDN = fqdn
+	return p.SaveDNSRecordsError
+}
+
+func (p *FakePlatform) SetupNetworking(networks boshsettings.Networks, mbusURL string) error {
 	p.SetupNetworkingCalled = true
 	p.SetupNetworkingNetworks = networks
+	p.SetupNetworkingMbusURL = mbusURL
 	return p.SetupNetworkingErr
 }
 
@@ -207,6 +257,11 @@ func (p *FakePlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
 
+func (p *FakePlatform) AssembleEphemeralDisks(devicePaths []string) (string, error) {
+	p.AssembleEphemeralDisksDevicePaths = devicePaths
+	return p.AssembleEphemeralDisksDevicePath, p.AssembleEphemeralDisksErr
+}
+
 func (p *FakePlatform) SetupEphemeralDiskWithPath(devicePath string) (err error) {
 	p.SetupEphemeralDiskWithPathDevicePath = devicePath
 	return p.SetupEphemeralDiskWithPathErr
@@ -272,10 +327,33 @@ func (p *FakePlatform) SetGetFilesContentsFromDisk(fileName string, contents []b
 	p.GetFileContentsFromDiskErrs[fileName] = err
 }
 
-func (p *FakePlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p *FakePlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string, options boshplatform.MigrationOptions) (err error) {
 	p.MigratePersistentDiskFromMountPoint = fromMountPoint
 	p.MigratePersistentDiskToMountPoint = toMountPoint
-	return
+	p.MigratePersistentDiskOptions = options
+
+	if options.ProgressFunc != nil {
+		for _, progress := range p.MigratePersistentDiskProgress {
+			options.ProgressFunc(progress)
+		}
+	}
+
+	return p.MigratePersistentDiskErr
+}
+
+func (p *FakePlatform) ResizePersistentDisk(diskSettings boshsettings.DiskSettings) (uint64, uint64, error) {
+	p.ResizePersistentDiskSettings = diskSettings
+	return p.ResizePersistentDiskOldSizeInBytes, p.ResizePersistentDiskNewSizeInBytes, p.ResizePersistentDiskErr
+}
+
+func (p *FakePlatform) FreezeFilesystem(mountPoint string) error {
+	p.FreezeFilesystemMountPoints = append(p.FreezeFilesystemMountPoints, mountPoint)
+	return p.FreezeFilesystemErr
+}
+
+func (p *FakePlatform) ThawFilesystem(mountPoint string) error {
+	p.ThawFilesystemMountPoints = append(p.ThawFilesystemMountPoints, mountPoint)
+	return p.ThawFilesystemErr
 }
 
 func (p *FakePlatform) IsMountPoint(path string) (bool, error) {
@@ -283,6 +361,11 @@ func (p *FakePlatform) IsMountPoint(path string) (bool, error) {
 	return p.IsMountPointResult, p.IsMountPointErr
 }
 
+func (p *FakePlatform) GetPersistentDiskMountPoint(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	p.GetPersistentDiskMountPointSettings = diskSettings
+	return p.GetPersistentDiskMountPointMountPoint, p.GetPersistentDiskMountPointFound, p.GetPersistentDiskMountPointErr
+}
+
 func (p *FakePlatform) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (result bool, err error) {
 	for _, mountedPath := range p.MountedDevicePaths {
 		if mountedPath == diskSettings.Path {
@@ -313,6 +396,21 @@ func (p *FakePlatform) PrepareForNetworkingChange() error {
 	return p.PrepareForNetworkingChangeErr
 }
 
+func (p *FakePlatform) MonitorNetworkInterfaces(handler boshnet.InterfaceAddedHandler) error {
+	if p.MonitorNetworkInterfacesAddedInterface != nil {
+		p.MonitorNetworkInterfacesHandlerErr = handler(*p.MonitorNetworkInterfacesAddedInterface)
+	}
+	return p.MonitorNetworkInterfacesErr
+}
+
+func (p *FakePlatform) MonitorDHCPAddresses(networksFunc boshnet.NetworksFunc, handler boshnet.AddressChangeHandler) error {
+	p.MonitorDHCPAddressesNetworks = networksFunc()
+	if p.MonitorDHCPAddressesChange != nil {
+		p.MonitorDHCPAddressesHandlerErr = handler(*p.MonitorDHCPAddressesChange)
+	}
+	return p.MonitorDHCPAddressesErr
+}
+
 func (p *FakePlatform) GetDefaultNetwork() (boshsettings.Network, error) {
 	return p.GetDefaultNetworkNetwork, p.GetDefaultNetworkErr
 }
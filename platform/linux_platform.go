@@ -2,12 +2,17 @@ package platform
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -40,6 +45,9 @@ const (
 	sshAuthKeysFilePermissions = os.FileMode(0600)
 
 	minRootEphemeralSpaceInBytes = uint64(1024 * 1024 * 1024)
+
+	// Multiple ephemeral disks are striped into single array
+	ephemeralRaidDevicePath = "/dev/md0"
 )
 
 type LinuxOptions struct {
@@ -62,6 +70,48 @@ type LinuxOptions struct {
 	// Strategy for resolving device paths;
 	// possible values: virtio, scsi, ''
 	DevicePathResolutionType string
+
+	// Tool used to partition persistent and ephemeral disks;
+	// possible values: sfdisk (MBR), parted (GPT), '' (GPT only for disks larger than 2TB)
+	PartitionerType string
+
+	// Tool used to configure network interfaces;
+	// possible values: networkd (systemd-networkd), '' (distribution specific)
+	NetManagerType string
+
+	// When greater than zero, DNS servers, NATS mbus and NetworkCheckEndpoints
+	// have to become reachable within given number of seconds after network
+	// is reconfigured; otherwise previous configuration is restored.
+	// Gateways have to answer ping only when there is nothing else to reach.
+	NetworkCheckTimeoutSeconds int
+
+	// Additional host:port addresses that have to be reachable
+	NetworkCheckEndpoints []string
+
+	// When set to true ephemeral partitions will be encrypted
+	// with a random key that is lost when machine reboots
+	EncryptEphemeralDisk bool
+
+	// Filesystems created on persistent and ephemeral disks;
+	// ext4 without additional options is used by default
+	PersistentDiskFilesystem DiskFilesystemOptions
+	EphemeralDiskFilesystem  DiskFilesystemOptions
+}
+
+type DiskFilesystemOptions struct {
+	// Possible values: ext4, xfs, ''
+	Type boshdisk.FileSystemType
+
+	// Additional arguments passed to mkfs and mount commands
+	MkfsOptions  []string
+	MountOptions []string
+}
+
+func (o DiskFilesystemOptions) FileSystemType() boshdisk.FileSystemType {
+	if o.Type == "" {
+		return boshdisk.FileSystemExt4
+	}
+	return o.Type
 }
 
 type linux struct {
@@ -75,12 +125,18 @@ type linux struct {
 	cdutil                 boshdevutil.DeviceUtil
 	diskManager            boshdisk.Manager
 	netManager             boshnet.Manager
+	interfaceMonitor       boshnet.InterfaceMonitor
+	addressMonitor         boshnet.AddressMonitor
 	monitRetryStrategy     boshretry.RetryStrategy
 	devicePathResolver     boshdpresolv.DevicePathResolver
 	diskScanDuration       time.Duration
 	options                LinuxOptions
 	logger                 boshlog.Logger
 	defaultNetworkResolver boshsettings.DefaultNetworkResolver
+
+	// setupNetworkingLock serializes network configuration
+	// requested at bootstrap and by hot-plugged interfaces
+	setupNetworkingLock *sync.Mutex
 }
 
 func NewLinuxPlatform(
@@ -94,6 +150,8 @@ func NewLinuxPlatform(
 	cdutil boshdevutil.DeviceUtil,
 	diskManager boshdisk.Manager,
 	netManager boshnet.Manager,
+	interfaceMonitor boshnet.InterfaceMonitor,
+	addressMonitor boshnet.AddressMonitor,
 	monitRetryStrategy boshretry.RetryStrategy,
 	devicePathResolver boshdpresolv.DevicePathResolver,
 	diskScanDuration time.Duration,
@@ -112,12 +170,15 @@ func NewLinuxPlatform(
 		cdutil:                 cdutil,
 		diskManager:            diskManager,
 		netManager:             netManager,
+		interfaceMonitor:       interfaceMonitor,
+		addressMonitor:         addressMonitor,
 		monitRetryStrategy:     monitRetryStrategy,
 		devicePathResolver:     devicePathResolver,
 		diskScanDuration:       diskScanDuration,
 		options:                options,
 		logger:                 logger,
 		defaultNetworkResolver: defaultNetworkResolver,
+		setupNetworkingLock:    &sync.Mutex{},
 	}
 }
 
@@ -164,8 +225,11 @@ func (p linux) GetDevicePathResolver() (devicePathResolver boshdpresolv.DevicePa
 	return p.devicePathResolver
 }
 
-func (p linux) SetupNetworking(networks boshsettings.Networks) (err error) {
-	return p.netManager.SetupNetworking(networks, nil)
+func (p linux) SetupNetworking(networks boshsettings.Networks, mbusURL string) (err error) {
+	p.setupNetworkingLock.Lock()
+	defer p.setupNetworkingLock.Unlock()
+
+	return p.netManager.SetupNetworking(networks, mbusURL, nil)
 }
 
 func (p linux) SetupRuntimeConfiguration() (err error) {
@@ -282,7 +346,7 @@ func (p linux) SetUserPassword(user, encryptedPwd string) (err error) {
 	return
 }
 
-func (p linux) SetupHostname(hostname string) (err error) {
+func (p linux) SetupHostname(hostname, fqdn string) (err error) {
 	_, _, _, err = p.cmdRunner.RunCommand("hostname", hostname)
 	if err != nil {
 		err = bosherr.WrapError(err, "Shelling out to hostname")
@@ -295,32 +359,148 @@ func (p linux) SetupHostname(hostname string) (err error) {
 		return
 	}
 
+	dnsRecords, err := p.readDNSRecords()
+	if err != nil {
+		err = bosherr.WrapError(err, "Reading DNS records")
+		return
+	}
+
+	err = p.writeEtcHosts(hostname, fqdn, dnsRecords)
+	return
+}
+
+func (p linux) SaveDNSRecords(dnsRecords boshsettings.DNSRecords, hostname, fqdn string) error {
+	savedDNSRecords, err := p.readDNSRecords()
+	if err != nil {
+		return bosherr.WrapError(err, "Reading DNS records")
+	}
+
+	if savedDNSRecords.Version > dnsRecords.Version {
+		p.logger.Info(logTag, "Ignoring DNS records version %d older than saved version %d", dnsRecords.Version, savedDNSRecords.Version)
+		return nil
+	}
+
+	err = validateDNSRecords(dnsRecords)
+	if err != nil {
+		return err
+	}
+
+	dnsRecordsJSON, err := json.Marshal(dnsRecords)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling DNS records")
+	}
+
+	err = p.fs.WriteFile(p.dnsRecordsPath(), dnsRecordsJSON)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing DNS records")
+	}
+
+	return p.writeEtcHosts(hostname, fqdn, dnsRecords)
+}
+
+// validateDNSRecords rejects records that would corrupt /etc/hosts
+func validateDNSRecords(dnsRecords boshsettings.DNSRecords) error {
+	for _, record := range dnsRecords.Records {
+		if net.ParseIP(record[0]) == nil {
+			return bosherr.Errorf("Invalid IP address '%s' in DNS record for '%s'", record[0], record[1])
+		}
+
+		if record[1] == "" || strings.ContainsAny(record[1], " \t\r\n") {
+			return bosherr.Errorf("Invalid host name '%s' in DNS record for '%s'", record[1], record[0])
+		}
+	}
+
+	return nil
+}
+
+func (p linux) dnsRecordsPath() string {
+	return filepath.Join(p.dirProvider.BoshDir(), "dns_records.json")
+}
+
+func (p linux) readDNSRecords() (boshsettings.DNSRecords, error) {
+	var dnsRecords boshsettings.DNSRecords
+
+	if !p.fs.FileExists(p.dnsRecordsPath()) {
+		return dnsRecords, nil
+	}
+
+	contents, err := p.fs.ReadFile(p.dnsRecordsPath())
+	if err != nil {
+		return dnsRecords, bosherr.WrapErrorf(err, "Reading %s", p.dnsRecordsPath())
+	}
+
+	err = json.Unmarshal(contents, &dnsRecords)
+	if err != nil {
+		return dnsRecords, bosherr.WrapErrorf(err, "Unmarshalling %s", p.dnsRecordsPath())
+	}
+
+	return dnsRecords, nil
+}
+
+type etcHostsValues struct {
+	Hostname string
+	FQDN     string
+	Records  [][2]string
+}
+
+// writeEtcHosts replaces /etc/hosts atomically so that
+// resolvers never observe partially written file
+func (p linux) writeEtcHosts(hostname, fqdn string, dnsRecords boshsettings.DNSRecords) error {
+	values := etcHostsValues{Hostname: hostname, Records: dnsRecords.Records}
+	if fqdn != hostname {
+		values.FQDN = fqdn
+	}
+
 	buffer := bytes.NewBuffer([]byte{})
 	t := template.Must(template.New("etc-hosts").Parse(etcHostsTemplate))
 
-	err = t.Execute(buffer, hostname)
+	err := t.Execute(buffer, values)
 	if err != nil {
-		err = bosherr.WrapError(err, "Generating config from template")
-		return
+		return bosherr.WrapError(err, "Generating config from template")
 	}
 
+	err = p.fs.WriteFile(etcHostsTmpPath, buffer.Bytes())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing to %s", etcHostsTmpPath)
+	}
+
+	err = p.fs.Rename(etcHostsTmpPath, "/etc/hosts")
+	if err == nil {
+		return nil
+	}
+
+	// /etc/hosts bind-mounted by container runtimes cannot be replaced
+	p.logger.Warn(logTag, "Falling back to writing /etc/hosts in place: %s", err.Error())
+
 	err = p.fs.WriteFile("/etc/hosts", buffer.Bytes())
 	if err != nil {
-		err = bosherr.WrapError(err, "Writing to /etc/hosts")
+		return bosherr.WrapError(err, "Writing to /etc/hosts")
 	}
-	return
+
+	err = p.fs.RemoveAll(etcHostsTmpPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing %s", etcHostsTmpPath)
+	}
+
+	return nil
 }
 
-const etcHostsTemplate = `127.0.0.1 localhost {{ . }}
+const etcHostsTmpPath = "/etc/hosts.bosh-tmp"
+
+const etcHostsTemplate = `127.0.0.1 localhost {{ .Hostname }}{{ if .FQDN }}
+127.0.1.1 {{ .FQDN }} {{ .Hostname }}{{ end }}
 
 # The following lines are desirable for IPv6 capable hosts
-::1 localhost ip6-localhost ip6-loopback {{ . }}
+::1 localhost ip6-localhost ip6-loopback {{ .Hostname }}
 fe00::0 ip6-localnet
 ff00::0 ip6-mcastprefix
 ff02::1 ip6-allnodes
 ff02::2 ip6-allrouters
 ff02::3 ip6-allhosts
-`
+{{ if .Records }}
+# The following lines are synced by bosh-agent
+{{ range .Records }}{{ index . 0 }} {{ index . 1 }}
+{{ end }}{{ end }}`
 
 func (p linux) SetupLogrotate(groupName, basePath, size string) (err error) {
 	buffer := bytes.NewBuffer([]byte{})
@@ -376,6 +556,24 @@ func (p linux) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
 
+func (p linux) AssembleEphemeralDisks(realPaths []string) (string, error) {
+	switch len(realPaths) {
+	case 0:
+		return "", nil
+	case 1:
+		return realPaths[0], nil
+	}
+
+	p.logger.Info(logTag, "Striping ephemeral disks %s into %s", realPaths, ephemeralRaidDevicePath)
+
+	err := p.diskManager.GetRaidAssembler().AssembleRaid0(ephemeralRaidDevicePath, realPaths)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Assembling RAID0 array from ephemeral disks")
+	}
+
+	return ephemeralRaidDevicePath, nil
+}
+
 func (p linux) SetupEphemeralDiskWithPath(realPath string) error {
 	p.logger.Info(logTag, "Setting up ephemeral disk...")
 	mountPoint := p.dirProvider.DataDir()
@@ -423,16 +621,25 @@ func (p linux) SetupEphemeralDiskWithPath(realPath string) error {
 		}
 	}
 
+	if p.options.EncryptEphemeralDisk {
+		swapPartitionPath, dataPartitionPath, err = p.encryptEphemeralPartitions(swapPartitionPath, dataPartitionPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Encrypting ephemeral partitions")
+		}
+	}
+
 	p.logger.Info(logTag, "Formatting `%s' as swap", swapPartitionPath)
 	err = p.diskManager.GetFormatter().Format(swapPartitionPath, boshdisk.FileSystemSwap)
 	if err != nil {
 		return bosherr.WrapError(err, "Formatting swap")
 	}
 
-	p.logger.Info(logTag, "Formatting `%s' as ext4", dataPartitionPath)
-	err = p.diskManager.GetFormatter().Format(dataPartitionPath, boshdisk.FileSystemExt4)
+	fsOptions := p.options.EphemeralDiskFilesystem
+
+	p.logger.Info(logTag, "Formatting `%s' as %s", dataPartitionPath, fsOptions.FileSystemType())
+	err = p.diskManager.GetFormatter().Format(dataPartitionPath, fsOptions.FileSystemType(), fsOptions.MkfsOptions...)
 	if err != nil {
-		return bosherr.WrapError(err, "Formatting data partition with ext4")
+		return bosherr.WrapErrorf(err, "Formatting data partition with %s", fsOptions.FileSystemType())
 	}
 
 	p.logger.Info(logTag, "Mounting `%s' as swap", swapPartitionPath)
@@ -442,7 +649,7 @@ func (p linux) SetupEphemeralDiskWithPath(realPath string) error {
 	}
 
 	p.logger.Info(logTag, "Mounting `%s' at `%s'", dataPartitionPath, mountPoint)
-	err = p.diskManager.GetMounter().Mount(dataPartitionPath, mountPoint)
+	err = p.diskManager.GetMounter().Mount(dataPartitionPath, mountPoint, fsOptions.MountOptions...)
 	if err != nil {
 		return bosherr.WrapError(err, "Mounting data partition")
 	}
@@ -617,22 +824,70 @@ func (p linux) MountPersistentDisk(diskSetting boshsettings.DiskSettings, mountP
 			return bosherr.WrapError(err, "Partitioning disk")
 		}
 
-		partitionPath := realPath + "1"
+		realPath += "1"
+	}
+
+	if diskSetting.EncryptionKey != "" {
+		realPath, err = p.openEncryptedPersistentDisk(realPath, diskSetting.EncryptionKey)
+		if err != nil {
+			return bosherr.WrapError(err, "Opening encrypted partition")
+		}
+	}
+
+	fsOptions := p.options.PersistentDiskFilesystem
+
+	var fsType boshdisk.FileSystemType
 
-		err = p.diskManager.GetFormatter().Format(partitionPath, boshdisk.FileSystemExt4)
+	// Pre-formatted disks still need a filesystem inside of the encrypted device
+	if !p.options.UsePreformattedPersistentDisk || diskSetting.EncryptionKey != "" {
+		fsType, err = p.formatPersistentDisk(realPath, fsOptions)
 		if err != nil {
-			return bosherr.WrapError(err, "Formatting partition with ext4")
+			return err
 		}
+	} else {
+		fsType, err = p.diskManager.GetFormatter().GetFileSystemType(realPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Detecting existing filesystem")
+		}
+	}
 
-		realPath = partitionPath
+	// Configured mount options may not be valid for filesystem kept on the disk
+	mountOptions := fsOptions.MountOptions
+	if fsType != "" && fsType != fsOptions.FileSystemType() {
+		p.logger.Warn(logTag, "Mounting existing %s filesystem on %s without options configured for %s",
+			fsType, realPath, fsOptions.FileSystemType())
+		mountOptions = nil
 	}
 
-	err = p.diskManager.GetMounter().Mount(realPath, mountPoint)
+	err = p.diskManager.GetMounter().Mount(realPath, mountPoint, mountOptions...)
 	if err != nil {
 		return bosherr.WrapError(err, "Mounting partition")
 	}
 
-	return nil
+	return p.checkMigrationIncomplete(mountPoint)
+}
+
+// checkMigrationIncomplete only allows disk with interrupted migration to be
+// mounted as migration target so that the migration can be resumed;
+// using it as the store would expose partially copied data to jobs
+func (p linux) checkMigrationIncomplete(mountPoint string) error {
+	if !p.fs.FileExists(filepath.Join(mountPoint, migrationIncompleteMarker)) {
+		return nil
+	}
+
+	migrationDir := p.dirProvider.StoreMigrationDir()
+
+	if mountPoint == migrationDir {
+		p.logger.Info(logTag, "Persistent disk mounted at %s contains interrupted migration", mountPoint)
+		return nil
+	}
+
+	_, err := p.diskManager.GetMounter().Unmount(mountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Unmounting persistent disk with incomplete migration")
+	}
+
+	return MigrationIncompleteError{MigrationDir: migrationDir}
 }
 
 func (p linux) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (bool, error) {
@@ -646,11 +901,7 @@ func (p linux) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (bo
 		return false, bosherr.WrapError(err, "Getting real device path")
 	}
 
-	if !p.options.UsePreformattedPersistentDisk {
-		realPath += "1"
-	}
-
-	return p.diskManager.GetMounter().Unmount(realPath)
+	return p.diskManager.GetMounter().Unmount(p.persistentDiskDevicePath(realPath, diskSettings))
 }
 
 func (p linux) GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string {
@@ -670,37 +921,252 @@ func (p linux) IsMountPoint(path string) (bool, error) {
 	return p.diskManager.GetMounter().IsMountPoint(path)
 }
 
-func (p linux) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p linux) MigratePersistentDisk(fromMountPoint, toMountPoint string, options MigrationOptions) (err error) {
 	p.logger.Debug(logTag, "Migrating persistent disk %v to %v", fromMountPoint, toMountPoint)
 
+	// Copying into a directory that is not a mount point
+	// would fill up the disk it resides on instead of the new disk
+	isMountPoint, err := p.diskManager.GetMounter().IsMountPoint(toMountPoint)
+	if err != nil {
+		err = bosherr.WrapError(err, "Checking if new disk is mounted")
+		return
+	}
+
+	if !isMountPoint {
+		err = bosherr.Errorf("New disk is not mounted at %s", toMountPoint)
+		return
+	}
+
 	err = p.diskManager.GetMounter().RemountAsReadonly(fromMountPoint)
 	if err != nil {
 		err = bosherr.WrapError(err, "Remounting persistent disk as readonly")
 		return
 	}
 
-	// Golang does not implement a file copy that would allow us to preserve dates...
-	// So we have to shell out to tar to perform the copy instead of delegating to the FileSystem
-	tarCopy := fmt.Sprintf("(tar -C %s -cf - .) | (tar -C %s -xpf -)", fromMountPoint, toMountPoint)
-	_, _, _, err = p.cmdRunner.RunCommand("sh", "-c", tarCopy)
+	fromTree, err := listMigrationTree(p.fs, fromMountPoint)
+	if err != nil {
+		err = bosherr.WrapError(err, "Listing files on old disk")
+		return
+	}
+
+	progress := &migrationProgressReporter{progressFunc: options.ProgressFunc}
+
+	totalFiles, totalBytes := fromTree.totals()
+	progress.Update(func(p *MigrationProgress) {
+		p.Stage = MigrationStageCopying
+		p.TotalFiles = totalFiles
+		p.TotalBytes = totalBytes
+	})
+
+	markerPath := filepath.Join(toMountPoint, migrationIncompleteMarker)
+	if p.fs.FileExists(markerPath) {
+		err = p.resumeMigration(toMountPoint)
+		if err != nil {
+			err = bosherr.WrapError(err, "Resuming interrupted migration")
+			return
+		}
+	} else {
+		err = p.fs.WriteFileString(markerPath, "")
+		if err != nil {
+			err = bosherr.WrapError(err, "Marking new disk as incomplete")
+			return
+		}
+	}
+
+	err = p.copyPersistentDiskFiles(fromMountPoint, toMountPoint, progress)
 	if err != nil {
 		err = bosherr.WrapError(err, "Copying files from old disk to new disk")
 		return
 	}
 
+	progress.Update(func(p *MigrationProgress) {
+		p.Stage = MigrationStageVerifying
+		p.CopiedFiles = totalFiles
+		p.CopiedBytes = totalBytes
+	})
+
+	verifier := migrationVerifier{fs: p.fs, progress: progress}
+
+	err = verifier.Verify(fromMountPoint, fromTree, toMountPoint, options.VerifyChecksums)
+	if err != nil {
+		err = bosherr.WrapError(err, "Verifying files copied to new disk")
+		return
+	}
+
+	err = p.fs.RemoveAll(markerPath)
+	if err != nil {
+		err = bosherr.WrapError(err, "Marking new disk as complete")
+		return
+	}
+
+	progress.Update(func(p *MigrationProgress) { p.Stage = MigrationStageRemounting })
+
 	_, err = p.diskManager.GetMounter().Unmount(fromMountPoint)
 	if err != nil {
 		err = bosherr.WrapError(err, "Unmounting old persistent disk")
 		return
 	}
 
-	err = p.diskManager.GetMounter().Remount(toMountPoint, fromMountPoint)
+	// New disk may use different filesystem than old one since it was
+	// formatted according to current configuration; keep its mount options
+	err = p.diskManager.GetMounter().Remount(toMountPoint, fromMountPoint, p.options.PersistentDiskFilesystem.MountOptions...)
 	if err != nil {
 		err = bosherr.WrapError(err, "Remounting new disk on original mountpoint")
 	}
 	return
 }
 
+// resumeMigration prepares new disk left by interrupted migration for copying;
+// rsync converges its contents by itself while tar cannot remove stale files
+func (p linux) resumeMigration(toMountPoint string) error {
+	p.logger.Info(logTag, "Resuming interrupted migration to %s", toMountPoint)
+
+	if p.cmdRunner.CommandExists("rsync") {
+		return nil
+	}
+
+	_, _, _, err := p.cmdRunner.RunCommand(
+		"find", toMountPoint, "-mindepth", "1", "-maxdepth", "1",
+		"!", "-name", migrationIncompleteMarker, "-exec", "rm", "-rf", "{}", "+",
+	)
+	if err != nil {
+		return bosherr.WrapError(err, "Removing partially copied files from new disk")
+	}
+
+	return nil
+}
+
+// copyPersistentDiskFiles prefers rsync since it reports progress and,
+// when rerun after interruption, only transfers files that are not yet complete;
+// files are written under temporary names so partially copied files are never left behind
+func (p linux) copyPersistentDiskFiles(fromMountPoint, toMountPoint string, progress *migrationProgressReporter) error {
+	if !p.cmdRunner.CommandExists("rsync") {
+		// Golang does not implement a file copy that would allow us to preserve dates...
+		// So we have to shell out to tar to perform the copy instead of delegating to the FileSystem
+		tarCopy := fmt.Sprintf("(tar -C %s -cf - .) | (tar -C %s -xpf -)", fromMountPoint, toMountPoint)
+		_, _, _, err := p.cmdRunner.RunCommand("sh", "-c", tarCopy)
+		return err
+	}
+
+	_, _, _, err := p.cmdRunner.RunComplexCommand(boshsys.Command{
+		Name: "rsync",
+		Args: []string{
+			"-aH",
+			"--numeric-ids",
+			"--delete",
+			"--no-inc-recursive",
+			"--info=progress2",
+			"--exclude=/" + migrationIncompleteMarker,
+			fromMountPoint + "/",
+			toMountPoint + "/",
+		},
+		Stdout: &rsyncProgressWriter{progress: progress},
+	})
+
+	return err
+}
+
+func (p linux) ResizePersistentDisk(diskSettings boshsettings.DiskSettings) (uint64, uint64, error) {
+	p.logger.Debug(logTag, "Resizing persistent disk %s", diskSettings.Path)
+
+	realPath, _, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
+	if err != nil {
+		return 0, 0, bosherr.WrapError(err, "Getting real device path")
+	}
+
+	partitionPath := realPath
+	if !p.options.UsePreformattedPersistentDisk {
+		partitionPath = realPath + "1"
+	}
+
+	partitioner := p.diskManager.GetPartitioner()
+
+	oldSize, err := partitioner.GetDeviceSizeInBytes(partitionPath)
+	if err != nil {
+		return 0, 0, bosherr.WrapError(err, "Getting partition size before resizing")
+	}
+
+	err = p.rescanDevice(realPath)
+	if err != nil {
+		return 0, 0, bosherr.WrapErrorf(err, "Rescanning device %s", realPath)
+	}
+
+	if !p.options.UsePreformattedPersistentDisk {
+		err = partitioner.GrowPartition(realPath, 1)
+		if err != nil {
+			return 0, 0, bosherr.WrapError(err, "Growing partition")
+		}
+
+		// Partition table of a disk in use cannot be re-read as a whole
+		_, _, _, err = p.cmdRunner.RunCommand("partx", "-u", realPath)
+		if err != nil {
+			return 0, 0, bosherr.WrapError(err, "Updating kernel partition table")
+		}
+	}
+
+	fsPath := partitionPath
+
+	if diskSettings.EncryptionKey != "" {
+		err = p.diskManager.GetEncryptor().Resize(partitionPath, diskSettings.EncryptionKey)
+		if err != nil {
+			return 0, 0, bosherr.WrapError(err, "Growing encrypted device")
+		}
+
+		fsPath = p.diskManager.GetEncryptor().MappedPath(partitionPath)
+	}
+
+	fsType, err := p.diskManager.GetFormatter().GetFileSystemType(fsPath)
+	if err != nil {
+		return 0, 0, bosherr.WrapError(err, "Detecting filesystem")
+	}
+
+	err = p.diskManager.GetFormatter().GrowFilesystem(fsPath, fsType)
+	if err != nil {
+		return 0, 0, bosherr.WrapError(err, "Growing filesystem")
+	}
+
+	newSize, err := partitioner.GetDeviceSizeInBytes(partitionPath)
+	if err != nil {
+		return 0, 0, bosherr.WrapError(err, "Getting partition size after resizing")
+	}
+
+	return oldSize, newSize, nil
+}
+
+func (p linux) FreezeFilesystem(mountPoint string) error {
+	p.logger.Info(logTag, "Freezing filesystem mounted at %s", mountPoint)
+
+	_, _, _, err := p.cmdRunner.RunCommand("fsfreeze", "--freeze", mountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to fsfreeze")
+	}
+
+	return nil
+}
+
+func (p linux) ThawFilesystem(mountPoint string) error {
+	p.logger.Info(logTag, "Thawing filesystem mounted at %s", mountPoint)
+
+	_, _, _, err := p.cmdRunner.RunCommand("fsfreeze", "--unfreeze", mountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to fsfreeze")
+	}
+
+	return nil
+}
+
+// rescanDevice makes kernel notice new size of SCSI devices;
+// other devices (e.g. virtio) are updated by the kernel automatically
+func (p linux) rescanDevice(realPath string) error {
+	rescanPath := filepath.Join("/sys/class/block", filepath.Base(realPath), "device", "rescan")
+
+	if !p.fs.FileExists(rescanPath) {
+		return nil
+	}
+
+	return p.fs.WriteFileString(rescanPath, "1")
+}
+
 func (p linux) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (bool, error) {
 	p.logger.Debug(logTag, "Checking whether persistent disk %s is mounted", diskSettings.Path)
 	realPath, timedOut, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
@@ -712,11 +1178,136 @@ func (p linux) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (
 		return false, bosherr.WrapError(err, "Getting real device path")
 	}
 
+	return p.diskManager.GetMounter().IsMounted(p.persistentDiskDevicePath(realPath, diskSettings))
+}
+
+func (p linux) GetPersistentDiskMountPoint(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	realPath, timedOut, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
+	if timedOut {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, bosherr.WrapError(err, "Getting real device path")
+	}
+
+	mounts, err := p.diskManager.GetMountsSearcher().SearchMounts()
+	if err != nil {
+		return "", false, bosherr.WrapError(err, "Searching mounts")
+	}
+
+	devicePath := p.persistentDiskDevicePath(realPath, diskSettings)
+
+	for _, mount := range mounts {
+		if mount.PartitionPath == devicePath {
+			return mount.MountPoint, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// formatPersistentDisk keeps existing filesystem even if it is of a different type
+// so that disks formatted before configuration change can still be migrated from;
+// it returns type of filesystem on the partition
+func (p linux) formatPersistentDisk(partitionPath string, fsOptions DiskFilesystemOptions) (boshdisk.FileSystemType, error) {
+	formatter := p.diskManager.GetFormatter()
+
+	existingFsType, err := formatter.GetFileSystemType(partitionPath)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Detecting existing filesystem")
+	}
+
+	if existingFsType != "" {
+		if existingFsType != fsOptions.FileSystemType() {
+			p.logger.Warn(logTag, "Keeping existing %s filesystem on %s instead of formatting it with %s",
+				existingFsType, partitionPath, fsOptions.FileSystemType())
+		}
+		return existingFsType, nil
+	}
+
+	err = formatter.Format(partitionPath, fsOptions.FileSystemType(), fsOptions.MkfsOptions...)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Formatting partition with %s", fsOptions.FileSystemType())
+	}
+
+	return fsOptions.FileSystemType(), nil
+}
+
+// persistentDiskDevicePath returns path of the device that holds filesystem of the persistent disk
+func (p linux) persistentDiskDevicePath(realPath string, diskSettings boshsettings.DiskSettings) string {
 	if !p.options.UsePreformattedPersistentDisk {
 		realPath += "1"
 	}
 
-	return p.diskManager.GetMounter().IsMounted(realPath)
+	if diskSettings.EncryptionKey != "" {
+		realPath = p.diskManager.GetEncryptor().MappedPath(realPath)
+	}
+
+	return realPath
+}
+
+func (p linux) openEncryptedPersistentDisk(partitionPath, key string) (string, error) {
+	encryptor := p.diskManager.GetEncryptor()
+
+	encrypted, err := encryptor.IsEncrypted(partitionPath)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Checking whether %s is encrypted", partitionPath)
+	}
+
+	if !encrypted {
+		err = encryptor.Format(partitionPath, key)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Encrypting %s", partitionPath)
+		}
+	}
+
+	return encryptor.Open(partitionPath, key)
+}
+
+// encryptEphemeralPartitions uses random key that is only kept in memory
+// so that ephemeral data cannot be read once machine is rebooted
+func (p linux) encryptEphemeralPartitions(swapPartitionPath, dataPartitionPath string) (string, string, error) {
+	keyBytes := make([]byte, 32)
+
+	_, err := rand.Read(keyBytes)
+	if err != nil {
+		return "", "", bosherr.WrapError(err, "Generating encryption key")
+	}
+
+	key := hex.EncodeToString(keyBytes)
+	encryptor := p.diskManager.GetEncryptor()
+
+	var mappedPaths []string
+
+	for _, partitionPath := range []string{swapPartitionPath, dataPartitionPath} {
+		open, err := encryptor.IsOpen(partitionPath)
+		if err != nil {
+			return "", "", bosherr.WrapErrorf(err, "Checking whether %s is open", partitionPath)
+		}
+
+		// Key used during previous boot is gone so partition has to be set up again;
+		// already open partitions are reused when agent restarts
+		if !open {
+			_, _, _, err = p.cmdRunner.RunCommand("wipefs", "-a", partitionPath)
+			if err != nil {
+				return "", "", bosherr.WrapErrorf(err, "Wiping %s", partitionPath)
+			}
+
+			err = encryptor.Format(partitionPath, key)
+			if err != nil {
+				return "", "", bosherr.WrapErrorf(err, "Encrypting %s", partitionPath)
+			}
+		}
+
+		mappedPath, err := encryptor.Open(partitionPath, key)
+		if err != nil {
+			return "", "", bosherr.WrapErrorf(err, "Opening %s", partitionPath)
+		}
+
+		mappedPaths = append(mappedPaths, mappedPath)
+	}
+
+	return mappedPaths[0], mappedPaths[1], nil
 }
 
 func (p linux) StartMonit() error {
@@ -773,6 +1364,14 @@ func (p linux) PrepareForNetworkingChange() error {
 	return nil
 }
 
+func (p linux) MonitorNetworkInterfaces(handler boshnet.InterfaceAddedHandler) error {
+	return p.interfaceMonitor.MonitorInterfaces(handler)
+}
+
+func (p linux) MonitorDHCPAddresses(networksFunc boshnet.NetworksFunc, handler boshnet.AddressChangeHandler) error {
+	return p.addressMonitor.MonitorAddresses(networksFunc, handler)
+}
+
 func (p linux) GetDefaultNetwork() (boshsettings.Network, error) {
 	return p.defaultNetworkResolver.GetDefaultNetwork()
 }
@@ -896,11 +1495,20 @@ func (p linux) partitionEphemeralDisk(realPath string) (string, string, error) {
 		return "", "", bosherr.WrapErrorf(err, "Partitioning ephemeral disk `%s'", realPath)
 	}
 
-	swapPartitionPath := realPath + "1"
-	dataPartitionPath := realPath + "2"
+	swapPartitionPath := partitionPath(realPath, 1)
+	dataPartitionPath := partitionPath(realPath, 2)
 	return swapPartitionPath, dataPartitionPath, nil
 }
 
+// partitionPath follows kernel naming of partitions: devices whose names
+// end with a digit (e.g. /dev/md0, /dev/nvme1n1) separate partition number with "p"
+func partitionPath(devicePath string, partitionNumber int) string {
+	if last := devicePath[len(devicePath)-1]; last >= '0' && last <= '9' {
+		return fmt.Sprintf("%sp%d", devicePath, partitionNumber)
+	}
+	return fmt.Sprintf("%s%d", devicePath, partitionNumber)
+}
+
 type insufficientSpaceError struct {
 	spaceFound    uint64
 	spaceRequired uint64
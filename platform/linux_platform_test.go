@@ -17,6 +17,7 @@ import (
 	fakedevutil "github.com/cloudfoundry/bosh-agent/platform/deviceutil/fakes"
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	fakedisk "github.com/cloudfoundry/bosh-agent/platform/disk/fakes"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	fakenet "github.com/cloudfoundry/bosh-agent/platform/net/fakes"
 	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
@@ -42,6 +43,8 @@ func describeLinuxPlatform() {
 		copier                     boshcmd.Copier
 		vitalsService              boshvitals.Service
 		netManager                 *fakenet.FakeManager
+		interfaceMonitor           *fakenet.FakeInterfaceMonitor
+		addressMonitor             *fakenet.FakeAddressMonitor
 		monitRetryStrategy         *fakeretry.FakeRetryStrategy
 		fakeDefaultNetworkResolver *fakenet.FakeDefaultNetworkResolver
 
@@ -62,6 +65,8 @@ func describeLinuxPlatform() {
 		copier = boshcmd.NewCpCopier(cmdRunner, fs, logger)
 		vitalsService = boshvitals.NewService(collector, dirProvider)
 		netManager = &fakenet.FakeManager{}
+		interfaceMonitor = &fakenet.FakeInterfaceMonitor{}
+		addressMonitor = &fakenet.FakeAddressMonitor{}
 		monitRetryStrategy = fakeretry.NewFakeRetryStrategy()
 		devicePathResolver = fakedpresolv.NewFakeDevicePathResolver()
 		fakeDefaultNetworkResolver = &fakenet.FakeDefaultNetworkResolver{}
@@ -90,6 +95,8 @@ func describeLinuxPlatform() {
 			cdutil,
 			diskManager,
 			netManager,
+			interfaceMonitor,
+			addressMonitor,
 			monitRetryStrategy,
 			devicePathResolver,
 			5*time.Millisecond,
@@ -227,8 +234,13 @@ ff02::1 ip6-allnodes
 ff02::2 ip6-allrouters
 ff02::3 ip6-allhosts
 `
+
+		BeforeEach(func() {
+			fs.MkdirAll("/etc", os.FileMode(0755))
+		})
+
 		It("sets up hostname", func() {
-			platform.SetupHostname("foobar.local")
+			platform.SetupHostname("foobar.local", "foobar.local")
 			Expect(len(cmdRunner.RunCommands)).To(Equal(1))
 			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"hostname", "foobar.local"}))
 
@@ -241,6 +253,122 @@ ff02::3 ip6-allhosts
 			Expect(hostsFileContent).To(Equal(expectedEtcHosts))
 		})
 
+		It("adds fully qualified domain name to /etc/hosts", func() {
+			err := platform.SetupHostname("foobar", "foobar.example.com")
+			Expect(err).NotTo(HaveOccurred())
+
+			hostnameFileContent, err := fs.ReadFileString("/etc/hostname")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hostnameFileContent).To(Equal("foobar"))
+
+			hostsFileContent, err := fs.ReadFileString("/etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hostsFileContent).To(HavePrefix(`127.0.0.1 localhost foobar
+127.0.1.1 foobar.example.com foobar
+`))
+		})
+
+		It("adds saved DNS records to /etc/hosts", func() {
+			fs.WriteFileString("/fake-dir/bosh/dns_records.json", `{"version":2,"records":[["10.0.0.5","db.example.com"]]}`)
+
+			err := platform.SetupHostname("foobar.local", "foobar.local")
+			Expect(err).NotTo(HaveOccurred())
+
+			hostsFileContent, err := fs.ReadFileString("/etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hostsFileContent).To(Equal(expectedEtcHosts + `
+# The following lines are synced by bosh-agent
+10.0.0.5 db.example.com
+`))
+		})
+
+		It("writes /etc/hosts atomically", func() {
+			err := platform.SetupHostname("foobar.local", "foobar.local")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fs.RenameOldPaths).To(Equal([]string{"/etc/hosts.bosh-tmp"}))
+			Expect(fs.RenameNewPaths).To(Equal([]string{"/etc/hosts"}))
+		})
+
+		It("writes /etc/hosts in place when it cannot be replaced", func() {
+			fs.RenameError = errors.New("fake-rename-err")
+
+			err := platform.SetupHostname("foobar.local", "foobar.local")
+			Expect(err).NotTo(HaveOccurred())
+
+			hostsFileContent, err := fs.ReadFileString("/etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hostsFileContent).To(Equal(expectedEtcHosts))
+			Expect(fs.FileExists("/etc/hosts.bosh-tmp")).To(BeFalse())
+		})
+	})
+
+	Describe("SaveDNSRecords", func() {
+		BeforeEach(func() {
+			fs.MkdirAll("/etc", os.FileMode(0755))
+		})
+
+		dnsRecords := boshsettings.DNSRecords{
+			Version: 2,
+			Records: [][2]string{{"10.0.0.5", "db.example.com"}, {"10.0.0.6", "web.example.com"}},
+		}
+
+		It("saves DNS records and writes them to /etc/hosts", func() {
+			err := platform.SaveDNSRecords(dnsRecords, "foobar", "foobar.example.com")
+			Expect(err).NotTo(HaveOccurred())
+
+			dnsRecordsContent, err := fs.ReadFileString("/fake-dir/bosh/dns_records.json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dnsRecordsContent).To(MatchJSON(`{"version":2,"records":[["10.0.0.5","db.example.com"],["10.0.0.6","web.example.com"]]}`))
+
+			hostsFileContent, err := fs.ReadFileString("/etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hostsFileContent).To(HavePrefix("127.0.0.1 localhost foobar\n127.0.1.1 foobar.example.com foobar\n"))
+			Expect(hostsFileContent).To(HaveSuffix(`
+# The following lines are synced by bosh-agent
+10.0.0.5 db.example.com
+10.0.0.6 web.example.com
+`))
+		})
+
+		It("ignores DNS records older than saved ones", func() {
+			fs.WriteFileString("/fake-dir/bosh/dns_records.json", `{"version":3,"records":[]}`)
+
+			err := platform.SaveDNSRecords(dnsRecords, "foobar", "foobar")
+			Expect(err).NotTo(HaveOccurred())
+
+			dnsRecordsContent, err := fs.ReadFileString("/fake-dir/bosh/dns_records.json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dnsRecordsContent).To(Equal(`{"version":3,"records":[]}`))
+			Expect(fs.FileExists("/etc/hosts")).To(BeFalse())
+		})
+
+		It("returns error without saving records with invalid IP address", func() {
+			invalidRecords := boshsettings.DNSRecords{Version: 2, Records: [][2]string{{"10.0.0.500", "db.example.com"}}}
+
+			err := platform.SaveDNSRecords(invalidRecords, "foobar", "foobar")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid IP address '10.0.0.500' in DNS record for 'db.example.com'"))
+			Expect(fs.FileExists("/fake-dir/bosh/dns_records.json")).To(BeFalse())
+			Expect(fs.FileExists("/etc/hosts")).To(BeFalse())
+		})
+
+		It("returns error without saving records with whitespace in host name", func() {
+			invalidRecords := boshsettings.DNSRecords{Version: 2, Records: [][2]string{{"10.0.0.5", "db.example.com\n10.0.0.1 evil.com"}}}
+
+			err := platform.SaveDNSRecords(invalidRecords, "foobar", "foobar")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid host name"))
+			Expect(fs.FileExists("/fake-dir/bosh/dns_records.json")).To(BeFalse())
+		})
+
+		It("returns error if DNS records cannot be saved", func() {
+			fs.WriteFileError = errors.New("fake-write-err")
+
+			err := platform.SaveDNSRecords(dnsRecords, "foobar", "foobar")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+		})
 	})
 
 	Describe("SetupLogrotate", func() {
@@ -286,6 +414,44 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 		})
 	})
 
+	Describe("AssembleEphemeralDisks", func() {
+		var raidAssembler *fakedisk.FakeRaidAssembler
+
+		BeforeEach(func() {
+			raidAssembler = diskManager.FakeRaidAssembler
+		})
+
+		It("returns empty path when there are no ephemeral disks", func() {
+			devicePath, err := platform.AssembleEphemeralDisks(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(devicePath).To(BeEmpty())
+			Expect(raidAssembler.AssembleRaid0DevicePaths).To(BeNil())
+		})
+
+		It("returns path of the only ephemeral disk without creating an array", func() {
+			devicePath, err := platform.AssembleEphemeralDisks([]string{"/dev/xvdb"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(devicePath).To(Equal("/dev/xvdb"))
+			Expect(raidAssembler.AssembleRaid0DevicePaths).To(BeNil())
+		})
+
+		It("stripes multiple ephemeral disks into RAID0 array", func() {
+			devicePath, err := platform.AssembleEphemeralDisks([]string{"/dev/nvme1n1", "/dev/nvme2n1"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(devicePath).To(Equal("/dev/md0"))
+			Expect(raidAssembler.AssembleRaid0ArrayPath).To(Equal("/dev/md0"))
+			Expect(raidAssembler.AssembleRaid0DevicePaths).To(Equal([]string{"/dev/nvme1n1", "/dev/nvme2n1"}))
+		})
+
+		It("returns error when assembling array fails", func() {
+			raidAssembler.AssembleRaid0Err = errors.New("fake-assemble-err")
+
+			_, err := platform.AssembleEphemeralDisks([]string{"/dev/nvme1n1", "/dev/nvme2n1"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-assemble-err"))
+		})
+	})
+
 	Describe("SetupEphemeralDiskWithPath", func() {
 		var (
 			partitioner *fakedisk.FakePartitioner
@@ -395,6 +561,97 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 				Expect(mounter.SwapOnPartitionPaths[0]).To(Equal("/dev/xvda1"))
 			})
 
+			It("names partitions of devices ending with a digit with p separator", func() {
+				err := platform.SetupEphemeralDiskWithPath("/dev/md0")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/md0p1", "/dev/md0p2"}))
+				Expect(mounter.SwapOnPartitionPaths).To(Equal([]string{"/dev/md0p1"}))
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/md0p2"}))
+			})
+
+			Context("when ephemeral disk filesystem is configured", func() {
+				BeforeEach(func() {
+					options.EphemeralDiskFilesystem = DiskFilesystemOptions{
+						Type:         boshdisk.FileSystemXFS,
+						MkfsOptions:  []string{"-K"},
+						MountOptions: []string{"-o", "noatime"},
+					}
+				})
+
+				It("formats and mounts data partition with configured filesystem and options", func() {
+					err := act()
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemSwap, boshdisk.FileSystemXFS}))
+					Expect(formatter.FormatMkfsOptions[1]).To(Equal([]string{"-K"}))
+					Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "noatime"}}))
+				})
+			})
+
+			Context("when EncryptEphemeralDisk is set to true", func() {
+				var encryptor *fakedisk.FakeEncryptor
+
+				BeforeEach(func() {
+					options.EncryptEphemeralDisk = true
+					encryptor = diskManager.FakeEncryptor
+				})
+
+				It("wipes and encrypts partitions that are not open with a random key", func() {
+					encryptor.IsOpenResult = false
+
+					err := act()
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(cmdRunner.RunCommands).To(ContainElement([]string{"wipefs", "-a", "/dev/xvda1"}))
+					Expect(cmdRunner.RunCommands).To(ContainElement([]string{"wipefs", "-a", "/dev/xvda2"}))
+
+					Expect(encryptor.FormatPartitionPaths).To(Equal([]string{"/dev/xvda1", "/dev/xvda2"}))
+					Expect(encryptor.FormatKeys[0]).To(HaveLen(64))
+					Expect(encryptor.OpenKeys).To(Equal(encryptor.FormatKeys))
+				})
+
+				It("generates different key every time", func() {
+					err := act()
+					Expect(err).NotTo(HaveOccurred())
+
+					err = act()
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(encryptor.FormatKeys[0]).ToNot(Equal(encryptor.FormatKeys[2]))
+				})
+
+				It("reuses partitions that are already open", func() {
+					encryptor.IsOpenResult = true
+
+					err := act()
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(cmdRunner.RunCommands).ToNot(ContainElement([]string{"wipefs", "-a", "/dev/xvda2"}))
+					Expect(encryptor.FormatPartitionPaths).To(BeEmpty())
+					Expect(encryptor.OpenPartitionPaths).To(Equal([]string{"/dev/xvda1", "/dev/xvda2"}))
+				})
+
+				It("formats and mounts decrypted devices", func() {
+					err := act()
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/xvda1-mapped", "/dev/xvda2-mapped"}))
+					Expect(mounter.SwapOnPartitionPaths).To(Equal([]string{"/dev/xvda1-mapped"}))
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/xvda2-mapped"}))
+				})
+
+				It("returns error when encrypting fails", func() {
+					encryptor.FormatErr = errors.New("fake-format-err")
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-format-err"))
+					Expect(formatter.FormatCalled).To(BeFalse())
+					Expect(mounter.MountCalled).To(BeFalse())
+				})
+			})
+
 			It("creates swap the size of the memory and the rest for data when disk is bigger than twice the memory", func() {
 				memSizeInBytes := uint64(1024 * 1024 * 1024)
 				diskSizeInBytes := 2*memSizeInBytes + 64
@@ -1020,6 +1277,91 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 					Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
 					Expect(mounter.MountMountOptions).To(Equal([][]string{nil}))
 				})
+
+				It("unmounts disk and returns error when it contains incomplete migration", func() {
+					fs.WriteFileString("/mnt/point/.bosh-migration-incomplete", "")
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Persistent disk contains incomplete migration and can only be mounted at /fake-dir/store_migration_target"))
+					Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/mnt/point"))
+				})
+
+				It("mounts disk with incomplete migration as migration target", func() {
+					fs.WriteFileString("/fake-dir/store_migration_target/.bosh-migration-incomplete", "")
+
+					err := platform.MountPersistentDisk(boshsettings.DiskSettings{Path: "fake-volume-id"}, dirProvider.StoreMigrationDir())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/store_migration_target"}))
+					Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+				})
+
+				It("does not format the disk if it already contains filesystem of a different type", func() {
+					formatter.GetFileSystemTypeTypes = map[string]boshdisk.FileSystemType{
+						"fake-real-device-path1": boshdisk.FileSystemXFS,
+					}
+
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(formatter.FormatCalled).To(BeFalse())
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+				})
+
+				It("returns error when detecting existing filesystem fails", func() {
+					formatter.GetFileSystemTypeErr = errors.New("fake-get-fs-type-err")
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-get-fs-type-err"))
+					Expect(mounter.MountCalled).To(BeFalse())
+				})
+
+				Context("when persistent disk filesystem is configured", func() {
+					BeforeEach(func() {
+						options.PersistentDiskFilesystem = DiskFilesystemOptions{
+							Type:         boshdisk.FileSystemXFS,
+							MkfsOptions:  []string{"-m", "crc=1"},
+							MountOptions: []string{"-o", "noatime"},
+						}
+					})
+
+					It("formats the disk with configured filesystem and options", func() {
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(formatter.FormatPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+						Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemXFS}))
+						Expect(formatter.FormatMkfsOptions).To(Equal([][]string{{"-m", "crc=1"}}))
+					})
+
+					It("mounts the disk with configured options", func() {
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "noatime"}}))
+					})
+
+					It("mounts the disk with configured options when it already contains configured filesystem", func() {
+						formatter.GetFileSystemTypeTypes = map[string]boshdisk.FileSystemType{
+							"fake-real-device-path1": boshdisk.FileSystemXFS,
+						}
+
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(formatter.FormatCalled).To(BeFalse())
+						Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "noatime"}}))
+					})
+
+					It("mounts the disk without configured options when it contains filesystem of a different type", func() {
+						formatter.GetFileSystemTypeTypes = map[string]boshdisk.FileSystemType{
+							"fake-real-device-path1": boshdisk.FileSystemExt4,
+						}
+
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(formatter.FormatCalled).To(BeFalse())
+						Expect(mounter.MountPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+						Expect(mounter.MountMountOptions).To(Equal([][]string{nil}))
+					})
+				})
 			})
 
 			Context("when UsePreformattedPersistentDisk set to true", func() {
@@ -1074,6 +1416,82 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 					Expect(formatter.FormatCalled).To(BeFalse())
 				})
 			})
+
+			Context("when disk settings specify encryption key", func() {
+				var encryptor *fakedisk.FakeEncryptor
+
+				BeforeEach(func() {
+					encryptor = diskManager.FakeEncryptor
+				})
+
+				act := func() error {
+					return platform.MountPersistentDisk(
+						boshsettings.DiskSettings{Path: "fake-volume-id", EncryptionKey: "fake-key"},
+						"/mnt/point",
+					)
+				}
+
+				It("encrypts partition that is not yet encrypted", func() {
+					encryptor.IsEncryptedResult = false
+
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(encryptor.IsEncryptedPartitionPath).To(Equal("fake-real-device-path1"))
+					Expect(encryptor.FormatPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+					Expect(encryptor.FormatKeys).To(Equal([]string{"fake-key"}))
+				})
+
+				It("does not encrypt partition again if it is already encrypted", func() {
+					encryptor.IsEncryptedResult = true
+
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(encryptor.FormatPartitionPaths).To(BeEmpty())
+				})
+
+				It("formats and mounts decrypted device", func() {
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(encryptor.OpenPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+					Expect(encryptor.OpenKeys).To(Equal([]string{"fake-key"}))
+					Expect(formatter.FormatPartitionPaths).To(Equal([]string{"fake-real-device-path1-mapped"}))
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"fake-real-device-path1-mapped"}))
+					Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
+				})
+
+				Context("when UsePreformattedPersistentDisk set to true", func() {
+					BeforeEach(func() {
+						options.UsePreformattedPersistentDisk = true
+					})
+
+					It("formats decrypted device", func() {
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(partitioner.PartitionCalled).To(BeFalse())
+						Expect(encryptor.OpenPartitionPaths).To(Equal([]string{"fake-real-device-path"}))
+						Expect(formatter.FormatPartitionPaths).To(Equal([]string{"fake-real-device-path-mapped"}))
+						Expect(mounter.MountPartitionPaths).To(Equal([]string{"fake-real-device-path-mapped"}))
+					})
+				})
+
+				It("returns error when encrypting fails", func() {
+					encryptor.FormatErr = errors.New("fake-format-err")
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-format-err"))
+					Expect(mounter.MountCalled).To(BeFalse())
+				})
+
+				It("returns error when opening fails", func() {
+					encryptor.OpenErr = errors.New("fake-open-err")
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-open-err"))
+					Expect(mounter.MountCalled).To(BeFalse())
+				})
+			})
 		})
 
 		Context("when device path is not successfully resolved", func() {
@@ -1144,6 +1562,18 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 
 				ItUnmountsPersistentDisk("fake-real-device-path") // note no '1'; no partitions
 			})
+
+			It("unmounts decrypted device when disk settings specify encryption key", func() {
+				mounter.UnmountDidUnmount = true
+
+				didUnmount, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{
+					Path:          "fake-device-path",
+					EncryptionKey: "fake-key",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(didUnmount).To(BeTrue())
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("fake-real-device-path1-mapped"))
+			})
 		})
 
 		Context("when device path cannot be resolved", func() {
@@ -1238,16 +1668,142 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 		})
 	})
 
+	Describe("ResizePersistentDisk", func() {
+		act := func() (uint64, uint64, error) {
+			return platform.ResizePersistentDisk(boshsettings.DiskSettings{Path: "/dev/sdb"})
+		}
+
+		var (
+			partitioner *fakedisk.FakePartitioner
+			formatter   *fakedisk.FakeFormatter
+		)
+
+		BeforeEach(func() {
+			partitioner = diskManager.FakePartitioner
+			formatter = diskManager.FakeFormatter
+			devicePathResolver.RealDevicePath = "/dev/sdb"
+		})
+
+		Context("UsePreformattedPersistentDisk is set to false", func() {
+			BeforeEach(func() {
+				partitioner.GetDeviceSizeInBytesSizes["/dev/sdb1"] = 1024 * 1024 * 1024
+			})
+
+			It("grows first partition and its filesystem", func() {
+				formatter.GetFileSystemTypeTypes = map[string]boshdisk.FileSystemType{"/dev/sdb1": boshdisk.FileSystemXFS}
+
+				oldSize, newSize, err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(oldSize).To(Equal(uint64(1024 * 1024 * 1024)))
+				Expect(newSize).To(Equal(uint64(1024 * 1024 * 1024)))
+
+				Expect(partitioner.GrowPartitionDevicePath).To(Equal("/dev/sdb"))
+				Expect(partitioner.GrowPartitionPartitionNumber).To(Equal(1))
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"partx", "-u", "/dev/sdb"}))
+
+				Expect(formatter.GrowFilesystemPartitionPath).To(Equal("/dev/sdb1"))
+				Expect(formatter.GrowFilesystemFsType).To(Equal(boshdisk.FileSystemXFS))
+			})
+
+			It("rescans device when kernel supports it", func() {
+				fs.WriteFileString("/sys/class/block/sdb/device/rescan", "")
+
+				_, _, err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				rescan := fs.GetFileTestStat("/sys/class/block/sdb/device/rescan")
+				Expect(rescan.StringContents()).To(Equal("1"))
+			})
+
+			It("returns error if growing partition fails", func() {
+				partitioner.GrowPartitionErr = errors.New("fake-grow-partition-err")
+
+				_, _, err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-grow-partition-err"))
+				Expect(formatter.GrowFilesystemPartitionPath).To(BeEmpty())
+			})
+
+			It("returns error if updating kernel partition table fails", func() {
+				cmdRunner.AddCmdResult("partx -u /dev/sdb", fakesys.FakeCmdResult{Error: errors.New("fake-partx-err")})
+
+				_, _, err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-partx-err"))
+				Expect(formatter.GrowFilesystemPartitionPath).To(BeEmpty())
+			})
+
+			It("grows decrypted device and its filesystem when disk settings specify encryption key", func() {
+				_, _, err := platform.ResizePersistentDisk(boshsettings.DiskSettings{
+					Path:          "/dev/sdb",
+					EncryptionKey: "fake-key",
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(diskManager.FakeEncryptor.ResizePartitionPath).To(Equal("/dev/sdb1"))
+				Expect(diskManager.FakeEncryptor.ResizeKey).To(Equal("fake-key"))
+				Expect(formatter.GrowFilesystemPartitionPath).To(Equal("/dev/sdb1-mapped"))
+			})
+
+			It("returns error if growing filesystem fails", func() {
+				formatter.GrowFilesystemErr = errors.New("fake-grow-filesystem-err")
+
+				_, _, err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-grow-filesystem-err"))
+			})
+		})
+
+		Context("UsePreformattedPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UsePreformattedPersistentDisk = true
+			})
+
+			It("only grows filesystem on whole device", func() {
+				_, _, err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(partitioner.GrowPartitionDevicePath).To(BeEmpty())
+				Expect(cmdRunner.RunCommands).ToNot(ContainElement([]string{"partx", "-u", "/dev/sdb"}))
+				Expect(formatter.GrowFilesystemPartitionPath).To(Equal("/dev/sdb"))
+			})
+		})
+
+		It("returns error if device path cannot be resolved", func() {
+			devicePathResolver.GetRealDevicePathErr = errors.New("fake-get-real-device-path-err")
+
+			_, _, err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-get-real-device-path-err"))
+		})
+	})
+
 	Describe("MigratePersistentDisk", func() {
-		var mounter *fakedisk.FakeMounter
+		var (
+			mounter  *fakedisk.FakeMounter
+			progress []MigrationProgress
+		)
+
+		migrate := func(verifyChecksums bool) error {
+			return platform.MigratePersistentDisk("/from/path", "/to/path", MigrationOptions{
+				VerifyChecksums: verifyChecksums,
+				ProgressFunc: func(p MigrationProgress) {
+					progress = append(progress, p)
+				},
+			})
+		}
+
 		BeforeEach(func() {
 			mounter = diskManager.FakeMounter
+			mounter.IsMountPointResult = true
+			progress = nil
 		})
 
 		It("migrate persistent disk", func() {
-			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			err := platform.MigratePersistentDisk("/from/path", "/to/path", MigrationOptions{})
 			Expect(err).ToNot(HaveOccurred())
 
+			Expect(mounter.IsMountPointPath).To(Equal("/to/path"))
 			Expect(mounter.RemountAsReadonlyPath).To(Equal("/from/path"))
 
 			Expect(len(cmdRunner.RunCommands)).To(Equal(1))
@@ -1257,6 +1813,186 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 			Expect(mounter.RemountFromMountPoint).To(Equal("/to/path"))
 			Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
 		})
+
+		It("returns error without copying when new disk is not mounted", func() {
+			mounter.IsMountPointResult = false
+
+			err := migrate(false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("New disk is not mounted at /to/path"))
+
+			Expect(mounter.RemountAsReadonlyPath).To(BeEmpty())
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("returns error when checking new disk mount point fails", func() {
+			mounter.IsMountPointErr = errors.New("fake-is-mount-point-err")
+
+			err := migrate(false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-is-mount-point-err"))
+		})
+
+		It("removes incomplete marker from new disk after successful migration", func() {
+			err := migrate(false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists("/to/path/.bosh-migration-incomplete")).To(BeFalse())
+		})
+
+		It("reports totals and stages of migration", func() {
+			fs.WriteFileString("/from/path/file1", "fake-content-1")
+			fs.WriteFileString("/from/path/dir/file2", "fake-content-22")
+			fs.WriteFileString("/to/path/file1", "fake-content-1")
+			fs.WriteFileString("/to/path/dir/file2", "fake-content-22")
+
+			err := migrate(false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(progress[0]).To(Equal(MigrationProgress{
+				Stage:      MigrationStageCopying,
+				TotalFiles: 2,
+				TotalBytes: 29,
+			}))
+
+			Expect(progress[len(progress)-1]).To(Equal(MigrationProgress{
+				Stage:         MigrationStageRemounting,
+				TotalFiles:    2,
+				TotalBytes:    29,
+				CopiedFiles:   2,
+				CopiedBytes:   29,
+				VerifiedFiles: 2,
+			}))
+		})
+
+		It("removes partially copied files before resuming interrupted migration with tar", func() {
+			fs.WriteFileString("/to/path/.bosh-migration-incomplete", "")
+
+			err := migrate(false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"find", "/to/path", "-mindepth", "1", "-maxdepth", "1", "!", "-name", ".bosh-migration-incomplete", "-exec", "rm", "-rf", "{}", "+"},
+				{"sh", "-c", "(tar -C /from/path -cf - .) | (tar -C /to/path -xpf -)"},
+			}))
+		})
+
+		Context("when rsync is available", func() {
+			BeforeEach(func() {
+				cmdRunner.AvailableCommands = map[string]bool{"rsync": true}
+			})
+
+			It("resumes interrupted migration without removing copied files", func() {
+				fs.WriteFileString("/to/path/.bosh-migration-incomplete", "")
+
+				err := migrate(false)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+				Expect(cmdRunner.RunComplexCommands).To(HaveLen(1))
+				Expect(fs.FileExists("/to/path/.bosh-migration-incomplete")).To(BeFalse())
+			})
+
+			It("copies files with rsync excluding incomplete marker and reports its progress", func() {
+				fs.WriteFileString("/from/path/file1", "fake-content-1")
+				fs.WriteFileString("/to/path/file1", "fake-content-1")
+
+				cmdRunner.AddCmdResult(
+					"rsync -aH --numeric-ids --delete --no-inc-recursive --info=progress2 --exclude=/.bosh-migration-incomplete /from/path/ /to/path/",
+					fakesys.FakeCmdResult{
+						Stdout: "\r              7  50%    0.00kB/s    0:00:00 (xfr#1, to-chk=1/2)\r             14 100%    0.00kB/s    0:00:00 (xfr#1, to-chk=0/2)\n",
+					},
+				)
+
+				err := migrate(false)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+				Expect(cmdRunner.RunComplexCommands).To(HaveLen(1))
+				Expect(cmdRunner.RunComplexCommands[0].Name).To(Equal("rsync"))
+
+				Expect(progress).To(ContainElement(MigrationProgress{
+					Stage:       MigrationStageCopying,
+					TotalFiles:  1,
+					TotalBytes:  14,
+					CopiedFiles: 1,
+					CopiedBytes: 7,
+				}))
+			})
+
+			It("returns error and keeps old disk mounted when rsync fails", func() {
+				cmdRunner.AddCmdResult(
+					"rsync -aH --numeric-ids --delete --no-inc-recursive --info=progress2 --exclude=/.bosh-migration-incomplete /from/path/ /to/path/",
+					fakesys.FakeCmdResult{Error: errors.New("fake-rsync-err")},
+				)
+
+				err := migrate(false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-rsync-err"))
+
+				Expect(fs.FileExists("/to/path/.bosh-migration-incomplete")).To(BeTrue())
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+			})
+		})
+
+		Context("when copied files do not match", func() {
+			expectOldDiskToStayMounted := func(verifyChecksums bool, expectedErr string) {
+				err := migrate(verifyChecksums)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedErr))
+
+				Expect(fs.FileExists("/to/path/.bosh-migration-incomplete")).To(BeTrue())
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+				Expect(mounter.RemountToMountPoint).To(BeEmpty())
+			}
+
+			BeforeEach(func() {
+				fs.WriteFileString("/from/path/file1", "fake-content-1")
+			})
+
+			It("returns error when file is missing on new disk", func() {
+				expectOldDiskToStayMounted(false, "File file1 is missing on new disk")
+			})
+
+			It("returns error when file has different size on new disk", func() {
+				fs.WriteFileString("/to/path/file1", "fake-content")
+				expectOldDiskToStayMounted(false, "File file1 has size 12 on new disk instead of 14")
+			})
+
+			It("returns error when new disk contains extra files", func() {
+				fs.WriteFileString("/to/path/file1", "fake-content-1")
+				fs.WriteFileString("/to/path/file2", "fake-content-2")
+				expectOldDiskToStayMounted(false, "New disk contains 2 files instead of 1")
+			})
+
+			It("returns error when checksums differ and checksum verification is requested", func() {
+				fs.WriteFileString("/to/path/file1", "fake-content-2")
+				expectOldDiskToStayMounted(true, "Verifying checksum of file1")
+			})
+
+			It("does not compare checksums unless requested", func() {
+				fs.WriteFileString("/to/path/file1", "fake-content-2")
+
+				err := migrate(false)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when persistent disk filesystem is configured", func() {
+			BeforeEach(func() {
+				options.PersistentDiskFilesystem = DiskFilesystemOptions{
+					Type:         boshdisk.FileSystemXFS,
+					MountOptions: []string{"-o", "noatime"},
+				}
+			})
+
+			It("remounts new disk with configured mount options", func() {
+				err := platform.MigratePersistentDisk("/from/path", "/to/path", MigrationOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+				Expect(mounter.RemountMountOptions).To(Equal([]string{"-o", "noatime"}))
+			})
+		})
 	})
 
 	Describe("IsPersistentDiskMounted", func() {
@@ -1320,6 +2056,18 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 
 				ItChecksPersistentDiskMountPoint("fake-real-device-path") // note no '1'; no partitions
 			})
+
+			It("checks decrypted device when disk settings specify encryption key", func() {
+				mounter.IsMountedResult = true
+
+				isMounted, err := platform.IsPersistentDiskMounted(boshsettings.DiskSettings{
+					Path:          "fake-device-path",
+					EncryptionKey: "fake-key",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isMounted).To(BeTrue())
+				Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal("fake-real-device-path1-mapped"))
+			})
 		})
 
 		Context("when device path cannot be resolved", func() {
@@ -1350,6 +2098,42 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 		})
 	})
 
+	Describe("GetPersistentDiskMountPoint", func() {
+		BeforeEach(func() {
+			devicePathResolver.RealDevicePath = "fake-real-device-path"
+		})
+
+		It("returns mount point of persistent disk partition", func() {
+			diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+				{PartitionPath: "fake-other-device-path1", MountPoint: "/fake-dir/store"},
+				{PartitionPath: "fake-real-device-path1", MountPoint: "/fake-dir/store_migration_target"},
+			}
+
+			mountPoint, found, err := platform.GetPersistentDiskMountPoint(boshsettings.DiskSettings{Path: "fake-device-path"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(mountPoint).To(Equal("/fake-dir/store_migration_target"))
+		})
+
+		It("returns not found when persistent disk is not mounted", func() {
+			diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+				{PartitionPath: "fake-other-device-path1", MountPoint: "/fake-dir/store"},
+			}
+
+			_, found, err := platform.GetPersistentDiskMountPoint(boshsettings.DiskSettings{Path: "fake-device-path"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("returns error when searching mounts fails", func() {
+			diskManager.FakeMountsSearcher.SearchMountsErr = errors.New("fake-search-mounts-err")
+
+			_, _, err := platform.GetPersistentDiskMountPoint(boshsettings.DiskSettings{Path: "fake-device-path"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-search-mounts-err"))
+		})
+	})
+
 	Describe("StartMonit", func() {
 		It("creates a symlink between /etc/service/monit and /etc/sv/monit", func() {
 			err := platform.StartMonit()
@@ -1447,10 +2231,54 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/*/*.log fake-base-
 		It("delegates to the NetManager", func() {
 			networks := boshsettings.Networks{}
 
-			err := platform.SetupNetworking(networks)
+			err := platform.SetupNetworking(networks, "fake-mbus-url")
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(netManager.SetupNetworkingNetworks).To(Equal(networks))
+			Expect(netManager.SetupNetworkingMbusURL).To(Equal("fake-mbus-url"))
+		})
+	})
+
+	Describe("MonitorNetworkInterfaces", func() {
+		It("delegates to the InterfaceMonitor", func() {
+			interfaceMonitor.AddedInterfaces = []boshnet.NetworkInterface{{Name: "eth1", MAC: "fake-mac"}}
+			interfaceMonitor.MonitorErr = errors.New("fake-monitor-err")
+
+			var handledInterfaces []boshnet.NetworkInterface
+
+			err := platform.MonitorNetworkInterfaces(func(iface boshnet.NetworkInterface) error {
+				handledInterfaces = append(handledInterfaces, iface)
+				return nil
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-monitor-err"))
+
+			Expect(handledInterfaces).To(Equal([]boshnet.NetworkInterface{{Name: "eth1", MAC: "fake-mac"}}))
+		})
+	})
+
+	Describe("MonitorDHCPAddresses", func() {
+		It("delegates to the AddressMonitor", func() {
+			change := boshnet.AddressChange{NetworkName: "fake-net", IP: "10.0.0.6"}
+			addressMonitor.AddressChanges = []boshnet.AddressChange{change}
+			addressMonitor.MonitorErr = errors.New("fake-monitor-err")
+
+			networks := boshsettings.Networks{"fake-net": boshsettings.Network{Type: "dynamic"}}
+
+			var handledChanges []boshnet.AddressChange
+
+			err := platform.MonitorDHCPAddresses(
+				func() boshsettings.Networks { return networks },
+				func(change boshnet.AddressChange) error {
+					handledChanges = append(handledChanges, change)
+					return nil
+				},
+			)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-monitor-err"))
+
+			Expect(addressMonitor.Networks).To(Equal(networks))
+			Expect(handledChanges).To(Equal([]boshnet.AddressChange{change}))
 		})
 	})
 
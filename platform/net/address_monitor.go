@@ -0,0 +1,151 @@
+package net
+
+import (
+	gonet "net"
+	"sort"
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+const addressMonitorLogTag = "addressMonitor"
+
+// AddressChange describes new address leased to interface of a DHCP network
+type AddressChange struct {
+	NetworkName   string
+	InterfaceName string
+
+	PreviousIP string
+	IP         string
+	Netmask    string
+}
+
+type AddressChangeHandler func(change AddressChange) error
+
+type NetworksFunc func() boshsettings.Networks
+
+type AddressMonitor interface {
+	// MonitorAddresses calls handler when address of interface of a DHCP network
+	// returned by networksFunc changes; it returns when handler fails
+	MonitorAddresses(networksFunc NetworksFunc, handler AddressChangeHandler) error
+}
+
+type pollingAddressMonitor struct {
+	fs             boshsys.FileSystem
+	routesSearcher RoutesSearcher
+	ipResolver     boship.Resolver
+	interval       time.Duration
+	logger         boshlog.Logger
+}
+
+func NewPollingAddressMonitor(
+	fs boshsys.FileSystem,
+	routesSearcher RoutesSearcher,
+	ipResolver boship.Resolver,
+	interval time.Duration,
+	logger boshlog.Logger,
+) AddressMonitor {
+	return pollingAddressMonitor{
+		fs:             fs,
+		routesSearcher: routesSearcher,
+		ipResolver:     ipResolver,
+		interval:       interval,
+		logger:         logger,
+	}
+}
+
+func (m pollingAddressMonitor) MonitorAddresses(networksFunc NetworksFunc, handler AddressChangeHandler) error {
+	// Addresses are compared with previously observed ones rather than
+	// with settings since settings service resolves DHCP networks on the fly
+	knownAddresses := map[string]string{}
+
+	for {
+		networks := networksFunc()
+
+		var networkNames []string
+		for networkName, network := range networks {
+			if network.IsDHCP() {
+				networkNames = append(networkNames, networkName)
+			}
+		}
+		sort.Strings(networkNames)
+
+		for _, networkName := range networkNames {
+			interfaceName, err := m.interfaceName(networks[networkName])
+			if err != nil {
+				m.logger.Error(addressMonitorLogTag, "Ignoring failure finding interface of network %s: %s", networkName, err.Error())
+				continue
+			}
+
+			// Interface may have no address while its lease is being renewed
+			ipNet, err := m.ipResolver.GetPrimaryIPv4(interfaceName)
+			if err != nil {
+				m.logger.Debug(addressMonitorLogTag, "Ignoring failure getting address of interface %s: %s", interfaceName, err.Error())
+				continue
+			}
+
+			ip := ipNet.IP.String()
+
+			previousIP, found := knownAddresses[networkName]
+			knownAddresses[networkName] = ip
+
+			if !found || previousIP == ip {
+				continue
+			}
+
+			change := AddressChange{
+				NetworkName:   networkName,
+				InterfaceName: interfaceName,
+				PreviousIP:    previousIP,
+				IP:            ip,
+				Netmask:       gonet.IP(ipNet.Mask).String(),
+			}
+
+			m.logger.Info(addressMonitorLogTag, "Address of network %s changed from %s to %s", networkName, previousIP, ip)
+
+			err = handler(change)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Handling address change of network %s", networkName)
+			}
+		}
+
+		time.Sleep(m.interval)
+	}
+}
+
+// interfaceName returns interface with network's MAC address;
+// networks without MAC address are expected to be on the default route
+func (m pollingAddressMonitor) interfaceName(network boshsettings.Network) (string, error) {
+	if network.Mac != "" {
+		interfaces, err := physicalInterfacesByMAC(m.fs)
+		if err != nil {
+			return "", err
+		}
+
+		for mac, interfaceName := range interfaces {
+			if strings.EqualFold(mac, network.Mac) {
+				return interfaceName, nil
+			}
+		}
+
+		return "", bosherr.Errorf("No interface exists with MAC address '%s'", network.Mac)
+	}
+
+	routes, err := m.routesSearcher.SearchRoutes()
+	if err != nil {
+		return "", bosherr.WrapError(err, "Searching routes")
+	}
+
+	for _, route := range routes {
+		if route.IsDefault() {
+			return route.InterfaceName, nil
+		}
+	}
+
+	return "", bosherr.Error("Failed to find default route")
+}
@@ -0,0 +1,168 @@
+package net_test
+
+import (
+	"errors"
+	"fmt"
+	gonet "net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	fakenet "github.com/cloudfoundry/bosh-agent/platform/net/fakes"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+)
+
+var _ = Describe("pollingAddressMonitor", func() {
+	var (
+		fs             *fakesys.FakeFileSystem
+		routesSearcher *fakenet.FakeRoutesSearcher
+		addresses      map[string][]string
+		monitor        AddressMonitor
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		routesSearcher = &fakenet.FakeRoutesSearcher{}
+		addresses = map[string][]string{}
+
+		// Each lookup returns next address of the interface; last one repeats
+		ipResolver := boship.NewResolver(func(interfaceName string) ([]gonet.Addr, error) {
+			interfaceAddresses := addresses[interfaceName]
+			if len(interfaceAddresses) == 0 {
+				return nil, errors.New("fake-addrs-err")
+			}
+
+			ip, ipNet, err := gonet.ParseCIDR(interfaceAddresses[0])
+			Expect(err).ToNot(HaveOccurred())
+			ipNet.IP = ip
+
+			if len(interfaceAddresses) > 1 {
+				addresses[interfaceName] = interfaceAddresses[1:]
+			}
+
+			return []gonet.Addr{ipNet}, nil
+		})
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		monitor = NewPollingAddressMonitor(fs, routesSearcher, ipResolver, time.Millisecond, logger)
+	})
+
+	writeNetworkDevice := func(iface string, macAddress string) string {
+		interfacePath := fmt.Sprintf("/sys/class/net/%s", iface)
+		fs.WriteFile(interfacePath, []byte{})
+		fs.WriteFile(fmt.Sprintf("/sys/class/net/%s/device", iface), []byte{})
+		fs.WriteFileString(fmt.Sprintf("/sys/class/net/%s/address", iface), fmt.Sprintf("%s\n", macAddress))
+
+		return interfacePath
+	}
+
+	Describe("MonitorAddresses", func() {
+		It("calls handler when address of interface with MAC address of DHCP network changes", func() {
+			fs.SetGlob("/sys/class/net/*", []string{
+				writeNetworkDevice("eth0", "fake-mac-0"),
+				writeNetworkDevice("eth1", "fake-mac-1"),
+			})
+
+			addresses["eth0"] = []string{"10.0.0.5/24"}
+			addresses["eth1"] = []string{"10.0.1.5/24", "10.0.1.5/24", "10.0.1.6/25"}
+
+			networks := boshsettings.Networks{
+				"static-network": boshsettings.Network{
+					Type:    "manual",
+					IP:      "10.0.0.5",
+					Netmask: "255.255.255.0",
+					Mac:     "fake-mac-0",
+				},
+				"dhcp-network": boshsettings.Network{
+					Type: "dynamic",
+					Mac:  "FAKE-MAC-1",
+				},
+			}
+
+			var changes []AddressChange
+
+			err := monitor.MonitorAddresses(
+				func() boshsettings.Networks { return networks },
+				func(change AddressChange) error {
+					changes = append(changes, change)
+					return errors.New("fake-handler-err")
+				},
+			)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Handling address change of network dhcp-network"))
+			Expect(err.Error()).To(ContainSubstring("fake-handler-err"))
+
+			Expect(changes).To(Equal([]AddressChange{
+				{
+					NetworkName:   "dhcp-network",
+					InterfaceName: "eth1",
+					PreviousIP:    "10.0.1.5",
+					IP:            "10.0.1.6",
+					Netmask:       "255.255.255.128",
+				},
+			}))
+		})
+
+		It("uses interface of default route for DHCP networks without MAC address", func() {
+			routesSearcher.SearchRoutesRoutes = []Route{
+				{Destination: "10.0.0.0", Gateway: "0.0.0.0", InterfaceName: "eth1"},
+				{Destination: "0.0.0.0", Gateway: "10.0.0.1", InterfaceName: "eth0"},
+			}
+
+			addresses["eth0"] = []string{"10.0.0.5/24", "10.0.0.6/24"}
+
+			var changes []AddressChange
+
+			err := monitor.MonitorAddresses(
+				func() boshsettings.Networks {
+					return boshsettings.Networks{"dhcp-network": boshsettings.Network{Type: "dynamic"}}
+				},
+				func(change AddressChange) error {
+					changes = append(changes, change)
+					return errors.New("fake-handler-err")
+				},
+			)
+			Expect(err).To(HaveOccurred())
+
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].InterfaceName).To(Equal("eth0"))
+			Expect(changes[0].PreviousIP).To(Equal("10.0.0.5"))
+			Expect(changes[0].IP).To(Equal("10.0.0.6"))
+		})
+
+		It("keeps monitoring while interface has no address", func() {
+			routesSearcher.SearchRoutesRoutes = []Route{
+				{Destination: "0.0.0.0", Gateway: "10.0.0.1", InterfaceName: "eth0"},
+			}
+
+			addresses["eth0"] = []string{"10.0.0.5/24"}
+
+			lookups := 0
+
+			err := monitor.MonitorAddresses(
+				func() boshsettings.Networks {
+					lookups++
+					switch lookups {
+					case 2:
+						delete(addresses, "eth0")
+					case 3:
+						addresses["eth0"] = []string{"10.0.0.6/24"}
+					}
+					return boshsettings.Networks{"dhcp-network": boshsettings.Network{Type: "dynamic"}}
+				},
+				func(change AddressChange) error {
+					Expect(change.PreviousIP).To(Equal("10.0.0.5"))
+					Expect(change.IP).To(Equal("10.0.0.6"))
+					return errors.New("fake-handler-err")
+				},
+			)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-handler-err"))
+		})
+	})
+})
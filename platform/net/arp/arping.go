@@ -1,6 +1,7 @@
 package arp
 
 import (
+	gonet "net"
 	"path/filepath"
 	"sync"
 	"time"
@@ -13,9 +14,10 @@ import (
 const arpingLogTag = "arping"
 
 type arping struct {
-	cmdRunner boshsys.CmdRunner
-	fs        boshsys.FileSystem
-	logger    boshlog.Logger
+	cmdRunner          boshsys.CmdRunner
+	neighborAdvertiser NeighborAdvertiser
+	fs                 boshsys.FileSystem
+	logger             boshlog.Logger
 
 	iterations          int
 	iterationDelay      time.Duration
@@ -24,6 +26,7 @@ type arping struct {
 
 func NewArping(
 	cmdRunner boshsys.CmdRunner,
+	neighborAdvertiser NeighborAdvertiser,
 	fs boshsys.FileSystem,
 	logger boshlog.Logger,
 	iterations int,
@@ -32,6 +35,7 @@ func NewArping(
 ) AddressBroadcaster {
 	return arping{
 		cmdRunner:           cmdRunner,
+		neighborAdvertiser:  neighborAdvertiser,
 		fs:                  fs,
 		logger:              logger,
 		iterations:          iterations,
@@ -86,8 +90,22 @@ func (a arping) broadcastMACAddress(address boship.InterfaceAddress) {
 
 	ifaceName := address.GetInterfaceName()
 
+	if isIPv6(ip) {
+		// ARP does not exist in IPv6; unsolicited neighbour advertisement is sent instead
+		err = a.neighborAdvertiser.Advertise(ip, ifaceName)
+		if err != nil {
+			a.logger.Info(arpingLogTag, "Ignoring neighbour advertisement failure: %s", err.Error())
+		}
+		return
+	}
+
 	_, _, _, err = a.cmdRunner.RunCommand("arping", "-c", "1", "-U", "-I", ifaceName, ip)
 	if err != nil {
 		a.logger.Info(arpingLogTag, "Ignoring arping failure: %s", err.Error())
 	}
 }
+
+func isIPv6(ip string) bool {
+	parsedIP := gonet.ParseIP(ip)
+	return parsedIP != nil && parsedIP.To4() == nil
+}
@@ -10,6 +10,7 @@ import (
 
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	. "github.com/cloudfoundry/bosh-agent/platform/net/arp"
+	fakearp "github.com/cloudfoundry/bosh-agent/platform/net/arp/fakes"
 	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
 )
@@ -26,16 +27,18 @@ var _ = Describe("arping", func() {
 	const arpingIterations = 6
 
 	var (
-		fs        *fakesys.FakeFileSystem
-		cmdRunner *fakesys.FakeCmdRunner
-		arping    AddressBroadcaster
+		fs                 *fakesys.FakeFileSystem
+		cmdRunner          *fakesys.FakeCmdRunner
+		neighborAdvertiser *fakearp.FakeNeighborAdvertiser
+		arping             AddressBroadcaster
 	)
 
 	BeforeEach(func() {
 		fs = fakesys.NewFakeFileSystem()
 		cmdRunner = fakesys.NewFakeCmdRunner()
+		neighborAdvertiser = &fakearp.FakeNeighborAdvertiser{}
 		logger := boshlog.NewLogger(boshlog.LevelNone)
-		arping = NewArping(cmdRunner, fs, logger, arpingIterations, 0, 0)
+		arping = NewArping(cmdRunner, neighborAdvertiser, fs, logger, arpingIterations, 0, 0)
 	})
 
 	Describe("BroadcastMACAddresses", func() {
@@ -73,6 +76,20 @@ var _ = Describe("arping", func() {
 			Expect(countB).To(Equal(arpingIterations))
 		})
 
+		It("sends unsolicited neighbour advertisements for IPv6 addresses", func() {
+			addresses := []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8::5"),
+			}
+
+			arping.BroadcastMACAddresses(addresses)
+
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+			Expect(neighborAdvertiser.AdvertiseInputs).To(HaveLen(arpingIterations))
+			for _, input := range neighborAdvertiser.AdvertiseInputs {
+				Expect(input).To(Equal(fakearp.FakeNeighborAdvertiserInput{IP: "2001:db8::5", InterfaceName: "eth0"}))
+			}
+		})
+
 		It("does not run arping command if failed to get interface IP address", func() {
 			addresses := []boship.InterfaceAddress{failingInterfaceAddress{}}
 
@@ -0,0 +1,26 @@
+package fakes
+
+import (
+	"sync"
+)
+
+type FakeNeighborAdvertiser struct {
+	AdvertiseInputs []FakeNeighborAdvertiserInput
+	AdvertiseErr    error
+
+	lock sync.Mutex
+}
+
+type FakeNeighborAdvertiserInput struct {
+	IP            string
+	InterfaceName string
+}
+
+func (a *FakeNeighborAdvertiser) Advertise(ip, interfaceName string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.AdvertiseInputs = append(a.AdvertiseInputs, FakeNeighborAdvertiserInput{IP: ip, InterfaceName: interfaceName})
+
+	return a.AdvertiseErr
+}
@@ -0,0 +1,106 @@
+package arp
+
+import (
+	gonet "net"
+	"syscall"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+)
+
+const (
+	icmpv6NeighborAdvertisementType = 136
+
+	// Override flag makes receivers replace cached link-layer address
+	neighborAdvertisementOverrideFlag = 0x20
+
+	targetLinkLayerAddressOption = 2
+
+	// Receivers drop neighbour discovery messages
+	// that could have been forwarded by a router
+	neighborDiscoveryHopLimit = 255
+)
+
+// NeighborAdvertiser announces IPv6 addresses the way
+// gratuitous ARP announces IPv4 addresses
+type NeighborAdvertiser interface {
+	Advertise(ip, interfaceName string) error
+}
+
+type icmpNeighborAdvertiser struct{}
+
+// NewICMPNeighborAdvertiser sends unsolicited neighbour advertisements
+// to all nodes itself since stemcells do not ship a tool for it
+func NewICMPNeighborAdvertiser() NeighborAdvertiser {
+	return icmpNeighborAdvertiser{}
+}
+
+func (a icmpNeighborAdvertiser) Advertise(ip, interfaceName string) error {
+	targetIP := gonet.ParseIP(ip)
+	if targetIP == nil || targetIP.To4() != nil {
+		return bosherr.Errorf("Invalid IPv6 address '%s'", ip)
+	}
+
+	iface, err := gonet.InterfaceByName(interfaceName)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Finding interface %s", interfaceName)
+	}
+
+	conn, err := gonet.ListenIP("ip6:ipv6-icmp", nil)
+	if err != nil {
+		return bosherr.WrapError(err, "Opening ICMPv6 socket")
+	}
+
+	defer conn.Close()
+
+	err = setMulticastHopLimit(conn, neighborDiscoveryHopLimit)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting multicast hop limit")
+	}
+
+	allNodes := &gonet.IPAddr{IP: gonet.IPv6linklocalallnodes, Zone: interfaceName}
+
+	// Kernel fills in ICMPv6 checksum
+	_, err = conn.WriteToIP(neighborAdvertisement(targetIP, iface.HardwareAddr), allNodes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Sending neighbour advertisement for %s", ip)
+	}
+
+	return nil
+}
+
+func setMulticastHopLimit(conn *gonet.IPConn, hopLimit int) error {
+	// File returns a duplicate of the socket descriptor
+	file, err := conn.File()
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return syscall.SetsockoptInt(int(file.Fd()), syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_HOPS, hopLimit)
+}
+
+// neighborAdvertisement builds ICMPv6 message described in RFC 4861 section 4.4
+// with target link-layer address option
+func neighborAdvertisement(targetIP gonet.IP, hardwareAddr gonet.HardwareAddr) []byte {
+	message := []byte{
+		icmpv6NeighborAdvertisementType, 0, // type, code
+		0, 0, // checksum
+		neighborAdvertisementOverrideFlag, 0, 0, 0,
+	}
+
+	message = append(message, targetIP.To16()...)
+
+	if len(hardwareAddr) == 0 {
+		return message
+	}
+
+	// Option length is in units of 8 bytes
+	option := append([]byte{targetLinkLayerAddressOption, 0}, hardwareAddr...)
+	for len(option)%8 != 0 {
+		option = append(option, 0)
+	}
+	option[1] = byte(len(option) / 8)
+
+	return append(message, option...)
+}
@@ -23,6 +23,7 @@ type centosNetManager struct {
 	ipResolver                    boship.Resolver
 	interfaceConfigurationCreator InterfaceConfigurationCreator
 	addressBroadcaster            bosharp.AddressBroadcaster
+	connectivityChecker           ConnectivityChecker
 	logger                        boshlog.Logger
 }
 
@@ -32,6 +33,7 @@ func NewCentosNetManager(
 	ipResolver boship.Resolver,
 	interfaceConfigurationCreator InterfaceConfigurationCreator,
 	addressBroadcaster bosharp.AddressBroadcaster,
+	connectivityChecker ConnectivityChecker,
 	logger boshlog.Logger,
 ) Manager {
 	return centosNetManager{
@@ -40,6 +42,7 @@ func NewCentosNetManager(
 		ipResolver:                    ipResolver,
 		interfaceConfigurationCreator: interfaceConfigurationCreator,
 		addressBroadcaster:            addressBroadcaster,
+		connectivityChecker:           connectivityChecker,
 		logger:                        logger,
 	}
 }
@@ -55,11 +58,12 @@ request subnet-mask, broadcast-address, time-offset, routers,
 	domain-name, domain-name-servers, domain-search, host-name,
 	netbios-name-servers, netbios-scope, interface-mtu,
 	rfc3442-classless-static-routes, ntp-servers;
-{{ if . }}
-prepend domain-name-servers {{ . }};{{ end }}
+{{ if .DNSServers }}
+prepend domain-name-servers {{ .DNSServers }};{{ end }}{{ if .DNSSearch }}
+supersede domain-search {{ .DNSSearch }};{{ end }}
 `
 
-func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
+func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, mbusURL string, errCh chan error) error {
 	nonVipNetworks := boshsettings.Networks{}
 	for networkName, networkSettings := range networks {
 		if networkSettings.IsVIP() {
@@ -73,24 +77,69 @@ func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errC
 		return err
 	}
 
-	dnsNetwork, _ := networks.DefaultNetworkFor("dns")
-	dnsServers := dnsNetwork.DNS
+	dnsConfiguration := NewDNSConfiguration(networks)
+
+	backup, err := newConfigurationBackup(
+		net.fs,
+		[]string{"/etc/dhcp/dhclient.conf", "/etc/resolv.conf"},
+		[]string{
+			"/etc/sysconfig/network-scripts/ifcfg-*",
+			"/etc/sysconfig/network-scripts/route-*",
+			"/etc/sysconfig/network-scripts/rule-*",
+		},
+	)
+	if err != nil {
+		return bosherr.WrapError(err, "Backing up network configuration")
+	}
 
-	interfacesChanged, err := net.writeNetworkInterfaces(dhcpInterfaceConfigurations, staticInterfaceConfigurations, dnsServers)
+	changedInterfaces, err := net.writeNetworkInterfaces(dhcpInterfaceConfigurations, staticInterfaceConfigurations, dnsConfiguration)
 	if err != nil {
+		net.restoreConfiguration(backup, false)
 		return bosherr.WrapError(err, "Writing network configuration")
 	}
 
-	dhcpChanged := false
 	if len(dhcpInterfaceConfigurations) > 0 {
-		dhcpChanged, err = net.writeDHCPConfiguration(dnsServers)
+		dhcpChanged, err := net.writeDHCPConfiguration(dnsConfiguration)
 		if err != nil {
+			net.restoreConfiguration(backup, false)
 			return err
 		}
+
+		// dhclient only reads its configuration when it starts
+		if dhcpChanged {
+			for _, config := range dhcpInterfaceConfigurations {
+				changedInterfaces[config.Name] = true
+			}
+		}
 	}
 
-	if interfacesChanged || dhcpChanged {
-		net.restartNetworkingInterfaces()
+	interfaceNames := orderedInterfaceNames(staticInterfaceConfigurations, dhcpInterfaceConfigurations)
+
+	for _, name := range interfaceNames {
+		if net.interfaceIsDown(name) {
+			changedInterfaces[name] = true
+		}
+	}
+
+	changedInterfaces = withDependents(changedInterfaces, linkDependents(staticInterfaceConfigurations, dhcpInterfaceConfigurations))
+
+	if len(changedInterfaces) > 0 {
+		var restartedInterfaces []string
+		for _, name := range interfaceNames {
+			if changedInterfaces[name] {
+				restartedInterfaces = append(restartedInterfaces, name)
+			}
+		}
+
+		net.loadKernelModules(linkKernelModules(staticInterfaceConfigurations, dhcpInterfaceConfigurations))
+		net.restartInterfaces(restartedInterfaces)
+
+		err = net.connectivityChecker.Check(nonVipNetworks, mbusURL)
+		if err != nil {
+			net.logger.Error(centosNetManagerLogTag, "Reverting network configuration because connectivity check failed: %s", err.Error())
+			net.restoreConfiguration(backup, true)
+			return bosherr.WrapError(err, "Verifying network connectivity")
+		}
 	}
 
 	net.broadcastIps(staticInterfaceConfigurations, dhcpInterfaceConfigurations, errCh)
@@ -103,38 +152,150 @@ BOOTPROTO=static
 IPADDR={{ .Address }}
 NETMASK={{ .Netmask }}
 BROADCAST={{ .Broadcast }}
-GATEWAY={{ .Gateway }}
+GATEWAY={{ .Gateway }}{{ if .IPv6Address }}
+IPV6INIT=yes
+IPV6ADDR={{ .IPv6Address }}/{{ .IPv6Prefix }}{{ if .IPv6Gateway }}
+IPV6_DEFAULTGW={{ .IPv6Gateway }}{{ end }}{{ end }}{{ template "link-options" . }}
+ONBOOT=yes` + centosLinkOptionsTemplate
+
+// Plain DHCP interfaces are configured by default network scripts,
+// only bonds and VLANs need ifcfg files
+const centosDHCPIfcfgTemplate = `DEVICE={{ .Name }}
+BOOTPROTO=dhcp{{ template "link-options" . }}
+ONBOOT=yes` + centosLinkOptionsTemplate
+
+const centosLinkIfcfgTemplate = `DEVICE={{ .Name }}
+BOOTPROTO=none{{ if .BondMaster }}
+MASTER={{ .BondMaster }}
+SLAVE=yes{{ end }}{{ if .BondMode }}
+TYPE=Bond
+BONDING_MASTER=yes
+BONDING_OPTS="mode={{ .BondMode }} miimon=100"{{ end }}{{ if .MTU }}
+MTU={{ .MTU }}{{ end }}
 ONBOOT=yes`
 
-func (net centosNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsServers []string) (bool, error) {
-	var anyInterfaceChanged bool
+const centosLinkOptionsTemplate = `{{ define "link-options" }}{{ if .IsBond }}
+TYPE=Bond
+BONDING_MASTER=yes
+BONDING_OPTS="mode={{ .BondMode }} miimon=100"{{ end }}{{ if .VLANID }}
+VLAN=yes
+PHYSDEV={{ .VLANParent }}{{ end }}{{ if .MTU }}
+MTU={{ .MTU }}{{ end }}{{ end }}`
+
+// writeNetworkInterfaces returns names of interfaces whose configuration changed
+func (net centosNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsConfiguration DNSConfiguration) (map[string]bool, error) {
+	changedInterfaces := map[string]bool{}
+
 	for _, iface := range staticInterfaceConfigurations {
 		buffer := bytes.NewBuffer([]byte{})
 		t := template.Must(template.New("ifcfg").Parse(centosIfcgfTemplate))
 
 		err := t.Execute(buffer, iface)
 		if err != nil {
-			return false, bosherr.WrapError(err, "Generating config from template")
+			return nil, bosherr.WrapError(err, "Generating config from template")
 		}
 
 		changed, err := net.fs.ConvergeFileContents(filepath.Join("/etc/sysconfig/network-scripts", "ifcfg-"+iface.Name), buffer.Bytes())
 		if err != nil {
-			return false, bosherr.WrapError(err, "Writing to /etc/sysconfig/network-scripts")
+			return nil, bosherr.WrapError(err, "Writing to /etc/sysconfig/network-scripts")
+		}
+
+		routesChanged, err := net.writeRoutingFile("route-"+iface.Name, iface.RouteSpecs())
+		if err != nil {
+			return nil, err
+		}
+
+		rulesChanged, err := net.writeRoutingFile("rule-"+iface.Name, iface.RuleSpecs())
+		if err != nil {
+			return nil, err
+		}
+
+		if changed || routesChanged || rulesChanged {
+			changedInterfaces[iface.Name] = true
+		}
+	}
+
+	for _, iface := range dhcpInterfaceConfigurations {
+		if iface.VLANID == 0 && !iface.IsBond() {
+			continue
+		}
+
+		changed, err := net.writeIfcfgFile(iface.Name, centosDHCPIfcfgTemplate, iface)
+		if err != nil {
+			return nil, err
+		}
+
+		if changed {
+			changedInterfaces[iface.Name] = true
+		}
+	}
+
+	for _, link := range linkInterfaceConfigurations(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		changed, err := net.writeIfcfgFile(link.Name, centosLinkIfcfgTemplate, link)
+		if err != nil {
+			return nil, err
+		}
+
+		if changed {
+			changedInterfaces[link.Name] = true
+		}
+	}
+
+	err := net.writeResolvConf(dnsConfiguration)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Writing to /etc/resolv.conf")
+	}
+
+	return changedInterfaces, nil
+}
+
+func (net centosNetManager) writeIfcfgFile(name string, ifcfgTemplate string, data interface{}) (bool, error) {
+	buffer := bytes.NewBuffer([]byte{})
+	t := template.Must(template.New("ifcfg").Parse(ifcfgTemplate))
+
+	err := t.Execute(buffer, data)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Generating config from template")
+	}
+
+	path := filepath.Join("/etc/sysconfig/network-scripts", "ifcfg-"+name)
+
+	changed, err := net.fs.ConvergeFileContents(path, buffer.Bytes())
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Writing to %s", path)
+	}
+
+	return changed, nil
+}
+
+// writeRoutingFile writes routes or rules in `ip` command format
+// read by ifup-routes; file is removed when there is nothing to configure
+func (net centosNetManager) writeRoutingFile(name string, specs []string) (bool, error) {
+	path := filepath.Join("/etc/sysconfig/network-scripts", name)
+
+	if len(specs) == 0 {
+		if !net.fs.FileExists(path) {
+			return false, nil
+		}
+
+		err := net.fs.RemoveAll(path)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Removing %s", path)
 		}
 
-		anyInterfaceChanged = anyInterfaceChanged || changed
+		return true, nil
 	}
 
-	err := net.writeResolvConf(dnsServers)
+	changed, err := net.fs.ConvergeFileContents(path, []byte(strings.Join(specs, "\n")+"\n"))
 	if err != nil {
-		return false, bosherr.WrapError(err, "Writing to /etc/resolv.conf")
+		return false, bosherr.WrapErrorf(err, "Writing to %s", path)
 	}
 
-	return anyInterfaceChanged, nil
+	return changed, nil
 }
 
 func (net centosNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := physicalInterfacesByMAC(net.fs)
 	if err != nil {
 		return nil, nil, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -152,6 +313,9 @@ func (net centosNetManager) broadcastIps(staticInterfaceConfigurations []StaticI
 	addresses := []boship.InterfaceAddress{}
 	for _, iface := range staticInterfaceConfigurations {
 		addresses = append(addresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.Address))
+		if iface.IPv6Address != "" {
+			addresses = append(addresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.IPv6Address))
+		}
 	}
 	for _, iface := range dhcpInterfaceConfigurations {
 		addresses = append(addresses, boship.NewResolvingInterfaceAddress(iface.Name, net.ipResolver))
@@ -165,6 +329,59 @@ func (net centosNetManager) broadcastIps(staticInterfaceConfigurations []StaticI
 	}()
 }
 
+// restoreConfiguration brings back previous configuration files
+// and optionally restarts networking to apply them
+func (net centosNetManager) restoreConfiguration(backup configurationBackup, restart bool) {
+	err := backup.Restore()
+	if err != nil {
+		net.logger.Error(centosNetManagerLogTag, "Failed to restore previous network configuration: %s", err.Error())
+		return
+	}
+
+	if restart {
+		net.restartNetworkingInterfaces()
+	}
+}
+
+func (net centosNetManager) loadKernelModules(modules []string) {
+	for _, module := range modules {
+		_, _, _, err := net.cmdRunner.RunCommand("modprobe", module)
+		if err != nil {
+			net.logger.Error(centosNetManagerLogTag, "Ignoring modprobe %s failure: %s", module, err.Error())
+		}
+	}
+}
+
+// interfaceIsDown reports whether kernel considers interface down;
+// interfaces with unknown state are not considered down
+func (net centosNetManager) interfaceIsDown(name string) bool {
+	operState, err := net.fs.ReadFileString(filepath.Join("/sys/class/net", name, "operstate"))
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(operState) == "down"
+}
+
+func (net centosNetManager) restartInterfaces(names []string) {
+	net.logger.Debug(centosNetManagerLogTag, "Restarting network interfaces %v", names)
+
+	// Dependent interfaces follow their parents so stop them first
+	for i := len(names) - 1; i >= 0; i-- {
+		_, _, _, err := net.cmdRunner.RunCommand("ifdown", names[i])
+		if err != nil {
+			net.logger.Error(centosNetManagerLogTag, "Ignoring ifdown %s failure: %s", names[i], err.Error())
+		}
+	}
+
+	for _, name := range names {
+		_, _, _, err := net.cmdRunner.RunCommand("ifup", name)
+		if err != nil {
+			net.logger.Error(centosNetManagerLogTag, "Ignoring ifup %s failure: %s", name, err.Error())
+		}
+	}
+}
+
 func (net centosNetManager) restartNetworkingInterfaces() {
 	net.logger.Debug(centosNetManagerLogTag, "Restarting network interfaces")
 
@@ -174,14 +391,11 @@ func (net centosNetManager) restartNetworkingInterfaces() {
 	}
 }
 
-func (net centosNetManager) writeDHCPConfiguration(dnsServers []string) (bool, error) {
+func (net centosNetManager) writeDHCPConfiguration(dnsConfiguration DNSConfiguration) (bool, error) {
 	buffer := bytes.NewBuffer([]byte{})
 	t := template.Must(template.New("dhcp-config").Parse(centosDHCPConfigTemplate))
 
-	// Keep DNS servers in the order specified by the network
-	// because they are added by a *single* DHCP's prepend command
-	dnsServersList := strings.Join(dnsServers, ", ")
-	err := t.Execute(buffer, dnsServersList)
+	err := t.Execute(buffer, dnsConfiguration.dhcpConfigTemplateValues())
 	if err != nil {
 		return false, bosherr.WrapError(err, "Generating config from template")
 	}
@@ -195,11 +409,11 @@ func (net centosNetManager) writeDHCPConfiguration(dnsServers []string) (bool, e
 	return changed, nil
 }
 
-func (net centosNetManager) writeResolvConf(dnsServers []string) error {
+func (net centosNetManager) writeResolvConf(dnsConfiguration DNSConfiguration) error {
 	buffer := bytes.NewBuffer([]byte{})
 	t := template.Must(template.New("resolv-conf").Parse(centosResolvConfTemplate))
 
-	err := t.Execute(buffer, dnsServers)
+	err := t.Execute(buffer, dnsConfiguration.ResolvConf())
 	if err != nil {
 		return bosherr.WrapError(err, "Generating config from template")
 	}
@@ -213,33 +427,4 @@ func (net centosNetManager) writeResolvConf(dnsServers []string) error {
 }
 
 const centosResolvConfTemplate = `# Generated by bosh-agent
-{{ range . }}nameserver {{ . }}
-{{ end }}`
-
-func (net centosNetManager) detectMacAddresses() (map[string]string, error) {
-	addresses := map[string]string{}
-
-	filePaths, err := net.fs.Glob("/sys/class/net/*")
-	if err != nil {
-		return addresses, bosherr.WrapError(err, "Getting file list from /sys/class/net")
-	}
-
-	var macAddress string
-	for _, filePath := range filePaths {
-		isPhysicalDevice := net.fs.FileExists(filepath.Join(filePath, "device"))
-
-		if isPhysicalDevice {
-			macAddress, err = net.fs.ReadFileString(filepath.Join(filePath, "address"))
-			if err != nil {
-				return addresses, bosherr.WrapError(err, "Reading mac address from file")
-			}
-
-			macAddress = strings.Trim(macAddress, "\n")
-
-			interfaceName := filepath.Base(filePath)
-			addresses[macAddress] = interfaceName
-		}
-	}
-
-	return addresses, nil
-}
+{{ . }}`
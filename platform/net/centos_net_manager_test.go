@@ -10,6 +10,7 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	. "github.com/cloudfoundry/bosh-agent/platform/net"
 	fakearp "github.com/cloudfoundry/bosh-agent/platform/net/arp/fakes"
+	fakenet "github.com/cloudfoundry/bosh-agent/platform/net/fakes"
 	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	fakeip "github.com/cloudfoundry/bosh-agent/platform/net/ip/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -24,6 +25,7 @@ func describeCentosNetManager() {
 		cmdRunner                     *fakesys.FakeCmdRunner
 		ipResolver                    *fakeip.FakeResolver
 		addressBroadcaster            *fakearp.FakeAddressBroadcaster
+		connectivityChecker           *fakenet.FakeConnectivityChecker
 		netManager                    Manager
 		interfaceConfigurationCreator InterfaceConfigurationCreator
 	)
@@ -35,12 +37,14 @@ func describeCentosNetManager() {
 		logger := boshlog.NewLogger(boshlog.LevelNone)
 		interfaceConfigurationCreator = NewInterfaceConfigurationCreator(logger)
 		addressBroadcaster = &fakearp.FakeAddressBroadcaster{}
+		connectivityChecker = &fakenet.FakeConnectivityChecker{}
 		netManager = NewCentosNetManager(
 			fs,
 			cmdRunner,
 			ipResolver,
 			interfaceConfigurationCreator,
 			addressBroadcaster,
+			connectivityChecker,
 			logger,
 		)
 	})
@@ -110,7 +114,7 @@ ONBOOT=yes`
 				"ethstatic": staticNetwork,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			networkConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
@@ -118,13 +122,202 @@ ONBOOT=yes`
 			Expect(networkConfig.StringContents()).To(Equal(expectedNetworkConfigurationForStatic))
 		})
 
+		It("writes IPv6 configuration in network script for dual-stack interfaces", func() {
+			staticNetwork.IPv6 = "2001:db8::5"
+			staticNetwork.IPv6Prefix = 64
+			staticNetwork.IPv6Gateway = "2001:db8::1"
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`DEVICE=ethstatic
+BOOTPROTO=static
+IPADDR=1.2.3.4
+NETMASK=255.255.255.0
+BROADCAST=1.2.3.255
+GATEWAY=3.4.5.6
+IPV6INIT=yes
+IPV6ADDR=2001:db8::5/64
+IPV6_DEFAULTGW=2001:db8::1
+ONBOOT=yes`))
+		})
+
+		It("writes route and rule files for static routes and policy routing", func() {
+			staticNetwork.Routes = []boshsettings.Route{
+				{Destination: "10.0.0.0", Netmask: "255.0.0.0", Gateway: "1.2.3.1"},
+			}
+			staticNetwork.PolicyRouting = true
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			routeFile := fs.GetFileTestStat("/etc/sysconfig/network-scripts/route-ethstatic")
+			Expect(routeFile).ToNot(BeNil())
+			Expect(routeFile.StringContents()).To(Equal(`10.0.0.0/8 via 1.2.3.1 dev ethstatic
+1.2.3.0/24 dev ethstatic src 1.2.3.4 table 101
+default via 3.4.5.6 dev ethstatic table 101
+10.0.0.0/8 via 1.2.3.1 dev ethstatic table 101
+`))
+
+			ruleFile := fs.GetFileTestStat("/etc/sysconfig/network-scripts/rule-ethstatic")
+			Expect(ruleFile).ToNot(BeNil())
+			Expect(ruleFile.StringContents()).To(Equal("from 1.2.3.4 table 101\n"))
+		})
+
+		It("removes route and rule files when interface no longer has routes", func() {
+			fs.WriteFileString("/etc/sysconfig/network-scripts/route-ethstatic", "10.0.0.0/8 via 1.2.3.1 dev ethstatic\n")
+			fs.WriteFileString("/etc/sysconfig/network-scripts/rule-ethstatic", "from 1.2.3.4 table 101\n")
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/sysconfig/network-scripts/route-ethstatic")).To(BeFalse())
+			Expect(fs.FileExists("/etc/sysconfig/network-scripts/rule-ethstatic")).To(BeFalse())
+		})
+
+		It("writes network scripts for bond members, bond and VLAN interfaces", func() {
+			staticNetwork.Mac = ""
+			staticNetwork.MTU = 9000
+			staticNetwork.VLAN = 100
+			staticNetwork.Bond = boshsettings.Bond{Mode: "802.3ad", Members: []string{"fake-mac-1", "fake-mac-2"}}
+
+			dhcpNetwork.Mac = ""
+			dhcpNetwork.VLAN = 200
+			dhcpNetwork.MTU = 9000
+			dhcpNetwork.Bond = staticNetwork.Bond
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": boshsettings.Network{Mac: "fake-mac-1"},
+				"eth1": boshsettings.Network{Mac: "fake-mac-2"},
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork, "dhcp-network": dhcpNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-bond0.100").StringContents()).To(Equal(`DEVICE=bond0.100
+BOOTPROTO=static
+IPADDR=1.2.3.4
+NETMASK=255.255.255.0
+BROADCAST=1.2.3.255
+GATEWAY=3.4.5.6
+VLAN=yes
+PHYSDEV=bond0
+MTU=9000
+ONBOOT=yes`))
+
+			Expect(fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-bond0.200").StringContents()).To(Equal(`DEVICE=bond0.200
+BOOTPROTO=dhcp
+VLAN=yes
+PHYSDEV=bond0
+MTU=9000
+ONBOOT=yes`))
+
+			Expect(fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-bond0").StringContents()).To(Equal(`DEVICE=bond0
+BOOTPROTO=none
+TYPE=Bond
+BONDING_MASTER=yes
+BONDING_OPTS="mode=802.3ad miimon=100"
+MTU=9000
+ONBOOT=yes`))
+
+			for _, member := range []string{"eth0", "eth1"} {
+				Expect(fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-" + member).StringContents()).To(Equal(`DEVICE=` + member + `
+BOOTPROTO=none
+MASTER=bond0
+SLAVE=yes
+MTU=9000
+ONBOOT=yes`))
+			}
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"modprobe", "bonding"}))
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"modprobe", "8021q"}))
+		})
+
+		It("keeps static configuration of interface used by untagged network and VLANs", func() {
+			staticNetwork.Mac = "fake-mac-1"
+
+			dhcpNetwork.Mac = "fake-mac-1"
+			dhcpNetwork.VLAN = 100
+			dhcpNetwork.MTU = 9000
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": boshsettings.Network{Mac: "fake-mac-1"},
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork, "dhcp-network": dhcpNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-eth0").StringContents()).To(Equal(`DEVICE=eth0
+BOOTPROTO=static
+IPADDR=1.2.3.4
+NETMASK=255.255.255.0
+BROADCAST=1.2.3.255
+GATEWAY=3.4.5.6
+MTU=9000
+ONBOOT=yes`))
+
+			Expect(fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-eth0.100").StringContents()).To(Equal(`DEVICE=eth0.100
+BOOTPROTO=dhcp
+VLAN=yes
+PHYSDEV=eth0
+MTU=9000
+ONBOOT=yes`))
+		})
+
+		It("restarts bond members and VLANs when bond configuration changes", func() {
+			staticNetwork.Mac = ""
+			staticNetwork.VLAN = 100
+			staticNetwork.Bond = boshsettings.Bond{Members: []string{"fake-mac-1", "fake-mac-2"}}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": boshsettings.Network{Mac: "fake-mac-1"},
+				"eth1": boshsettings.Network{Mac: "fake-mac-2"},
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			fs.WriteFileString("/etc/sysconfig/network-scripts/ifcfg-bond0", "fake-previous-bond")
+			cmdRunner.RunCommands = nil
+
+			err = netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"modprobe", "bonding"},
+				{"modprobe", "8021q"},
+				{"ifdown", "bond0.100"},
+				{"ifdown", "bond0"},
+				{"ifdown", "eth1"},
+				{"ifdown", "eth0"},
+				{"ifup", "eth0"},
+				{"ifup", "eth1"},
+				{"ifup", "bond0"},
+				{"ifup", "bond0.100"},
+			}))
+		})
+
 		It("writes /etc/resolv.conf with dns servers", func() {
 			stubInterfaces(map[string]boshsettings.Network{
 				"ethdhcp":   dhcpNetwork,
 				"ethstatic": staticNetwork,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			resolvConf := fs.GetFileTestStat("/etc/resolv.conf")
@@ -135,6 +328,32 @@ nameserver 9.9.9.9
 `))
 		})
 
+		It("writes /etc/resolv.conf with dns search domains and resolver options", func() {
+			dhcpNetwork.DNSSearch = []string{"example.com", "internal"}
+			dhcpNetwork.DNSOptions = []string{"rotate", "timeout:2"}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			resolvConf := fs.GetFileTestStat("/etc/resolv.conf")
+			Expect(resolvConf).ToNot(BeNil())
+			Expect(resolvConf.StringContents()).To(Equal(`# Generated by bosh-agent
+nameserver 8.8.8.8
+nameserver 9.9.9.9
+search example.com internal
+options rotate timeout:2
+`))
+
+			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
+			Expect(dhcpConfig).ToNot(BeNil())
+			Expect(dhcpConfig.StringContents()).To(ContainSubstring(`supersede domain-search "example.com", "internal";`))
+		})
+
 		It("doesn't write /etc/resolv.conf if there are no dns servers", func() {
 			dhcpNetworkWithoutDNS := boshsettings.Network{
 				Type: "dynamic",
@@ -145,7 +364,7 @@ nameserver 9.9.9.9
 				"ethdhcp": dhcpNetworkWithoutDNS,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetworkWithoutDNS}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetworkWithoutDNS}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			resolvConf := fs.GetFileTestStat("/etc/resolv.conf")
@@ -156,7 +375,7 @@ nameserver 9.9.9.9
 
 		It("returns errors from glob /sys/class/net/", func() {
 			fs.GlobErr = errors.New("fs-glob-error")
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fs-glob-error"))
 		})
@@ -167,14 +386,14 @@ nameserver 9.9.9.9
 				"static": staticNetwork,
 			})
 			fs.WriteFileError = errors.New("fs-write-file-error")
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fs-write-file-error"))
 		})
 
 		It("returns errors when it can't creating network interface configurations", func() {
 			staticNetwork.Netmask = "not an ip" //will cause InterfaceConfigurationCreator to fail
-			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Creating interface configurations"))
 		})
@@ -185,7 +404,7 @@ nameserver 9.9.9.9
 				"ethstatic": staticNetwork,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
@@ -216,7 +435,7 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 				"ethdhcp": dhcpNetwork,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetworkWithoutDNS}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetworkWithoutDNS}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
@@ -244,7 +463,7 @@ request subnet-mask, broadcast-address, time-offset, routers,
 
 			fs.WriteFileErrors["/etc/dhcp/dhclient.conf"] = errors.New("dhclient.conf-write-error")
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("dhclient.conf-write-error"))
 		})
@@ -254,14 +473,14 @@ request subnet-mask, broadcast-address, time-offset, routers,
 				"ethstatic": staticNetwork,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
 			Expect(dhcpConfig).To(BeNil())
 		})
 
-		It("restarts the networks if any ifconfig file changes", func() {
+		It("restarts only interfaces whose ifconfig file changes", func() {
 			initialDhcpConfig := `# Generated by bosh-agent
 
 option rfc3442-classless-static-routes code 121 = array of unsigned integer 8;
@@ -297,12 +516,30 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 				"dhcp-network":            dhcpNetwork,
 				"changing-static-network": changingStaticNetwork,
 				"static-network":          staticNetwork,
-			},
-				nil)
+			}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ifdown", "ethstatic-that-changes"},
+				{"ifup", "ethstatic-that-changes"},
+			}))
+		})
+
+		It("restarts interfaces that are down even if their ifconfig file does not change", func() {
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			fs.WriteFileString("/etc/sysconfig/network-scripts/ifcfg-ethstatic", expectedNetworkConfigurationForStatic)
+			fs.WriteFileString("/sys/class/net/ethstatic/operstate", "down\n")
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(len(cmdRunner.RunCommands)).To(Equal(1))
-			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"service", "network", "restart"}))
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ifdown", "ethstatic"},
+				{"ifup", "ethstatic"},
+			}))
 		})
 
 		It("doesn't restart the networks if ifconfig and /etc/dhcp/dhclient.conf don't change", func() {
@@ -327,7 +564,7 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 			fs.WriteFileString("/etc/sysconfig/network-scripts/ifcfg-ethstatic", expectedNetworkConfigurationForStatic)
 			fs.WriteFileString("/etc/dhcp/dhclient.conf", initialDhcpConfig)
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			networkConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
@@ -348,15 +585,75 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 
 			fs.WriteFileString("/etc/sysconfig/network-scripts/ifcfg-ethstatic", expectedNetworkConfigurationForStatic)
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			networkConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
 			Expect(networkConfig).ToNot(BeNil())
 			Expect(networkConfig.StringContents()).To(Equal(expectedNetworkConfigurationForStatic))
+			Expect(fs.FileExists("/etc/dhcp/dhclient.conf")).To(BeTrue())
+
+			// dhclient only reads its configuration when it starts
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ifdown", "ethdhcp"},
+				{"ifup", "ethdhcp"},
+			}))
+		})
+
+		It("checks connectivity of networks after restarting them", func() {
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
 
-			Expect(len(cmdRunner.RunCommands)).To(Equal(1))
-			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"service", "network", "restart"}))
+			networks := boshsettings.Networks{"static-network": staticNetwork}
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(connectivityChecker.CheckNetworks).To(Equal([]boshsettings.Networks{networks}))
+		})
+
+		Context("when connectivity check fails", func() {
+			BeforeEach(func() {
+				connectivityChecker.CheckErr = errors.New("fake-check-err")
+			})
+
+			It("restores previous network scripts, restarts networks again and returns error", func() {
+				staticNetwork.Routes = []boshsettings.Route{
+					{Destination: "10.0.0.0", Netmask: "255.0.0.0", Gateway: "1.2.3.1"},
+				}
+
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethstatic": staticNetwork,
+				})
+
+				fs.WriteFileString("/etc/sysconfig/network-scripts/ifcfg-ethstatic", "fake-previous-ifcfg")
+				fs.SetGlob(
+					"/etc/sysconfig/network-scripts/ifcfg-*",
+					[]string{"/etc/sysconfig/network-scripts/ifcfg-ethstatic"},
+					[]string{"/etc/sysconfig/network-scripts/ifcfg-ethstatic"},
+				)
+				fs.SetGlob(
+					"/etc/sysconfig/network-scripts/route-*",
+					[]string{},
+					[]string{"/etc/sysconfig/network-scripts/route-ethstatic"},
+				)
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Verifying network connectivity"))
+				Expect(err.Error()).To(ContainSubstring("fake-check-err"))
+
+				staticConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+				Expect(staticConfig.StringContents()).To(Equal("fake-previous-ifcfg"))
+				Expect(fs.FileExists("/etc/sysconfig/network-scripts/route-ethstatic")).To(BeFalse())
+
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"ifdown", "ethstatic"},
+					{"ifup", "ethstatic"},
+					{"service", "network", "restart"},
+				}))
+			})
 		})
 
 		It("broadcasts MAC addresses for all interfaces", func() {
@@ -366,7 +663,7 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 			})
 
 			errCh := make(chan error)
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, errCh)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", errCh)
 			Expect(err).ToNot(HaveOccurred())
 
 			broadcastErr := <-errCh // wait for all arpings
@@ -379,6 +676,27 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 
 		})
 
+		It("broadcasts IPv6 addresses of dual-stack interfaces", func() {
+			staticNetwork.IPv6 = "2001:db8::5"
+			staticNetwork.IPv6Prefix = 64
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			errCh := make(chan error)
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", errCh)
+			Expect(err).ToNot(HaveOccurred())
+
+			broadcastErr := <-errCh // wait for all arpings
+			Expect(broadcastErr).ToNot(HaveOccurred())
+
+			Expect(addressBroadcaster.BroadcastMACAddressesAddresses).To(Equal([]boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::5"),
+			}))
+		})
+
 		It("skips vip networks", func() {
 			stubInterfaces(map[string]boshsettings.Network{
 				"ethdhcp":   dhcpNetwork,
@@ -397,7 +715,7 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 				"dhcp-network":   dhcpNetwork,
 				"static-network": staticNetwork,
 				"vip-network":    vipNetwork,
-			}, nil)
+			}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			networkConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
@@ -422,7 +740,7 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 
 				err := netManager.SetupNetworking(boshsettings.Networks{
 					"static-network": staticNetworkWithoutMAC,
-				}, nil)
+				}, "", nil)
 				Expect(err).ToNot(HaveOccurred())
 
 				expectedNetworkConfiguration := `DEVICE=ethstatic
@@ -455,7 +773,7 @@ ONBOOT=yes`
 
 				err := netManager.SetupNetworking(boshsettings.Networks{
 					"static-network": staticNetworkWithoutMAC,
-				}, nil)
+				}, "", nil)
 				Expect(err).ToNot(HaveOccurred())
 
 				expectedNetworkConfiguration := `DEVICE=ethstatic
@@ -1,20 +1,30 @@
 package net
 
 import (
+	gonet "net"
+	"strconv"
 	"strings"
 
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	boshsys "github.com/cloudfoundry/bosh-agent/system"
 )
 
 // cmdRoutesSearcher uses `route -n` command to list routes
-// which routes in a same format on Ubuntu and CentOS
+// which routes in a same format on Ubuntu and CentOS;
+// routes from policy routing tables are listed with `ip route`
 type cmdRoutesSearcher struct {
 	runner boshsys.CmdRunner
+	logTag string
+	logger boshlog.Logger
 }
 
-func NewCmdRoutesSearcher(runner boshsys.CmdRunner) RoutesSearcher {
-	return cmdRoutesSearcher{runner}
+func NewCmdRoutesSearcher(runner boshsys.CmdRunner, logger boshlog.Logger) RoutesSearcher {
+	return cmdRoutesSearcher{
+		runner: runner,
+		logTag: "cmdRoutesSearcher",
+		logger: logger,
+	}
 }
 
 func (s cmdRoutesSearcher) SearchRoutes() ([]Route, error) {
@@ -38,10 +48,75 @@ func (s cmdRoutesSearcher) SearchRoutes() ([]Route, error) {
 
 		routes = append(routes, Route{
 			Destination:   routeFields[0],
+			Netmask:       routeFields[2],
 			Gateway:       routeFields[1],
 			InterfaceName: routeFields[7],
 		})
 	}
 
+	// Policy routing tables are optional; main table routes are still usable
+	tableRoutes, err := s.searchTableRoutes()
+	if err != nil {
+		s.logger.Error(s.logTag, "Searching policy routing table routes: %s", err.Error())
+		return routes, nil
+	}
+
+	return append(routes, tableRoutes...), nil
+}
+
+// searchTableRoutes lists routes from numbered routing tables, e.g.:
+//
+//	default via 10.0.1.1 dev eth1 table 101
+//	10.0.1.0/24 dev eth1 table 101 proto kernel scope link src 10.0.1.5
+func (s cmdRoutesSearcher) searchTableRoutes() ([]Route, error) {
+	var routes []Route
+
+	stdout, _, _, err := s.runner.RunCommand("ip", "-4", "route", "show", "table", "all")
+	if err != nil {
+		return routes, bosherr.WrapError(err, "Running ip route")
+	}
+
+	for _, routeEntry := range strings.Split(stdout, "\n") {
+		routeFields := strings.Fields(routeEntry)
+		if len(routeFields) == 0 {
+			continue
+		}
+
+		route := Route{Gateway: "0.0.0.0"}
+
+		for i := 1; i < len(routeFields)-1; i++ {
+			switch routeFields[i] {
+			case "via":
+				route.Gateway = routeFields[i+1]
+			case "dev":
+				route.InterfaceName = routeFields[i+1]
+			case "table":
+				// Named tables such as local are not used for policy routing
+				route.Table, _ = strconv.Atoi(routeFields[i+1])
+			}
+		}
+
+		if route.Table == 0 {
+			continue
+		}
+
+		route.Destination, route.Netmask = parseRouteDestination(routeFields[0])
+
+		routes = append(routes, route)
+	}
+
 	return routes, nil
 }
+
+func parseRouteDestination(destination string) (string, string) {
+	if destination == "default" {
+		return "0.0.0.0", "0.0.0.0"
+	}
+
+	_, ipNet, err := gonet.ParseCIDR(destination)
+	if err != nil {
+		return destination, "255.255.255.255"
+	}
+
+	return ipNet.IP.String(), gonet.IP(ipNet.Mask).String()
+}
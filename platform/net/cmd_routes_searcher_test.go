@@ -6,6 +6,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	. "github.com/cloudfoundry/bosh-agent/platform/net"
 	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
 )
@@ -18,7 +19,7 @@ var _ = Describe("cmdRoutesSeacher", func() {
 
 	BeforeEach(func() {
 		runner = fakesys.NewFakeCmdRunner()
-		searcher = NewCmdRoutesSearcher(runner)
+		searcher = NewCmdRoutesSearcher(runner, boshlog.NewLogger(boshlog.LevelNone))
 	})
 
 	Describe("SearchRoutes", func() {
@@ -36,12 +37,37 @@ Destination     Gateway         Genmask         Flags Metric Ref    Use Iface
 				routes, err := searcher.SearchRoutes()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(routes).To(Equal([]Route{
-					Route{Destination: "172.16.79.0", Gateway: "0.0.0.0", InterfaceName: "eth0"},
-					Route{Destination: "169.254.0.0", Gateway: "0.0.0.0", InterfaceName: "eth0"},
-					Route{Destination: "0.0.0.0", Gateway: "172.16.79.1", InterfaceName: "eth0"},
+					Route{Destination: "172.16.79.0", Netmask: "255.255.255.0", Gateway: "0.0.0.0", InterfaceName: "eth0"},
+					Route{Destination: "169.254.0.0", Netmask: "255.255.0.0", Gateway: "0.0.0.0", InterfaceName: "eth0"},
+					Route{Destination: "0.0.0.0", Netmask: "0.0.0.0", Gateway: "172.16.79.1", InterfaceName: "eth0"},
 				}))
 			})
 
+			It("returns routes from policy routing tables after main table routes", func() {
+				runner.AddCmdResult("route -n", fakesys.FakeCmdResult{
+					Stdout: `Kernel IP routing table
+Destination     Gateway         Genmask         Flags Metric Ref    Use Iface
+0.0.0.0         172.16.79.1     0.0.0.0         UG    0      0        0 eth0
+`,
+				})
+				runner.AddCmdResult("ip -4 route show table all", fakesys.FakeCmdResult{
+					Stdout: `default via 10.0.1.1 dev eth1 table 101
+10.0.1.0/24 dev eth1 table 101 proto kernel scope link src 10.0.1.5
+default via 172.16.79.1 dev eth0
+broadcast 127.0.0.0 dev lo table local proto kernel scope link src 127.0.0.1
+`,
+				})
+
+				routes, err := searcher.SearchRoutes()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(routes).To(Equal([]Route{
+					Route{Destination: "0.0.0.0", Netmask: "0.0.0.0", Gateway: "172.16.79.1", InterfaceName: "eth0"},
+					Route{Destination: "0.0.0.0", Netmask: "0.0.0.0", Gateway: "10.0.1.1", InterfaceName: "eth1", Table: 101},
+					Route{Destination: "10.0.1.0", Netmask: "255.255.255.0", Gateway: "0.0.0.0", InterfaceName: "eth1", Table: 101},
+				}))
+				Expect(routes[1].IsDefault()).To(BeFalse())
+			})
+
 			It("ignores empty lines", func() {
 				runner.AddCmdResult("route -n", fakesys.FakeCmdResult{
 					Stdout: `Kernel IP routing table
@@ -67,5 +93,25 @@ Destination     Gateway         Genmask         Flags Metric Ref    Use Iface
 				Expect(routes).To(BeEmpty())
 			})
 		})
+
+		Context("when listing policy routing tables fails", func() {
+			It("returns main table routes", func() {
+				runner.AddCmdResult("route -n", fakesys.FakeCmdResult{
+					Stdout: `Kernel IP routing table
+Destination     Gateway         Genmask         Flags Metric Ref    Use Iface
+0.0.0.0         172.16.79.1     0.0.0.0         UG    0      0        0 eth0
+`,
+				})
+				runner.AddCmdResult("ip -4 route show table all", fakesys.FakeCmdResult{
+					Error: errors.New("fake-ip-err"),
+				})
+
+				routes, err := searcher.SearchRoutes()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(routes).To(Equal([]Route{
+					Route{Destination: "0.0.0.0", Netmask: "0.0.0.0", Gateway: "172.16.79.1", InterfaceName: "eth0"},
+				}))
+			})
+		})
 	})
 })
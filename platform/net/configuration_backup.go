@@ -0,0 +1,117 @@
+package net
+
+import (
+	"sort"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+// configurationBackup keeps previous contents of network configuration files
+// so that configuration which breaks connectivity can be reverted
+type configurationBackup struct {
+	fs boshsys.FileSystem
+
+	// Files that did not exist are not included in contents
+	paths    []string
+	contents map[string][]byte
+
+	// Symlinks are restored as links instead of copies of their targets
+	links map[string]string
+
+	// Files matching globs that are created after backup are removed on restore
+	globs []string
+}
+
+func newConfigurationBackup(fs boshsys.FileSystem, paths []string, globs []string) (configurationBackup, error) {
+	backup := configurationBackup{
+		fs:       fs,
+		contents: map[string][]byte{},
+		links:    map[string]string{},
+		globs:    globs,
+	}
+
+	for _, glob := range globs {
+		matches, err := fs.Glob(glob)
+		if err != nil {
+			return backup, bosherr.WrapErrorf(err, "Finding files matching %s", glob)
+		}
+
+		paths = append(paths, matches...)
+	}
+
+	backup.paths = paths
+
+	for _, path := range paths {
+		if target, isSymlink := readSymlink(fs, path); isSymlink {
+			backup.links[path] = target
+			continue
+		}
+
+		if !fs.FileExists(path) {
+			continue
+		}
+
+		contents, err := fs.ReadFile(path)
+		if err != nil {
+			return backup, bosherr.WrapErrorf(err, "Backing up %s", path)
+		}
+
+		backup.contents[path] = contents
+	}
+
+	return backup, nil
+}
+
+func (b configurationBackup) Restore() error {
+	paths := append([]string{}, b.paths...)
+
+	for _, glob := range b.globs {
+		matches, err := b.fs.Glob(glob)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Finding files matching %s", glob)
+		}
+
+		paths = append(paths, matches...)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if target, found := b.links[path]; found {
+			err := b.fs.Symlink(target, path)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Restoring %s symlink", path)
+			}
+			continue
+		}
+
+		contents, found := b.contents[path]
+
+		if !found {
+			err := b.fs.RemoveAll(path)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Removing %s", path)
+			}
+			continue
+		}
+
+		err := b.fs.WriteFile(path, contents)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Restoring %s", path)
+		}
+	}
+
+	return nil
+}
+
+// readSymlink returns target of path only when it is a symlink
+// since file system resolves regular files to themselves
+func readSymlink(fs boshsys.FileSystem, path string) (string, bool) {
+	target, err := fs.ReadLink(path)
+	if err != nil || target == "" || target == path {
+		return "", false
+	}
+
+	return target, true
+}
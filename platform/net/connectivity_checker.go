@@ -0,0 +1,174 @@
+package net
+
+import (
+	gonet "net"
+	"net/url"
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+const connectivityCheckerLogTag = "connectivityChecker"
+
+type ConnectivityChecker interface {
+	// Check returns an error if networks cannot reach
+	// their gateways, DNS servers, required endpoints or mbus
+	Check(networks boshsettings.Networks, mbusURL string) error
+}
+
+type DialFunc func(network, address string, timeout time.Duration) (gonet.Conn, error)
+
+type connectivityChecker struct {
+	cmdRunner boshsys.CmdRunner
+	dial      DialFunc
+	endpoints []string
+
+	timeout    time.Duration
+	retryDelay time.Duration
+
+	logger boshlog.Logger
+}
+
+func NewConnectivityChecker(
+	cmdRunner boshsys.CmdRunner,
+	dial DialFunc,
+	endpoints []string,
+	timeout time.Duration,
+	retryDelay time.Duration,
+	logger boshlog.Logger,
+) ConnectivityChecker {
+	return connectivityChecker{
+		cmdRunner:  cmdRunner,
+		dial:       dial,
+		endpoints:  endpoints,
+		timeout:    timeout,
+		retryDelay: retryDelay,
+		logger:     logger,
+	}
+}
+
+// Check retries until all targets are reachable or timeout passes
+// since interfaces may take a while to come up after restart
+func (c connectivityChecker) Check(networks boshsettings.Networks, mbusURL string) error {
+	endpoints := c.endpoints
+
+	if endpoint, found := mbusEndpoint(mbusURL); found {
+		endpoints = append(append([]string{}, c.endpoints...), endpoint)
+	}
+
+	deadline := time.Now().Add(c.timeout)
+
+	for {
+		err := c.checkOnce(networks, endpoints)
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return err
+		}
+
+		c.logger.Debug(connectivityCheckerLogTag, "Retrying connectivity check: %s", err.Error())
+		time.Sleep(c.retryDelay)
+	}
+}
+
+func (c connectivityChecker) checkOnce(networks boshsettings.Networks, endpoints []string) error {
+	var gatewayErr error
+
+	for networkName, network := range networks {
+		if network.IsVIP() || network.IsDHCP() || network.Gateway == "" {
+			continue
+		}
+
+		_, _, _, err := c.cmdRunner.RunCommand("ping", "-c", "1", "-W", "1", network.Gateway)
+		if err != nil {
+			gatewayErr = bosherr.WrapErrorf(err, "Reaching gateway '%s' of network '%s'", network.Gateway, networkName)
+			break
+		}
+	}
+
+	dnsNetwork, _ := networks.DefaultNetworkFor("dns")
+
+	err := c.checkAnyReachable(dnsNetwork.DNS, "53")
+	if err != nil {
+		return bosherr.WrapError(err, "Reaching DNS servers")
+	}
+
+	for _, endpoint := range endpoints {
+		err = c.checkReachable(endpoint)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reaching endpoint '%s'", endpoint)
+		}
+	}
+
+	if gatewayErr == nil {
+		return nil
+	}
+
+	// Gateways commonly drop ICMP; reaching targets behind them proves they work
+	if len(dnsNetwork.DNS) > 0 || len(endpoints) > 0 {
+		c.logger.Debug(connectivityCheckerLogTag, "Ignoring failed ping since other targets are reachable: %s", gatewayErr.Error())
+		return nil
+	}
+
+	return gatewayErr
+}
+
+// mbusEndpoint returns address of NATS server agent connects to;
+// there is nothing to reach for HTTPS mbus since agent serves it
+func mbusEndpoint(mbusURL string) (string, bool) {
+	parsedURL, err := url.Parse(mbusURL)
+	if err != nil || parsedURL.Scheme != "nats" || parsedURL.Host == "" {
+		return "", false
+	}
+
+	if _, _, err = gonet.SplitHostPort(parsedURL.Host); err != nil {
+		return gonet.JoinHostPort(parsedURL.Host, "4222"), true
+	}
+
+	return parsedURL.Host, true
+}
+
+func (c connectivityChecker) checkAnyReachable(hosts []string, port string) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	var errs []string
+
+	for _, host := range hosts {
+		err := c.checkReachable(gonet.JoinHostPort(host, port))
+		if err == nil {
+			return nil
+		}
+
+		errs = append(errs, err.Error())
+	}
+
+	return bosherr.Errorf("None of %s are reachable: %s", strings.Join(hosts, ", "), strings.Join(errs, "; "))
+}
+
+func (c connectivityChecker) checkReachable(address string) error {
+	conn, err := c.dial("tcp", address, c.retryDelay+time.Second)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+type noopConnectivityChecker struct{}
+
+// NewNoopConnectivityChecker returns checker that considers all networks reachable
+func NewNoopConnectivityChecker() ConnectivityChecker {
+	return noopConnectivityChecker{}
+}
+
+func (c noopConnectivityChecker) Check(networks boshsettings.Networks, mbusURL string) error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package net
+
+import (
+	"strings"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+// DNSConfiguration is resolver configuration taken
+// from the network that is default for DNS
+type DNSConfiguration struct {
+	Servers []string
+	Search  []string
+	Options []string
+}
+
+func NewDNSConfiguration(networks boshsettings.Networks) DNSConfiguration {
+	nonVipNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if networkSettings.IsVIP() {
+			continue
+		}
+		nonVipNetworks[networkName] = networkSettings
+	}
+
+	dnsNetwork, _ := nonVipNetworks.DefaultNetworkFor("dns")
+
+	return DNSConfiguration{
+		Servers: dnsNetwork.DNS,
+		Search:  dnsNetwork.DNSSearch,
+		Options: dnsNetwork.DNSOptions,
+	}
+}
+
+// ResolvConf returns configuration in resolv.conf format
+func (c DNSConfiguration) ResolvConf() string {
+	var lines []string
+
+	for _, server := range c.Servers {
+		lines = append(lines, "nameserver "+server+"\n")
+	}
+
+	if len(c.Search) > 0 {
+		lines = append(lines, "search "+strings.Join(c.Search, " ")+"\n")
+	}
+
+	if len(c.Options) > 0 {
+		lines = append(lines, "options "+strings.Join(c.Options, " ")+"\n")
+	}
+
+	return strings.Join(lines, "")
+}
+
+// dhcpConfigTemplateValues are DNS values rendered into dhclient.conf
+type dhcpConfigTemplateValues struct {
+	DNSServers string
+	DNSSearch  string
+}
+
+func (c DNSConfiguration) dhcpConfigTemplateValues() dhcpConfigTemplateValues {
+	// Keep DNS servers in the order specified by the network
+	// because they are added by a *single* DHCP's prepend command
+	return dhcpConfigTemplateValues{
+		DNSServers: strings.Join(ipv4DNSServers(c.Servers), ", "),
+		DNSSearch:  c.dhclientSearchList(),
+	}
+}
+
+// dhclientSearchList returns search domains as quoted dhclient option value
+func (c DNSConfiguration) dhclientSearchList() string {
+	var domains []string
+
+	for _, domain := range c.Search {
+		domains = append(domains, `"`+domain+`"`)
+	}
+
+	return strings.Join(domains, ", ")
+}
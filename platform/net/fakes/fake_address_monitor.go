@@ -0,0 +1,21 @@
+package fakes
+
+import (
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+type FakeAddressMonitor struct {
+	Networks       boshsettings.Networks
+	AddressChanges []boshnet.AddressChange
+	HandlerErrs    []error
+	MonitorErr     error
+}
+
+func (m *FakeAddressMonitor) MonitorAddresses(networksFunc boshnet.NetworksFunc, handler boshnet.AddressChangeHandler) error {
+	m.Networks = networksFunc()
+	for _, change := range m.AddressChanges {
+		m.HandlerErrs = append(m.HandlerErrs, handler(change))
+	}
+	return m.MonitorErr
+}
@@ -0,0 +1,17 @@
+package fakes
+
+import (
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+type FakeConnectivityChecker struct {
+	CheckNetworks []boshsettings.Networks
+	CheckMbusURL  string
+	CheckErr      error
+}
+
+func (c *FakeConnectivityChecker) Check(networks boshsettings.Networks, mbusURL string) error {
+	c.CheckNetworks = append(c.CheckNetworks, networks)
+	c.CheckMbusURL = mbusURL
+	return c.CheckErr
+}
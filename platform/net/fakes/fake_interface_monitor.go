@@ -0,0 +1,18 @@
+package fakes
+
+import (
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+)
+
+type FakeInterfaceMonitor struct {
+	AddedInterfaces []boshnet.NetworkInterface
+	HandlerErrs     []error
+	MonitorErr      error
+}
+
+func (m *FakeInterfaceMonitor) MonitorInterfaces(handler boshnet.InterfaceAddedHandler) error {
+	for _, iface := range m.AddedInterfaces {
+		m.HandlerErrs = append(m.HandlerErrs, handler(iface))
+	}
+	return m.MonitorErr
+}
@@ -8,14 +8,16 @@ type FakeManager struct {
 	FakeDefaultNetworkResolver
 
 	SetupNetworkingNetworks boshsettings.Networks
+	SetupNetworkingMbusURL  string
 	SetupNetworkingErr      error
 
 	SetupDhcpNetworks boshsettings.Networks
 	SetupDhcpErr      error
 }
 
-func (net *FakeManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
+func (net *FakeManager) SetupNetworking(networks boshsettings.Networks, mbusURL string, errCh chan error) error {
 	net.SetupNetworkingNetworks = networks
+	net.SetupNetworkingMbusURL = mbusURL
 	return net.SetupNetworkingErr
 }
 
@@ -1,6 +1,11 @@
 package net
 
 import (
+	"fmt"
+	gonet "net"
+	"reflect"
+	"sort"
+
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -15,10 +20,87 @@ type StaticInterfaceConfiguration struct {
 	Broadcast string
 	Mac       string
 	Gateway   string
+
+	// IPv6 configuration is optional
+	IPv6Address string
+	IPv6Prefix  int
+	IPv6Gateway string
+
+	Routes []StaticRoute
+
+	// PolicyRoutingTable is set when traffic sourced from interface's
+	// address is routed via a separate routing table; 0 otherwise
+	PolicyRoutingTable int
+
+	InterfaceLink
+}
+
+type StaticRoute struct {
+	Destination  string
+	PrefixLength int
+	Gateway      string
+}
+
+// First routing table used for policy routing; tables below 100
+// are left for manual configuration (local, main and default are above 250)
+const policyRoutingTableBase = 100
+
+// RouteSpecs returns routes in `ip route` format, e.g. "10.0.0.0/8 via 1.2.3.1 dev eth0"
+func (c StaticInterfaceConfiguration) RouteSpecs() []string {
+	var specs []string
+
+	for _, route := range c.Routes {
+		specs = append(specs, c.routeSpec(route, 0))
+	}
+
+	if c.PolicyRoutingTable == 0 {
+		return specs
+	}
+
+	prefixLength, _ := netmaskPrefixLength(c.Netmask)
+
+	specs = append(specs, fmt.Sprintf("%s/%d dev %s src %s table %d", c.Network, prefixLength, c.Name, c.Address, c.PolicyRoutingTable))
+
+	if c.Gateway != "" {
+		specs = append(specs, fmt.Sprintf("default via %s dev %s table %d", c.Gateway, c.Name, c.PolicyRoutingTable))
+	}
+
+	for _, route := range c.Routes {
+		specs = append(specs, c.routeSpec(route, c.PolicyRoutingTable))
+	}
+
+	return specs
+}
+
+// RuleSpecs returns rules in `ip rule` format, e.g. "from 1.2.3.4 table 101"
+func (c StaticInterfaceConfiguration) RuleSpecs() []string {
+	if c.PolicyRoutingTable == 0 {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("from %s table %d", c.Address, c.PolicyRoutingTable)}
+}
+
+func (c StaticInterfaceConfiguration) routeSpec(route StaticRoute, table int) string {
+	spec := fmt.Sprintf("%s/%d", route.Destination, route.PrefixLength)
+
+	if route.Gateway != "" {
+		spec += " via " + route.Gateway
+	}
+
+	spec += " dev " + c.Name
+
+	if table != 0 {
+		spec += fmt.Sprintf(" table %d", table)
+	}
+
+	return spec
 }
 
 type DHCPInterfaceConfiguration struct {
 	Name string
+
+	InterfaceLink
 }
 
 type InterfaceConfigurationCreator interface {
@@ -37,13 +119,30 @@ func NewInterfaceConfigurationCreator(logger boshlog.Logger) InterfaceConfigurat
 	}
 }
 
-func (creator interfaceConfigurationCreator) createInterfaceConfiguration(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, ifaceName string, networkMACAddress string, networkSettings boshsettings.Network) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
+func (creator interfaceConfigurationCreator) createInterfaceConfiguration(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, ifaceName string, networkMACAddress string, ifaceLink InterfaceLink, networkSettings boshsettings.Network) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
 	creator.logger.Debug(creator.logTag, "Creating network configuration with IP: '%s', netmask: '%s'", networkSettings.IP, networkSettings.Netmask)
 
+	if networkSettings.VLAN != 0 {
+		if networkSettings.VLAN < 1 || networkSettings.VLAN > 4094 {
+			return nil, nil, bosherr.Errorf("Invalid VLAN id %d", networkSettings.VLAN)
+		}
+
+		ifaceLink.VLANID = networkSettings.VLAN
+		ifaceLink.VLANParent = ifaceName
+		ifaceName = fmt.Sprintf("%s.%d", ifaceName, networkSettings.VLAN)
+	}
+
+	ifaceLink.MTU = networkSettings.MTU
+
 	if networkSettings.IsDHCP() {
+		if len(networkSettings.Routes) > 0 || networkSettings.PolicyRouting {
+			return nil, nil, bosherr.Error("Static routes and policy routing are not supported on dynamic networks")
+		}
+
 		creator.logger.Debug(creator.logTag, "Using dhcp networking")
 		dhcpInterfaceConfigurations = append(dhcpInterfaceConfigurations, DHCPInterfaceConfiguration{
-			Name: ifaceName,
+			Name:          ifaceName,
+			InterfaceLink: ifaceLink,
 		})
 	} else {
 		creator.logger.Debug(creator.logTag, "Using static networking")
@@ -51,6 +150,23 @@ func (creator interfaceConfigurationCreator) createInterfaceConfiguration(static
 		if err != nil {
 			return nil, nil, bosherr.WrapError(err, "Calculating Network and Broadcast")
 		}
+
+		routes, err := creator.createStaticRoutes(networkSettings.Routes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var policyRoutingTable int
+		if networkSettings.PolicyRouting {
+			_, err = netmaskPrefixLength(networkSettings.Netmask)
+			if err != nil {
+				return nil, nil, bosherr.WrapError(err, "Configuring policy routing")
+			}
+
+			// Actual table is assigned once all interfaces are known
+			policyRoutingTable = -1
+		}
+
 		staticInterfaceConfigurations = append(staticInterfaceConfigurations, StaticInterfaceConfiguration{
 			Name:      ifaceName,
 			Address:   networkSettings.IP,
@@ -59,6 +175,15 @@ func (creator interfaceConfigurationCreator) createInterfaceConfiguration(static
 			Broadcast: broadcastAddress,
 			Mac:       networkMACAddress,
 			Gateway:   networkSettings.Gateway,
+
+			IPv6Address: networkSettings.IPv6,
+			IPv6Prefix:  networkSettings.IPv6Prefix,
+			IPv6Gateway: networkSettings.IPv6Gateway,
+
+			Routes:             routes,
+			PolicyRoutingTable: policyRoutingTable,
+
+			InterfaceLink: ifaceLink,
 		})
 	}
 	return staticInterfaceConfigurations, dhcpInterfaceConfigurations, nil
@@ -75,18 +200,33 @@ func (creator interfaceConfigurationCreator) CreateInterfaceConfigurations(netwo
 	// it's an old CPI), if we only have one interface, we should map them
 	if len(networks) == 1 {
 		_, networkSettings := creator.getTheOnlyNetwork(networks)
-		if networkSettings.Mac == "" && len(interfacesByMAC) == 1 {
+		if networkSettings.Mac == "" && !networkSettings.IsBond() && len(interfacesByMAC) == 1 {
 			networkMACAddress, ifaceName := creator.getTheOnlyInterface(interfacesByMAC)
-			staticInterfaceConfigurations, dhcpInterfaceConfigurations, err = creator.createInterfaceConfiguration(staticInterfaceConfigurations, dhcpInterfaceConfigurations, ifaceName, networkMACAddress, networkSettings)
+			staticInterfaceConfigurations, dhcpInterfaceConfigurations, err = creator.createInterfaceConfiguration(staticInterfaceConfigurations, dhcpInterfaceConfigurations, ifaceName, networkMACAddress, InterfaceLink{}, networkSettings)
 			if err != nil {
 				return nil, nil, bosherr.WrapError(err, "Creating interface configuration")
 			}
+			assignPolicyRoutingTables(staticInterfaceConfigurations)
 			return staticInterfaceConfigurations, dhcpInterfaceConfigurations, nil
 		}
 	}
 
 	// otherwise map all the networks by MAC address
 	for networkName, networkSettings := range networks {
+		if networkSettings.IsBond() {
+			ifaceName, ifaceLink, err := creator.createBondLink(networkSettings.Bond, interfacesByMAC)
+			if err != nil {
+				return nil, nil, bosherr.WrapErrorf(err, "Creating bond for network '%s'", networkName)
+			}
+
+			staticInterfaceConfigurations, dhcpInterfaceConfigurations, err = creator.createInterfaceConfiguration(staticInterfaceConfigurations, dhcpInterfaceConfigurations, ifaceName, "", ifaceLink, networkSettings)
+			if err != nil {
+				return nil, nil, bosherr.WrapError(err, "Creating interface configuration")
+			}
+
+			continue
+		}
+
 		if networkSettings.Mac == "" {
 			return nil, nil, bosherr.Errorf("Network '%s' doesn't specify a MAC address", networkName)
 		}
@@ -96,14 +236,149 @@ func (creator interfaceConfigurationCreator) CreateInterfaceConfigurations(netwo
 			return nil, nil, bosherr.Errorf("No interface exists with MAC address '%s'", networkSettings.Mac)
 		}
 
-		staticInterfaceConfigurations, dhcpInterfaceConfigurations, err = creator.createInterfaceConfiguration(staticInterfaceConfigurations, dhcpInterfaceConfigurations, ifaceName, networkSettings.Mac, networkSettings)
+		staticInterfaceConfigurations, dhcpInterfaceConfigurations, err = creator.createInterfaceConfiguration(staticInterfaceConfigurations, dhcpInterfaceConfigurations, ifaceName, networkSettings.Mac, InterfaceLink{}, networkSettings)
 		if err != nil {
 			return nil, nil, bosherr.WrapError(err, "Creating interface configuration")
 		}
 	}
+
+	err = validateBonds(staticInterfaceConfigurations, dhcpInterfaceConfigurations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mergeParentLinkOptions(staticInterfaceConfigurations, dhcpInterfaceConfigurations)
+
+	assignPolicyRoutingTables(staticInterfaceConfigurations)
+
 	return staticInterfaceConfigurations, dhcpInterfaceConfigurations, nil
 }
 
+func (creator interfaceConfigurationCreator) createBondLink(bond boshsettings.Bond, interfacesByMAC map[string]string) (string, InterfaceLink, error) {
+	bondName := bond.Name
+	if bondName == "" {
+		bondName = defaultBondName
+	}
+
+	ifaceLink := InterfaceLink{BondMode: bond.Mode}
+	if ifaceLink.BondMode == "" {
+		ifaceLink.BondMode = defaultBondMode
+	}
+
+	for _, memberMAC := range bond.Members {
+		memberName, found := interfacesByMAC[memberMAC]
+		if !found {
+			return "", InterfaceLink{}, bosherr.Errorf("No interface exists with MAC address '%s'", memberMAC)
+		}
+
+		ifaceLink.BondMembers = append(ifaceLink.BondMembers, memberName)
+	}
+
+	return bondName, ifaceLink, nil
+}
+
+// validateBonds makes sure that networks sharing a bond (e.g. VLANs on top of it)
+// describe it the same way
+func validateBonds(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) error {
+	bonds := map[string]InterfaceLink{}
+
+	check := func(name string, ifaceLink InterfaceLink) error {
+		if len(ifaceLink.BondMembers) == 0 {
+			return nil
+		}
+
+		bondName := name
+		if ifaceLink.VLANID != 0 {
+			bondName = ifaceLink.VLANParent
+		}
+
+		bond := InterfaceLink{BondMode: ifaceLink.BondMode, BondMembers: ifaceLink.BondMembers}
+
+		existingBond, found := bonds[bondName]
+		if found && !reflect.DeepEqual(existingBond, bond) {
+			return bosherr.Errorf("Bond '%s' is configured differently by multiple networks", bondName)
+		}
+
+		bonds[bondName] = bond
+
+		return nil
+	}
+
+	for _, iface := range staticInterfaceConfigurations {
+		err := check(iface.Name, iface.InterfaceLink)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, iface := range dhcpInterfaceConfigurations {
+		err := check(iface.Name, iface.InterfaceLink)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (creator interfaceConfigurationCreator) createStaticRoutes(routes []boshsettings.Route) ([]StaticRoute, error) {
+	var staticRoutes []StaticRoute
+
+	for _, route := range routes {
+		if gonet.ParseIP(route.Destination) == nil {
+			return nil, bosherr.Errorf("Invalid route destination '%s'", route.Destination)
+		}
+
+		if route.Gateway != "" && gonet.ParseIP(route.Gateway) == nil {
+			return nil, bosherr.Errorf("Invalid route gateway '%s'", route.Gateway)
+		}
+
+		prefixLength, err := netmaskPrefixLength(route.Netmask)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Creating route to '%s'", route.Destination)
+		}
+
+		// Destination with host bits set is rejected by ip route
+		// and most likely means a mistyped netmask
+		destination := gonet.ParseIP(route.Destination).To4()
+		if destination == nil || !destination.Equal(destination.Mask(gonet.CIDRMask(prefixLength, 32))) {
+			return nil, bosherr.Errorf("Route destination '%s' does not match netmask '%s'", route.Destination, route.Netmask)
+		}
+
+		staticRoutes = append(staticRoutes, StaticRoute{
+			Destination:  route.Destination,
+			PrefixLength: prefixLength,
+			Gateway:      route.Gateway,
+		})
+	}
+
+	return staticRoutes, nil
+}
+
+// assignPolicyRoutingTables numbers tables by interface name
+// so that they do not depend on networks iteration order
+func assignPolicyRoutingTables(staticInterfaceConfigurations []StaticInterfaceConfiguration) {
+	var names []string
+	for _, iface := range staticInterfaceConfigurations {
+		if iface.PolicyRoutingTable != 0 {
+			names = append(names, iface.Name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for i := range staticInterfaceConfigurations {
+		if staticInterfaceConfigurations[i].PolicyRoutingTable == 0 {
+			continue
+		}
+		for j, name := range names {
+			if staticInterfaceConfigurations[i].Name == name {
+				staticInterfaceConfigurations[i].PolicyRoutingTable = policyRoutingTableBase + j + 1
+			}
+		}
+	}
+}
+
 func (creator interfaceConfigurationCreator) getTheOnlyNetwork(networks boshsettings.Networks) (string, boshsettings.Network) {
 	for networkName, networkSettings := range networks {
 		return networkName, networkSettings
@@ -117,3 +392,31 @@ func (creator interfaceConfigurationCreator) getTheOnlyInterface(interfacesByMAC
 	}
 	return "", ""
 }
+
+// ipv4DNSServers filters out IPv6 servers since dhclient
+// only accepts IPv4 addresses in domain-name-servers option
+func ipv4DNSServers(dnsServers []string) []string {
+	var servers []string
+	for _, server := range dnsServers {
+		ip := gonet.ParseIP(server)
+		if ip != nil && ip.To4() == nil {
+			continue
+		}
+		servers = append(servers, server)
+	}
+	return servers
+}
+
+func netmaskPrefixLength(netmask string) (int, error) {
+	ip := gonet.ParseIP(netmask)
+	if ip == nil || ip.To4() == nil {
+		return 0, bosherr.Errorf("Invalid netmask '%s'", netmask)
+	}
+
+	ones, bits := gonet.IPMask(ip.To4()).Size()
+	if bits == 0 {
+		return 0, bosherr.Errorf("Non-contiguous netmask '%s'", netmask)
+	}
+
+	return ones, nil
+}
@@ -65,6 +65,37 @@ func describeInterfaceConfigurationCreator() {
 				Expect(len(dhcpInterfaceConfigurations)).To(Equal(0))
 			})
 
+			It("includes IPv6 configuration of dual-stack network", func() {
+				staticNetwork.IPv6 = "2001:db8::5"
+				staticNetwork.IPv6Prefix = 64
+				staticNetwork.IPv6Gateway = "2001:db8::1"
+
+				networks := boshsettings.Networks{
+					"foo": staticNetwork,
+				}
+				interfacesByMAC := map[string]string{
+					"fake-static-mac-address": "static-interface-name",
+				}
+
+				staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(staticInterfaceConfigurations).To(Equal([]StaticInterfaceConfiguration{
+					{
+						Name:        "static-interface-name",
+						Address:     "1.2.3.4",
+						Netmask:     "255.255.255.0",
+						Network:     "1.2.3.0",
+						Broadcast:   "1.2.3.255",
+						Mac:         "fake-static-mac-address",
+						Gateway:     "3.4.5.6",
+						IPv6Address: "2001:db8::5",
+						IPv6Prefix:  64,
+						IPv6Gateway: "2001:db8::1",
+					},
+				}))
+			})
+
 			It("returns an error the network doesn't have a matching interface", func() {
 				networks := boshsettings.Networks{
 					"foo": staticNetwork,
@@ -209,6 +240,267 @@ func describeInterfaceConfigurationCreator() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(Equal("Network 'foo' doesn't specify a MAC address"))
 			})
+
+			It("assigns policy routing tables ordered by interface name", func() {
+				secondStaticNetwork := boshsettings.Network{
+					Type:          "manual",
+					IP:            "5.6.7.8",
+					Netmask:       "255.255.255.0",
+					Gateway:       "5.6.7.1",
+					Mac:           "fake-second-static-mac-address",
+					PolicyRouting: true,
+				}
+				staticNetwork.PolicyRouting = true
+
+				networks := boshsettings.Networks{
+					"foo": staticNetwork,
+					"bar": secondStaticNetwork,
+					"baz": dhcpNetwork,
+				}
+				interfacesByMAC := map[string]string{
+					"fake-dhcp-mac-address":          "eth0",
+					"fake-static-mac-address":        "eth2",
+					"fake-second-static-mac-address": "eth1",
+				}
+
+				staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				tables := map[string]int{}
+				for _, iface := range staticInterfaceConfigurations {
+					tables[iface.Name] = iface.PolicyRoutingTable
+				}
+				Expect(tables).To(Equal(map[string]int{"eth1": 101, "eth2": 102}))
+			})
+		})
+
+		Context("Static routes", func() {
+			var interfacesByMAC map[string]string
+
+			BeforeEach(func() {
+				interfacesByMAC = map[string]string{
+					"fake-static-mac-address": "eth0",
+				}
+			})
+
+			It("includes routes of static network", func() {
+				staticNetwork.Routes = []boshsettings.Route{
+					{Destination: "10.0.0.0", Netmask: "255.0.0.0", Gateway: "1.2.3.1"},
+					{Destination: "192.168.0.0", Netmask: "255.255.0.0"},
+				}
+
+				staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(staticInterfaceConfigurations[0].Routes).To(Equal([]StaticRoute{
+					{Destination: "10.0.0.0", PrefixLength: 8, Gateway: "1.2.3.1"},
+					{Destination: "192.168.0.0", PrefixLength: 16},
+				}))
+				Expect(staticInterfaceConfigurations[0].PolicyRoutingTable).To(Equal(0))
+			})
+
+			It("returns an error if route netmask is invalid", func() {
+				staticNetwork.Routes = []boshsettings.Route{
+					{Destination: "10.0.0.0", Netmask: "255.0.255.0"},
+				}
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Non-contiguous netmask '255.0.255.0'"))
+			})
+
+			It("returns an error if route destination is invalid", func() {
+				staticNetwork.Routes = []boshsettings.Route{
+					{Destination: "not-an-ip", Netmask: "255.0.0.0"},
+				}
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid route destination 'not-an-ip'"))
+			})
+
+			It("returns an error if route destination has bits outside of netmask", func() {
+				staticNetwork.Routes = []boshsettings.Route{
+					{Destination: "10.0.0.5", Netmask: "255.0.0.0"},
+				}
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Route destination '10.0.0.5' does not match netmask '255.0.0.0'"))
+			})
+
+			It("returns an error if dynamic network specifies routes", func() {
+				dhcpNetwork.PolicyRouting = true
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": dhcpNetwork}, map[string]string{"fake-dhcp-mac-address": "eth0"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("not supported on dynamic networks"))
+			})
+		})
+	})
+
+	Describe("Bonds and VLANs", func() {
+		var interfacesByMAC map[string]string
+
+		BeforeEach(func() {
+			interfacesByMAC = map[string]string{
+				"fake-mac-1": "eth0",
+				"fake-mac-2": "eth1",
+			}
+			staticNetwork.Mac = ""
+			staticNetwork.MTU = 9000
+			staticNetwork.Bond = boshsettings.Bond{Members: []string{"fake-mac-1", "fake-mac-2"}}
+		})
+
+		It("creates bond of interfaces matching member MAC addresses", func() {
+			staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(staticInterfaceConfigurations).To(HaveLen(1))
+			Expect(staticInterfaceConfigurations[0].Name).To(Equal("bond0"))
+			Expect(staticInterfaceConfigurations[0].InterfaceLink).To(Equal(InterfaceLink{
+				MTU:         9000,
+				BondMode:    "active-backup",
+				BondMembers: []string{"eth0", "eth1"},
+			}))
+			Expect(staticInterfaceConfigurations[0].IsBond()).To(BeTrue())
+		})
+
+		It("creates VLAN interfaces on top of named bond", func() {
+			staticNetwork.Bond.Name = "bond1"
+			staticNetwork.Bond.Mode = "802.3ad"
+			staticNetwork.VLAN = 100
+
+			dhcpNetwork.Mac = ""
+			dhcpNetwork.Bond = staticNetwork.Bond
+			dhcpNetwork.VLAN = 200
+
+			staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork, "bar": dhcpNetwork}, interfacesByMAC)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(staticInterfaceConfigurations[0].Name).To(Equal("bond1.100"))
+			Expect(staticInterfaceConfigurations[0].InterfaceLink).To(Equal(InterfaceLink{
+				MTU:         9000,
+				BondMode:    "802.3ad",
+				BondMembers: []string{"eth0", "eth1"},
+				VLANID:      100,
+				VLANParent:  "bond1",
+			}))
+			Expect(staticInterfaceConfigurations[0].IsBond()).To(BeFalse())
+
+			Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{
+				{
+					Name: "bond1.200",
+					InterfaceLink: InterfaceLink{
+						BondMode:    "802.3ad",
+						BondMembers: []string{"eth0", "eth1"},
+						VLANID:      200,
+						VLANParent:  "bond1",
+					},
+				},
+			}))
+		})
+
+		It("creates VLAN interface on top of interface matching MAC address", func() {
+			staticNetwork.Bond = boshsettings.Bond{}
+			staticNetwork.Mac = "fake-mac-2"
+			staticNetwork.VLAN = 100
+
+			staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(staticInterfaceConfigurations[0].Name).To(Equal("eth1.100"))
+			Expect(staticInterfaceConfigurations[0].InterfaceLink).To(Equal(InterfaceLink{
+				MTU:        9000,
+				VLANID:     100,
+				VLANParent: "eth1",
+			}))
+		})
+
+		It("uses largest MTU of VLANs for their parent interface", func() {
+			staticNetwork.Bond = boshsettings.Bond{}
+			staticNetwork.Mac = "fake-mac-1"
+			staticNetwork.MTU = 0
+
+			dhcpNetwork.Mac = "fake-mac-1"
+			dhcpNetwork.VLAN = 100
+			dhcpNetwork.MTU = 1500
+
+			vlanNetwork := dhcpNetwork
+			vlanNetwork.VLAN = 200
+			vlanNetwork.MTU = 9000
+
+			networks := boshsettings.Networks{"foo": staticNetwork, "bar": dhcpNetwork, "baz": vlanNetwork}
+
+			staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(staticInterfaceConfigurations).To(HaveLen(1))
+			Expect(staticInterfaceConfigurations[0].Name).To(Equal("eth0"))
+			Expect(staticInterfaceConfigurations[0].MTU).To(Equal(9000))
+			Expect(dhcpInterfaceConfigurations).To(HaveLen(2))
+		})
+
+		It("returns an error if VLAN id is out of range", func() {
+			staticNetwork.VLAN = 4095
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid VLAN id 4095"))
+		})
+
+		It("returns an error if bond member does not match any interface", func() {
+			staticNetwork.Bond.Members = []string{"fake-mac-1", "fake-mac-3"}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No interface exists with MAC address 'fake-mac-3'"))
+		})
+
+		It("returns an error if networks describe the same bond differently", func() {
+			staticNetwork.VLAN = 100
+
+			dhcpNetwork.Mac = ""
+			dhcpNetwork.Bond = boshsettings.Bond{Mode: "802.3ad", Members: staticNetwork.Bond.Members}
+			dhcpNetwork.VLAN = 200
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork, "bar": dhcpNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Bond 'bond0' is configured differently by multiple networks"))
+		})
+	})
+
+	Describe("StaticInterfaceConfiguration", func() {
+		var iface StaticInterfaceConfiguration
+
+		BeforeEach(func() {
+			iface = StaticInterfaceConfiguration{
+				Name:    "eth1",
+				Address: "1.2.3.4",
+				Netmask: "255.255.255.0",
+				Network: "1.2.3.0",
+				Gateway: "1.2.3.1",
+				Routes: []StaticRoute{
+					{Destination: "10.0.0.0", PrefixLength: 8, Gateway: "1.2.3.254"},
+				},
+			}
+		})
+
+		It("returns route and no rule specs without policy routing", func() {
+			Expect(iface.RouteSpecs()).To(Equal([]string{"10.0.0.0/8 via 1.2.3.254 dev eth1"}))
+			Expect(iface.RuleSpecs()).To(BeEmpty())
+		})
+
+		It("returns routes in policy routing table and source rule with policy routing", func() {
+			iface.PolicyRoutingTable = 101
+
+			Expect(iface.RouteSpecs()).To(Equal([]string{
+				"10.0.0.0/8 via 1.2.3.254 dev eth1",
+				"1.2.3.0/24 dev eth1 src 1.2.3.4 table 101",
+				"default via 1.2.3.1 dev eth1 table 101",
+				"10.0.0.0/8 via 1.2.3.254 dev eth1 table 101",
+			}))
+			Expect(iface.RuleSpecs()).To(Equal([]string{"from 1.2.3.4 table 101"}))
 		})
 	})
 
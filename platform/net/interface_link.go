@@ -0,0 +1,255 @@
+package net
+
+const (
+	defaultBondName = "bond0"
+	defaultBondMode = "active-backup"
+)
+
+// InterfaceLink describes how configured interface is built
+// on top of physical interfaces
+type InterfaceLink struct {
+	MTU int
+
+	// Bond fields describe bond that is either the interface itself
+	// or its VLAN parent
+	BondMode    string
+	BondMembers []string
+
+	// VLAN interfaces are named <parent>.<id>
+	VLANID     int
+	VLANParent string
+}
+
+// IsBond reports whether interface itself is a bond
+func (l InterfaceLink) IsBond() bool {
+	return len(l.BondMembers) > 0 && l.VLANID == 0
+}
+
+// LinkInterfaceConfiguration describes interface that does not get an address
+// but has to be brought up for other interfaces, e.g. bond members
+type LinkInterfaceConfiguration struct {
+	Name string
+	MTU  int
+
+	// Set for bond members
+	BondMaster string
+
+	// Set for bonds that are VLAN parents
+	BondMode    string
+	BondMembers []string
+}
+
+// linkInterfaceConfigurations returns link interfaces required by configured
+// interfaces that are not configured themselves, e.g. VLAN parent with
+// untagged network is configured by its own network
+func linkInterfaceConfigurations(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) []LinkInterfaceConfiguration {
+	configured := map[string]bool{}
+
+	for _, iface := range staticInterfaceConfigurations {
+		configured[iface.Name] = true
+	}
+
+	for _, iface := range dhcpInterfaceConfigurations {
+		configured[iface.Name] = true
+	}
+
+	var links []LinkInterfaceConfiguration
+
+	for _, link := range requiredLinks(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		if !configured[link.Name] {
+			links = append(links, link)
+		}
+	}
+
+	return links
+}
+
+// mergeParentLinkOptions adds options required by interfaces built on top of
+// configured interfaces to their configuration, e.g. MTU of VLANs
+func mergeParentLinkOptions(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) {
+	links := map[string]LinkInterfaceConfiguration{}
+	for _, link := range requiredLinks(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		links[link.Name] = link
+	}
+
+	merge := func(ifaceLink *InterfaceLink, link LinkInterfaceConfiguration) {
+		if link.MTU > ifaceLink.MTU {
+			ifaceLink.MTU = link.MTU
+		}
+
+		if len(ifaceLink.BondMembers) == 0 && len(link.BondMembers) > 0 {
+			ifaceLink.BondMode = link.BondMode
+			ifaceLink.BondMembers = link.BondMembers
+		}
+	}
+
+	for i, iface := range staticInterfaceConfigurations {
+		if link, found := links[iface.Name]; found {
+			merge(&staticInterfaceConfigurations[i].InterfaceLink, link)
+		}
+	}
+
+	for i, iface := range dhcpInterfaceConfigurations {
+		if link, found := links[iface.Name]; found {
+			merge(&dhcpInterfaceConfigurations[i].InterfaceLink, link)
+		}
+	}
+}
+
+// requiredLinks returns all interfaces that configured interfaces are built on top of;
+// interfaces shared by several VLANs are returned once with the largest MTU
+func requiredLinks(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) []LinkInterfaceConfiguration {
+	var links []LinkInterfaceConfiguration
+	indexes := map[string]int{}
+
+	add := func(link LinkInterfaceConfiguration) {
+		if i, found := indexes[link.Name]; found {
+			if link.MTU > links[i].MTU {
+				links[i].MTU = link.MTU
+			}
+			return
+		}
+
+		indexes[link.Name] = len(links)
+		links = append(links, link)
+	}
+
+	addFor := func(name string, ifaceLink InterfaceLink) {
+		if len(ifaceLink.BondMembers) > 0 {
+			bondName := name
+			if ifaceLink.VLANID != 0 {
+				bondName = ifaceLink.VLANParent
+			}
+
+			for _, member := range ifaceLink.BondMembers {
+				add(LinkInterfaceConfiguration{Name: member, MTU: ifaceLink.MTU, BondMaster: bondName})
+			}
+
+			if ifaceLink.VLANID != 0 {
+				add(LinkInterfaceConfiguration{
+					Name:        bondName,
+					MTU:         ifaceLink.MTU,
+					BondMode:    ifaceLink.BondMode,
+					BondMembers: ifaceLink.BondMembers,
+				})
+			}
+		} else if ifaceLink.VLANID != 0 {
+			add(LinkInterfaceConfiguration{Name: ifaceLink.VLANParent, MTU: ifaceLink.MTU})
+		}
+	}
+
+	for _, iface := range staticInterfaceConfigurations {
+		addFor(iface.Name, iface.InterfaceLink)
+	}
+
+	for _, iface := range dhcpInterfaceConfigurations {
+		addFor(iface.Name, iface.InterfaceLink)
+	}
+
+	return links
+}
+
+// linkKernelModules returns kernel modules needed by bonds and VLANs
+func linkKernelModules(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) []string {
+	var needsBonding, needs8021q bool
+
+	ifaceLinks := []InterfaceLink{}
+	for _, iface := range staticInterfaceConfigurations {
+		ifaceLinks = append(ifaceLinks, iface.InterfaceLink)
+	}
+	for _, iface := range dhcpInterfaceConfigurations {
+		ifaceLinks = append(ifaceLinks, iface.InterfaceLink)
+	}
+
+	for _, ifaceLink := range ifaceLinks {
+		needsBonding = needsBonding || len(ifaceLink.BondMembers) > 0
+		needs8021q = needs8021q || ifaceLink.VLANID != 0
+	}
+
+	var modules []string
+	if needsBonding {
+		modules = append(modules, "bonding")
+	}
+	if needs8021q {
+		modules = append(modules, "8021q")
+	}
+
+	return modules
+}
+
+// linkDependents maps interfaces to interfaces built on top of them,
+// i.e. VLANs of a parent and members of a bond, that go down with them
+func linkDependents(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) map[string][]string {
+	dependents := map[string][]string{}
+
+	for _, iface := range staticInterfaceConfigurations {
+		if iface.VLANID != 0 {
+			dependents[iface.VLANParent] = append(dependents[iface.VLANParent], iface.Name)
+		}
+	}
+
+	for _, iface := range dhcpInterfaceConfigurations {
+		if iface.VLANID != 0 {
+			dependents[iface.VLANParent] = append(dependents[iface.VLANParent], iface.Name)
+		}
+	}
+
+	for _, link := range requiredLinks(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		if link.BondMaster != "" {
+			dependents[link.BondMaster] = append(dependents[link.BondMaster], link.Name)
+		}
+	}
+
+	return dependents
+}
+
+// withDependents adds interfaces that depend on given interfaces
+func withDependents(names map[string]bool, dependents map[string][]string) map[string]bool {
+	pending := []string{}
+	for name := range names {
+		pending = append(pending, name)
+	}
+
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+
+		for _, dependent := range dependents[name] {
+			if !names[dependent] {
+				names[dependent] = true
+				pending = append(pending, dependent)
+			}
+		}
+	}
+
+	return names
+}
+
+// orderedInterfaceNames returns names of all configured interfaces
+// with interfaces they are built on top of coming first
+func orderedInterfaceNames(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) []string {
+	var names, vlanNames []string
+
+	for _, link := range linkInterfaceConfigurations(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		names = append(names, link.Name)
+	}
+
+	for _, iface := range dhcpInterfaceConfigurations {
+		if iface.VLANID != 0 {
+			vlanNames = append(vlanNames, iface.Name)
+		} else {
+			names = append(names, iface.Name)
+		}
+	}
+
+	for _, iface := range staticInterfaceConfigurations {
+		if iface.VLANID != 0 {
+			vlanNames = append(vlanNames, iface.Name)
+		} else {
+			names = append(names, iface.Name)
+		}
+	}
+
+	// VLAN parents may be configured by their own networks
+	return append(names, vlanNames...)
+}
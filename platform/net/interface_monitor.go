@@ -0,0 +1,153 @@
+package net
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+const interfaceMonitorLogTag = "interfaceMonitor"
+
+// NetworkInterface is a physical interface identified by its MAC address
+type NetworkInterface struct {
+	Name string
+	MAC  string
+}
+
+type InterfaceAddedHandler func(iface NetworkInterface) error
+
+type InterfaceMonitor interface {
+	// MonitorInterfaces calls handler for each physical interface that
+	// appears after monitoring started; it returns when handler fails
+	MonitorInterfaces(handler InterfaceAddedHandler) error
+}
+
+type pollingInterfaceMonitor struct {
+	fs       boshsys.FileSystem
+	udev     boshudev.UdevDevice
+	interval time.Duration
+	logger   boshlog.Logger
+}
+
+func NewPollingInterfaceMonitor(
+	fs boshsys.FileSystem,
+	udev boshudev.UdevDevice,
+	interval time.Duration,
+	logger boshlog.Logger,
+) InterfaceMonitor {
+	return pollingInterfaceMonitor{
+		fs:       fs,
+		udev:     udev,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (m pollingInterfaceMonitor) MonitorInterfaces(handler InterfaceAddedHandler) error {
+	knownInterfaces, err := m.physicalInterfaces()
+	if err != nil {
+		return bosherr.WrapError(err, "Listing network interfaces")
+	}
+
+	for {
+		time.Sleep(m.interval)
+
+		currentInterfaces, err := m.physicalInterfaces()
+		if err != nil {
+			m.logger.Error(interfaceMonitorLogTag, "Ignoring failure listing network interfaces: %s", err.Error())
+			continue
+		}
+
+		if !m.hasNewInterfaces(knownInterfaces, currentInterfaces) {
+			knownInterfaces = currentInterfaces
+			continue
+		}
+
+		// Attached interfaces are renamed by udev rules
+		// so wait for udev before looking at their names
+		err = m.udev.Settle()
+		if err != nil {
+			m.logger.Error(interfaceMonitorLogTag, "Ignoring udev settle failure: %s", err.Error())
+		}
+
+		currentInterfaces, err = m.physicalInterfaces()
+		if err != nil {
+			m.logger.Error(interfaceMonitorLogTag, "Ignoring failure listing network interfaces: %s", err.Error())
+			continue
+		}
+
+		macs := []string{}
+		for mac := range currentInterfaces {
+			macs = append(macs, mac)
+		}
+		sort.Strings(macs)
+
+		for _, mac := range macs {
+			if _, found := knownInterfaces[mac]; found {
+				continue
+			}
+
+			iface := NetworkInterface{Name: currentInterfaces[mac], MAC: mac}
+
+			m.logger.Info(interfaceMonitorLogTag, "Detected new network interface %s with MAC address %s", iface.Name, iface.MAC)
+
+			err = handler(iface)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Handling new network interface %s", iface.Name)
+			}
+		}
+
+		knownInterfaces = currentInterfaces
+	}
+}
+
+func (m pollingInterfaceMonitor) hasNewInterfaces(knownInterfaces, currentInterfaces map[string]string) bool {
+	for mac := range currentInterfaces {
+		if _, found := knownInterfaces[mac]; !found {
+			return true
+		}
+	}
+	return false
+}
+
+func (m pollingInterfaceMonitor) physicalInterfaces() (map[string]string, error) {
+	return physicalInterfacesByMAC(m.fs)
+}
+
+// physicalInterfacesByMAC returns interface names keyed by MAC address
+func physicalInterfacesByMAC(fs boshsys.FileSystem) (map[string]string, error) {
+	interfaces := map[string]string{}
+
+	filePaths, err := fs.Glob("/sys/class/net/*")
+	if err != nil {
+		return interfaces, bosherr.WrapError(err, "Getting file list from /sys/class/net")
+	}
+
+	for _, filePath := range filePaths {
+		if !fs.FileExists(filepath.Join(filePath, "device")) {
+			continue
+		}
+
+		// Bonded interfaces take over MAC address of the bond;
+		// their own address is kept in perm_hwaddr
+		addressPath := filepath.Join(filePath, "bonding_slave", "perm_hwaddr")
+		if !fs.FileExists(addressPath) {
+			addressPath = filepath.Join(filePath, "address")
+		}
+
+		macAddress, err := fs.ReadFileString(addressPath)
+		if err != nil {
+			return interfaces, bosherr.WrapError(err, "Reading mac address from file")
+		}
+
+		interfaces[strings.Trim(macAddress, "\n")] = filepath.Base(filePath)
+	}
+
+	return interfaces, nil
+}
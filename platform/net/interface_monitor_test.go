@@ -0,0 +1,126 @@
+package net_test
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	fakeudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice/fakes"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+)
+
+var _ = Describe("pollingInterfaceMonitor", func() {
+	var (
+		fs      *fakesys.FakeFileSystem
+		udev    *fakeudev.FakeUdevDevice
+		monitor InterfaceMonitor
+	)
+
+	writeNetworkDevice := func(iface string, macAddress string, isPhysical bool) string {
+		interfacePath := fmt.Sprintf("/sys/class/net/%s", iface)
+		fs.WriteFile(interfacePath, []byte{})
+		if isPhysical {
+			fs.WriteFile(fmt.Sprintf("/sys/class/net/%s/device", iface), []byte{})
+		}
+		fs.WriteFileString(fmt.Sprintf("/sys/class/net/%s/address", iface), fmt.Sprintf("%s\n", macAddress))
+
+		return interfacePath
+	}
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		udev = fakeudev.NewFakeUdevDevice()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		monitor = NewPollingInterfaceMonitor(fs, udev, time.Millisecond, logger)
+	})
+
+	Describe("MonitorInterfaces", func() {
+		It("calls handler for physical interfaces that appear after monitoring started", func() {
+			eth0 := writeNetworkDevice("eth0", "fake-mac-0", true)
+			eth1 := writeNetworkDevice("eth1", "fake-mac-1", true)
+			bond0 := writeNetworkDevice("bond0", "fake-mac-0", false)
+
+			fs.SetGlob(
+				"/sys/class/net/*",
+				[]string{eth0},
+				[]string{eth0},
+				[]string{eth0, bond0},
+				[]string{eth0, eth1, bond0},
+			)
+
+			var addedInterfaces []NetworkInterface
+
+			err := monitor.MonitorInterfaces(func(iface NetworkInterface) error {
+				addedInterfaces = append(addedInterfaces, iface)
+				return errors.New("fake-handler-err")
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Handling new network interface eth1"))
+			Expect(err.Error()).To(ContainSubstring("fake-handler-err"))
+
+			Expect(addedInterfaces).To(Equal([]NetworkInterface{{Name: "eth1", MAC: "fake-mac-1"}}))
+		})
+
+		It("waits for udev before reading names of new interfaces", func() {
+			eth0 := writeNetworkDevice("eth0", "fake-mac-0", true)
+			renamedEth := writeNetworkDevice("rename3", "fake-mac-1", true)
+			eth1 := writeNetworkDevice("eth1", "fake-mac-1", true)
+
+			fs.SetGlob(
+				"/sys/class/net/*",
+				[]string{eth0},
+				[]string{eth0, renamedEth},
+				[]string{eth0, eth1},
+			)
+
+			var addedInterfaces []NetworkInterface
+
+			err := monitor.MonitorInterfaces(func(iface NetworkInterface) error {
+				addedInterfaces = append(addedInterfaces, iface)
+				return errors.New("fake-handler-err")
+			})
+			Expect(err).To(HaveOccurred())
+
+			Expect(udev.Settled).To(BeTrue())
+			Expect(addedInterfaces).To(Equal([]NetworkInterface{{Name: "eth1", MAC: "fake-mac-1"}}))
+		})
+
+		It("identifies bond members by their permanent MAC address", func() {
+			eth0 := writeNetworkDevice("eth0", "fake-bond-mac", true)
+			fs.WriteFileString("/sys/class/net/eth0/bonding_slave/perm_hwaddr", "fake-mac-0\n")
+			eth1 := writeNetworkDevice("eth1", "fake-mac-1", true)
+
+			fs.SetGlob(
+				"/sys/class/net/*",
+				[]string{eth0},
+				[]string{eth0, eth1},
+				[]string{eth0, eth1},
+			)
+
+			var addedInterfaces []NetworkInterface
+
+			err := monitor.MonitorInterfaces(func(iface NetworkInterface) error {
+				addedInterfaces = append(addedInterfaces, iface)
+				return errors.New("fake-handler-err")
+			})
+			Expect(err).To(HaveOccurred())
+
+			Expect(addedInterfaces).To(Equal([]NetworkInterface{{Name: "eth1", MAC: "fake-mac-1"}}))
+		})
+
+		It("returns error if interfaces cannot be listed initially", func() {
+			fs.GlobErr = errors.New("fake-glob-err")
+
+			err := monitor.MonitorInterfaces(func(iface NetworkInterface) error {
+				return nil
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-glob-err"))
+		})
+	})
+})
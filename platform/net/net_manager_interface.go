@@ -8,5 +8,6 @@ type Manager interface {
 	// SetupNetworking configures network interfaces with either a static ip or dhcp.
 	// If errCh is provided, nil or an error will be sent
 	// upon completion of background network reconfiguration (e.g. arping).
-	SetupNetworking(networks boshsettings.Networks, errCh chan error) error
+	// Mbus URL is used to verify connectivity after reconfiguration.
+	SetupNetworking(networks boshsettings.Networks, mbusURL string, errCh chan error) error
 }
@@ -0,0 +1,382 @@
+package net
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	bosherr "github.com/cloudfoundry/bosh-agent/errors"
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	bosharp "github.com/cloudfoundry/bosh-agent/platform/net/arp"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshsys "github.com/cloudfoundry/bosh-agent/system"
+)
+
+const networkdNetManagerLogTag = "networkdNetManager"
+
+const (
+	NetManagerTypeNetworkd = "networkd"
+
+	// resolv.conf pointing to systemd-resolved stub resolver
+	resolvedStubResolvConfPath = "/run/systemd/resolve/stub-resolv.conf"
+
+	networkdConfigDir = "/etc/systemd/network"
+
+	// Prefix makes generated files take precedence over distribution defaults
+	// and allows to recognize files that are no longer needed
+	networkdConfigFilePrefix = "10-bosh-"
+)
+
+type networkdNetManager struct {
+	cmdRunner                     boshsys.CmdRunner
+	fs                            boshsys.FileSystem
+	ipResolver                    boship.Resolver
+	interfaceConfigurationCreator InterfaceConfigurationCreator
+	addressBroadcaster            bosharp.AddressBroadcaster
+	connectivityChecker           ConnectivityChecker
+	logger                        boshlog.Logger
+}
+
+func NewNetworkdNetManager(
+	fs boshsys.FileSystem,
+	cmdRunner boshsys.CmdRunner,
+	ipResolver boship.Resolver,
+	interfaceConfigurationCreator InterfaceConfigurationCreator,
+	addressBroadcaster bosharp.AddressBroadcaster,
+	connectivityChecker ConnectivityChecker,
+	logger boshlog.Logger,
+) Manager {
+	return networkdNetManager{
+		cmdRunner:                     cmdRunner,
+		fs:                            fs,
+		ipResolver:                    ipResolver,
+		interfaceConfigurationCreator: interfaceConfigurationCreator,
+		addressBroadcaster:            addressBroadcaster,
+		connectivityChecker:           connectivityChecker,
+		logger:                        logger,
+	}
+}
+
+func (net networkdNetManager) SetupNetworking(networks boshsettings.Networks, mbusURL string, errCh chan error) error {
+	nonVipNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if networkSettings.IsVIP() {
+			continue
+		}
+		nonVipNetworks[networkName] = networkSettings
+	}
+
+	staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := net.buildInterfaces(nonVipNetworks)
+	if err != nil {
+		return err
+	}
+
+	dnsConfiguration := NewDNSConfiguration(nonVipNetworks)
+
+	backup, err := newConfigurationBackup(net.fs, []string{"/etc/resolv.conf"}, []string{filepath.Join(networkdConfigDir, networkdConfigFilePrefix+"*")})
+	if err != nil {
+		return bosherr.WrapError(err, "Backing up network configuration")
+	}
+
+	err = net.writeResolvConf(dnsConfiguration)
+	if err != nil {
+		net.restoreConfiguration(backup, false)
+		return bosherr.WrapError(err, "Writing resolv.conf")
+	}
+
+	changed, err := net.writeNetworkFiles(dhcpInterfaceConfigurations, staticInterfaceConfigurations, dnsConfiguration)
+	if err != nil {
+		net.restoreConfiguration(backup, false)
+		return bosherr.WrapError(err, "Writing network configuration")
+	}
+
+	if changed {
+		net.reloadNetworkd()
+
+		err = net.connectivityChecker.Check(nonVipNetworks, mbusURL)
+		if err != nil {
+			net.logger.Error(networkdNetManagerLogTag, "Reverting network configuration because connectivity check failed: %s", err.Error())
+			net.restoreConfiguration(backup, true)
+			return bosherr.WrapError(err, "Verifying network connectivity")
+		}
+	}
+
+	net.broadcastIps(staticInterfaceConfigurations, dhcpInterfaceConfigurations, errCh)
+
+	return nil
+}
+
+const networkdNetworkTemplate = `# Generated by bosh-agent
+[Match]
+Name={{ .Name }}
+{{ if .MTU }}
+[Link]
+MTUBytes={{ .MTU }}
+{{ end }}
+[Network]
+{{ if .DHCP }}DHCP=ipv4
+{{ else }}Address={{ .Address }}/{{ .PrefixLength }}
+{{ if .Gateway }}Gateway={{ .Gateway }}
+{{ end }}{{ if .IPv6Address }}Address={{ .IPv6Address }}/{{ .IPv6Prefix }}
+{{ if .IPv6Gateway }}Gateway={{ .IPv6Gateway }}
+{{ end }}{{ end }}{{ end }}{{ range .DNSServers }}DNS={{ . }}
+{{ end }}{{ if .DNSSearch }}Domains={{ range $i, $d := .DNSSearch }}{{ if $i }} {{ end }}{{ $d }}{{ end }}
+{{ end }}{{ range .Routes }}
+[Route]
+Destination={{ .Destination }}/{{ .PrefixLength }}
+{{ if .Gateway }}Gateway={{ .Gateway }}
+{{ end }}{{ end }}{{ if .PolicyRoutingTable }}
+[Route]
+Destination={{ .Network }}/{{ .PrefixLength }}
+Scope=link
+PreferredSource={{ .Address }}
+Table={{ .PolicyRoutingTable }}
+{{ if .Gateway }}
+[Route]
+Gateway={{ .Gateway }}
+Table={{ .PolicyRoutingTable }}
+{{ end }}{{ range .Routes }}
+[Route]
+Destination={{ .Destination }}/{{ .PrefixLength }}
+{{ if .Gateway }}Gateway={{ .Gateway }}
+{{ end }}Table={{ $.PolicyRoutingTable }}
+{{ end }}
+[RoutingPolicyRule]
+From={{ .Address }}/32
+Table={{ .PolicyRoutingTable }}
+{{ end }}`
+
+type networkdNetworkConfig struct {
+	Name string
+	DHCP bool
+	MTU  int
+
+	Address      string
+	Network      string
+	PrefixLength int
+	Gateway      string
+
+	IPv6Address string
+	IPv6Prefix  int
+	IPv6Gateway string
+
+	DNSServers []string
+	DNSSearch  []string
+
+	Routes             []StaticRoute
+	PolicyRoutingTable int
+}
+
+func (net networkdNetManager) writeNetworkFiles(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsConfiguration DNSConfiguration) (bool, error) {
+	var configs []networkdNetworkConfig
+
+	for _, iface := range dhcpInterfaceConfigurations {
+		err := net.checkInterfaceLink(iface.Name, iface.InterfaceLink)
+		if err != nil {
+			return false, err
+		}
+
+		configs = append(configs, networkdNetworkConfig{
+			Name:       iface.Name,
+			DHCP:       true,
+			MTU:        iface.MTU,
+			DNSServers: dnsConfiguration.Servers,
+			DNSSearch:  dnsConfiguration.Search,
+		})
+	}
+
+	for _, iface := range staticInterfaceConfigurations {
+		err := net.checkInterfaceLink(iface.Name, iface.InterfaceLink)
+		if err != nil {
+			return false, err
+		}
+
+		prefixLength, err := netmaskPrefixLength(iface.Netmask)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Converting netmask of %s", iface.Name)
+		}
+
+		configs = append(configs, networkdNetworkConfig{
+			Name:         iface.Name,
+			MTU:          iface.MTU,
+			Address:      iface.Address,
+			Network:      iface.Network,
+			PrefixLength: prefixLength,
+			Gateway:      iface.Gateway,
+			IPv6Address:  iface.IPv6Address,
+			IPv6Prefix:   iface.IPv6Prefix,
+			IPv6Gateway:  iface.IPv6Gateway,
+			DNSServers:   dnsConfiguration.Servers,
+			DNSSearch:    dnsConfiguration.Search,
+
+			Routes:             iface.Routes,
+			PolicyRoutingTable: iface.PolicyRoutingTable,
+		})
+	}
+
+	t := template.Must(template.New("networkd-network").Parse(networkdNetworkTemplate))
+
+	var anyFileChanged bool
+	writtenPaths := map[string]bool{}
+
+	for _, config := range configs {
+		buffer := bytes.NewBuffer([]byte{})
+
+		err := t.Execute(buffer, config)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Generating config from template")
+		}
+
+		filePath := filepath.Join(networkdConfigDir, networkdConfigFilePrefix+config.Name+".network")
+
+		changed, err := net.fs.ConvergeFileContents(filePath, buffer.Bytes())
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Writing to %s", filePath)
+		}
+
+		writtenPaths[filePath] = true
+		anyFileChanged = anyFileChanged || changed
+	}
+
+	existingPaths, err := net.fs.Glob(filepath.Join(networkdConfigDir, networkdConfigFilePrefix+"*.network"))
+	if err != nil {
+		return false, bosherr.WrapError(err, "Finding existing network configuration")
+	}
+
+	for _, existingPath := range existingPaths {
+		if writtenPaths[existingPath] {
+			continue
+		}
+
+		err = net.fs.RemoveAll(existingPath)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Removing %s", existingPath)
+		}
+
+		anyFileChanged = true
+	}
+
+	return anyFileChanged, nil
+}
+
+// writeResolvConf replaces resolv.conf managed by systemd-resolved with
+// generated one when DNS options are configured since systemd-resolved
+// does not offer equivalents of resolver options
+func (net networkdNetManager) writeResolvConf(dnsConfiguration DNSConfiguration) error {
+	_, isSymlink := readSymlink(net.fs, "/etc/resolv.conf")
+
+	if len(dnsConfiguration.Options) == 0 {
+		if isSymlink || !net.fs.FileExists("/etc/resolv.conf") {
+			return nil
+		}
+
+		contents, err := net.fs.ReadFileString("/etc/resolv.conf")
+		if err != nil {
+			return bosherr.WrapError(err, "Reading /etc/resolv.conf")
+		}
+
+		// Give resolv.conf back to systemd-resolved once options are removed
+		if strings.HasPrefix(contents, networkdResolvConfHeader) {
+			err = net.fs.Symlink(resolvedStubResolvConfPath, "/etc/resolv.conf")
+			if err != nil {
+				return bosherr.WrapError(err, "Linking /etc/resolv.conf to systemd-resolved")
+			}
+		}
+
+		return nil
+	}
+
+	// Writing through the symlink would modify file owned by systemd-resolved
+	if isSymlink {
+		err := net.fs.RemoveAll("/etc/resolv.conf")
+		if err != nil {
+			return bosherr.WrapError(err, "Removing /etc/resolv.conf symlink")
+		}
+	}
+
+	_, err := net.fs.ConvergeFileContents("/etc/resolv.conf", []byte(networkdResolvConfHeader+dnsConfiguration.ResolvConf()))
+	if err != nil {
+		return bosherr.WrapError(err, "Writing to /etc/resolv.conf")
+	}
+
+	return nil
+}
+
+const networkdResolvConfHeader = "# Generated by bosh-agent\n"
+
+// checkInterfaceLink rejects bonds and VLANs which require
+// netdev files that are not generated yet
+func (net networkdNetManager) checkInterfaceLink(name string, ifaceLink InterfaceLink) error {
+	if len(ifaceLink.BondMembers) > 0 || ifaceLink.VLANID != 0 {
+		return bosherr.Errorf("Bonds and VLANs are not supported by systemd-networkd net manager: %s", name)
+	}
+	return nil
+}
+
+func (net networkdNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
+	interfacesByMacAddress, err := physicalInterfacesByMAC(net.fs)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Getting network interfaces")
+	}
+
+	staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := net.interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMacAddress)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Creating interface configurations")
+	}
+
+	return staticInterfaceConfigurations, dhcpInterfaceConfigurations, nil
+}
+
+func (net networkdNetManager) broadcastIps(staticInterfaceConfigurations []StaticInterfaceConfiguration, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, errCh chan error) {
+	addresses := []boship.InterfaceAddress{}
+	for _, iface := range staticInterfaceConfigurations {
+		addresses = append(addresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.Address))
+		if iface.IPv6Address != "" {
+			addresses = append(addresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.IPv6Address))
+		}
+	}
+	for _, iface := range dhcpInterfaceConfigurations {
+		addresses = append(addresses, boship.NewResolvingInterfaceAddress(iface.Name, net.ipResolver))
+	}
+
+	go func() {
+		net.addressBroadcaster.BroadcastMACAddresses(addresses)
+		if errCh != nil {
+			errCh <- nil
+		}
+	}()
+}
+
+// restoreConfiguration brings back previous configuration files
+// and optionally reloads networkd to apply them
+func (net networkdNetManager) restoreConfiguration(backup configurationBackup, reload bool) {
+	err := backup.Restore()
+	if err != nil {
+		net.logger.Error(networkdNetManagerLogTag, "Failed to restore previous network configuration: %s", err.Error())
+		return
+	}
+
+	if reload {
+		net.reloadNetworkd()
+	}
+}
+
+// reloadNetworkd applies changed configuration; older systemd versions
+// do not support networkctl reload so networkd is restarted instead
+func (net networkdNetManager) reloadNetworkd() {
+	net.logger.Debug(networkdNetManagerLogTag, "Reloading systemd-networkd")
+
+	_, _, _, err := net.cmdRunner.RunCommand("networkctl", "reload")
+	if err == nil {
+		return
+	}
+
+	net.logger.Debug(networkdNetManagerLogTag, "Falling back to restarting systemd-networkd: %s", err.Error())
+
+	_, _, _, err = net.cmdRunner.RunCommand("systemctl", "restart", "systemd-networkd")
+	if err != nil {
+		net.logger.Error(networkdNetManagerLogTag, "Ignoring systemd-networkd restart failure: %s", err.Error())
+	}
+}
@@ -0,0 +1,434 @@
+package net_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	fakearp "github.com/cloudfoundry/bosh-agent/platform/net/arp/fakes"
+	fakenet "github.com/cloudfoundry/bosh-agent/platform/net/fakes"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	fakeip "github.com/cloudfoundry/bosh-agent/platform/net/ip/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesys "github.com/cloudfoundry/bosh-agent/system/fakes"
+)
+
+var _ = Describe("networkdNetManager", describeNetworkdNetManager)
+
+func describeNetworkdNetManager() {
+	var (
+		fs                  *fakesys.FakeFileSystem
+		cmdRunner           *fakesys.FakeCmdRunner
+		ipResolver          *fakeip.FakeResolver
+		addressBroadcaster  *fakearp.FakeAddressBroadcaster
+		connectivityChecker *fakenet.FakeConnectivityChecker
+		netManager          Manager
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		ipResolver = &fakeip.FakeResolver{}
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		addressBroadcaster = &fakearp.FakeAddressBroadcaster{}
+		connectivityChecker = &fakenet.FakeConnectivityChecker{}
+		netManager = NewNetworkdNetManager(
+			fs,
+			cmdRunner,
+			ipResolver,
+			NewInterfaceConfigurationCreator(logger),
+			addressBroadcaster,
+			connectivityChecker,
+			logger,
+		)
+	})
+
+	Describe("SetupNetworking", func() {
+		var (
+			dhcpNetwork   boshsettings.Network
+			staticNetwork boshsettings.Network
+			networks      boshsettings.Networks
+		)
+
+		BeforeEach(func() {
+			dhcpNetwork = boshsettings.Network{
+				Type:    "dynamic",
+				Default: []string{"dns"},
+				DNS:     []string{"8.8.8.8", "9.9.9.9"},
+				Mac:     "fake-dhcp-mac-address",
+			}
+			staticNetwork = boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Netmask: "255.255.255.0",
+				Gateway: "3.4.5.6",
+				Mac:     "fake-static-mac-address",
+			}
+			networks = boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}
+
+			interfacePaths := []string{}
+			for iface, mac := range map[string]string{"ethdhcp": dhcpNetwork.Mac, "ethstatic": staticNetwork.Mac} {
+				interfacePath := fmt.Sprintf("/sys/class/net/%s", iface)
+				fs.WriteFile(interfacePath, []byte{})
+				fs.WriteFile(fmt.Sprintf("/sys/class/net/%s/device", iface), []byte{})
+				fs.WriteFileString(fmt.Sprintf("/sys/class/net/%s/address", iface), mac+"\n")
+				interfacePaths = append(interfacePaths, interfacePath)
+			}
+			fs.SetGlob("/sys/class/net/*", interfacePaths)
+		})
+
+		It("writes network file for static interface", func() {
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkFile := fs.GetFileTestStat("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(networkFile).ToNot(BeNil())
+			Expect(networkFile.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+Gateway=3.4.5.6
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+		})
+
+		It("writes IPv6 address and gateway for dual-stack interface", func() {
+			staticNetwork.IPv6 = "2001:db8::5"
+			staticNetwork.IPv6Prefix = 64
+			staticNetwork.IPv6Gateway = "2001:db8::1"
+			networks["static-network"] = staticNetwork
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkFile := fs.GetFileTestStat("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(networkFile).ToNot(BeNil())
+			Expect(networkFile.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+Gateway=3.4.5.6
+Address=2001:db8::5/64
+Gateway=2001:db8::1
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+		})
+
+		It("writes route sections and routing policy rule for static routes and policy routing", func() {
+			staticNetwork.Routes = []boshsettings.Route{
+				{Destination: "10.0.0.0", Netmask: "255.0.0.0", Gateway: "1.2.3.1"},
+			}
+			staticNetwork.PolicyRouting = true
+			staticNetwork.DNS = nil
+			networks = boshsettings.Networks{"static-network": staticNetwork}
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkFile := fs.GetFileTestStat("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(networkFile).ToNot(BeNil())
+			Expect(networkFile.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+Gateway=3.4.5.6
+
+[Route]
+Destination=10.0.0.0/8
+Gateway=1.2.3.1
+
+[Route]
+Destination=1.2.3.0/24
+Scope=link
+PreferredSource=1.2.3.4
+Table=101
+
+[Route]
+Gateway=3.4.5.6
+Table=101
+
+[Route]
+Destination=10.0.0.0/8
+Gateway=1.2.3.1
+Table=101
+
+[RoutingPolicyRule]
+From=1.2.3.4/32
+Table=101
+`))
+		})
+
+		It("writes MTU of interface", func() {
+			staticNetwork.MTU = 9000
+			networks["static-network"] = staticNetwork
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkFile := fs.GetFileTestStat("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(networkFile).ToNot(BeNil())
+			Expect(networkFile.StringContents()).To(HavePrefix(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Link]
+MTUBytes=9000
+
+[Network]
+Address=1.2.3.4/24
+`))
+		})
+
+		It("returns error for VLAN interfaces", func() {
+			staticNetwork.VLAN = 100
+			networks["static-network"] = staticNetwork
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Bonds and VLANs are not supported"))
+		})
+
+		It("writes network file for dhcp interface", func() {
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkFile := fs.GetFileTestStat("/etc/systemd/network/10-bosh-ethdhcp.network")
+			Expect(networkFile).ToNot(BeNil())
+			Expect(networkFile.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethdhcp
+
+[Network]
+DHCP=ipv4
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+		})
+
+		It("writes dns search domains to network files", func() {
+			dhcpNetwork.DNSSearch = []string{"example.com", "internal"}
+			networks["dhcp-network"] = dhcpNetwork
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkFile := fs.GetFileTestStat("/etc/systemd/network/10-bosh-ethdhcp.network")
+			Expect(networkFile).ToNot(BeNil())
+			Expect(networkFile.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethdhcp
+
+[Network]
+DHCP=ipv4
+DNS=8.8.8.8
+DNS=9.9.9.9
+Domains=example.com internal
+`))
+		})
+
+		It("replaces resolv.conf symlink with generated file for resolver options", func() {
+			fs.Symlink("/run/systemd/resolve/stub-resolv.conf", "/etc/resolv.conf")
+
+			dhcpNetwork.DNSOptions = []string{"rotate", "timeout:1"}
+			networks["dhcp-network"] = dhcpNetwork
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			resolvConf := fs.GetFileTestStat("/etc/resolv.conf")
+			Expect(resolvConf.FileType).To(Equal(fakesys.FakeFileTypeFile))
+			Expect(resolvConf.StringContents()).To(Equal(`# Generated by bosh-agent
+nameserver 8.8.8.8
+nameserver 9.9.9.9
+options rotate timeout:1
+`))
+		})
+
+		It("links resolv.conf back to systemd-resolved when resolver options are removed", func() {
+			fs.WriteFileString("/etc/resolv.conf", "# Generated by bosh-agent\nnameserver 8.8.8.8\noptions rotate\n")
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			resolvConf := fs.GetFileTestStat("/etc/resolv.conf")
+			Expect(resolvConf.FileType).To(Equal(fakesys.FakeFileTypeSymlink))
+			Expect(resolvConf.SymlinkTarget).To(Equal("/run/systemd/resolve/stub-resolv.conf"))
+		})
+
+		It("keeps resolv.conf not generated by agent without resolver options", func() {
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 1.1.1.1\n")
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.GetFileTestStat("/etc/resolv.conf").StringContents()).To(Equal("nameserver 1.1.1.1\n"))
+		})
+
+		It("does not configure vip networks", func() {
+			networks["vip-network"] = boshsettings.Network{Type: "vip", IP: "5.6.7.8"}
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("reloads networkd when configuration changes", func() {
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"networkctl", "reload"}}))
+		})
+
+		It("restarts networkd when it cannot be reloaded", func() {
+			cmdRunner.AddCmdResult("networkctl reload", fakesys.FakeCmdResult{Error: errors.New("fake-reload-err")})
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"networkctl", "reload"},
+				{"systemctl", "restart", "systemd-networkd"},
+			}))
+		})
+
+		It("does not reload networkd when configuration does not change", func() {
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			cmdRunner.RunCommands = nil
+
+			err = netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("removes network files of interfaces that are no longer configured", func() {
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			fs.WriteFileString("/etc/systemd/network/10-bosh-ethold.network", "fake-content")
+			fs.SetGlob("/etc/systemd/network/10-bosh-*.network", []string{
+				"/etc/systemd/network/10-bosh-ethdhcp.network",
+				"/etc/systemd/network/10-bosh-ethold.network",
+				"/etc/systemd/network/10-bosh-ethstatic.network",
+			})
+			cmdRunner.RunCommands = nil
+
+			err = netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethold.network")).To(BeFalse())
+			Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethstatic.network")).To(BeTrue())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"networkctl", "reload"}}))
+		})
+
+		It("checks connectivity of networks and mbus after reloading networkd", func() {
+			err := netManager.SetupNetworking(networks, "nats://fake-mbus", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(connectivityChecker.CheckNetworks).To(Equal([]boshsettings.Networks{networks}))
+			Expect(connectivityChecker.CheckMbusURL).To(Equal("nats://fake-mbus"))
+		})
+
+		It("does not check connectivity when configuration does not change", func() {
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connectivityChecker.CheckNetworks).To(HaveLen(1))
+		})
+
+		Context("when connectivity check fails", func() {
+			BeforeEach(func() {
+				connectivityChecker.CheckErr = errors.New("fake-check-err")
+			})
+
+			It("restores previous network files, reloads networkd again and returns error", func() {
+				fs.WriteFileString("/etc/systemd/network/10-bosh-ethstatic.network", "fake-previous-network")
+				fs.SetGlob("/etc/systemd/network/10-bosh-*", []string{"/etc/systemd/network/10-bosh-ethstatic.network"})
+
+				err := netManager.SetupNetworking(networks, "", nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Verifying network connectivity"))
+				Expect(err.Error()).To(ContainSubstring("fake-check-err"))
+
+				Expect(fs.GetFileTestStat("/etc/systemd/network/10-bosh-ethstatic.network").StringContents()).To(Equal("fake-previous-network"))
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{{"networkctl", "reload"}, {"networkctl", "reload"}}))
+				Expect(addressBroadcaster.BroadcastMACAddressesAddresses).To(BeEmpty())
+			})
+
+			It("removes network files that did not exist before", func() {
+				fs.SetGlob("/etc/systemd/network/10-bosh-*", []string{"/etc/systemd/network/10-bosh-ethdhcp.network"})
+
+				err := netManager.SetupNetworking(networks, "", nil)
+				Expect(err).To(HaveOccurred())
+				Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethdhcp.network")).To(BeFalse())
+			})
+
+			It("links resolv.conf back to systemd-resolved when it was replaced for resolver options", func() {
+				fs.Symlink("/run/systemd/resolve/stub-resolv.conf", "/etc/resolv.conf")
+
+				dhcpNetwork.DNSOptions = []string{"rotate"}
+				networks["dhcp-network"] = dhcpNetwork
+
+				err := netManager.SetupNetworking(networks, "", nil)
+				Expect(err).To(HaveOccurred())
+
+				resolvConf := fs.GetFileTestStat("/etc/resolv.conf")
+				Expect(resolvConf.FileType).To(Equal(fakesys.FakeFileTypeSymlink))
+				Expect(resolvConf.SymlinkTarget).To(Equal("/run/systemd/resolve/stub-resolv.conf"))
+			})
+
+			It("restores previous resolv.conf contents", func() {
+				fs.WriteFileString("/etc/resolv.conf", "# Generated by bosh-agent\nnameserver 8.8.8.8\noptions ndots:2\n")
+
+				dhcpNetwork.DNSOptions = []string{"rotate"}
+				networks["dhcp-network"] = dhcpNetwork
+
+				err := netManager.SetupNetworking(networks, "", nil)
+				Expect(err).To(HaveOccurred())
+				Expect(fs.GetFileTestStat("/etc/resolv.conf").StringContents()).To(Equal("# Generated by bosh-agent\nnameserver 8.8.8.8\noptions ndots:2\n"))
+			})
+		})
+
+		It("returns error when netmask is invalid", func() {
+			staticNetwork.Netmask = "255.0.255.0"
+			networks["static-network"] = staticNetwork
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Non-contiguous netmask '255.0.255.0'"))
+		})
+
+		It("returns error when writing network file fails", func() {
+			fs.WriteFileError = errors.New("fake-write-err")
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+		})
+
+		It("broadcasts MAC addresses for all interfaces", func() {
+			errCh := make(chan error)
+			err := netManager.SetupNetworking(networks, "", errCh)
+			Expect(err).ToNot(HaveOccurred())
+
+			broadcastErr := <-errCh // wait for all arpings
+			Expect(broadcastErr).ToNot(HaveOccurred())
+
+			Expect(addressBroadcaster.BroadcastMACAddressesAddresses).To(Equal([]boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewResolvingInterfaceAddress("ethdhcp", ipResolver),
+			}))
+		})
+	})
+}
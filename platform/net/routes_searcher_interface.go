@@ -2,8 +2,12 @@ package net
 
 type Route struct {
 	Destination   string
+	Netmask       string
 	Gateway       string
 	InterfaceName string
+
+	// Table is 0 for routes in the main routing table
+	Table int
 }
 
 type RoutesSearcher interface {
@@ -11,5 +15,5 @@ type RoutesSearcher interface {
 }
 
 func (r Route) IsDefault() bool {
-	return r.Destination == "0.0.0.0"
+	return r.Destination == "0.0.0.0" && r.Table == 0
 }
@@ -17,5 +17,9 @@ var _ = Describe("Route", func() {
 			Expect(Route{}.IsDefault()).To(BeFalse())
 			Expect(Route{Destination: "1.1.1.1"}.IsDefault()).To(BeFalse())
 		})
+
+		It("returns false if route is in policy routing table", func() {
+			Expect(Route{Destination: "0.0.0.0", Table: 101}.IsDefault()).To(BeFalse())
+		})
 	})
 })
@@ -0,0 +1,103 @@
+package net
+
+import (
+	"strings"
+)
+
+// interfacesStanzas is /etc/network/interfaces split by interface
+// so that only interfaces whose configuration changed are restarted
+type interfacesStanzas struct {
+	// Interface names in order of appearance; loopback is not included
+	names    []string
+	stanzas  map[string]string
+	dnsLines string
+	dnsOwner string
+
+	// Address family of stanza with dns-* options, e.g. inet6
+	dnsFamily string
+}
+
+func parseInterfacesStanzas(contents string) interfacesStanzas {
+	parsed := interfacesStanzas{stanzas: map[string]string{}}
+
+	var current, currentFamily string
+
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// ifupdown registers dns-* options with resolvconf
+		// as part of the stanza they appear in
+		if strings.HasPrefix(fields[0], "dns-") {
+			parsed.dnsLines += line + "\n"
+			parsed.dnsOwner = current
+			parsed.dnsFamily = currentFamily
+			continue
+		}
+
+		switch fields[0] {
+		case "auto", "allow-auto", "allow-hotplug", "iface", "mapping":
+			if len(fields) > 1 && fields[1] != current {
+				current = fields[1]
+				currentFamily = ""
+				if _, found := parsed.stanzas[current]; !found && current != "lo" {
+					parsed.names = append(parsed.names, current)
+				}
+			}
+		}
+
+		if fields[0] == "iface" && len(fields) > 2 {
+			currentFamily = fields[2]
+		}
+
+		if current != "" && current != "lo" {
+			parsed.stanzas[current] += line + "\n"
+		}
+	}
+
+	return parsed
+}
+
+// changedNames returns interfaces that are missing or configured
+// differently in previous stanzas
+func (s interfacesStanzas) changedNames(previous interfacesStanzas) map[string]bool {
+	changed := map[string]bool{}
+
+	for _, name := range s.names {
+		previousStanza, found := previous.stanzas[name]
+		if !found || previousStanza != s.stanzas[name] {
+			changed[name] = true
+		}
+	}
+
+	return changed
+}
+
+func (s interfacesStanzas) dnsChanged(previous interfacesStanzas) bool {
+	return s.dnsLines != previous.dnsLines || s.dnsRecord() != previous.dnsRecord()
+}
+
+// dnsRecord returns name of resolvconf record that ifupdown
+// created for dns-* options, e.g. eth0.inet6
+func (s interfacesStanzas) dnsRecord() string {
+	if s.dnsOwner == "" {
+		return ""
+	}
+
+	return s.dnsOwner + "." + s.dnsFamily
+}
+
+// orderedNames returns given interfaces in order of appearance
+func (s interfacesStanzas) orderedNames(names map[string]bool) []string {
+	var ordered []string
+
+	for _, name := range s.names {
+		if names[name] {
+			ordered = append(ordered, name)
+		}
+	}
+
+	return ordered
+}
@@ -2,7 +2,6 @@ package net
 
 import (
 	"bytes"
-	"path/filepath"
 	"strings"
 	"text/template"
 
@@ -16,12 +15,16 @@ import (
 
 const UbuntuNetManagerLogTag = "UbuntuNetManager"
 
+// ifupdown keeps names of interfaces that are up in ifstate file
+const ubuntuIfStatePath = "/run/network/ifstate"
+
 type UbuntuNetManager struct {
 	cmdRunner                     boshsys.CmdRunner
 	fs                            boshsys.FileSystem
 	ipResolver                    boship.Resolver
 	interfaceConfigurationCreator InterfaceConfigurationCreator
 	addressBroadcaster            bosharp.AddressBroadcaster
+	connectivityChecker           ConnectivityChecker
 	logger                        boshlog.Logger
 }
 
@@ -31,6 +34,7 @@ func NewUbuntuNetManager(
 	ipResolver boship.Resolver,
 	interfaceConfigurationCreator InterfaceConfigurationCreator,
 	addressBroadcaster bosharp.AddressBroadcaster,
+	connectivityChecker ConnectivityChecker,
 	logger boshlog.Logger,
 ) Manager {
 	return UbuntuNetManager{
@@ -39,6 +43,7 @@ func NewUbuntuNetManager(
 		ipResolver:                    ipResolver,
 		interfaceConfigurationCreator: interfaceConfigurationCreator,
 		addressBroadcaster:            addressBroadcaster,
+		connectivityChecker:           connectivityChecker,
 		logger:                        logger,
 	}
 }
@@ -55,8 +60,9 @@ request subnet-mask, broadcast-address, time-offset, routers,
 	domain-name, domain-name-servers, domain-search, host-name,
 	netbios-name-servers, netbios-scope, interface-mtu,
 	rfc3442-classless-static-routes, ntp-servers;
-{{ if . }}
-prepend domain-name-servers {{ . }};{{ end }}
+{{ if .DNSServers }}
+prepend domain-name-servers {{ .DNSServers }};{{ end }}{{ if .DNSSearch }}
+supersede domain-search {{ .DNSSearch }};{{ end }}
 `
 
 func (net UbuntuNetManager) ComputeNetworkConfig(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, []string, error) {
@@ -73,36 +79,91 @@ func (net UbuntuNetManager) ComputeNetworkConfig(networks boshsettings.Networks)
 		return nil, nil, nil, err
 	}
 
-	dnsNetwork, _ := nonVipNetworks.DefaultNetworkFor("dns")
-	dnsServers := dnsNetwork.DNS
-	return staticInterfaceConfigurations, dhcpInterfaceConfigurations, dnsServers, nil
+	return staticInterfaceConfigurations, dhcpInterfaceConfigurations, NewDNSConfiguration(nonVipNetworks).Servers, nil
 }
 
-func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
-	staticInterfaceConfigurations, dhcpInterfaceConfigurations, dnsServers, err := net.ComputeNetworkConfig(networks)
+func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, mbusURL string, errCh chan error) error {
+	staticInterfaceConfigurations, dhcpInterfaceConfigurations, _, err := net.ComputeNetworkConfig(networks)
 	if err != nil {
 		return bosherr.WrapError(err, "Computing network configuration")
 	}
 
-	interfacesChanged, err := net.writeNetworkInterfaces(dhcpInterfaceConfigurations, staticInterfaceConfigurations, dnsServers)
+	dnsConfiguration := NewDNSConfiguration(networks)
+
+	backup, err := newConfigurationBackup(net.fs, []string{"/etc/network/interfaces", "/etc/dhcp/dhclient.conf"}, nil)
+	if err != nil {
+		return bosherr.WrapError(err, "Backing up network configuration")
+	}
+
+	previousStanzas, err := net.readNetworkInterfaces()
+	if err != nil {
+		return bosherr.WrapError(err, "Reading network configuration")
+	}
+
+	contents, err := net.generateNetworkInterfaces(dhcpInterfaceConfigurations, staticInterfaceConfigurations, dnsConfiguration)
 	if err != nil {
 		return bosherr.WrapError(err, "Writing network configuration")
 	}
 
-	dhcpChanged := false
+	stanzas := parseInterfacesStanzas(string(contents))
+
+	var dhcpChanged bool
 	if len(dhcpInterfaceConfigurations) > 0 {
-		dhcpChanged, err = net.writeDHCPConfiguration(dnsServers)
+		dhcpChanged, err = net.writeDHCPConfiguration(dnsConfiguration)
 		if err != nil {
+			net.restoreConfiguration(backup, false)
 			return err
 		}
 	}
 
-	if interfacesChanged || dhcpChanged {
-		err = net.removeDhcpDNSConfiguration()
+	restartedInterfaces := stanzas.changedNames(previousStanzas)
+	for _, name := range net.downInterfaces(stanzas.names) {
+		restartedInterfaces[name] = true
+	}
+
+	// dhclient only reads its configuration when it starts
+	if dhcpChanged {
+		for _, config := range dhcpInterfaceConfigurations {
+			restartedInterfaces[config.Name] = true
+		}
+	}
+	restartedInterfaces = withDependents(restartedInterfaces, linkDependents(staticInterfaceConfigurations, dhcpInterfaceConfigurations))
+
+	// Interfaces have to be stopped with their previous configuration
+	// since ifdown relies on it to remove addresses and routes
+	stoppedInterfaces := map[string]bool{}
+	for _, name := range previousStanzas.names {
+		if _, found := stanzas.stanzas[name]; !found || restartedInterfaces[name] {
+			stoppedInterfaces[name] = true
+		}
+	}
+
+	net.stopInterfaces(previousStanzas.orderedNames(stoppedInterfaces))
+
+	err = net.writeNetworkInterfaces(contents)
+	if err != nil {
+		net.restoreConfiguration(backup, len(stoppedInterfaces) > 0)
+		return bosherr.WrapError(err, "Writing network configuration")
+	}
+
+	dnsChanged := stanzas.dnsChanged(previousStanzas)
+
+	if len(restartedInterfaces) > 0 || dnsChanged {
+		if len(restartedInterfaces) > 0 {
+			net.loadKernelModules(linkKernelModules(staticInterfaceConfigurations, dhcpInterfaceConfigurations))
+			net.startInterfaces(stanzas.orderedNames(restartedInterfaces))
+		}
+
+		if dnsChanged {
+			net.updateDNSConfiguration(previousStanzas, stanzas, dnsConfiguration, restartedInterfaces, stoppedInterfaces)
+		}
+
+		err = net.connectivityChecker.Check(networks, mbusURL)
 		if err != nil {
-			return err
+			net.logger.Error(UbuntuNetManagerLogTag, "Reverting network configuration because connectivity check failed: %s", err.Error())
+			net.restoreConfiguration(backup, true)
+			return bosherr.WrapError(err, "Verifying network connectivity")
 		}
-		net.restartNetworkingInterfaces()
 	}
 
 	net.broadcastIps(staticInterfaceConfigurations, dhcpInterfaceConfigurations, errCh)
@@ -110,6 +171,107 @@ func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, errC
 	return nil
 }
 
+func (net UbuntuNetManager) readNetworkInterfaces() (interfacesStanzas, error) {
+	if !net.fs.FileExists("/etc/network/interfaces") {
+		return parseInterfacesStanzas(""), nil
+	}
+
+	contents, err := net.fs.ReadFileString("/etc/network/interfaces")
+	if err != nil {
+		return interfacesStanzas{}, bosherr.WrapError(err, "Reading /etc/network/interfaces")
+	}
+
+	return parseInterfacesStanzas(contents), nil
+}
+
+// downInterfaces returns interfaces that ifupdown does not consider up;
+// all interfaces are considered up when ifupdown state is not available
+func (net UbuntuNetManager) downInterfaces(names []string) []string {
+	if !net.fs.FileExists(ubuntuIfStatePath) {
+		return nil
+	}
+
+	contents, err := net.fs.ReadFileString(ubuntuIfStatePath)
+	if err != nil {
+		net.logger.Error(UbuntuNetManagerLogTag, "Ignoring failure reading %s: %s", ubuntuIfStatePath, err.Error())
+		return nil
+	}
+
+	upInterfaces := map[string]bool{}
+	for _, line := range strings.Split(contents, "\n") {
+		name := strings.SplitN(strings.TrimSpace(line), "=", 2)[0]
+		if name != "" {
+			upInterfaces[name] = true
+		}
+	}
+
+	var downInterfaces []string
+	for _, name := range names {
+		if !upInterfaces[name] {
+			downInterfaces = append(downInterfaces, name)
+		}
+	}
+
+	return downInterfaces
+}
+
+func (net UbuntuNetManager) stopInterfaces(names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	net.logger.Debug(UbuntuNetManagerLogTag, "Stopping network interfaces %v", names)
+
+	// Dependent interfaces follow their parents so stop them first
+	reversed := make([]string, len(names))
+	for i, name := range names {
+		reversed[len(names)-1-i] = name
+	}
+
+	_, _, _, err := net.cmdRunner.RunCommand("ifdown", reversed...)
+	if err != nil {
+		net.logger.Error(UbuntuNetManagerLogTag, "Ignoring ifdown failure: %s", err.Error())
+	}
+}
+
+func (net UbuntuNetManager) startInterfaces(names []string) {
+	net.logger.Debug(UbuntuNetManagerLogTag, "Starting network interfaces %v", names)
+
+	for _, name := range names {
+		// Explicitly delete the resolvconf record about given iface
+		// It seems to hold on to old dhclient records after dhcp configuration
+		// is removed from /etc/network/interfaces.
+		_, _, _, err := net.cmdRunner.RunCommand("resolvconf", "-d", name+".dhclient")
+		if err != nil {
+			net.logger.Error(UbuntuNetManagerLogTag, "Ignoring failure calling 'resolvconf -d %s.dhclient': %s", name, err)
+		}
+	}
+
+	_, _, _, err := net.cmdRunner.RunCommand("ifup", names...)
+	if err != nil {
+		net.logger.Error(UbuntuNetManagerLogTag, "Ignoring ifup failure: %s", err.Error())
+	}
+}
+
+// updateDNSConfiguration replaces resolvconf records that ifupdown created
+// from dns-* options for interfaces that were not restarted
+func (net UbuntuNetManager) updateDNSConfiguration(previousStanzas, stanzas interfacesStanzas, dnsConfiguration DNSConfiguration, restartedInterfaces, stoppedInterfaces map[string]bool) {
+	if stanzas.dnsOwner != "" && !restartedInterfaces[stanzas.dnsOwner] {
+		_, _, _, err := net.cmdRunner.RunCommandWithInput(dnsConfiguration.ResolvConf(), "resolvconf", "-a", stanzas.dnsRecord())
+		if err != nil {
+			net.logger.Error(UbuntuNetManagerLogTag, "Ignoring failure updating DNS configuration of %s: %s", stanzas.dnsOwner, err.Error())
+		}
+	}
+
+	previousOwner := previousStanzas.dnsOwner
+	if previousOwner != "" && previousStanzas.dnsRecord() != stanzas.dnsRecord() && !stoppedInterfaces[previousOwner] {
+		_, _, _, err := net.cmdRunner.RunCommand("resolvconf", "-d", previousStanzas.dnsRecord())
+		if err != nil {
+			net.logger.Error(UbuntuNetManagerLogTag, "Ignoring failure removing DNS configuration of %s: %s", previousOwner, err.Error())
+		}
+	}
+}
+
 func (net UbuntuNetManager) removeDhcpDNSConfiguration() error {
 	// Removing dhcp configuration from /etc/network/interfaces
 	// and restarting network does not stop dhclient if dhcp
@@ -119,7 +281,7 @@ func (net UbuntuNetManager) removeDhcpDNSConfiguration() error {
 		net.logger.Error(UbuntuNetManagerLogTag, "Ignoring failure calling 'pkill dhclient': %s", err)
 	}
 
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := physicalInterfacesByMAC(net.fs)
 	if err != nil {
 		return err
 	}
@@ -138,7 +300,7 @@ func (net UbuntuNetManager) removeDhcpDNSConfiguration() error {
 }
 
 func (net UbuntuNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := physicalInterfacesByMAC(net.fs)
 	if err != nil {
 		return nil, nil, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -155,6 +317,9 @@ func (net UbuntuNetManager) broadcastIps(staticInterfaceConfigurations []StaticI
 	addresses := []boship.InterfaceAddress{}
 	for _, iface := range staticInterfaceConfigurations {
 		addresses = append(addresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.Address))
+		if iface.IPv6Address != "" {
+			addresses = append(addresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.IPv6Address))
+		}
 	}
 	for _, iface := range dhcpInterfaceConfigurations {
 		addresses = append(addresses, boship.NewResolvingInterfaceAddress(iface.Name, net.ipResolver))
@@ -168,6 +333,33 @@ func (net UbuntuNetManager) broadcastIps(staticInterfaceConfigurations []StaticI
 	}()
 }
 
+// restoreConfiguration brings back previous configuration files
+// and optionally restarts networking to apply them
+func (net UbuntuNetManager) restoreConfiguration(backup configurationBackup, restart bool) {
+	err := backup.Restore()
+	if err != nil {
+		net.logger.Error(UbuntuNetManagerLogTag, "Failed to restore previous network configuration: %s", err.Error())
+		return
+	}
+
+	if restart {
+		err = net.removeDhcpDNSConfiguration()
+		if err != nil {
+			net.logger.Error(UbuntuNetManagerLogTag, "Ignoring failure to stop dhclient: %s", err.Error())
+		}
+		net.restartNetworkingInterfaces()
+	}
+}
+
+func (net UbuntuNetManager) loadKernelModules(modules []string) {
+	for _, module := range modules {
+		_, _, _, err := net.cmdRunner.RunCommand("modprobe", module)
+		if err != nil {
+			net.logger.Error(UbuntuNetManagerLogTag, "Ignoring modprobe %s failure: %s", module, err.Error())
+		}
+	}
+}
+
 func (net UbuntuNetManager) restartNetworkingInterfaces() {
 	net.logger.Debug(UbuntuNetManagerLogTag, "Restarting network interfaces")
 
@@ -182,14 +374,11 @@ func (net UbuntuNetManager) restartNetworkingInterfaces() {
 	}
 }
 
-func (net UbuntuNetManager) writeDHCPConfiguration(dnsServers []string) (bool, error) {
+func (net UbuntuNetManager) writeDHCPConfiguration(dnsConfiguration DNSConfiguration) (bool, error) {
 	buffer := bytes.NewBuffer([]byte{})
 	t := template.Must(template.New("dhcp-config").Parse(ubuntuDHCPConfigTemplate))
 
-	// Keep DNS servers in the order specified by the network
-	// because they are added by a *single* DHCP's prepend command
-	dnsServersList := strings.Join(dnsServers, ", ")
-	err := t.Execute(buffer, dnsServersList)
+	err := t.Execute(buffer, dnsConfiguration.dhcpConfigTemplateValues())
 	if err != nil {
 		return false, bosherr.WrapError(err, "Generating config from template")
 	}
@@ -205,17 +394,23 @@ func (net UbuntuNetManager) writeDHCPConfiguration(dnsServers []string) (bool, e
 
 type networkInterfaceConfig struct {
 	DNSServers                    []string
+	DNSSearch                     []string
+	DNSOptions                    []string
 	StaticInterfaceConfigurations []StaticInterfaceConfiguration
 	DHCPInterfaceConfigurations   []DHCPInterfaceConfiguration
+	LinkInterfaceConfigurations   []LinkInterfaceConfiguration
 	HasDNSNameServers             bool
 }
 
-func (net UbuntuNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsServers []string) (bool, error) {
+func (net UbuntuNetManager) generateNetworkInterfaces(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsConfiguration DNSConfiguration) ([]byte, error) {
 	networkInterfaceValues := networkInterfaceConfig{
 		StaticInterfaceConfigurations: staticInterfaceConfigurations,
 		DHCPInterfaceConfigurations:   dhcpInterfaceConfigurations,
+		LinkInterfaceConfigurations:   linkInterfaceConfigurations(staticInterfaceConfigurations, dhcpInterfaceConfigurations),
 		HasDNSNameServers:             true,
-		DNSServers:                    dnsServers,
+		DNSServers:                    dnsConfiguration.Servers,
+		DNSSearch:                     dnsConfiguration.Search,
+		DNSOptions:                    dnsConfiguration.Options,
 	}
 
 	buffer := bytes.NewBuffer([]byte{})
@@ -224,59 +419,52 @@ func (net UbuntuNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations [
 
 	err := t.Execute(buffer, networkInterfaceValues)
 	if err != nil {
-		return false, bosherr.WrapError(err, "Generating config from template")
+		return nil, bosherr.WrapError(err, "Generating config from template")
 	}
 
-	changed, err := net.fs.ConvergeFileContents("/etc/network/interfaces", buffer.Bytes())
+	return buffer.Bytes(), nil
+}
+
+func (net UbuntuNetManager) writeNetworkInterfaces(contents []byte) error {
+	_, err := net.fs.ConvergeFileContents("/etc/network/interfaces", contents)
 	if err != nil {
-		return changed, bosherr.WrapError(err, "Writing to /etc/network/interfaces")
+		return bosherr.WrapError(err, "Writing to /etc/network/interfaces")
 	}
 
-	return changed, nil
+	return nil
 }
 
 const networkInterfacesTemplate = `# Generated by bosh-agent
 auto lo
 iface lo inet loopback
-{{ range .DHCPInterfaceConfigurations }}auto {{ .Name }}
-iface {{ .Name }} inet dhcp{{ end }}
+{{ range .LinkInterfaceConfigurations }}auto {{ .Name }}
+iface {{ .Name }} inet manual{{ if .BondMaster }}
+    bond-master {{ .BondMaster }}{{ end }}{{ if .BondMode }}
+    bond-mode {{ .BondMode }}
+    bond-miimon 100
+    bond-slaves none{{ end }}{{ if .MTU }}
+    mtu {{ .MTU }}{{ end }}
+{{ end }}{{ range .DHCPInterfaceConfigurations }}auto {{ .Name }}
+iface {{ .Name }} inet dhcp{{ template "link-options" . }}{{ end }}
 {{ range .StaticInterfaceConfigurations }}auto {{ .Name }}
 iface {{ .Name }} inet static
     address {{ .Address }}
     network {{ .Network }}
     netmask {{ .Netmask }}
     broadcast {{ .Broadcast }}
-    gateway {{ .Gateway }}{{ end }}
-{{ if .DNSServers }}dns-nameservers{{ range .DNSServers }} {{ . }}{{ end }}{{ end }}`
-
-func (net UbuntuNetManager) detectMacAddresses() (map[string]string, error) {
-	addresses := map[string]string{}
-
-	filePaths, err := net.fs.Glob("/sys/class/net/*")
-	if err != nil {
-		return addresses, bosherr.WrapError(err, "Getting file list from /sys/class/net")
-	}
-
-	if len(filePaths) == 0 {
-		return addresses, bosherr.Error("No network interfaces found")
-	}
-
-	var macAddress string
-	for _, filePath := range filePaths {
-		isPhysicalDevice := net.fs.FileExists(filepath.Join(filePath, "device"))
-
-		if isPhysicalDevice {
-			macAddress, err = net.fs.ReadFileString(filepath.Join(filePath, "address"))
-			if err != nil {
-				return addresses, bosherr.WrapError(err, "Reading mac address from file")
-			}
-
-			macAddress = strings.Trim(macAddress, "\n")
-
-			interfaceName := filepath.Base(filePath)
-			addresses[macAddress] = interfaceName
-		}
-	}
-
-	return addresses, nil
-}
+    gateway {{ .Gateway }}{{ template "link-options" . }}{{ range .RouteSpecs }}
+    post-up ip route add {{ . }}{{ end }}{{ range .RuleSpecs }}
+    post-up ip rule add {{ . }}
+    pre-down ip rule del {{ . }}{{ end }}{{ if .IPv6Address }}
+iface {{ .Name }} inet6 static
+    address {{ .IPv6Address }}
+    netmask {{ .IPv6Prefix }}{{ if .IPv6Gateway }}
+    gateway {{ .IPv6Gateway }}{{ end }}{{ end }}{{ end }}
+{{ if .DNSServers }}dns-nameservers{{ range .DNSServers }} {{ . }}{{ end }}{{ end }}{{ if .DNSSearch }}
+dns-search{{ range .DNSSearch }} {{ . }}{{ end }}{{ end }}{{ if .DNSOptions }}
+dns-options{{ range .DNSOptions }} {{ . }}{{ end }}{{ end }}{{ define "link-options" }}{{ if .IsBond }}
+    bond-mode {{ .BondMode }}
+    bond-miimon 100
+    bond-slaves none{{ end }}{{ if .VLANID }}
+    vlan-raw-device {{ .VLANParent }}{{ end }}{{ if .MTU }}
+    mtu {{ .MTU }}{{ end }}{{ end }}`
@@ -3,6 +3,7 @@ package net_test
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -11,6 +12,7 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
 	. "github.com/cloudfoundry/bosh-agent/platform/net"
 	fakearp "github.com/cloudfoundry/bosh-agent/platform/net/arp/fakes"
+	fakenet "github.com/cloudfoundry/bosh-agent/platform/net/fakes"
 	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	fakeip "github.com/cloudfoundry/bosh-agent/platform/net/ip/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -25,6 +27,7 @@ func describeUbuntuNetManager() {
 		cmdRunner                     *fakesys.FakeCmdRunner
 		ipResolver                    *fakeip.FakeResolver
 		addressBroadcaster            *fakearp.FakeAddressBroadcaster
+		connectivityChecker           *fakenet.FakeConnectivityChecker
 		netManager                    UbuntuNetManager
 		interfaceConfigurationCreator InterfaceConfigurationCreator
 	)
@@ -65,12 +68,14 @@ func describeUbuntuNetManager() {
 		logger := boshlog.NewLogger(boshlog.LevelNone)
 		interfaceConfigurationCreator = NewInterfaceConfigurationCreator(logger)
 		addressBroadcaster = &fakearp.FakeAddressBroadcaster{}
+		connectivityChecker = &fakenet.FakeConnectivityChecker{}
 		netManager = NewUbuntuNetManager(
 			fs,
 			cmdRunner,
 			ipResolver,
 			interfaceConfigurationCreator,
 			addressBroadcaster,
+			connectivityChecker,
 			logger,
 		).(UbuntuNetManager)
 	})
@@ -150,6 +155,7 @@ func describeUbuntuNetManager() {
 			dhcpNetwork                                  boshsettings.Network
 			staticNetwork                                boshsettings.Network
 			expectedNetworkConfigurationForStaticAndDhcp string
+			expectedDhcpConfiguration                    string
 		)
 
 		BeforeEach(func() {
@@ -179,6 +185,19 @@ iface ethstatic inet static
     broadcast 1.2.3.255
     gateway 3.4.5.6
 dns-nameservers 8.8.8.8 9.9.9.9`
+			expectedDhcpConfiguration = `# Generated by bosh-agent
+
+option rfc3442-classless-static-routes code 121 = array of unsigned integer 8;
+
+send host-name "<hostname>";
+
+request subnet-mask, broadcast-address, time-offset, routers,
+	domain-name, domain-name-servers, domain-search, host-name,
+	netbios-name-servers, netbios-scope, interface-mtu,
+	rfc3442-classless-static-routes, ntp-servers;
+
+prepend domain-name-servers 8.8.8.8, 9.9.9.9;
+`
 		})
 
 		It("writes /etc/network/interfaces", func() {
@@ -187,7 +206,7 @@ dns-nameservers 8.8.8.8 9.9.9.9`
 				"ethstatic": staticNetwork,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
@@ -195,6 +214,25 @@ dns-nameservers 8.8.8.8 9.9.9.9`
 			Expect(networkConfig.StringContents()).To(Equal(expectedNetworkConfigurationForStaticAndDhcp))
 		})
 
+		It("writes dns search domains and resolver options to /etc/network/interfaces", func() {
+			dhcpNetwork.DNSSearch = []string{"example.com", "internal"}
+			dhcpNetwork.DNSOptions = []string{"rotate", "timeout:2"}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(expectedNetworkConfigurationForStaticAndDhcp + `
+dns-search example.com internal
+dns-options rotate timeout:2`))
+		})
+
 		It("writes /etc/network/interfaces without dns-namservers if there are no dns servers", func() {
 			staticNetworkWithoutDNS := boshsettings.Network{
 				Type:    "manual",
@@ -208,7 +246,68 @@ dns-nameservers 8.8.8.8 9.9.9.9`
 				"ethstatic": staticNetworkWithoutDNS,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetworkWithoutDNS}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetworkWithoutDNS}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto ethstatic
+iface ethstatic inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+`))
+		})
+
+		It("writes inet6 configuration for dual-stack networks", func() {
+			staticNetwork.IPv6 = "2001:db8::5"
+			staticNetwork.IPv6Prefix = 64
+			staticNetwork.IPv6Gateway = "2001:db8::1"
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto ethstatic
+iface ethstatic inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+iface ethstatic inet6 static
+    address 2001:db8::5
+    netmask 64
+    gateway 2001:db8::1
+`))
+		})
+
+		It("writes static routes and policy routing rules", func() {
+			staticNetwork.Routes = []boshsettings.Route{
+				{Destination: "10.0.0.0", Netmask: "255.0.0.0", Gateway: "1.2.3.1"},
+			}
+			staticNetwork.PolicyRouting = true
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
@@ -224,12 +323,159 @@ iface ethstatic inet static
     netmask 255.255.255.0
     broadcast 1.2.3.255
     gateway 3.4.5.6
+    post-up ip route add 10.0.0.0/8 via 1.2.3.1 dev ethstatic
+    post-up ip route add 1.2.3.0/24 dev ethstatic src 1.2.3.4 table 101
+    post-up ip route add default via 3.4.5.6 dev ethstatic table 101
+    post-up ip route add 10.0.0.0/8 via 1.2.3.1 dev ethstatic table 101
+    post-up ip rule add from 1.2.3.4 table 101
+    pre-down ip rule del from 1.2.3.4 table 101
+`))
+		})
+
+		It("writes bond members, bond and VLAN interface and loads kernel modules", func() {
+			staticNetwork.Mac = ""
+			staticNetwork.MTU = 9000
+			staticNetwork.VLAN = 100
+			staticNetwork.Bond = boshsettings.Bond{
+				Name:    "bond1",
+				Mode:    "802.3ad",
+				Members: []string{"fake-mac-1", "fake-mac-2"},
+			}
+
+			// Bonded interfaces report MAC address of the bond
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": boshsettings.Network{Mac: "fake-mac-1"},
+				"eth1": boshsettings.Network{Mac: "fake-mac-1"},
+			})
+			fs.WriteFileString("/sys/class/net/eth1/bonding_slave/perm_hwaddr", "fake-mac-2\n")
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+auto eth0
+iface eth0 inet manual
+    bond-master bond1
+    mtu 9000
+auto eth1
+iface eth1 inet manual
+    bond-master bond1
+    mtu 9000
+auto bond1
+iface bond1 inet manual
+    bond-mode 802.3ad
+    bond-miimon 100
+    bond-slaves none
+    mtu 9000
+
+auto bond1.100
+iface bond1.100 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+    vlan-raw-device bond1
+    mtu 9000
+`))
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"modprobe", "bonding"}))
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"modprobe", "8021q"}))
+		})
+
+		It("writes single stanza for interface used by untagged network and VLANs", func() {
+			staticNetwork.Mac = "fake-mac-1"
+
+			dhcpNetwork.Mac = "fake-mac-1"
+			dhcpNetwork.VLAN = 100
+			dhcpNetwork.MTU = 9000
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": boshsettings.Network{Mac: "fake-mac-1"},
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork, "dhcp-network": dhcpNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+auto eth0.100
+iface eth0.100 inet dhcp
+    vlan-raw-device eth0
+    mtu 9000
+auto eth0
+iface eth0 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+    mtu 9000
+dns-nameservers 8.8.8.8 9.9.9.9`))
+		})
+
+		It("writes largest MTU of VLANs for their parent interface", func() {
+			staticNetwork.Mac = "fake-mac-1"
+			staticNetwork.VLAN = 100
+			staticNetwork.MTU = 1500
+
+			dhcpNetwork.Mac = "fake-mac-1"
+			dhcpNetwork.VLAN = 200
+			dhcpNetwork.MTU = 9000
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": boshsettings.Network{Mac: "fake-mac-1"},
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork, "dhcp-network": dhcpNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(HavePrefix(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+auto eth0
+iface eth0 inet manual
+    mtu 9000
+auto eth0.200
+`))
+		})
+
+		It("writes bond options for network using bond directly", func() {
+			staticNetwork.Mac = ""
+			staticNetwork.Bond = boshsettings.Bond{Members: []string{"fake-mac-1"}}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": boshsettings.Network{Mac: "fake-mac-1"},
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(ContainSubstring(`auto eth0
+iface eth0 inet manual
+    bond-master bond0
+`))
+			Expect(networkConfig.StringContents()).To(ContainSubstring(`    gateway 3.4.5.6
+    bond-mode active-backup
+    bond-miimon 100
+    bond-slaves none
 `))
 		})
 
 		It("returns errors from glob /sys/class/net/", func() {
 			fs.GlobErr = errors.New("fs-glob-error")
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fs-glob-error"))
 		})
@@ -240,7 +486,7 @@ iface ethstatic inet static
 				"static": staticNetwork,
 			})
 			fs.WriteFileError = errors.New("fs-write-file-error")
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fs-write-file-error"))
 		})
@@ -251,7 +497,7 @@ iface ethstatic inet static
 				"ethstatic": staticNetwork,
 			})
 			staticNetwork.Netmask = "not an ip" //will cause InterfaceConfigurationCreator to fail
-			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Creating interface configurations"))
 		})
@@ -262,7 +508,7 @@ iface ethstatic inet static
 				"ethstatic": staticNetwork,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
@@ -283,6 +529,43 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 
 		})
 
+		It("writes dns search domains to dhcp configuration", func() {
+			dhcpNetwork.DNSSearch = []string{"example.com", "internal"}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
+			Expect(dhcpConfig).ToNot(BeNil())
+			Expect(dhcpConfig.StringContents()).To(ContainSubstring(`
+prepend domain-name-servers 8.8.8.8, 9.9.9.9;
+supersede domain-search "example.com", "internal";
+`))
+		})
+
+		It("does not prepend IPv6 dns servers in dhcp configuration", func() {
+			dhcpNetwork.DNS = []string{"8.8.8.8", "2001:4860:4860::8888"}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp": dhcpNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
+			Expect(dhcpConfig).ToNot(BeNil())
+			Expect(dhcpConfig.StringContents()).To(ContainSubstring("prepend domain-name-servers 8.8.8.8;"))
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig.StringContents()).To(ContainSubstring("dns-nameservers 8.8.8.8 2001:4860:4860::8888"))
+		})
+
 		It("writes a dhcp configuration without prepended dns servers if there are no dns servers specified", func() {
 			dhcpNetworkWithoutDNS := boshsettings.Network{
 				Type: "dynamic",
@@ -293,7 +576,7 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 				"ethdhcp": dhcpNetwork,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetworkWithoutDNS}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetworkWithoutDNS}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
@@ -321,7 +604,7 @@ request subnet-mask, broadcast-address, time-offset, routers,
 
 			fs.WriteFileErrors["/etc/dhcp/dhclient.conf"] = errors.New("dhclient.conf-write-error")
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("dhclient.conf-write-error"))
 		})
@@ -331,75 +614,134 @@ request subnet-mask, broadcast-address, time-offset, routers,
 				"ethstatic": staticNetwork,
 			})
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
 			Expect(dhcpConfig).To(BeNil())
 		})
 
-		It("restarts the networks if /etc/network/interfaces changes", func() {
-			initialDhcpConfig := `# Generated by bosh-agent
+		It("starts interfaces that were not configured before", func() {
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
 
-option rfc3442-classless-static-routes code 121 = array of unsigned integer 8;
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
 
-send host-name "<hostname>";
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"resolvconf", "-d", "ethdhcp.dhclient"},
+				{"resolvconf", "-d", "ethstatic.dhclient"},
+				{"ifup", "ethdhcp", "ethstatic"},
+			}))
+		})
 
-request subnet-mask, broadcast-address, time-offset, routers,
-	domain-name, domain-name-servers, domain-search, host-name,
-	netbios-name-servers, netbios-scope, interface-mtu,
-	rfc3442-classless-static-routes, ntp-servers;
+		It("restarts only interfaces whose configuration changed", func() {
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
 
-prepend domain-name-servers 8.8.8.8, 9.9.9.9;
-`
+			fs.WriteFileString("/etc/network/interfaces", strings.Replace(expectedNetworkConfigurationForStaticAndDhcp, "1.2.3.4", "1.2.3.5", 1))
+			fs.WriteFileString("/etc/dhcp/dhclient.conf", expectedDhcpConfiguration)
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
 
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig.StringContents()).To(Equal(expectedNetworkConfigurationForStaticAndDhcp))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ifdown", "ethstatic"},
+				{"resolvconf", "-d", "ethstatic.dhclient"},
+				{"ifup", "ethstatic"},
+			}))
+		})
+
+		It("stops interfaces that are no longer configured and removes dns servers that are no longer configured", func() {
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			fs.WriteFileString("/etc/network/interfaces", expectedNetworkConfigurationForStaticAndDhcp)
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ifdown", "ethdhcp"},
+				{"resolvconf", "-d", "ethstatic.inet"},
+			}))
+		})
+
+		It("starts configured interfaces that are not up", func() {
 			stubInterfaces(map[string]boshsettings.Network{
 				"ethdhcp":   dhcpNetwork,
 				"ethstatic": staticNetwork,
 			})
 
-			fs.WriteFileString("/etc/dhcp/dhclient.conf", initialDhcpConfig)
+			fs.WriteFileString("/etc/network/interfaces", expectedNetworkConfigurationForStaticAndDhcp)
+			fs.WriteFileString("/run/network/ifstate", "lo=lo\nethdhcp=ethdhcp\n")
+			fs.WriteFileString("/etc/dhcp/dhclient.conf", expectedDhcpConfiguration)
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(len(cmdRunner.RunCommands)).To(Equal(5))
-			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"pkill", "dhclient"}))
-			Expect(cmdRunner.RunCommands[1:3]).To(ContainElement([]string{"resolvconf", "-d", "ethdhcp.dhclient"}))
-			Expect(cmdRunner.RunCommands[1:3]).To(ContainElement([]string{"resolvconf", "-d", "ethstatic.dhclient"}))
-			Expect(cmdRunner.RunCommands[3]).To(Equal([]string{"ifdown", "-a", "--no-loopback"}))
-			Expect(cmdRunner.RunCommands[4]).To(Equal([]string{"ifup", "-a", "--no-loopback"}))
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ifdown", "ethstatic"},
+				{"resolvconf", "-d", "ethstatic.dhclient"},
+				{"ifup", "ethstatic"},
+			}))
 		})
 
-		It("doesn't restart the networks if /etc/network/interfaces and /etc/dhcp/dhclient.conf don't change", func() {
-			initialDhcpConfig := `# Generated by bosh-agent
+		It("restarts bond members and VLANs when bond configuration changes", func() {
+			vlanNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Netmask: "255.255.255.0",
+				Gateway: "3.4.5.6",
+				VLAN:    100,
+				Bond: boshsettings.Bond{
+					Members: []string{"fake-mac-1", "fake-mac-2"},
+				},
+			}
 
-option rfc3442-classless-static-routes code 121 = array of unsigned integer 8;
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": {Mac: "fake-mac-1"},
+				"eth1": {Mac: "fake-mac-2"},
+			})
 
-send host-name "<hostname>";
+			err := netManager.SetupNetworking(boshsettings.Networks{"vlan-network": vlanNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
 
-request subnet-mask, broadcast-address, time-offset, routers,
-	domain-name, domain-name-servers, domain-search, host-name,
-	netbios-name-servers, netbios-scope, interface-mtu,
-	rfc3442-classless-static-routes, ntp-servers;
+			previousConfig := fs.GetFileTestStat("/etc/network/interfaces").StringContents()
+			fs.WriteFileString("/etc/network/interfaces", strings.Replace(previousConfig, "bond-mode active-backup", "bond-mode balance-rr", 1))
+			cmdRunner.RunCommands = nil
 
-prepend domain-name-servers 8.8.8.8, 9.9.9.9;
-`
+			err = netManager.SetupNetworking(boshsettings.Networks{"vlan-network": vlanNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ifdown", "bond0.100", "bond0", "eth1", "eth0"}))
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ifup", "eth0", "eth1", "bond0", "bond0.100"}))
+		})
+
+		It("doesn't restart the networks if /etc/network/interfaces and /etc/dhcp/dhclient.conf don't change", func() {
 			stubInterfaces(map[string]boshsettings.Network{
 				"ethdhcp":   dhcpNetwork,
 				"ethstatic": staticNetwork,
 			})
 
 			fs.WriteFileString("/etc/network/interfaces", expectedNetworkConfigurationForStaticAndDhcp)
-			fs.WriteFileString("/etc/dhcp/dhclient.conf", initialDhcpConfig)
+			fs.WriteFileString("/etc/dhcp/dhclient.conf", expectedDhcpConfiguration)
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
 			Expect(networkConfig.StringContents()).To(Equal(expectedNetworkConfigurationForStaticAndDhcp))
 			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
-			Expect(dhcpConfig.StringContents()).To(Equal(initialDhcpConfig))
+			Expect(dhcpConfig.StringContents()).To(Equal(expectedDhcpConfiguration))
 
 			Expect(len(cmdRunner.RunCommands)).To(Equal(0))
 		})
@@ -411,19 +753,166 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 			})
 
 			fs.WriteFileString("/etc/network/interfaces", expectedNetworkConfigurationForStaticAndDhcp)
+			fs.WriteFileString("/etc/dhcp/dhclient.conf", strings.Replace(expectedDhcpConfiguration, "9.9.9.9", "10.10.10.10", 1))
 
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
 			Expect(networkConfig.StringContents()).To(Equal(expectedNetworkConfigurationForStaticAndDhcp))
+			dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
+			Expect(dhcpConfig.StringContents()).To(Equal(expectedDhcpConfiguration))
+
+			// dhclient only reads its configuration when it starts
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ifdown", "ethdhcp"},
+				{"resolvconf", "-d", "ethdhcp.dhclient"},
+				{"ifup", "ethdhcp"},
+			}))
+		})
+
+		It("updates resolvconf record without restarting interfaces if only dns servers change", func() {
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+
+			fs.WriteFileString("/etc/network/interfaces", strings.Replace(expectedNetworkConfigurationForStaticAndDhcp, "9.9.9.9", "10.10.10.10", 1))
+			fs.WriteFileString("/etc/dhcp/dhclient.conf", expectedDhcpConfiguration)
+
+			networks := boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+			Expect(cmdRunner.RunCommandsWithInput).To(Equal([][]string{
+				{"nameserver 8.8.8.8\nnameserver 9.9.9.9\n", "resolvconf", "-a", "ethstatic.inet"},
+			}))
+
+			Expect(connectivityChecker.CheckNetworks).To(Equal([]boshsettings.Networks{networks}))
+		})
+
+		It("updates resolvconf record of inet6 stanza if dns servers follow it", func() {
+			staticNetwork.IPv6 = "2001:db8::5"
+			staticNetwork.IPv6Prefix = 64
+			staticNetwork.DNS = []string{"8.8.8.8", "9.9.9.9"}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			networks := boshsettings.Networks{"static-network": staticNetwork}
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			previousConfig := fs.GetFileTestStat("/etc/network/interfaces").StringContents()
+			fs.WriteFileString("/etc/network/interfaces", strings.Replace(previousConfig, "9.9.9.9", "10.10.10.10", 1))
+			cmdRunner.RunCommands = nil
+			cmdRunner.RunCommandsWithInput = nil
 
-			Expect(len(cmdRunner.RunCommands)).To(Equal(5))
-			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"pkill", "dhclient"}))
-			Expect(cmdRunner.RunCommands[1:3]).To(ContainElement([]string{"resolvconf", "-d", "ethdhcp.dhclient"}))
-			Expect(cmdRunner.RunCommands[1:3]).To(ContainElement([]string{"resolvconf", "-d", "ethstatic.dhclient"}))
-			Expect(cmdRunner.RunCommands[3]).To(Equal([]string{"ifdown", "-a", "--no-loopback"}))
-			Expect(cmdRunner.RunCommands[4]).To(Equal([]string{"ifup", "-a", "--no-loopback"}))
+			err = netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+			Expect(cmdRunner.RunCommandsWithInput).To(Equal([][]string{
+				{"nameserver 8.8.8.8\nnameserver 9.9.9.9\n", "resolvconf", "-a", "ethstatic.inet6"},
+			}))
+		})
+
+		It("restarts dhcp interfaces and updates resolvconf record of static interfaces if dns search domains change", func() {
+			dhcpNetwork.DNSSearch = []string{"example.com"}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+
+			fs.WriteFileString("/etc/network/interfaces", expectedNetworkConfigurationForStaticAndDhcp)
+			fs.WriteFileString("/etc/dhcp/dhclient.conf", expectedDhcpConfiguration)
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.GetFileTestStat("/etc/dhcp/dhclient.conf").StringContents()).To(ContainSubstring("supersede domain-search \"example.com\";"))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ifdown", "ethdhcp"},
+				{"resolvconf", "-d", "ethdhcp.dhclient"},
+				{"ifup", "ethdhcp"},
+			}))
+			Expect(cmdRunner.RunCommandsWithInput).To(Equal([][]string{
+				{"nameserver 8.8.8.8\nnameserver 9.9.9.9\nsearch example.com\n", "resolvconf", "-a", "ethstatic.inet"},
+			}))
+		})
+
+		It("checks connectivity of networks after restarting them", func() {
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+
+			networks := boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}
+
+			err := netManager.SetupNetworking(networks, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(connectivityChecker.CheckNetworks).To(Equal([]boshsettings.Networks{networks}))
+		})
+
+		It("doesn't check connectivity if networks were not restarted", func() {
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connectivityChecker.CheckNetworks).To(HaveLen(1))
+
+			err = netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connectivityChecker.CheckNetworks).To(HaveLen(1))
+		})
+
+		Context("when connectivity check fails", func() {
+			BeforeEach(func() {
+				connectivityChecker.CheckErr = errors.New("fake-check-err")
+			})
+
+			It("restores previous configuration, restarts networks again and returns error", func() {
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethdhcp":   dhcpNetwork,
+					"ethstatic": staticNetwork,
+				})
+
+				fs.WriteFileString("/etc/network/interfaces", "fake-previous-interfaces")
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Verifying network connectivity"))
+				Expect(err.Error()).To(ContainSubstring("fake-check-err"))
+
+				networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+				Expect(networkConfig.StringContents()).To(Equal("fake-previous-interfaces"))
+				Expect(fs.FileExists("/etc/dhcp/dhclient.conf")).To(BeFalse())
+
+				Expect(len(cmdRunner.RunCommands)).To(Equal(8))
+				Expect(cmdRunner.RunCommands[2]).To(Equal([]string{"ifup", "ethdhcp", "ethstatic"}))
+				Expect(cmdRunner.RunCommands[3]).To(Equal([]string{"pkill", "dhclient"}))
+				Expect(cmdRunner.RunCommands[6]).To(Equal([]string{"ifdown", "-a", "--no-loopback"}))
+				Expect(cmdRunner.RunCommands[7]).To(Equal([]string{"ifup", "-a", "--no-loopback"}))
+			})
+
+			It("does not broadcast addresses", func() {
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethstatic": staticNetwork,
+				})
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, "", nil)
+				Expect(err).To(HaveOccurred())
+				Expect(addressBroadcaster.BroadcastMACAddressesAddresses).To(BeEmpty())
+			})
 		})
 
 		It("broadcasts MAC addresses for all interfaces", func() {
@@ -433,7 +922,7 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 			})
 
 			errCh := make(chan error)
-			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, errCh)
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, "", errCh)
 			Expect(err).ToNot(HaveOccurred())
 
 			broadcastErr := <-errCh // wait for all arpings
@@ -464,7 +953,7 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 				"dhcp-network":   dhcpNetwork,
 				"static-network": staticNetwork,
 				"vip-network":    vipNetwork,
-			}, nil)
+			}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
@@ -489,7 +978,7 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 
 				err := netManager.SetupNetworking(boshsettings.Networks{
 					"static-network": staticNetworkWithoutMAC,
-				}, nil)
+				}, "", nil)
 				Expect(err).ToNot(HaveOccurred())
 
 				networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
@@ -528,7 +1017,7 @@ iface ethstatic inet static
 
 				err := netManager.SetupNetworking(boshsettings.Networks{
 					"static-network": staticNetworkWithoutMAC,
-				}, nil)
+				}, "", nil)
 				Expect(err).ToNot(HaveOccurred())
 
 				networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
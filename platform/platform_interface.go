@@ -3,6 +3,7 @@ package platform
 import (
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	boshcmd "github.com/cloudfoundry/bosh-agent/platform/commands"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -27,11 +28,15 @@ type Platform interface {
 	// Bootstrap functionality
 	SetupSSH(publicKey, username string) (err error)
 	SetUserPassword(user, encryptedPwd string) (err error)
-	SetupHostname(hostname string) (err error)
-	SetupNetworking(networks boshsettings.Networks) (err error)
+	SetupHostname(hostname, fqdn string) (err error)
+	SaveDNSRecords(dnsRecords boshsettings.DNSRecords, hostname, fqdn string) error
+	SetupNetworking(networks boshsettings.Networks, mbusURL string) (err error)
 	SetupLogrotate(groupName, basePath, size string) (err error)
 	SetTimeWithNtpServers(servers []string) (err error)
 	SetupEphemeralDiskWithPath(devicePath string) (err error)
+
+	// AssembleEphemeralDisks returns device that combines all ephemeral disks
+	AssembleEphemeralDisks(devicePaths []string) (devicePath string, err error)
 	SetupDataDir() (err error)
 	SetupTmpDir() (err error)
 	SetupMonitUser() (err error)
@@ -41,11 +46,23 @@ type Platform interface {
 	// Disk management
 	MountPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error
 	UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (didUnmount bool, err error)
-	MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error)
+
+	// MigratePersistentDisk copies and verifies contents of the disk mounted at fromMountPoint
+	// before remounting new disk in its place; it can be rerun after being interrupted
+	MigratePersistentDisk(fromMountPoint, toMountPoint string, options MigrationOptions) (err error)
+	ResizePersistentDisk(diskSettings boshsettings.DiskSettings) (oldSizeInBytes, newSizeInBytes uint64, err error)
+
+	// FreezeFilesystem suspends writes to the filesystem so that it can be consistently snapshotted
+	FreezeFilesystem(mountPoint string) (err error)
+	ThawFilesystem(mountPoint string) (err error)
 	GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string
 	IsMountPoint(path string) (result bool, err error)
 	IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (result bool, err error)
 
+	// GetPersistentDiskMountPoint finds where persistent disk is mounted,
+	// e.g. at migration target while it is being migrated
+	GetPersistentDiskMountPoint(diskSettings boshsettings.DiskSettings) (mountPoint string, found bool, err error)
+
 	GetFileContentsFromCDROM(filePath string) (contents []byte, err error)
 	GetFilesContentsFromDisk(diskPath string, fileNames []string) (contents [][]byte, err error)
 
@@ -53,6 +70,12 @@ type Platform interface {
 	GetDefaultNetwork() (boshsettings.Network, error)
 	PrepareForNetworkingChange() error
 
+	// MonitorNetworkInterfaces calls handler for interfaces attached after boot
+	MonitorNetworkInterfaces(handler boshnet.InterfaceAddedHandler) error
+
+	// MonitorDHCPAddresses calls handler when DHCP lease changes address of a network
+	MonitorDHCPAddresses(networksFunc boshnet.NetworksFunc, handler boshnet.AddressChangeHandler) error
+
 	// Additional monit management
 	GetMonitCredentials() (username, password string, err error)
 }
@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"net"
 	"time"
 
 	sigar "github.com/cloudfoundry/gosigar"
@@ -28,6 +29,12 @@ const (
 	ArpInterfaceCheckDelay = 100 * time.Millisecond
 )
 
+const NetworkCheckRetryDelay = 2 * time.Second
+
+const NetworkInterfaceMonitorInterval = 5 * time.Second
+
+const DHCPAddressMonitorInterval = 30 * time.Second
+
 const (
 	SigarStatsCollectionInterval = 10 * time.Second
 )
@@ -48,7 +55,13 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, options O
 	runner := boshsys.NewExecCmdRunner(logger)
 	fs := boshsys.NewOsFileSystem(logger)
 
-	linuxDiskManager := boshdisk.NewLinuxDiskManager(logger, runner, fs, options.Linux.BindMountPersistentDisk)
+	linuxDiskManager := boshdisk.NewLinuxDiskManager(
+		logger,
+		runner,
+		fs,
+		options.Linux.BindMountPersistentDisk,
+		options.Linux.PartitionerType,
+	)
 
 	udev := boshudev.NewConcreteUdevDevice(runner, logger)
 	linuxCdrom := boshcdrom.NewLinuxCdrom("/dev/sr0", udev, runner)
@@ -66,13 +79,34 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, options O
 
 	ipResolver := boship.NewResolver(boship.NetworkInterfaceToAddrsFunc)
 
-	arping := bosharp.NewArping(runner, fs, logger, ArpIterations, ArpIterationDelay, ArpInterfaceCheckDelay)
+	arping := bosharp.NewArping(runner, bosharp.NewICMPNeighborAdvertiser(), fs, logger, ArpIterations, ArpIterationDelay, ArpInterfaceCheckDelay)
 	interfaceConfigurationCreator := boshnet.NewInterfaceConfigurationCreator(logger)
 
-	centosNetManager := boshnet.NewCentosNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, arping, logger)
-	ubuntuNetManager := boshnet.NewUbuntuNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, arping, logger)
+	connectivityChecker := boshnet.NewNoopConnectivityChecker()
+	if options.Linux.NetworkCheckTimeoutSeconds > 0 {
+		connectivityChecker = boshnet.NewConnectivityChecker(
+			runner,
+			net.DialTimeout,
+			options.Linux.NetworkCheckEndpoints,
+			time.Duration(options.Linux.NetworkCheckTimeoutSeconds)*time.Second,
+			NetworkCheckRetryDelay,
+			logger,
+		)
+	}
+
+	centosNetManager := boshnet.NewCentosNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, arping, connectivityChecker, logger)
+	ubuntuNetManager := boshnet.NewUbuntuNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, arping, connectivityChecker, logger)
+
+	if options.Linux.NetManagerType == boshnet.NetManagerTypeNetworkd {
+		networkdNetManager := boshnet.NewNetworkdNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, arping, connectivityChecker, logger)
+		centosNetManager = networkdNetManager
+		ubuntuNetManager = networkdNetManager
+	}
+
+	interfaceMonitor := boshnet.NewPollingInterfaceMonitor(fs, udev, NetworkInterfaceMonitorInterval, logger)
 
-	routesSearcher := boshnet.NewCmdRoutesSearcher(runner)
+	routesSearcher := boshnet.NewCmdRoutesSearcher(runner, logger)
+	addressMonitor := boshnet.NewPollingAddressMonitor(fs, routesSearcher, ipResolver, DHCPAddressMonitorInterval, logger)
 	linuxDefaultNetworkResolver := boshnet.NewDefaultNetworkResolver(routesSearcher, ipResolver)
 
 	monitRetryable := NewMonitRetryable(runner)
@@ -102,6 +136,8 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, options O
 		linuxCdutil,
 		linuxDiskManager,
 		centosNetManager,
+		interfaceMonitor,
+		addressMonitor,
 		monitRetryStrategy,
 		devicePathResolver,
 		500*time.Millisecond,
@@ -121,6 +157,8 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, options O
 		linuxCdutil,
 		linuxDiskManager,
 		ubuntuNetManager,
+		interfaceMonitor,
+		addressMonitor,
 		monitRetryStrategy,
 		devicePathResolver,
 		500*time.Millisecond,
@@ -0,0 +1,95 @@
+package retrystrategy
+
+import (
+	"math/rand"
+	"time"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	boshtime "github.com/cloudfoundry/bosh-agent/time"
+)
+
+type backoffRetryStrategy struct {
+	options     Options
+	retryable   Retryable
+	timeService boshtime.Service
+	logger      boshlog.Logger
+	logTag      string
+}
+
+func NewBackoffRetryStrategy(
+	options Options,
+	retryable Retryable,
+	timeService boshtime.Service,
+	logger boshlog.Logger,
+) RetryStrategy {
+	return &backoffRetryStrategy{
+		options:     options,
+		retryable:   retryable,
+		timeService: timeService,
+		logger:      logger,
+		logTag:      "backoffRetryStrategy",
+	}
+}
+
+func (s *backoffRetryStrategy) Try() error {
+	var err error
+	var isRetryable bool
+
+	start := s.timeService.Now()
+	delay := s.options.InitialDelayDuration()
+
+	for i := 0; true; i++ {
+		s.logger.Debug(s.logTag, "Making attempt #%d", i)
+
+		isRetryable, err = s.retryable.Attempt()
+		if err == nil {
+			return nil
+		}
+
+		if !isRetryable {
+			return err
+		}
+
+		if s.options.MaxAttempts > 0 && i+1 >= s.options.MaxAttempts {
+			return err
+		}
+
+		sleep := s.jitter(delay)
+
+		if s.options.MaxElapsedTime > 0 {
+			elapsed := s.timeService.Now().Sub(start)
+			if elapsed+sleep > s.options.MaxElapsedTimeDuration() {
+				return err
+			}
+		}
+
+		s.logger.Debug(s.logTag, "Retrying in %s", sleep)
+		s.timeService.Sleep(sleep)
+
+		delay = s.nextDelay(delay)
+	}
+
+	return err
+}
+
+func (s *backoffRetryStrategy) nextDelay(delay time.Duration) time.Duration {
+	if s.options.Multiplier > 0 {
+		delay = time.Duration(float64(delay) * s.options.Multiplier)
+	}
+
+	if s.options.MaxDelay > 0 && delay > s.options.MaxDelayDuration() {
+		delay = s.options.MaxDelayDuration()
+	}
+
+	return delay
+}
+
+func (s *backoffRetryStrategy) jitter(delay time.Duration) time.Duration {
+	if s.options.Jitter <= 0 {
+		return delay
+	}
+
+	spread := float64(delay) * s.options.Jitter
+
+	return delay + time.Duration(spread*(2*rand.Float64()-1))
+}
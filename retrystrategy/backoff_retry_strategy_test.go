@@ -0,0 +1,132 @@
+package retrystrategy_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-agent/logger"
+	faketime "github.com/cloudfoundry/bosh-agent/time/fakes"
+
+	. "github.com/cloudfoundry/bosh-agent/retrystrategy"
+)
+
+var _ = Describe("BackoffRetryStrategy", func() {
+	var (
+		fakeTimeService *faketime.FakeService
+		logger          boshlog.Logger
+	)
+
+	BeforeEach(func() {
+		fakeTimeService = &faketime.FakeService{}
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+	})
+
+	failingRetryable := func(attempts int) *simpleRetryable {
+		outputs := []attemptOutput{}
+		for i := 0; i < attempts; i++ {
+			outputs = append(outputs, attemptOutput{IsRetryable: true, AttemptErr: errors.New("fake-error")})
+		}
+		return newSimpleRetryable(outputs)
+	}
+
+	Describe("Try", func() {
+		It("retries until the max attempts are used up", func() {
+			retryable := failingRetryable(5)
+			options := Options{MaxAttempts: 3, InitialDelay: 10}
+
+			err := NewBackoffRetryStrategy(options, retryable, fakeTimeService, logger).Try()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-error"))
+			Expect(retryable.Attempts).To(Equal(3))
+			Expect(fakeTimeService.SleepInputs).To(Equal([]time.Duration{
+				10 * time.Millisecond,
+				10 * time.Millisecond,
+			}))
+		})
+
+		It("multiplies delay after each attempt up to the max delay", func() {
+			retryable := failingRetryable(5)
+			options := Options{MaxAttempts: 5, InitialDelay: 10, Multiplier: 2, MaxDelay: 50}
+
+			err := NewBackoffRetryStrategy(options, retryable, fakeTimeService, logger).Try()
+			Expect(err).To(HaveOccurred())
+			Expect(fakeTimeService.SleepInputs).To(Equal([]time.Duration{
+				10 * time.Millisecond,
+				20 * time.Millisecond,
+				40 * time.Millisecond,
+				50 * time.Millisecond,
+			}))
+		})
+
+		It("randomizes delays by the jitter fraction", func() {
+			retryable := failingRetryable(20)
+			options := Options{MaxAttempts: 20, InitialDelay: 100, Jitter: 0.5}
+
+			err := NewBackoffRetryStrategy(options, retryable, fakeTimeService, logger).Try()
+			Expect(err).To(HaveOccurred())
+			Expect(fakeTimeService.SleepInputs).To(HaveLen(19))
+
+			for _, delay := range fakeTimeService.SleepInputs {
+				Expect(delay).To(BeNumerically(">=", 50*time.Millisecond))
+				Expect(delay).To(BeNumerically("<=", 150*time.Millisecond))
+			}
+		})
+
+		It("stops retrying when next attempt would exceed max elapsed time", func() {
+			now := time.Now()
+			fakeTimeService.NowTimes = []time.Time{
+				now,
+				now.Add(10 * time.Millisecond),
+				now.Add(30 * time.Millisecond),
+				now.Add(50 * time.Millisecond),
+			}
+
+			retryable := failingRetryable(10)
+			options := Options{InitialDelay: 20, MaxElapsedTime: 60}
+
+			err := NewBackoffRetryStrategy(options, retryable, fakeTimeService, logger).Try()
+			Expect(err).To(HaveOccurred())
+			Expect(retryable.Attempts).To(Equal(3))
+			Expect(fakeTimeService.SleepInputs).To(HaveLen(2))
+		})
+
+		It("stops trying when the attempt is not retryable", func() {
+			retryable := newSimpleRetryable([]attemptOutput{
+				{IsRetryable: true, AttemptErr: errors.New("first-error")},
+				{IsRetryable: false, AttemptErr: errors.New("second-error")},
+			})
+			options := Options{MaxAttempts: 10}
+
+			err := NewBackoffRetryStrategy(options, retryable, fakeTimeService, logger).Try()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("second-error"))
+			Expect(retryable.Attempts).To(Equal(2))
+		})
+
+		It("keeps retrying when attempts are unlimited", func() {
+			var outputs []attemptOutput
+			for i := 0; i < 30; i++ {
+				outputs = append(outputs, attemptOutput{IsRetryable: true, AttemptErr: errors.New("fake-error")})
+			}
+			retryable := newSimpleRetryable(append(outputs, attemptOutput{IsRetryable: true}))
+			options := Options{MaxAttempts: Unlimited}
+
+			err := NewBackoffRetryStrategy(options, retryable, fakeTimeService, logger).Try()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retryable.Attempts).To(Equal(31))
+		})
+
+		It("does not retry when there are no errors", func() {
+			retryable := newSimpleRetryable([]attemptOutput{{IsRetryable: true}})
+			options := Options{MaxAttempts: 3}
+
+			err := NewBackoffRetryStrategy(options, retryable, fakeTimeService, logger).Try()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retryable.Attempts).To(Equal(1))
+			Expect(fakeTimeService.SleepInputs).To(BeEmpty())
+		})
+	})
+})
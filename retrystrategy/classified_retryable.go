@@ -0,0 +1,28 @@
+package retrystrategy
+
+// ErrorClassifier reports whether an attempt that failed
+// with given error might succeed if it is made again
+type ErrorClassifier func(err error) bool
+
+type classifiedRetryable struct {
+	retryable  Retryable
+	classifier ErrorClassifier
+}
+
+// NewClassifiedRetryable stops retrying errors that classifier
+// considers permanent even if retryable reports them as retryable
+func NewClassifiedRetryable(retryable Retryable, classifier ErrorClassifier) Retryable {
+	return &classifiedRetryable{
+		retryable:  retryable,
+		classifier: classifier,
+	}
+}
+
+func (r *classifiedRetryable) Attempt() (bool, error) {
+	isRetryable, err := r.retryable.Attempt()
+	if err == nil || !isRetryable {
+		return isRetryable, err
+	}
+
+	return r.classifier(err), err
+}
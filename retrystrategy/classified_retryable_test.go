@@ -0,0 +1,49 @@
+package retrystrategy_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/retrystrategy"
+)
+
+var _ = Describe("ClassifiedRetryable", func() {
+	var (
+		permanentErr = errors.New("fake-permanent-error")
+		classifier   = func(err error) bool { return err != permanentErr }
+	)
+
+	Describe("Attempt", func() {
+		It("is not retryable when classifier considers error permanent", func() {
+			retryable := NewClassifiedRetryable(newSimpleRetryable([]attemptOutput{
+				{IsRetryable: true, AttemptErr: permanentErr},
+			}), classifier)
+
+			isRetryable, err := retryable.Attempt()
+			Expect(err).To(Equal(permanentErr))
+			Expect(isRetryable).To(BeFalse())
+		})
+
+		It("is retryable when classifier considers error temporary", func() {
+			retryable := NewClassifiedRetryable(newSimpleRetryable([]attemptOutput{
+				{IsRetryable: true, AttemptErr: errors.New("fake-temporary-error")},
+			}), classifier)
+
+			isRetryable, err := retryable.Attempt()
+			Expect(err).To(HaveOccurred())
+			Expect(isRetryable).To(BeTrue())
+		})
+
+		It("is not retryable when inner retryable is not retryable", func() {
+			retryable := NewClassifiedRetryable(newSimpleRetryable([]attemptOutput{
+				{IsRetryable: false, AttemptErr: errors.New("fake-temporary-error")},
+			}), classifier)
+
+			isRetryable, err := retryable.Attempt()
+			Expect(err).To(HaveOccurred())
+			Expect(isRetryable).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package retrystrategy
+
+import (
+	"time"
+)
+
+// Unlimited can be used as MaxAttempts or MaxElapsedTime to remove the limit;
+// zero values are considered unset and are taken from defaults
+const Unlimited = -1
+
+// Options describe a retry policy that can be set from agent config.
+// Delays and elapsed time are given in milliseconds.
+type Options struct {
+	// Total number of attempts; Unlimited does not limit attempts
+	MaxAttempts int
+
+	InitialDelay int
+	MaxDelay     int
+
+	// Each delay is Multiplier times longer than the previous one
+	Multiplier float64
+
+	// Fraction (0-1) of each delay that is randomly added or subtracted
+	Jitter float64
+
+	// Retrying stops once next attempt would start after MaxElapsedTime;
+	// Unlimited does not limit elapsed time
+	MaxElapsedTime int
+}
+
+// WithDefaults returns options with zero values taken from defaults
+func (o Options) WithDefaults(defaults Options) Options {
+	if o.MaxAttempts == 0 {
+		o.MaxAttempts = defaults.MaxAttempts
+	}
+
+	if o.InitialDelay == 0 {
+		o.InitialDelay = defaults.InitialDelay
+	}
+
+	if o.MaxDelay == 0 {
+		o.MaxDelay = defaults.MaxDelay
+	}
+
+	if o.Multiplier == 0 {
+		o.Multiplier = defaults.Multiplier
+	}
+
+	if o.Jitter == 0 {
+		o.Jitter = defaults.Jitter
+	}
+
+	if o.MaxElapsedTime == 0 {
+		o.MaxElapsedTime = defaults.MaxElapsedTime
+	}
+
+	return o
+}
+
+func (o Options) InitialDelayDuration() time.Duration {
+	return time.Duration(o.InitialDelay) * time.Millisecond
+}
+
+func (o Options) MaxDelayDuration() time.Duration {
+	return time.Duration(o.MaxDelay) * time.Millisecond
+}
+
+func (o Options) MaxElapsedTimeDuration() time.Duration {
+	return time.Duration(o.MaxElapsedTime) * time.Millisecond
+}
@@ -0,0 +1,43 @@
+package retrystrategy_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/retrystrategy"
+)
+
+var _ = Describe("Options", func() {
+	Describe("WithDefaults", func() {
+		It("takes unset values from defaults", func() {
+			options := Options{MaxAttempts: 5, Jitter: 0.2}
+			defaults := Options{
+				MaxAttempts:    3,
+				InitialDelay:   1000,
+				MaxDelay:       10000,
+				Multiplier:     2,
+				Jitter:         0.1,
+				MaxElapsedTime: 60000,
+			}
+
+			Expect(options.WithDefaults(defaults)).To(Equal(Options{
+				MaxAttempts:    5,
+				InitialDelay:   1000,
+				MaxDelay:       10000,
+				Multiplier:     2,
+				Jitter:         0.2,
+				MaxElapsedTime: 60000,
+			}))
+		})
+
+		It("keeps unlimited values", func() {
+			options := Options{MaxAttempts: Unlimited, MaxElapsedTime: Unlimited}
+			defaults := Options{MaxAttempts: 3, MaxElapsedTime: 60000}
+
+			Expect(options.WithDefaults(defaults)).To(Equal(Options{
+				MaxAttempts:    Unlimited,
+				MaxElapsedTime: Unlimited,
+			}))
+		})
+	})
+})
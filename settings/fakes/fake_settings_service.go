@@ -15,6 +15,11 @@ type FakeSettingsService struct {
 	SettingsWereInvalidated bool
 
 	Settings boshsettings.Settings
+
+	UpdateDHCPAddressNetworkName string
+	UpdateDHCPAddressIP          string
+	UpdateDHCPAddressNetmask     string
+	UpdateDHCPAddressErr         error
 }
 
 func (service *FakeSettingsService) InvalidateSettings() error {
@@ -34,3 +39,10 @@ func (service *FakeSettingsService) LoadSettings() error {
 func (service FakeSettingsService) GetSettings() boshsettings.Settings {
 	return service.Settings
 }
+
+func (service *FakeSettingsService) UpdateDHCPAddress(networkName, ip, netmask string) error {
+	service.UpdateDHCPAddressNetworkName = networkName
+	service.UpdateDHCPAddressIP = ip
+	service.UpdateDHCPAddressNetmask = netmask
+	return service.UpdateDHCPAddressErr
+}
@@ -2,6 +2,7 @@ package settings
 
 import (
 	"encoding/json"
+	"sync"
 
 	bosherr "github.com/cloudfoundry/bosh-agent/errors"
 	boshlog "github.com/cloudfoundry/bosh-agent/logger"
@@ -17,14 +18,24 @@ type Service interface {
 	PublicSSHKeyForUsername(string) (string, error)
 
 	InvalidateSettings() error
+
+	// UpdateDHCPAddress overrides address of a DHCP network observed by the agent.
+	// It is only kept in memory since it is only valid for the current lease.
+	UpdateDHCPAddress(networkName, ip, netmask string) error
 }
 
 const settingsServiceLogTag = "settingsService"
 
 type settingsService struct {
-	fs                     boshsys.FileSystem
-	settingsPath           string
-	settings               Settings
+	fs           boshsys.FileSystem
+	settingsPath string
+
+	// settingsLock guards settings and dhcpAddresses since settings
+	// are read and loaded concurrently by actions and agent monitors
+	settingsLock  sync.Mutex
+	settings      Settings
+	dhcpAddresses Networks
+
 	settingsSource         Source
 	defaultNetworkResolver DefaultNetworkResolver
 	logger                 boshlog.Logger
@@ -47,6 +58,7 @@ func NewService(
 		fs:                     fs,
 		settingsPath:           settingsPath,
 		settings:               Settings{},
+		dhcpAddresses:          Networks{},
 		settingsSource:         settingsSource,
 		defaultNetworkResolver: defaultNetworkResolver,
 		logger:                 logger,
@@ -72,17 +84,21 @@ func (s *settingsService) LoadSettings() error {
 
 		s.logger.Debug(settingsServiceLogTag, "Successfully read settings from file")
 
-		err := json.Unmarshal(existingSettingsJSON, &s.settings)
+		var existingSettings Settings
+
+		err := json.Unmarshal(existingSettingsJSON, &existingSettings)
 		if err != nil {
 			s.logger.Error(settingsServiceLogTag, "Failed unmarshalling settings from file %s", err.Error())
 			return bosherr.WrapError(fetchErr, "Invoking settings fetcher")
 		}
 
+		s.setSettings(existingSettings)
+
 		return nil
 	}
 
 	s.logger.Debug(settingsServiceLogTag, "Successfully received settings from fetcher")
-	s.settings = newSettings
+	s.setSettings(newSettings)
 
 	newSettingsJSON, err := json.Marshal(newSettings)
 	if err != nil {
@@ -98,8 +114,23 @@ func (s *settingsService) LoadSettings() error {
 }
 
 // GetSettings returns setting even if it fails to resolve IPs for dynamic networks.
+// Returned networks are a copy so resolving them does not modify loaded settings.
 func (s *settingsService) GetSettings() Settings {
-	for networkName, network := range s.settings.Networks {
+	s.settingsLock.Lock()
+
+	settings := s.settings
+	dhcpAddresses := s.dhcpAddresses
+
+	if s.settings.Networks != nil {
+		settings.Networks = Networks{}
+		for networkName, network := range s.settings.Networks {
+			settings.Networks[networkName] = network
+		}
+	}
+
+	s.settingsLock.Unlock()
+
+	for networkName, network := range settings.Networks {
 		if !network.IsDHCP() {
 			continue
 		}
@@ -109,10 +140,15 @@ func (s *settingsService) GetSettings() Settings {
 			break
 		}
 
-		s.settings.Networks[networkName] = resolvedNetwork
+		if address, found := dhcpAddresses[networkName]; found {
+			resolvedNetwork.IP = address.IP
+			resolvedNetwork.Netmask = address.Netmask
+		}
+
+		settings.Networks[networkName] = resolvedNetwork
 	}
 
-	return s.settings
+	return settings
 }
 
 func (s *settingsService) InvalidateSettings() error {
@@ -124,6 +160,37 @@ func (s *settingsService) InvalidateSettings() error {
 	return nil
 }
 
+func (s *settingsService) UpdateDHCPAddress(networkName, ip, netmask string) error {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+
+	network, found := s.settings.Networks[networkName]
+	if !found {
+		return bosherr.Errorf("Network '%s' not found in settings", networkName)
+	}
+
+	if !network.IsDHCP() {
+		return bosherr.Errorf("Network '%s' is not a DHCP network", networkName)
+	}
+
+	// Replace map instead of modifying it since GetSettings may still be using it
+	dhcpAddresses := Networks{}
+	for name, address := range s.dhcpAddresses {
+		dhcpAddresses[name] = address
+	}
+	dhcpAddresses[networkName] = Network{IP: ip, Netmask: netmask}
+	s.dhcpAddresses = dhcpAddresses
+
+	return nil
+}
+
+func (s *settingsService) setSettings(settings Settings) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+
+	s.settings = settings
+}
+
 func (s *settingsService) resolveNetwork(network Network) (Network, error) {
 	// Ideally this would be GetNetworkByMACAddress(mac string)
 	// Currently, we are relying that if the default network does not contain
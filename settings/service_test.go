@@ -182,6 +182,68 @@ func init() {
 			})
 		})
 
+		Describe("UpdateDHCPAddress", func() {
+			var service Service
+
+			BeforeEach(func() {
+				fakeSettingsSource.SettingsValue = Settings{
+					AgentID: "some-agent-id",
+					Networks: Networks{
+						"fake-dynamic": Network{Type: NetworkTypeDynamic, DNS: []string{"fake-dns"}},
+						"fake-manual":  Network{Type: "manual", IP: "10.0.0.5", Netmask: "255.255.255.0"},
+					},
+				}
+
+				fakeDefaultNetworkResolver.GetDefaultNetworkNetwork = Network{
+					IP:      "fake-resolved-ip",
+					Netmask: "fake-resolved-netmask",
+					Gateway: "fake-resolved-gateway",
+				}
+
+				service, fs = buildService()
+
+				err := service.LoadSettings()
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("overrides address of DHCP network in returned settings", func() {
+				err := service.UpdateDHCPAddress("fake-dynamic", "10.0.0.6", "255.255.0.0")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(service.GetSettings().Networks["fake-dynamic"]).To(Equal(Network{
+					Type:     NetworkTypeDynamic,
+					IP:       "10.0.0.6",
+					Netmask:  "255.255.0.0",
+					Gateway:  "fake-resolved-gateway",
+					DNS:      []string{"fake-dns"},
+					Resolved: true,
+				}))
+			})
+
+			It("does not save address to the settings file", func() {
+				contentsBefore := fs.GetFileTestStat("/setting/path.json").StringContents()
+
+				err := service.UpdateDHCPAddress("fake-dynamic", "10.0.0.6", "255.255.0.0")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.GetFileTestStat("/setting/path.json").StringContents()).To(Equal(contentsBefore))
+			})
+
+			It("returns error if network is not found", func() {
+				err := service.UpdateDHCPAddress("fake-unknown", "10.0.0.6", "255.255.0.0")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Network 'fake-unknown' not found in settings"))
+			})
+
+			It("returns error if network is not a DHCP network", func() {
+				err := service.UpdateDHCPAddress("fake-manual", "10.0.0.6", "255.255.0.0")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Network 'fake-manual' is not a DHCP network"))
+
+				Expect(service.GetSettings().Networks["fake-manual"].IP).To(Equal("10.0.0.5"))
+			})
+		})
+
 		Describe("GetSettings", func() {
 			var (
 				loadedSettings Settings
@@ -237,6 +299,16 @@ func init() {
 						}
 					})
 
+					It("does not modify loaded settings when resolving networks", func() {
+						settings := service.GetSettings()
+						Expect(settings.Networks["fake-net2"].IP).To(Equal("fake-resolved-ip"))
+
+						settings.Networks["fake-net1"] = Network{IP: "fake-modified-ip"}
+
+						fakeDefaultNetworkResolver.GetDefaultNetworkErr = errors.New("fake-get-default-network-err")
+						Expect(service.GetSettings()).To(Equal(loadedSettings))
+					})
+
 					It("returns settings with resolved dynamic network ip, netmask, gateway and keeping everything else the same", func() {
 						settings := service.GetSettings()
 						Expect(settings).To(Equal(Settings{
@@ -1,5 +1,9 @@
 package settings
 
+import (
+	"strings"
+)
+
 const (
 	RootUsername        = "root"
 	VCAPUsername        = "vcap"
@@ -38,7 +42,9 @@ type Disks struct {
 	System string `json:"system"`
 
 	// e.g "/dev/sdb", "2"
-	Ephemeral string `json:"ephemeral"`
+	// Multiple ephemeral disks are striped together:
+	// e.g ["/dev/nvme1n1", "/dev/nvme2n1"]
+	Ephemeral interface{} `json:"ephemeral"`
 
 	// Older CPIs returned disk settings as strings
 	// e.g {"disk-3845-43758-7243-38754" => "/dev/sdc"}
@@ -46,6 +52,8 @@ type Disks struct {
 	// Newer CPIs will populate it in a hash:
 	// e.g {"disk-3845-43758-7243-38754" => {"path" => "/dev/sdc"}}
 	//     {"disk-3845-43758-7243-38754" => {"volume_id" => "3"}}
+	// Disks that should be encrypted additionally specify a key:
+	// e.g {"disk-3845-43758-7243-38754" => {"path" => "/dev/sdc", "encryption_key" => "..."}}
 	Persistent map[string]interface{} `json:"persistent"`
 }
 
@@ -53,6 +61,9 @@ type DiskSettings struct {
 	ID       string
 	VolumeID string
 	Path     string
+
+	// Disk is encrypted with LUKS when key is present
+	EncryptionKey string
 }
 
 type VM struct {
@@ -69,6 +80,7 @@ func (s Settings) PersistentDiskSettings(diskID string) (DiskSettings, bool) {
 			if hashSettings, ok := settings.(map[string]interface{}); ok {
 				diskSettings.Path = hashSettings["path"].(string)
 				diskSettings.VolumeID = hashSettings["volume_id"].(string)
+				diskSettings.EncryptionKey, _ = hashSettings["encryption_key"].(string)
 			} else {
 				// Old CPIs return disk path (string) or volume id (string) as disk settings
 				diskSettings.Path = settings.(string)
@@ -82,11 +94,40 @@ func (s Settings) PersistentDiskSettings(diskID string) (DiskSettings, bool) {
 	return diskSettings, false
 }
 
-func (s Settings) EphemeralDiskSettings() DiskSettings {
-	return DiskSettings{
-		VolumeID: s.Disks.Ephemeral,
-		Path:     s.Disks.Ephemeral,
+func (s Settings) EphemeralDisksSettings() []DiskSettings {
+	var disksSettings []DiskSettings
+
+	switch ephemeral := s.Disks.Ephemeral.(type) {
+	case string:
+		if ephemeral != "" {
+			disksSettings = append(disksSettings, DiskSettings{VolumeID: ephemeral, Path: ephemeral})
+		}
+	case []interface{}:
+		for _, disk := range ephemeral {
+			if path, ok := disk.(string); ok && path != "" {
+				disksSettings = append(disksSettings, DiskSettings{VolumeID: path, Path: path})
+			}
+		}
+	}
+
+	return disksSettings
+}
+
+// FQDN returns agent ID qualified with the first search domain of the network
+// that is default for DNS; agent ID is returned when there is no search domain
+func (s Settings) FQDN() string {
+	dnsNetwork, _ := s.Networks.DefaultNetworkFor("dns")
+	if len(dnsNetwork.DNSSearch) == 0 {
+		return s.AgentID
 	}
+
+	return s.AgentID + "." + dnsNetwork.DNSSearch[0]
+}
+
+// DNSRecords are address and hostname pairs added to /etc/hosts
+type DNSRecords struct {
+	Version uint64      `json:"version"`
+	Records [][2]string `json:"records"`
 }
 
 type Env struct {
@@ -116,14 +157,94 @@ type Network struct {
 	Gateway  string `json:"gateway"`
 	Resolved bool   `json:"resolved"` // was resolved via DHCP
 
+	// IPv6 address is configured in addition to IPv4 address
+	// on networks that are not dynamic; DNS may contain IPv6 servers
+	IPv6        string `json:"ipv6"`
+	IPv6Prefix  int    `json:"ipv6_prefix"`
+	IPv6Gateway string `json:"ipv6_gateway"`
+
 	Default []string `json:"default"`
 	DNS     []string `json:"dns"`
 
+	// Search domains and resolver options (e.g. ndots:2) are used
+	// together with DNS servers of the network that is default for DNS
+	DNSSearch  []string `json:"dns_search"`
+	DNSOptions []string `json:"dns_options"`
+
 	Mac string `json:"mac"`
+
+	// Routes are added in addition to the default gateway route
+	Routes []Route `json:"routes"`
+
+	// PolicyRouting makes traffic sourced from network's IP leave
+	// through its interface using a separate routing table
+	PolicyRouting bool `json:"policy_routing"`
+
+	// MTU of network's interface; system default is kept when 0
+	MTU int `json:"mtu"`
+
+	// Bond makes network use bond of interfaces instead of interface matching Mac
+	Bond Bond `json:"bond"`
+
+	// VLAN makes network use 802.1q subinterface with given id
+	VLAN int `json:"vlan"`
+}
+
+type Route struct {
+	Destination string `json:"destination"`
+	Netmask     string `json:"netmask"`
+	Gateway     string `json:"gateway"`
+}
+
+type Bond struct {
+	// Name defaults to bond0
+	Name string `json:"name"`
+
+	// Mode is a bonding driver mode, e.g. 802.3ad or active-backup (default)
+	Mode string `json:"mode"`
+
+	// Members are MAC addresses of bonded interfaces
+	Members []string `json:"members"`
 }
 
 type Networks map[string]Network
 
+// IPv6Addresses returns IPv6 addresses keyed by network name;
+// nil is returned when none of the networks has IPv6 address
+func (n Networks) IPv6Addresses() map[string]string {
+	var addresses map[string]string
+
+	for networkName, network := range n {
+		if network.IPv6 == "" {
+			continue
+		}
+		if addresses == nil {
+			addresses = map[string]string{}
+		}
+		addresses[networkName] = network.IPv6
+	}
+
+	return addresses
+}
+
+// NetworkForMAC returns name of the network that uses interface
+// with given MAC address either directly or as a bond member
+func (n Networks) NetworkForMAC(mac string) (string, bool) {
+	for networkName, network := range n {
+		if network.Mac != "" && strings.EqualFold(network.Mac, mac) {
+			return networkName, true
+		}
+
+		for _, member := range network.Bond.Members {
+			if strings.EqualFold(member, mac) {
+				return networkName, true
+			}
+		}
+	}
+
+	return "", false
+}
+
 func (n Networks) DefaultNetworkFor(category string) (Network, bool) {
 	if len(n) == 1 {
 		for _, net := range n {
@@ -190,6 +311,10 @@ func (n Network) IsDHCP() bool {
 	return n.Resolved || !isStatic
 }
 
+func (n Network) IsBond() bool {
+	return len(n.Bond.Members) > 0
+}
+
 func (n Network) isDynamic() bool {
 	return n.Type == NetworkTypeDynamic
 }
@@ -40,6 +40,33 @@ func init() {
 				})
 			})
 
+			Context("when the disk settings are hash with encryption key", func() {
+				BeforeEach(func() {
+					settings = Settings{
+						Disks: Disks{
+							Persistent: map[string]interface{}{
+								"fake-disk-id": map[string]interface{}{
+									"volume_id":      "fake-disk-volume-id",
+									"path":           "fake-disk-path",
+									"encryption_key": "fake-encryption-key",
+								},
+							},
+						},
+					}
+				})
+
+				It("returns disk settings with encryption key", func() {
+					diskSettings, found := settings.PersistentDiskSettings("fake-disk-id")
+					Expect(found).To(BeTrue())
+					Expect(diskSettings).To(Equal(DiskSettings{
+						ID:            "fake-disk-id",
+						VolumeID:      "fake-disk-volume-id",
+						Path:          "fake-disk-path",
+						EncryptionKey: "fake-encryption-key",
+					}))
+				})
+			})
+
 			Context("when the disk settings is a string", func() {
 				BeforeEach(func() {
 					settings = Settings{
@@ -81,22 +108,107 @@ func init() {
 			})
 		})
 
-		Describe("EphemeralDiskSettings", func() {
-			BeforeEach(func() {
+		Describe("EphemeralDisksSettings", func() {
+			It("converts disk settings", func() {
 				settings = Settings{
 					Disks: Disks{
 						Ephemeral: "fake-disk-value",
 					},
 				}
+
+				Expect(settings.EphemeralDisksSettings()).To(Equal([]DiskSettings{
+					{
+						ID:       "",
+						VolumeID: "fake-disk-value",
+						Path:     "fake-disk-value",
+					},
+				}))
 			})
 
-			It("converts disk settings", func() {
-				Expect(settings.EphemeralDiskSettings()).To(Equal(DiskSettings{
-					ID:       "",
-					VolumeID: "fake-disk-value",
-					Path:     "fake-disk-value",
+			It("converts settings of multiple disks", func() {
+				settings = Settings{
+					Disks: Disks{
+						Ephemeral: []interface{}{"fake-disk-value-1", "fake-disk-value-2"},
+					},
+				}
+
+				Expect(settings.EphemeralDisksSettings()).To(Equal([]DiskSettings{
+					{VolumeID: "fake-disk-value-1", Path: "fake-disk-value-1"},
+					{VolumeID: "fake-disk-value-2", Path: "fake-disk-value-2"},
 				}))
 			})
+
+			It("returns no disk settings when ephemeral disk is not specified", func() {
+				settings = Settings{}
+				Expect(settings.EphemeralDisksSettings()).To(BeEmpty())
+
+				settings.Disks.Ephemeral = ""
+				Expect(settings.EphemeralDisksSettings()).To(BeEmpty())
+			})
+		})
+
+		Describe("FQDN", func() {
+			It("qualifies agent ID with the first search domain of the network that is default for DNS", func() {
+				settings = Settings{
+					AgentID: "fake-agent-id",
+					Networks: Networks{
+						"fake-net1": Network{DNSSearch: []string{"other.internal"}},
+						"fake-net2": Network{Default: []string{"dns"}, DNSSearch: []string{"bosh.internal", "example.com"}},
+					},
+				}
+
+				Expect(settings.FQDN()).To(Equal("fake-agent-id.bosh.internal"))
+			})
+
+			It("returns agent ID when there are no search domains", func() {
+				settings = Settings{
+					AgentID:  "fake-agent-id",
+					Networks: Networks{"fake-net": Network{DNS: []string{"8.8.8.8"}}},
+				}
+
+				Expect(settings.FQDN()).To(Equal("fake-agent-id"))
+			})
+		})
+
+		Describe("IPv6Addresses", func() {
+			It("returns IPv6 addresses of networks that have them", func() {
+				networks := Networks{
+					"fake-net1": Network{IP: "10.0.0.5", IPv6: "2001:db8::5", IPv6Prefix: 64},
+					"fake-net2": Network{IP: "10.0.1.5"},
+				}
+
+				Expect(networks.IPv6Addresses()).To(Equal(map[string]string{"fake-net1": "2001:db8::5"}))
+			})
+
+			It("returns nil when no network has IPv6 address", func() {
+				networks := Networks{"fake-net": Network{IP: "10.0.0.5"}}
+				Expect(networks.IPv6Addresses()).To(BeNil())
+			})
+		})
+
+		Describe("NetworkForMAC", func() {
+			networks := Networks{
+				"fake-net1": Network{Mac: "aa:bb:cc:dd:ee:01"},
+				"fake-net2": Network{Bond: Bond{Members: []string{"aa:bb:cc:dd:ee:02", "aa:bb:cc:dd:ee:03"}}},
+				"fake-net3": Network{},
+			}
+
+			It("returns network that uses interface with given MAC address", func() {
+				networkName, found := networks.NetworkForMAC("AA:BB:CC:DD:EE:01")
+				Expect(found).To(BeTrue())
+				Expect(networkName).To(Equal("fake-net1"))
+			})
+
+			It("returns network that uses interface as a bond member", func() {
+				networkName, found := networks.NetworkForMAC("aa:bb:cc:dd:ee:03")
+				Expect(found).To(BeTrue())
+				Expect(networkName).To(Equal("fake-net2"))
+			})
+
+			It("returns found=false when no network uses the interface", func() {
+				_, found := networks.NetworkForMAC("aa:bb:cc:dd:ee:04")
+				Expect(found).To(BeFalse())
+			})
 		})
 
 		Describe("DefaultNetworkFor", func() {
@@ -272,6 +384,40 @@ func init() {
 				"map":    map[string]interface{}{},
 			}))
 		})
+		It("unmarshals single ephemeral disk of previous settings format", func() {
+			var settings Settings
+			settingsJSON := `{"disks":{"ephemeral":"/dev/sdb","persistent":{}}}`
+
+			err := json.Unmarshal([]byte(settingsJSON), &settings)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings.EphemeralDisksSettings()).To(Equal([]DiskSettings{
+				{VolumeID: "/dev/sdb", Path: "/dev/sdb"},
+			}))
+		})
+
+		It("unmarshals multiple ephemeral disks", func() {
+			var settings Settings
+			settingsJSON := `{"disks":{"ephemeral":["/dev/sdb","/dev/sdc"]}}`
+
+			err := json.Unmarshal([]byte(settingsJSON), &settings)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings.EphemeralDisksSettings()).To(Equal([]DiskSettings{
+				{VolumeID: "/dev/sdb", Path: "/dev/sdb"},
+				{VolumeID: "/dev/sdc", Path: "/dev/sdc"},
+			}))
+		})
+
+		It("unmarshals static routes and policy routing of networks", func() {
+			var settings Settings
+			settingsJSON := `{"networks":{"net":{"policy_routing":true,"routes":[{"destination":"10.0.0.0","netmask":"255.0.0.0","gateway":"1.2.3.1"}]}}}`
+
+			err := json.Unmarshal([]byte(settingsJSON), &settings)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings.Networks["net"].PolicyRouting).To(BeTrue())
+			Expect(settings.Networks["net"].Routes).To(Equal([]Route{
+				{Destination: "10.0.0.0", Netmask: "255.0.0.0", Gateway: "1.2.3.1"},
+			}))
+		})
 	})
 
 	Describe("Network", func() {
@@ -328,5 +474,17 @@ func init() {
 				})
 			})
 		})
+
+		Describe("IsBond", func() {
+			It("returns true when bond members are specified", func() {
+				network.Bond = Bond{Members: []string{"fake-mac-1", "fake-mac-2"}}
+				Expect(network.IsBond()).To(BeTrue())
+			})
+
+			It("returns false when bond members are not specified", func() {
+				network.Bond = Bond{Name: "bond1"}
+				Expect(network.IsBond()).To(BeFalse())
+			})
+		})
 	})
 }
@@ -95,6 +95,9 @@ type FakeFileInfo struct {
 }
 
 func (fi FakeFileInfo) Size() int64 {
+	if fi.file.Contents == nil && fi.file.Stats != nil {
+		return int64(len(fi.file.Stats.Content))
+	}
 	return int64(len(fi.file.Contents))
 }
 
@@ -102,6 +105,21 @@ func (fi FakeFileInfo) IsDir() bool {
 	return fi.file.Stats.FileType == FakeFileTypeDir
 }
 
+func (fi FakeFileInfo) Mode() os.FileMode {
+	if fi.file.Stats == nil {
+		return 0
+	}
+
+	switch fi.file.Stats.FileType {
+	case FakeFileTypeDir:
+		return fi.file.Stats.FileMode | os.ModeDir
+	case FakeFileTypeSymlink:
+		return fi.file.Stats.FileMode | os.ModeSymlink
+	}
+
+	return fi.file.Stats.FileMode
+}
+
 type FakeFile struct {
 	path string
 	fs   *FakeFileSystem
@@ -251,6 +269,10 @@ func (fs *FakeFileSystem) OpenFile(path string, flag int, perm os.FileMode) (bos
 		fs:   fs,
 	}
 
+	if flag&os.O_TRUNC == 0 {
+		file.Contents = stats.Content
+	}
+
 	return file, nil
 }
 